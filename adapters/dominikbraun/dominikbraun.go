@@ -0,0 +1,73 @@
+// Package dominikbraun converts between topo graphs and graphs from
+// github.com/dominikbraun/graph, so graphs stored with that library can be
+// sorted into layers and executed with topo.
+//
+// Edges in converted graphs point from a dependency to the vertices that
+// depend on it, matching that library's own topological sort, which orders
+// the source of every edge before its target.
+package dominikbraun
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dominikbraun/graph"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ToGraph returns a new graph whose vertices are the values of g. Traits
+// such as graph.Acyclic can be given as options; if graph.PreventCycles is
+// among them and g contains a cycle, graph.ErrEdgeCreatesCycle is
+// returned.
+func ToGraph[T comparable](g *topo.Graph[T], options ...func(*graph.Traits)) (graph.Graph[T, T], error) {
+	options = append([]func(*graph.Traits){graph.Directed()}, options...)
+	result := graph.New(func(value T) T { return value }, options...)
+
+	values := g.Nodes()
+	for _, value := range values {
+		if err := result.AddVertex(value); err != nil {
+			return nil, err
+		}
+	}
+	for _, value := range values {
+		for _, dep := range g.Dependencies(value) {
+			err := result.AddEdge(dep, value)
+			if err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// FromGraph returns a new topo graph with a node for every vertex hash of
+// g, depending on the hashes of its predecessors. The graph must be
+// directed. Its predecessor map has no order, so nodes and dependencies are
+// added in the order of their hashes formatted with fmt.Sprint, making the
+// result the same every time.
+func FromGraph[K comparable, T any](g graph.Graph[K, T]) (*topo.Graph[K], error) {
+	if !g.Traits().IsDirected {
+		return nil, errors.New("graph must be directed")
+	}
+	predecessors, err := g.PredecessorMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var result topo.Graph[K]
+	for _, hash := range sortedKeys(predecessors) {
+		result.AddNode(hash, sortedKeys(predecessors[hash]))
+	}
+	return &result, nil
+}
+
+// sortedKeys returns the keys of a map, sorted by how they're formatted.
+func sortedKeys[K comparable, V any](m map[K]V) []K {
+	return slices.SortedFunc(maps.Keys(m), func(a, b K) int {
+		return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	})
+}
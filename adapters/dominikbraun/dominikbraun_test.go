@@ -0,0 +1,87 @@
+package dominikbraun_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dominikbraun/graph"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/adapters/dominikbraun"
+)
+
+// TestToGraph checks that the converted graph sorts the same way.
+func TestToGraph(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "base", "lib"})
+	g.AddNode("lib", []string{"base"})
+
+	converted, err := dominikbraun.ToGraph(&g, graph.Acyclic(), graph.PreventCycles())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	order, err := graph.TopologicalSort(converted)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"base", "lib", "app"}
+	if !slices.Equal(order, expected) {
+		t.Errorf("Expected %v, got %v", expected, order)
+	}
+
+	var cyclic topo.Graph[string]
+	cyclic.AddNode("a", []string{"b"})
+	cyclic.AddNode("b", []string{"a"})
+	_, err = dominikbraun.ToGraph(&cyclic, graph.PreventCycles())
+	if !errors.Is(err, graph.ErrEdgeCreatesCycle) {
+		t.Errorf("Expected error %v, got %v", graph.ErrEdgeCreatesCycle, err)
+	}
+}
+
+// TestFromGraph checks converting a graph into a topo graph.
+func TestFromGraph(t *testing.T) {
+	type image struct {
+		name string
+	}
+	g := graph.New(func(img image) string { return img.name }, graph.Directed())
+	for _, name := range []string{"base", "app", "cache", "test"} {
+		if err := g.AddVertex(image{name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, edge := range [][2]string{{"base", "app"}, {"base", "cache"}, {"app", "test"}, {"cache", "test"}} {
+		if err := g.AddEdge(edge[0], edge[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	converted, err := dominikbraun.FromGraph(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	layers, err := converted.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range layers {
+		slices.Sort(layers[i])
+	}
+	expected := [][]string{{"base"}, {"app", "cache"}, {"test"}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+	// the predecessor map has no order, so nodes are added sorted
+	expectedNodes := []string{"app", "base", "cache", "test"}
+	if nodes := converted.Nodes(); !slices.Equal(nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, nodes)
+	}
+	if deps := converted.Dependencies("test"); !slices.Equal(deps, []string{"app", "cache"}) {
+		t.Errorf("Expected test to depend on [app cache], got %v", deps)
+	}
+
+	if _, err := dominikbraun.FromGraph(graph.New(graph.StringHash)); err == nil {
+		t.Errorf("Expected error converting an undirected graph")
+	}
+}
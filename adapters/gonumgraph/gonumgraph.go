@@ -0,0 +1,195 @@
+// Package gonumgraph converts between topo graphs and gonum graphs, so
+// gonum's algorithms can be used on graphs built with topo and vice versa.
+//
+// Edges in gonum graphs point from a dependency to the nodes that depend
+// on it. This matches gonum's own topological sort, which orders the
+// source of every edge before its target.
+package gonumgraph
+
+import (
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/iterator"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Node is a node of a [Directed] graph, carrying the topo value it
+// represents.
+type Node[T comparable] struct {
+	id    int64
+	Value T
+}
+
+// ID implements graph.Node.
+func (n Node[T]) ID() int64 {
+	return n.id
+}
+
+// Edge is an edge of a [Directed] graph, from a dependency to a node
+// depending on it.
+type Edge[T comparable] struct {
+	F, T Node[T]
+}
+
+// From implements graph.Edge.
+func (e Edge[T]) From() graph.Node { return e.F }
+
+// To implements graph.Edge.
+func (e Edge[T]) To() graph.Node { return e.T }
+
+// ReversedEdge implements graph.Edge.
+func (e Edge[T]) ReversedEdge() graph.Edge { return Edge[T]{F: e.T, T: e.F} }
+
+// Directed is a gonum graph.Directed view of a snapshot of a topo graph.
+// Node IDs are assigned in the order values were first added to the
+// topo graph, starting at zero.
+type Directed[T comparable] struct {
+	nodes []Node[T]
+	ids   map[T]int64
+	// from maps a node to its dependents, to maps a node to its
+	// dependencies
+	from, to [][]int64
+}
+
+// FromTopo returns a gonum view of the current state of g. Later changes
+// to g are not reflected in the view.
+func FromTopo[T comparable](g *topo.Graph[T]) *Directed[T] {
+	values := g.Nodes()
+	d := &Directed[T]{
+		nodes: make([]Node[T], len(values)),
+		ids:   make(map[T]int64, len(values)),
+		from:  make([][]int64, len(values)),
+		to:    make([][]int64, len(values)),
+	}
+	for i, value := range values {
+		d.nodes[i] = Node[T]{id: int64(i), Value: value}
+		d.ids[value] = int64(i)
+	}
+	for _, value := range values {
+		id := d.ids[value]
+		for _, dep := range g.Dependencies(value) {
+			depID := d.ids[dep]
+			if d.HasEdgeFromTo(depID, id) {
+				continue
+			}
+			d.from[depID] = append(d.from[depID], id)
+			d.to[id] = append(d.to[id], depID)
+		}
+	}
+	return d
+}
+
+// ID returns the ID of the node for value.
+func (d *Directed[T]) ID(value T) (int64, bool) {
+	id, ok := d.ids[value]
+	return id, ok
+}
+
+// Value returns the topo value of the node with the given ID.
+func (d *Directed[T]) Value(id int64) (T, bool) {
+	if !d.valid(id) {
+		var zero T
+		return zero, false
+	}
+	return d.nodes[id].Value, true
+}
+
+// Node implements graph.Graph.
+func (d *Directed[T]) Node(id int64) graph.Node {
+	if !d.valid(id) {
+		return nil
+	}
+	return d.nodes[id]
+}
+
+// Nodes implements graph.Graph.
+func (d *Directed[T]) Nodes() graph.Nodes {
+	nodes := make([]graph.Node, len(d.nodes))
+	for i, n := range d.nodes {
+		nodes[i] = n
+	}
+	return iterator.NewOrderedNodes(nodes)
+}
+
+// From implements graph.Graph, returning the nodes that depend on the
+// node with the given ID.
+func (d *Directed[T]) From(id int64) graph.Nodes {
+	if !d.valid(id) {
+		return graph.Empty
+	}
+	return d.nodesOf(d.from[id])
+}
+
+// To implements graph.Directed, returning the dependencies of the node
+// with the given ID.
+func (d *Directed[T]) To(id int64) graph.Nodes {
+	if !d.valid(id) {
+		return graph.Empty
+	}
+	return d.nodesOf(d.to[id])
+}
+
+// HasEdgeBetween implements graph.Graph.
+func (d *Directed[T]) HasEdgeBetween(xid, yid int64) bool {
+	return d.HasEdgeFromTo(xid, yid) || d.HasEdgeFromTo(yid, xid)
+}
+
+// HasEdgeFromTo implements graph.Directed.
+func (d *Directed[T]) HasEdgeFromTo(uid, vid int64) bool {
+	if !d.valid(uid) || !d.valid(vid) {
+		return false
+	}
+	for _, id := range d.from[uid] {
+		if id == vid {
+			return true
+		}
+	}
+	return false
+}
+
+// Edge implements graph.Graph.
+func (d *Directed[T]) Edge(uid, vid int64) graph.Edge {
+	if !d.HasEdgeFromTo(uid, vid) {
+		return nil
+	}
+	return Edge[T]{F: d.nodes[uid], T: d.nodes[vid]}
+}
+
+func (d *Directed[T]) valid(id int64) bool {
+	return id >= 0 && id < int64(len(d.nodes))
+}
+
+func (d *Directed[T]) nodesOf(ids []int64) graph.Nodes {
+	if len(ids) == 0 {
+		return graph.Empty
+	}
+	nodes := make([]graph.Node, len(ids))
+	for i, id := range ids {
+		nodes[i] = d.nodes[id]
+	}
+	return iterator.NewOrderedNodes(nodes)
+}
+
+// ToTopo builds a topo graph from a gonum directed graph, where an edge
+// from u to v means v depends on u. The value function gives the topo
+// value for each gonum node; for graphs created by [FromTopo], use
+// [NodeValue].
+func ToTopo[T comparable](g graph.Directed, value func(graph.Node) T) *topo.Graph[T] {
+	var result topo.Graph[T]
+	nodes := graph.NodesOf(g.Nodes())
+	for _, n := range nodes {
+		deps := graph.NodesOf(g.To(n.ID()))
+		values := make([]T, len(deps))
+		for i, dep := range deps {
+			values[i] = value(dep)
+		}
+		result.AddNode(value(n), values)
+	}
+	return &result
+}
+
+// NodeValue returns the value of a node from a [Directed] graph. It can
+// be passed to [ToTopo].
+func NodeValue[T comparable](n graph.Node) T {
+	return n.(Node[T]).Value
+}
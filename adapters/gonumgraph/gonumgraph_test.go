@@ -0,0 +1,76 @@
+package gonumgraph_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+	gonumtopo "gonum.org/v1/gonum/graph/topo"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/adapters/gonumgraph"
+)
+
+// TestFromTopo checks that gonum algorithms see the same graph.
+func TestFromTopo(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "base"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+
+	d := gonumgraph.FromTopo(&g)
+	sorted, err := gonumtopo.Sort(d)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	position := make(map[string]int)
+	for i, n := range sorted {
+		position[gonumgraph.NodeValue[string](n)] = i
+	}
+	for _, value := range g.Nodes() {
+		for _, dep := range g.Dependencies(value) {
+			if position[dep] >= position[value] {
+				t.Errorf("Expected %s before %s in %v", dep, value, position)
+			}
+		}
+	}
+
+	base, _ := d.ID("base")
+	app, _ := d.ID("app")
+	if !d.HasEdgeFromTo(base, app) || d.HasEdgeFromTo(app, base) {
+		t.Errorf("Expected edge from base to app")
+	}
+	if got := len(graph.NodesOf(d.From(base))); got != 3 {
+		t.Errorf("Expected 3 dependents of base, got %d", got)
+	}
+	if value, ok := d.Value(app); !ok || value != "app" {
+		t.Errorf("Expected app, got %v", value)
+	}
+	if d.Node(42) != nil {
+		t.Errorf("Expected no node for unknown ID")
+	}
+}
+
+// TestToTopo checks converting a gonum graph into a topo graph.
+func TestToTopo(t *testing.T) {
+	gg := simple.NewDirectedGraph()
+	gg.SetEdge(gg.NewEdge(simple.Node(1), simple.Node(2)))
+	gg.SetEdge(gg.NewEdge(simple.Node(1), simple.Node(3)))
+	gg.SetEdge(gg.NewEdge(simple.Node(2), simple.Node(4)))
+	gg.SetEdge(gg.NewEdge(simple.Node(3), simple.Node(4)))
+
+	g := gonumgraph.ToTopo(gg, graph.Node.ID)
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range layers {
+		slices.Sort(layers[i])
+	}
+	expected := [][]int64{{1}, {2, 3}, {4}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
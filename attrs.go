@@ -0,0 +1,72 @@
+package topo
+
+import (
+	"maps"
+	"slices"
+)
+
+// SetAttr sets an attribute of a value, like its owner or a link to its
+// documentation, so that metadata can be kept with the graph rather than
+// in maps beside it. Attributes are kept by Clone, OnlyKinds, PruneTo,
+// PruneFrom, and Merge, and written by exporters that support them. They
+// aren't changes to the graph's structure: they don't notify observers and
+// aren't recorded by EnableHistory on their own, but Undo and Redo put them
+// back as they were with the nodes, so undoing RemoveNode restores them.
+func (g *Graph[T]) SetAttr(value T, key, attr string) {
+	value = g.normalize(value)
+	if g.attrs == nil {
+		g.attrs = make(map[T]map[string]string)
+	}
+	if g.attrs[value] == nil {
+		g.attrs[value] = make(map[string]string)
+	}
+	g.attrs[value][key] = attr
+}
+
+// DeleteAttr removes an attribute of a value.
+func (g *Graph[T]) DeleteAttr(value T, key string) {
+	value = g.normalize(value)
+	delete(g.attrs[value], key)
+	if len(g.attrs[value]) == 0 {
+		delete(g.attrs, value)
+	}
+}
+
+// Attr returns an attribute of a value, and whether it's set.
+func (g *Graph[T]) Attr(value T, key string) (string, bool) {
+	attr, ok := g.attrs[value][key]
+	return attr, ok
+}
+
+// Attrs returns a copy of every attribute of a value, or nil if it has
+// none.
+func (g *Graph[T]) Attrs(value T) map[string]string {
+	return maps.Clone(g.attrs[value])
+}
+
+// Clone returns a copy of the graph, with its pins, attributes, and
+// options like WithKeyNormalizer, but without its observers or history.
+func (g *Graph[T]) Clone() *Graph[T] {
+	return &Graph[T]{
+		nodes:      slices.Clone(g.nodes),
+		pins:       maps.Clone(g.pins),
+		attrs:      cloneAttrs(g.attrs, nil),
+		normalizer: g.normalizer,
+		zeroGuard:  g.zeroGuard,
+	}
+}
+
+// cloneAttrs copies attributes, only of the values keep returns true for,
+// or of every value if keep is nil.
+func cloneAttrs[T comparable](attrs map[T]map[string]string, keep func(T) bool) map[T]map[string]string {
+	if attrs == nil {
+		return nil
+	}
+	clone := make(map[T]map[string]string, len(attrs))
+	for value, a := range attrs {
+		if keep == nil || keep(value) {
+			clone[value] = maps.Clone(a)
+		}
+	}
+	return clone
+}
@@ -0,0 +1,100 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestAttrs checks setting, reading, and removing attributes.
+func TestAttrs(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.SetAttr("app", "owner", "team-x")
+	g.SetAttr("app", "tier", "1")
+	g.SetAttr("lib", "owner", "team-y")
+
+	if attr, ok := g.Attr("app", "owner"); !ok || attr != "team-x" {
+		t.Errorf("Expected team-x, got %q (%v)", attr, ok)
+	}
+	if _, ok := g.Attr("app", "missing"); ok {
+		t.Errorf("Expected missing attribute not to be set")
+	}
+	g.DeleteAttr("app", "tier")
+	expected := map[string]string{"owner": "team-x"}
+	if attrs := g.Attrs("app"); !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected %v, got %v", expected, attrs)
+	}
+	g.Attrs("app")["owner"] = "changed"
+	if attr, _ := g.Attr("app", "owner"); attr != "team-x" {
+		t.Errorf("Expected Attrs to return a copy, got %q", attr)
+	}
+
+	g.RemoveNode("lib")
+	if attrs := g.Attrs("lib"); attrs != nil {
+		t.Errorf("Expected removed node's attributes to be gone, got %v", attrs)
+	}
+}
+
+// TestAttrsCarried checks that attributes are kept by graphs derived from
+// one.
+func TestAttrsCarried(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+	g.AddNode("tool", nil)
+	g.SetAttr("app", "owner", "team-x")
+	g.SetAttr("tool", "owner", "team-z")
+
+	tests := []struct {
+		name     string
+		graph    *topo.Graph[string]
+		app      map[string]string
+		tool     map[string]string
+		original map[string]string
+	}{
+		{"clone", g.Clone(), map[string]string{"owner": "team-x"}, map[string]string{"owner": "team-z"}, nil},
+		{"only kinds", g.OnlyKinds(topo.DefaultEdgeKind), map[string]string{"owner": "team-x"}, map[string]string{"owner": "team-z"}, nil},
+		{"prune", g.PruneTo([]string{"app"}), map[string]string{"owner": "team-x"}, nil, nil},
+	}
+	for _, test := range tests {
+		if attrs := test.graph.Attrs("app"); !reflect.DeepEqual(attrs, test.app) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.app, attrs)
+		}
+		if attrs := test.graph.Attrs("tool"); !reflect.DeepEqual(attrs, test.tool) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.tool, attrs)
+		}
+		// changing the copy leaves the original alone
+		test.graph.SetAttr("app", "owner", "changed")
+		if attr, _ := g.Attr("app", "owner"); attr != "team-x" {
+			t.Errorf("%s: expected original to keep team-x, got %q", test.name, attr)
+		}
+	}
+}
+
+// TestMergeAttrs checks merging attributes changed on either side.
+func TestMergeAttrs(t *testing.T) {
+	var base topo.Graph[string]
+	base.AddNode("app", nil)
+	base.SetAttr("app", "owner", "team-x")
+	base.SetAttr("app", "tier", "1")
+	base.SetAttr("app", "old", "yes")
+
+	ours := base.Clone()
+	ours.SetAttr("app", "owner", "team-y")
+	ours.SetAttr("app", "tier", "2")
+	theirs := base.Clone()
+	theirs.SetAttr("app", "tier", "3")
+	theirs.DeleteAttr("app", "old")
+	theirs.SetAttr("app", "link", "wiki")
+
+	merged, conflicts := topo.Merge(&base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+	expected := map[string]string{"owner": "team-y", "tier": "2", "link": "wiki"}
+	if attrs := merged.Attrs("app"); !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected %v, got %v", expected, attrs)
+	}
+}
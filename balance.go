@@ -0,0 +1,131 @@
+package topo
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// SortByLayersBalanced is like SortByLayers, but moves nodes into later
+// layers, where their dependents leave room, to even out the work in each
+// layer. An executor that waits for each layer to finish before starting
+// the next spends as long on a layer as its slowest node takes, so a slow
+// node that nothing needs soon is better off in a layer that already waits
+// on something as slow.
+//
+// The number of layers stays the same. Nodes on the longest chain of
+// dependencies can't move, and neither can nodes pinned by PinLayer; every
+// other node goes in the layer within its range where it adds least to the
+// layer's longest duration, preferring the layer with less total work, and
+// then the earliest. Nodes missing from durations take no time.
+func (g *Graph[T]) SortByLayersBalanced(durations map[T]time.Duration) ([][]T, error) {
+	layers, err := g.SortByLayers()
+	if err != nil || len(layers) == 0 {
+		return layers, err
+	}
+	_, dependsOn := g.edges()
+	dependedOnBy := make(map[T][]T)
+	earliest := make(map[T]int)
+	for i, layer := range layers {
+		for _, value := range layer {
+			earliest[value] = i
+			for _, dep := range dependsOn[value] {
+				dependedOnBy[dep] = append(dependedOnBy[dep], value)
+			}
+		}
+	}
+	latest := make(map[T]int)
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, value := range layers[i] {
+			l := len(layers) - 1
+			for _, dependent := range dependedOnBy[value] {
+				l = min(l, latest[dependent]-1)
+			}
+			if pin, ok := g.pins[value]; ok && pin.exact {
+				l = i
+			}
+			latest[value] = l
+		}
+	}
+
+	longest := make([]time.Duration, len(layers))
+	total := make([]time.Duration, len(layers))
+	assigned := make(map[T]int)
+	assign := func(value T, layer int) {
+		assigned[value] = layer
+		longest[layer] = max(longest[layer], durations[value])
+		total[layer] += durations[value]
+	}
+	for _, layer := range layers {
+		for _, value := range layer {
+			if earliest[value] == latest[value] {
+				assign(value, earliest[value])
+			}
+		}
+	}
+	// dependencies are always in earlier layers, so they're placed first
+	for _, layer := range layers {
+		for _, value := range layer {
+			if _, ok := assigned[value]; ok {
+				continue
+			}
+			lo := 0
+			for _, dep := range dependsOn[value] {
+				lo = max(lo, assigned[dep]+1)
+			}
+			best := lo
+			cost := func(l int) time.Duration {
+				return max(0, durations[value]-longest[l])
+			}
+			for l := lo + 1; l <= latest[value]; l++ {
+				if cost(l) < cost(best) || cost(l) == cost(best) && total[l] < total[best] {
+					best = l
+				}
+			}
+			assign(value, best)
+		}
+	}
+
+	result := make([][]T, len(layers))
+	for _, layer := range layers {
+		for _, value := range layer {
+			result[assigned[value]] = append(result[assigned[value]], value)
+		}
+	}
+	return result, nil
+}
+
+// PackLayers splits each layer among a fixed number of workers, for
+// executors that can't run a whole layer at once. For each layer, it
+// returns one queue per worker, each to be run in order; a layer is done
+// when every queue is. Values are assigned longest first to the worker with
+// the least work so far, which keeps a layer's slowest queue within a third
+// of the best possible.
+//
+// Workers with nothing to do get no queue, so a layer can have fewer queues
+// than workers. Values missing from durations take no time, and fewer than
+// one worker is treated as one.
+func PackLayers[T comparable](layers [][]T, durations map[T]time.Duration, workers int) [][][]T {
+	workers = max(workers, 1)
+	packed := make([][][]T, len(layers))
+	for i, layer := range layers {
+		values := slices.Clone(layer)
+		slices.SortStableFunc(values, func(a, b T) int {
+			return cmp.Compare(durations[b], durations[a])
+		})
+		queues := make([][]T, min(workers, len(values)))
+		load := make([]time.Duration, len(queues))
+		for _, value := range values {
+			w := 0
+			for j := range load {
+				if load[j] < load[w] {
+					w = j
+				}
+			}
+			queues[w] = append(queues[w], value)
+			load[w] += durations[value]
+		}
+		packed[i] = queues
+	}
+	return packed
+}
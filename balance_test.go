@@ -0,0 +1,116 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestSortByLayersBalanced runs some basic test cases.
+func TestSortByLayersBalanced(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     [][]string
+		durations map[string]time.Duration
+		expected  [][]string
+	}{
+		{
+			name:      "slow node joins slow layer",
+			nodes:     [][]string{{"a"}, {"b"}, {"c", "a"}},
+			durations: map[string]time.Duration{"a": time.Second, "b": 10 * time.Second, "c": 10 * time.Second},
+			expected:  [][]string{{"a"}, {"b", "c"}},
+		},
+		{
+			name:      "less total work",
+			nodes:     [][]string{{"x"}, {"y", "x"}, {"z"}, {"w"}},
+			durations: map[string]time.Duration{"x": 5 * time.Second, "y": 5 * time.Second, "z": 2 * time.Second, "w": 2 * time.Second},
+			expected:  [][]string{{"x", "z"}, {"w", "y"}},
+		},
+		{
+			name:  "dependencies kept before dependents",
+			nodes: [][]string{{"a"}, {"b", "a"}, {"c", "b"}, {"p"}, {"q", "p"}},
+			durations: map[string]time.Duration{
+				"a": time.Second, "b": time.Second, "c": 10 * time.Second,
+				"p": 10 * time.Second, "q": 10 * time.Second,
+			},
+			expected: [][]string{{"a", "p"}, {"b"}, {"q", "c"}},
+		},
+		{
+			name:     "no durations",
+			nodes:    [][]string{{"a"}, {"b", "a"}, {"c"}},
+			expected: [][]string{{"a", "c"}, {"b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			for _, node := range tt.nodes {
+				g.AddNode(node[0], node[1:])
+			}
+			layers, err := g.SortByLayersBalanced(tt.durations)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(layers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, layers)
+			}
+		})
+	}
+
+	var g topo.Graph[string]
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+	if _, err := g.SortByLayersBalanced(nil); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestPackLayers runs some basic test cases.
+func TestPackLayers(t *testing.T) {
+	durations := map[string]time.Duration{
+		"a": 7 * time.Second, "b": 5 * time.Second, "c": 4 * time.Second,
+		"d": 3 * time.Second, "e": 3 * time.Second, "f": time.Second,
+	}
+	tests := []struct {
+		name     string
+		layers   [][]string
+		workers  int
+		expected [][][]string
+	}{
+		{
+			name:     "longest first",
+			layers:   [][]string{{"f", "e", "d", "c", "b", "a"}},
+			workers:  2,
+			expected: [][][]string{{{"a", "e", "f"}, {"b", "c", "d"}}},
+		},
+		{
+			name:     "fewer values than workers",
+			layers:   [][]string{{"a", "b"}, {"c"}},
+			workers:  3,
+			expected: [][][]string{{{"a"}, {"b"}}, {{"c"}}},
+		},
+		{
+			name:     "one worker",
+			layers:   [][]string{{"f", "unknown", "a"}},
+			workers:  0,
+			expected: [][][]string{{{"a", "f", "unknown"}}},
+		},
+		{
+			name:     "empty",
+			layers:   nil,
+			workers:  2,
+			expected: [][][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packed := topo.PackLayers(tt.layers, durations, tt.workers)
+			if !reflect.DeepEqual(packed, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, packed)
+			}
+		})
+	}
+}
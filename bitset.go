@@ -0,0 +1,41 @@
+package topo
+
+// bitset is a set of small non-negative integers, like the positions of
+// values in a graph, that's cheaper to use than a map[T]bool.
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (s bitset) set(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s bitset) unset(i int) {
+	s[i/64] &^= 1 << (i % 64)
+}
+
+func (s bitset) has(i int) bool {
+	return s[i/64]&(1<<(i%64)) != 0
+}
+
+// intern returns each value's dependencies and dependents by their
+// position in order, as given by index.
+func intern[T comparable](order []T, dependsOn map[T][]T, index map[T]int32) (deps, dependents [][]int32) {
+	deps = make([][]int32, len(order))
+	dependents = make([][]int32, len(order))
+	for i, value := range order {
+		d := dependsOn[value]
+		if len(d) == 0 {
+			continue
+		}
+		deps[i] = make([]int32, len(d))
+		for j, dep := range d {
+			k := index[dep]
+			deps[i][j] = k
+			dependents[k] = append(dependents[k], int32(i))
+		}
+	}
+	return deps, dependents
+}
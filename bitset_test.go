@@ -0,0 +1,36 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestSortLarge checks sorting a graph spanning many bitset words, against
+// the definition of a layering.
+func TestSortLarge(t *testing.T) {
+	g := denseGraph(1_000, 4)
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := g.ValidateLayers(layers); err != nil {
+		t.Errorf("Expected valid layers, got %v", err)
+	}
+	order, err := g.SortStable()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if flat := topo.Flatten(layers, nil); len(flat) != len(order) {
+		t.Errorf("Expected %d values, got %d", len(flat), len(order))
+	}
+	// every node's dependencies are earlier, so this order is already stable
+	expected := make([]int, 1_000)
+	for i := range expected {
+		expected[i] = i
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected values in the order they were added, got %v", order)
+	}
+}
@@ -0,0 +1,86 @@
+package topo
+
+import (
+	"slices"
+	"sync"
+)
+
+// analysis holds results computed from a graph, so that asking for them
+// again before the graph changes doesn't compute them again. A graph drops
+// its analysis whenever it changes.
+type analysis[T comparable] struct {
+	mu        sync.Mutex
+	order     []T
+	dependsOn map[T][]T
+	index     map[T]int32
+	// deps and dependents are by position in order; see interned
+	deps, dependents [][]int32
+	sorted           bool
+	layers           [][]T
+	layersErr        error
+	ancestors        map[T][]T
+}
+
+// analysis returns the graph's cached results, starting them if the graph
+// changed since they were last asked for.
+func (g *Graph[T]) analysis() *analysis[T] {
+	for {
+		old := g.cache.Load()
+		if a, _ := old.(*analysis[T]); a != nil {
+			return a
+		}
+		a := &analysis[T]{ancestors: make(map[T][]T)}
+		if g.cache.CompareAndSwap(old, a) {
+			return a
+		}
+	}
+}
+
+// invalidate drops the cached results, after the graph has changed.
+func (g *Graph[T]) invalidate() {
+	if g.cache.Load() != nil {
+		g.cache.Store((*analysis[T])(nil))
+	}
+}
+
+// sortedLayers returns the layers of a cached sort, computing them with
+// sort if they haven't been. They're copied, so callers can change them.
+func (a *analysis[T]) sortedLayers(sort func() ([][]T, error)) ([][]T, error) {
+	a.mu.Lock()
+	layers, err, sorted := a.layers, a.layersErr, a.sorted
+	a.mu.Unlock()
+	if !sorted {
+		layers, err = sort()
+		a.mu.Lock()
+		a.layers, a.layersErr, a.sorted = layers, err, true
+		a.mu.Unlock()
+	}
+	return cloneLayers(layers), err
+}
+
+// ancestorsOf returns the cached ancestors of a single value, computing
+// them with find if they haven't been. They're copied, as with layers.
+func (a *analysis[T]) ancestorsOf(value T, find func() []T) []T {
+	a.mu.Lock()
+	ancestors, ok := a.ancestors[value]
+	a.mu.Unlock()
+	if !ok {
+		ancestors = find()
+		a.mu.Lock()
+		a.ancestors[value] = ancestors
+		a.mu.Unlock()
+	}
+	return slices.Clone(ancestors)
+}
+
+// cloneLayers copies layers and each layer in them.
+func cloneLayers[T any](layers [][]T) [][]T {
+	if layers == nil {
+		return nil
+	}
+	clone := make([][]T, len(layers))
+	for i, layer := range layers {
+		clone[i] = slices.Clone(layer)
+	}
+	return clone
+}
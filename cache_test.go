@@ -0,0 +1,149 @@
+package topo_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestCacheInvalidation checks that cached results follow changes to the
+// graph.
+func TestCacheInvalidation(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	tests := []struct {
+		name      string
+		change    func()
+		layers    [][]string
+		ancestors []string
+	}{
+		{"unchanged", func() {}, [][]string{{"lib"}, {"app"}}, []string{"lib"}},
+		{"add node", func() { g.AddNode("lib", []string{"base"}) }, [][]string{{"base"}, {"lib"}, {"app"}}, []string{"lib", "base"}},
+		{"add dependency", func() { g.AddDependency("app", "log") }, [][]string{{"log", "base"}, {"lib"}, {"app"}}, []string{"lib", "log", "base"}},
+		{"remove dependency", func() { g.RemoveDependency("lib", "base") }, [][]string{{"lib", "log"}, {"app"}}, []string{"lib", "log"}},
+		{"pin", func() { g.PinLayer("log", 1) }, [][]string{{"lib"}, {"log"}, {"app"}}, []string{"lib", "log"}},
+		{"unpin", func() { g.Unpin("log") }, [][]string{{"lib", "log"}, {"app"}}, []string{"lib", "log"}},
+		{"remove node", func() { g.RemoveNode("lib") }, [][]string{{"log"}, {"app"}}, []string{"log"}},
+		{"apply", func() {
+			_ = g.Apply(func(tx *topo.Tx[string]) error {
+				tx.AddNode("app", []string{"db"})
+				tx.AddNode("db", nil)
+				tx.AddNode("log", nil)
+				return nil
+			})
+		}, [][]string{{"log", "db"}, {"app"}}, []string{"db"}},
+		{"changeset", func() {
+			_ = topo.Changeset[string]{{Op: topo.OpRemoveDependency, Value: "app", Deps: []string{"db"}}}.ApplyTo(&g)
+		}, [][]string{{"app", "log", "db"}}, nil},
+	}
+	for _, test := range tests {
+		// ask first, so that there's a cached result to invalidate
+		_, _ = g.SortByLayers()
+		_ = g.Ancestors("app")
+		test.change()
+
+		layers, err := g.SortByLayers()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(layers, test.layers) {
+			t.Errorf("%s: expected layers %v, got %v", test.name, test.layers, layers)
+		}
+		if got := g.Ancestors("app"); !reflect.DeepEqual(got, test.ancestors) {
+			t.Errorf("%s: expected ancestors %v, got %v", test.name, test.ancestors, got)
+		}
+	}
+}
+
+// TestCacheUndo checks that cached results follow Undo and Redo.
+func TestCacheUndo(t *testing.T) {
+	var g topo.Graph[string]
+	g.EnableHistory(0)
+	g.AddNode("app", nil)
+	g.AddNode("app", []string{"lib"})
+
+	expected := []string{"app", "lib"}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Expected %v, got %v", expected, nodes)
+	}
+	g.Undo()
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app"}) {
+		t.Errorf("Expected [app] after Undo, got %v", nodes)
+	}
+	g.Redo()
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Expected %v after Redo, got %v", expected, nodes)
+	}
+}
+
+// TestCacheCopies checks that changing returned results doesn't change the
+// cached ones.
+func TestCacheCopies(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+
+	layers, _ := g.SortByLayers()
+	layers[0][0] = "changed"
+	g.Nodes()[0] = "changed"
+	g.Ancestors("app")[0] = "changed"
+
+	expected := [][]string{{"lib"}, {"app"}}
+	if layers, _ := g.SortByLayers(); !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib"}) {
+		t.Errorf("Expected [app lib], got %v", nodes)
+	}
+	if ancestors := g.Ancestors("app"); !reflect.DeepEqual(ancestors, []string{"lib"}) {
+		t.Errorf("Expected [lib], got %v", ancestors)
+	}
+}
+
+// TestCacheGraphCopy checks that a Graph can be copied by value, and that
+// changing the copy doesn't change the results cached for the original.
+func TestCacheGraphCopy(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	_, _ = g.SortByLayers()
+
+	c := g
+	c.AddNode("lib", []string{"base"})
+
+	if layers, _ := g.SortByLayers(); !reflect.DeepEqual(layers, [][]string{{"lib"}, {"app"}}) {
+		t.Errorf("Expected the original's layers, got %v", layers)
+	}
+	expected := [][]string{{"base"}, {"lib"}, {"app"}}
+	if layers, _ := c.SortByLayers(); !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestCacheConcurrent checks that queries can run concurrently; run it
+// with -race.
+func TestCacheConcurrent(t *testing.T) {
+	var g topo.Graph[int]
+	for i := 1; i < 100; i++ {
+		g.AddNode(i, []int{i - 1})
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if layers, err := g.SortByLayers(); err != nil || len(layers) != 100 {
+					t.Errorf("Expected 100 layers, got %d (%v)", len(layers), err)
+				}
+				if ancestors := g.Ancestors(99); len(ancestors) != 99 {
+					t.Errorf("Expected 99 ancestors, got %d", len(ancestors))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
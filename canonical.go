@@ -0,0 +1,76 @@
+package topo
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EncodeCanonical writes the graph as text that's the same for equal
+// graphs however they were built, so that graph files kept in version
+// control change by a line for each value or dependency added or removed,
+// and can be compared with diff:
+//
+//	app
+//	app -> db (runtime)
+//	app -> lib
+//	db
+//	lib
+//
+// Each value has a line of its own, followed by a line for each of its
+// dependencies, and both are sorted by their text, as printed by fmt.
+// Dependencies of a kind other than DefaultEdgeKind have their kind in
+// parentheses. Text that's empty, or has spaces, parentheses, quotes, or
+// characters that can't be printed, is quoted as strconv.Quote does. Pins
+// and attributes aren't written.
+func (g *Graph[T]) EncodeCanonical(w io.Writer) error {
+	type edge struct {
+		dep, kind string
+	}
+	order, _ := g.edges()
+	edges := make(map[string][]edge, len(order))
+	for _, value := range order {
+		edges[canonicalText(value)] = nil
+	}
+	for _, decl := range g.declarations() {
+		value := canonicalText(decl.value)
+		for _, dep := range decl.deps {
+			e := edge{dep: canonicalText(dep)}
+			if decl.kind != DefaultEdgeKind {
+				e.kind = canonicalText(decl.kind)
+			}
+			edges[value] = append(edges[value], e)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, value := range slices.Sorted(maps.Keys(edges)) {
+		fmt.Fprintln(bw, value)
+		deps := edges[value]
+		slices.SortFunc(deps, func(a, b edge) int {
+			return cmp.Or(strings.Compare(a.dep, b.dep), strings.Compare(a.kind, b.kind))
+		})
+		for _, e := range slices.Compact(deps) {
+			if e.kind == "" {
+				fmt.Fprintf(bw, "%s -> %s\n", value, e.dep)
+			} else {
+				fmt.Fprintf(bw, "%s -> %s (%s)\n", value, e.dep, e.kind)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// canonicalText returns a value as EncodeCanonical writes it.
+func canonicalText(value any) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " ()\"") || strconv.Quote(s) != `"`+s+`"` {
+		return strconv.Quote(s)
+	}
+	return s
+}
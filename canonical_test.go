@@ -0,0 +1,79 @@
+package topo_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestEncodeCanonical checks that equal graphs are encoded the same,
+// however they were built.
+func TestEncodeCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(g *topo.Graph[string])
+		expected string
+	}{
+		{"empty", func(*topo.Graph[string]) {}, ""},
+		{
+			name: "sorted",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("lib", nil)
+				g.AddNode("app", []string{"lib", "db"})
+			},
+			expected: "app\napp -> db\napp -> lib\ndb\nlib\n",
+		},
+		{
+			name: "built differently",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("app", []string{"lib"})
+				g.AddNode("db", nil)
+				g.AddNode("app", []string{"db", "lib", "db"})
+			},
+			expected: "app\napp -> db\napp -> lib\ndb\nlib\n",
+		},
+		{
+			name: "kinds",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("app", []string{"lib"})
+				g.AddNodeOfKind("app", "runtime", []string{"db", "lib"})
+			},
+			expected: "app\napp -> db (runtime)\napp -> lib\napp -> lib (runtime)\ndb\nlib\n",
+		},
+		{
+			name: "quoted",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("my app", []string{"", "lib(v2)"})
+				g.AddNodeOfKind("my app", "run time", []string{"db"})
+			},
+			// sorted as written, so quoted text comes first
+			expected: "\"\"\n\"lib(v2)\"\n\"my app\"\n\"my app\" -> \"\"\n" +
+				"\"my app\" -> \"lib(v2)\"\n\"my app\" -> db (\"run time\")\ndb\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			tt.build(&g)
+			var out bytes.Buffer
+			if err := g.EncodeCanonical(&out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+
+	var g topo.Graph[int]
+	g.AddNode(10, []int{9})
+	var out bytes.Buffer
+	if err := g.EncodeCanonical(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// sorted as text, not as numbers
+	if expected := "10\n10 -> 9\n9\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,185 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrChangeConflict is returned by Changeset.ApplyTo when a change doesn't
+// fit the graph it's applied to.
+var ErrChangeConflict = errors.New("change does not apply")
+
+// Changeset is a list of changes that turns one graph into another. It can
+// be written as JSON, for an audit log of how a graph evolved or to send to
+// other processes keeping copies of it, applied to a graph, and inverted to
+// undo it.
+//
+// The changes in a changeset are simpler than the operations reported to
+// observers, so that each can be undone exactly:
+//
+//   - OpAddNode adds a node that isn't in the graph yet, with no
+//     dependencies.
+//   - OpRemoveNode removes a node without dependencies. Nodes depending on
+//     it still do, so it stays in the graph as their dependency.
+//   - OpAddDependency adds one dependency, of Kind, to a node that doesn't
+//     have it.
+//   - OpRemoveDependency removes one dependency, of Kind, from a node that
+//     has it.
+//
+// Changesets don't keep the order of each node's dependencies.
+type Changeset[T comparable] []Change[T]
+
+// Diff returns the changes that turn one graph into another: nodes added,
+// then dependencies added, then dependencies removed, then nodes removed,
+// each in the order the nodes were first added.
+func Diff[T comparable](from, to *Graph[T]) Changeset[T] {
+	var cs Changeset[T]
+	fromDecls, toDecls := from.declarations(), to.declarations()
+	fromDeps, toDeps := make(map[kindKey[T]][]T), make(map[kindKey[T]][]T)
+	fromNodes, toNodes := make(map[T]bool), make(map[T]bool)
+	for _, decl := range fromDecls {
+		fromDeps[kindKey[T]{decl.value, decl.kind}] = decl.deps
+		fromNodes[decl.value] = true
+	}
+	for _, decl := range toDecls {
+		toDeps[kindKey[T]{decl.value, decl.kind}] = decl.deps
+		toNodes[decl.value] = true
+	}
+
+	for _, value := range to.Nodes() {
+		if toNodes[value] && !fromNodes[value] {
+			cs = append(cs, Change[T]{Op: OpAddNode, Value: value})
+		}
+	}
+	edges := func(op ChangeOp, decls []node[T], other map[kindKey[T]][]T) {
+		for _, decl := range decls {
+			var done []T
+			for _, dep := range decl.deps {
+				if slices.Contains(done, dep) {
+					continue
+				}
+				done = append(done, dep)
+				if !slices.Contains(other[kindKey[T]{decl.value, decl.kind}], dep) {
+					cs = append(cs, Change[T]{Op: op, Value: decl.value, Kind: decl.kind, Deps: []T{dep}})
+				}
+			}
+		}
+	}
+	edges(OpAddDependency, toDecls, fromDeps)
+	edges(OpRemoveDependency, fromDecls, toDeps)
+	for _, value := range from.Nodes() {
+		if fromNodes[value] && !toNodes[value] {
+			cs = append(cs, Change[T]{Op: OpRemoveNode, Value: value})
+		}
+	}
+	return cs
+}
+
+// Invert returns the changes that undo this changeset.
+func (cs Changeset[T]) Invert() Changeset[T] {
+	inverse := make(Changeset[T], len(cs))
+	for i, c := range cs {
+		switch c.Op {
+		case OpAddNode:
+			c.Op = OpRemoveNode
+		case OpRemoveNode:
+			c.Op = OpAddNode
+		case OpAddDependency:
+			c.Op = OpRemoveDependency
+		case OpRemoveDependency:
+			c.Op = OpAddDependency
+		}
+		inverse[len(cs)-1-i] = c
+	}
+	return inverse
+}
+
+// ApplyTo makes the changes to a graph, all at once or not at all: if any
+// change doesn't fit the graph, like adding a node that's already there,
+// the graph is left as it was and an error wrapping ErrChangeConflict is
+// returned. Observers of the graph are notified of each change.
+func (cs Changeset[T]) ApplyTo(g *Graph[T]) error {
+	tx, changes := g.begin()
+	for _, c := range cs {
+		if err := tx.applyChange(c); err != nil {
+			return fmt.Errorf("%w: %v: %w", ErrChangeConflict, c, err)
+		}
+	}
+	g.commit(tx, *changes)
+	return nil
+}
+
+// applyChange makes a single change of a changeset. It's only used on the
+// copy of a graph in a transaction, so it changes the nodes in place.
+func (g *Graph[T]) applyChange(c Change[T]) error {
+	c.Value, c.Deps = g.normalize(c.Value), g.normalizeAll(c.Deps)
+	declared := false
+	last := -1
+	for i, n := range g.nodes {
+		if n.value == c.Value {
+			declared = true
+			if n.kind == c.Kind {
+				last = i
+			}
+		}
+	}
+
+	switch c.Op {
+	case OpAddNode:
+		if declared {
+			return errors.New("node already added")
+		}
+		if !g.allowNode(c.Value) {
+			return errors.New("zero value rejected")
+		}
+		g.add(c.Value, DefaultEdgeKind, nil)
+	case OpRemoveNode:
+		if !declared {
+			return errors.New("node not added")
+		}
+		if len(g.Dependencies(c.Value)) > 0 {
+			return errors.New("node has dependencies")
+		}
+		g.nodes = slices.DeleteFunc(g.nodes, func(n node[T]) bool {
+			return n.value == c.Value
+		})
+	case OpAddDependency, OpRemoveDependency:
+		if len(c.Deps) != 1 {
+			return errors.New("expected one dependency")
+		}
+		dep := c.Deps[0]
+		if !declared {
+			return errors.New("node not added")
+		}
+		has := last >= 0 && slices.Contains(g.nodes[last].deps, dep)
+		if c.Op == OpAddDependency {
+			if has {
+				return errors.New("dependency already added")
+			}
+			if len(g.allowDeps(c.Value, c.Deps)) == 0 {
+				return errors.New("zero value rejected")
+			}
+			if last < 0 {
+				g.add(c.Value, c.Kind, []T{dep})
+				break
+			}
+			g.nodes[last].deps = append(slices.Clip(g.nodes[last].deps), dep)
+		} else {
+			if !has {
+				return errors.New("dependency not added")
+			}
+			// earlier declarations of the kind too, so it's gone entirely
+			for i, n := range g.nodes {
+				if n.value == c.Value && n.kind == c.Kind {
+					g.nodes[i].deps = slices.DeleteFunc(slices.Clone(n.deps), func(d T) bool { return d == dep })
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown %v", c.Op)
+	}
+	g.invalidate()
+	g.notify(c)
+	return nil
+}
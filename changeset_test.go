@@ -0,0 +1,151 @@
+package topo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// describe returns the nodes of a graph with their dependencies of each
+// kind, for comparing graphs.
+func describe(g *topo.Graph[string]) map[string]map[topo.EdgeKind][]string {
+	d := make(map[string]map[topo.EdgeKind][]string)
+	for _, value := range g.Nodes() {
+		d[value] = make(map[topo.EdgeKind][]string)
+		for _, kind := range append(g.Kinds(), topo.DefaultEdgeKind) {
+			if deps := g.OnlyKinds(kind).Dependencies(value); len(deps) > 0 {
+				d[value][kind] = deps
+			}
+		}
+	}
+	return d
+}
+
+// TestChangeset checks diffing, applying, and inverting changesets.
+func TestChangeset(t *testing.T) {
+	var from topo.Graph[string]
+	from.AddNode("app", []string{"lib", "db"})
+	from.AddNode("lib", nil)
+	from.AddNode("old", []string{"lib"})
+	from.AddNodeOfKind("app", "runtime", []string{"cache"})
+
+	var to topo.Graph[string]
+	to.AddNode("app", []string{"lib", "queue"})
+	to.AddNode("lib", []string{"base"})
+	to.AddNode("worker", []string{"queue"})
+	to.AddNodeOfKind("app", "runtime", []string{"cache", "old"})
+
+	cs := topo.Diff(&from, &to)
+	var got []string
+	for _, c := range cs {
+		got = append(got, c.String())
+	}
+	expected := []string{
+		"add node worker",
+		"add dependency app [queue]",
+		"add dependency lib [base]",
+		"add dependency worker [queue]",
+		"add dependency app [old] (runtime)",
+		"remove dependency app [db]",
+		"remove dependency old [lib]",
+		"remove node old",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	var observed int
+	from.Observe(func(topo.Change[string]) { observed++ })
+	original := describe(&from)
+	if err := cs.ApplyTo(&from); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(describe(&from), describe(&to)) {
+		t.Errorf("Expected %v, got %v", describe(&to), describe(&from))
+	}
+	if observed != len(cs) {
+		t.Errorf("Expected %d changes observed, got %d", len(cs), observed)
+	}
+
+	if err := cs.Invert().ApplyTo(&from); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(describe(&from), original) {
+		t.Errorf("Expected %v, got %v", original, describe(&from))
+	}
+	if cs := topo.Diff(&to, &to); len(cs) != 0 {
+		t.Errorf("Expected no changes, got %v", cs)
+	}
+}
+
+// TestChangesetJSON checks that changesets survive being written as JSON.
+func TestChangesetJSON(t *testing.T) {
+	cs := topo.Changeset[string]{
+		{Op: topo.OpAddNode, Value: "app"},
+		{Op: topo.OpAddDependency, Value: "app", Kind: "runtime", Deps: []string{"db"}},
+	}
+	data, err := json.Marshal(cs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[{"op":"add node","value":"app"},{"op":"add dependency","value":"app","kind":"runtime","deps":["db"]}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	var decoded topo.Changeset[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cs) {
+		t.Errorf("Expected %v, got %v", cs, decoded)
+	}
+	if err := json.Unmarshal([]byte(`[{"op":"rename node"}]`), &decoded); err == nil {
+		t.Errorf("Expected error for unknown operation")
+	}
+}
+
+// TestChangesetConflict checks that changes that don't fit leave the graph
+// unchanged.
+func TestChangesetConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  topo.Change[string]
+		message string
+	}{
+		{"add existing node", topo.Change[string]{Op: topo.OpAddNode, Value: "app"}, "node already added"},
+		{"remove missing node", topo.Change[string]{Op: topo.OpRemoveNode, Value: "nope"}, "node not added"},
+		{"remove node with dependencies", topo.Change[string]{Op: topo.OpRemoveNode, Value: "app"}, "node has dependencies"},
+		{
+			"add existing dependency",
+			topo.Change[string]{Op: topo.OpAddDependency, Value: "app", Deps: []string{"lib"}},
+			"dependency already added",
+		},
+		{
+			"remove dependency of other kind",
+			topo.Change[string]{Op: topo.OpRemoveDependency, Value: "app", Kind: "runtime", Deps: []string{"lib"}},
+			"dependency not added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("app", []string{"lib"})
+			cs := topo.Changeset[string]{{Op: topo.OpAddNode, Value: "db"}, tt.change}
+
+			err := cs.ApplyTo(&g)
+			if !errors.Is(err, topo.ErrChangeConflict) {
+				t.Fatalf("Expected %v, got %v", topo.ErrChangeConflict, err)
+			}
+			if expected := "change does not apply: " + tt.change.String() + ": " + tt.message; err.Error() != expected {
+				t.Errorf("Expected %q, got %q", expected, err.Error())
+			}
+			if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib"}) {
+				t.Errorf("Expected graph unchanged, got %v", nodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// readInput reads the graph from the named file, or from stdin if name is
+// empty. If format is "auto", it's detected from the file's extension, or
+// from the content if the extension isn't registered.
+func readInput(stdin io.Reader, name, format string) (*topo.Graph[string], error) {
+	if name == "" {
+		return readGraph(stdin, format)
+	}
+	if format == "auto" {
+		if ext, _, ok := graphio.ForExtension(filepath.Ext(name)); ok {
+			format = ext
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readGraph(f, format)
+}
+
+// readGraph reads a graph in the given format, detecting the format if it
+// is "auto".
+func readGraph(r io.Reader, format string) (*topo.Graph[string], error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "auto" {
+		format = detectFormat(data)
+	}
+
+	f, ok := graphio.Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	fr, ok := f.(graphio.Reader)
+	if !ok {
+		return nil, fmt.Errorf("format %q can't be read", format)
+	}
+	return fr.Read(bytes.NewReader(data))
+}
+
+// detectFormat guesses the format of the input from its start and, for
+// edge lists, from the separator used on the first line with an edge.
+func detectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("nodes:")), bytes.HasPrefix(trimmed, []byte("---")):
+		return "yaml"
+	case bytes.HasPrefix(trimmed, []byte("digraph")), bytes.HasPrefix(trimmed, []byte("strict")):
+		return "dot"
+	}
+
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case strings.Contains(line, ","):
+			return "csv"
+		case strings.Contains(line, "\t"):
+			return "tsv"
+		case strings.Contains(line, " "):
+			return "edges"
+		}
+	}
+	return "edges"
+}
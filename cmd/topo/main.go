@@ -0,0 +1,323 @@
+// Command topo analyzes dependency graphs.
+//
+// Usage:
+//
+//	topo [-f format] [-i file] <command> [args]
+//
+// The commands are:
+//
+//	sort       print the nodes in dependency order, one per line
+//	layers     print the layers of the graph, one per line
+//	cycles     print each group of nodes that form a cycle, one per line
+//	validate   print every structural problem found, one per line
+//	dot        print the graph in Graphviz DOT format
+//	mermaid    print the graph as a Mermaid flowchart
+//	render     draw the graph by layer, for reading in a terminal
+//	convert    print the graph in the given format
+//	stats      print node, edge, and layer counts
+//	affected   print the given nodes and every node depending on them
+//	isolated   print the nodes without any dependencies or dependents
+//	unused     print the nodes that none of the given roots need
+//	impact     print how many nodes fail with the given node, and the
+//	           longest chain of them
+//
+// Graphs can be given as JSON or YAML definitions in the format documented
+// by the graphio package, for example
+//
+//	{"nodes": [{"id": "app", "deps": ["lib"]}, {"id": "lib"}]}
+//
+// as edge lists, with one "node dependency" pair per line, separated by
+// commas (csv), tabs (tsv), or spaces (edges), or in Graphviz DOT (dot). A
+// line of an edge list with a single node declares a node without
+// dependencies. Any other format registered with graphio.Register can be
+// given by name as well.
+//
+// The graph is read from the file given by -i, or from standard input. The
+// format is detected from the file's extension or, failing that, from the
+// input itself, unless -f is given.
+//
+// The dot and mermaid commands accept -highlight to color the nodes and
+// edges that form cycles, and -cycles to print only those.
+//
+// Nodes within a layer, and in lists of nodes, are sorted by name so output
+// is deterministic. The exit status is 1 if the graph has cycles and the
+// command needs an acyclic graph, if cycles finds any, or if validate finds
+// an error.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// errFound is returned by commands that succeeded but found a problem, such
+// as cycles, that should be reflected in the exit status.
+var errFound = errors.New("found")
+
+type command struct {
+	name  string
+	usage string
+	run   func(g *topo.Graph[string], args []string, w io.Writer) error
+}
+
+var commands = []command{
+	{"sort", "sort", runSort},
+	{"layers", "layers", runLayers},
+	{"cycles", "cycles", runCycles},
+	{"validate", "validate", runValidate},
+	{"dot", "dot [-highlight] [-cycles]", runDOT},
+	{"mermaid", "mermaid [-highlight] [-cycles]", runMermaid},
+	{"render", "render", runRender},
+	{"convert", "convert format", runConvert},
+	{"stats", "stats", runStats},
+	{"affected", "affected node...", runAffected},
+	{"impact", "impact node", runImpact},
+	{"isolated", "isolated", runIsolated},
+	{"unused", "unused root...", runUnused},
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("topo", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("f", "auto", "input `format`: auto, or one of "+strings.Join(graphio.Formats(), ", "))
+	input := flags.String("i", "", "read the graph from `file` instead of standard input")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: topo [-f format] [-i file] <command> [args]")
+		fmt.Fprintln(stderr, "\ncommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %s\n", cmd.usage)
+		}
+		fmt.Fprintln(stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == flags.Arg(0) })
+	if i < 0 {
+		fmt.Fprintf(stderr, "topo: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	g, err := readInput(stdin, *input, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "topo: %v\n", err)
+		return 1
+	}
+	if err := commands[i].run(g, flags.Args()[1:], stdout); err != nil {
+		if !errors.Is(err, errFound) {
+			fmt.Fprintf(stderr, "topo: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func runSort(g *topo.Graph[string], _ []string, w io.Writer) error {
+	layers, err := sortedLayers(g)
+	if err != nil {
+		return err
+	}
+	for _, value := range topo.Flatten(layers, nil) {
+		fmt.Fprintln(w, value)
+	}
+	return nil
+}
+
+func runLayers(g *topo.Graph[string], _ []string, w io.Writer) error {
+	layers, err := sortedLayers(g)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		fmt.Fprintln(w, strings.Join(layer, " "))
+	}
+	return nil
+}
+
+func runCycles(g *topo.Graph[string], _ []string, w io.Writer) error {
+	cycles := g.Cycles()
+	for _, cycle := range cycles {
+		fmt.Fprintln(w, strings.Join(cycle, " "))
+	}
+	if len(cycles) > 0 {
+		return errFound
+	}
+	return nil
+}
+
+func runValidate(g *topo.Graph[string], _ []string, w io.Writer) error {
+	failed := false
+	for _, issue := range g.Validate() {
+		fmt.Fprintln(w, issue)
+		failed = failed || issue.Severity == topo.SeverityError
+	}
+	if failed {
+		return errFound
+	}
+	return nil
+}
+
+func runDOT(g *topo.Graph[string], args []string, w io.Writer) error {
+	var d graphio.DOTGraph
+	if err := parseCycleFlags("dot", args, &d.HighlightCycles, &d.CyclesOnly); err != nil {
+		return err
+	}
+	return d.Write(w, g)
+}
+
+func runMermaid(g *topo.Graph[string], args []string, w io.Writer) error {
+	var m graphio.MermaidChart
+	if err := parseCycleFlags("mermaid", args, &m.HighlightCycles, &m.CyclesOnly); err != nil {
+		return err
+	}
+	return m.Write(w, g)
+}
+
+// parseCycleFlags parses the options of the commands that export diagrams.
+func parseCycleFlags(name string, args []string, highlight, only *bool) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(highlight, "highlight", false, "")
+	flags.BoolVar(only, "cycles", false, "")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected argument %q", name, flags.Arg(0))
+	}
+	return nil
+}
+
+func runRender(g *topo.Graph[string], _ []string, w io.Writer) error {
+	return graphio.Render(w, g)
+}
+
+func runConvert(g *topo.Graph[string], args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("convert: expected a format")
+	}
+	f, ok := graphio.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("convert: unknown format %q", args[0])
+	}
+	fw, ok := f.(graphio.Writer)
+	if !ok {
+		return fmt.Errorf("convert: format %q can't be written", args[0])
+	}
+	return fw.Write(w, g)
+}
+
+func runStats(g *topo.Graph[string], _ []string, w io.Writer) error {
+	nodes := g.Nodes()
+	edges, leaves := 0, 0
+	for _, value := range nodes {
+		deps := g.Dependencies(value)
+		edges += len(deps)
+		if len(deps) == 0 {
+			leaves++
+		}
+	}
+
+	fmt.Fprintf(w, "nodes: %d\n", len(nodes))
+	fmt.Fprintf(w, "edges: %d\n", edges)
+	fmt.Fprintf(w, "roots: %d\n", len(g.Roots()))
+	fmt.Fprintf(w, "leaves: %d\n", leaves)
+	fmt.Fprintf(w, "cycles: %d\n", len(g.Cycles()))
+
+	// a cyclic graph has no layers, so there's nothing more to say
+	path, err := g.LongestPath()
+	if errors.Is(err, topo.ErrCyclicDependency) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	width, _ := g.Width()
+	fmt.Fprintf(w, "layers: %d\n", len(path))
+	fmt.Fprintf(w, "max width: %d\n", width)
+	fmt.Fprintf(w, "longest path: %s\n", strings.Join(path, " -> "))
+	return nil
+}
+
+func runAffected(g *topo.Graph[string], args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("affected: no nodes given")
+	}
+	nodes := g.Nodes()
+	for _, arg := range args {
+		if !slices.Contains(nodes, arg) {
+			return fmt.Errorf("affected: unknown node %q", arg)
+		}
+	}
+
+	affected := append(g.Descendants(args...), args...)
+	slices.Sort(affected)
+	for _, value := range slices.Compact(affected) {
+		fmt.Fprintln(w, value)
+	}
+	return nil
+}
+
+func runIsolated(g *topo.Graph[string], _ []string, w io.Writer) error {
+	for _, value := range g.Isolated() {
+		fmt.Fprintln(w, value)
+	}
+	return nil
+}
+
+func runUnused(g *topo.Graph[string], args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("unused: no roots given")
+	}
+	for _, value := range g.Unreachable(args...) {
+		fmt.Fprintln(w, value)
+	}
+	return nil
+}
+
+func runImpact(g *topo.Graph[string], args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("impact: expected one node")
+	}
+	impact := g.BlastRadius(args[0])
+	if len(impact.LongestChain) == 0 {
+		return fmt.Errorf("impact: unknown node %q", args[0])
+	}
+	fmt.Fprintf(w, "direct: %d\n", len(impact.Direct))
+	fmt.Fprintf(w, "affected: %d\n", len(impact.Affected))
+	fmt.Fprintf(w, "longest chain: %s\n", strings.Join(impact.LongestChain, " -> "))
+	return nil
+}
+
+// sortedLayers sorts the graph into layers, sorting the nodes within each
+// layer. If the graph has cycles, the error names them.
+func sortedLayers(g *topo.Graph[string]) ([][]string, error) {
+	layers, err := g.SortByLayersFunc(strings.Compare)
+	if errors.Is(err, topo.ErrCyclicDependency) {
+		var cycles []string
+		for _, cycle := range g.Cycles() {
+			cycles = append(cycles, "["+strings.Join(cycle, " ")+"]")
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.Join(cycles, ", "))
+	}
+	return layers, err
+}
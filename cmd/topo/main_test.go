@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const graphJSON = `{"nodes": [
+	{"id": "app", "deps": ["lib", "config"]},
+	{"id": "lib", "deps": ["base"]},
+	{"id": "tool", "deps": ["base"]},
+	{"id": "base"}
+]}`
+
+const graphYAML = `
+nodes:
+  - id: app
+    deps: [lib, config]
+  - id: lib
+    deps: [base]
+  - id: tool
+    deps: [base]
+  - id: base
+`
+
+const graphEdges = `
+# node dependency
+app lib
+app config
+lib base
+tool base
+base
+`
+
+const graphCSV = `app,lib
+app,config
+lib,base
+tool,base
+`
+
+// TestRun checks the output of each command.
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+		status   int
+	}{
+		{"sort", []string{"sort"}, graphJSON, "base\nconfig\nlib\ntool\napp\n", 0},
+		{"layers", []string{"layers"}, graphJSON, "base config\nlib tool\napp\n", 0},
+		{"layers yaml", []string{"layers"}, graphYAML, "base config\nlib tool\napp\n", 0},
+		{"layers edges", []string{"layers"}, graphEdges, "base config\nlib tool\napp\n", 0},
+		{"layers csv", []string{"layers"}, graphCSV, "base config\nlib tool\napp\n", 0},
+		{"layers tsv", []string{"-f", "tsv", "layers"}, "app\tlib\n", "lib\napp\n", 0},
+		{"forced format", []string{"-f", "edges", "layers"}, "b a\n", "a\nb\n", 0},
+		{"no cycles", []string{"cycles"}, graphJSON, "", 0},
+		{"cycles", []string{"cycles"}, "a b\nb a\nc c\n", "a b\nc\n", 1},
+		{"validate", []string{"validate"}, "a b\na c\nb c\n", "warning: undeclared dependency: [c]\ninfo: redundant edge: [a c]\n", 0},
+		{"validate cycle", []string{"validate"}, "a a\n", "error: self-loop: [a]\n", 1},
+		{"cyclic sort", []string{"sort"}, "a b\nb a\n", "", 1},
+		{
+			"dot", []string{"dot"}, "a b\nb\n",
+			"digraph {\n\t\"a\" -> \"b\";\n\t\"b\";\n}\n", 0,
+		},
+		{"render", []string{"render"}, "a b\n", "── layer 1 ──\n  b\n── layer 2 ──\n  a ──▶ b\n", 0},
+		{
+			"dot cycles", []string{"dot", "-cycles"}, "a b\nb a\nc a\n",
+			"digraph {\n\t\"a\" -> \"b\";\n\t\"b\" -> \"a\";\n}\n", 0,
+		},
+		{"mermaid", []string{"mermaid"}, "a b\n", "flowchart TD\n    n0[\"a\"]\n    n1[\"b\"]\n    n0 --> n1\n", 0},
+		{"mermaid bad flag", []string{"mermaid", "-nope"}, "a b\n", "", 1},
+		{"layers dot", []string{"layers"}, "digraph { a -> b; c }\n", "b c\na\n", 0},
+		{"convert", []string{"convert", "csv"}, "a b\nb\n", "a,b\nb\n", 0},
+		{"convert unknown", []string{"convert", "nope"}, "a b\n", "", 1},
+		{"unknown format", []string{"-f", "nope", "sort"}, "a b\n", "", 1},
+		{
+			"stats", []string{"stats"}, graphJSON,
+			"nodes: 5\nedges: 4\nroots: 2\nleaves: 2\ncycles: 0\nlayers: 3\nmax width: 2\nlongest path: base -> lib -> app\n", 0,
+		},
+		{"stats cycle", []string{"stats"}, "a b\nb a\nc a\n", "nodes: 3\nedges: 3\nroots: 1\nleaves: 0\ncycles: 1\n", 0},
+		{"affected", []string{"affected", "lib"}, graphJSON, "app\nlib\n", 0},
+		{"affected base", []string{"affected", "base"}, graphJSON, "app\nbase\nlib\ntool\n", 0},
+		{"affected unknown", []string{"affected", "nope"}, graphJSON, "", 1},
+		{
+			"impact", []string{"impact", "base"}, graphJSON,
+			"direct: 2\naffected: 3\nlongest chain: base -> lib -> app\n", 0,
+		},
+		{"impact unknown", []string{"impact", "nope"}, graphJSON, "", 1},
+		{"isolated", []string{"isolated"}, "a b\nc\n", "c\n", 0},
+		{"unused", []string{"unused", "lib"}, graphJSON, "app\nconfig\ntool\n", 0},
+		{"unknown command", []string{"frobnicate"}, graphJSON, "", 2},
+		{"no command", nil, graphJSON, "", 2},
+		{"bad input", []string{"sort"}, "{", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(tt.args, strings.NewReader(tt.input), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("Expected status %d, got %d (stderr: %s)", tt.status, status, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, stdout.String())
+			}
+		})
+	}
+}
+
+// TestRunInputFile checks that the format of an input file is detected from
+// its extension.
+func TestRunInputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.tsv")
+	if err := os.WriteFile(path, []byte("a b\tc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-i", path, "layers"}, strings.NewReader(""), &stdout, &stderr)
+	if status != 0 {
+		t.Fatalf("Expected status 0, got %d (stderr: %s)", status, stderr.String())
+	}
+	if expected := "c\na b\n"; stdout.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, stdout.String())
+	}
+}
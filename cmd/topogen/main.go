@@ -0,0 +1,198 @@
+// Command topogen generates Go source for a static dependency graph, so that
+// code refers to its nodes by typed constants and a misspelt node is a
+// compile error rather than a failure at run time.
+//
+// Usage:
+//
+//	topogen -i file [-o file] [-pkg name] [-type name] [-func name]
+//
+// It's meant to be run by go generate, with a directive like
+//
+//	//go:generate go run github.com/sam-fredrickson/go-topo/cmd/topogen -i deploy.yaml
+//
+// The graph is read from the file given by -i, in any format graphio can
+// read, such as a JSON or YAML definition. topogen refuses graphs that can't
+// be sorted, and dependencies that aren't declared as nodes themselves,
+// which are usually typos.
+//
+// For a graph with nodes "app" and "load-balancer", the generated file
+// declares
+//
+//	type Node string
+//
+//	const (
+//		NodeApp          Node = "app"
+//		NodeLoadBalancer Node = "load-balancer"
+//	)
+//
+//	func NewGraph() *topo.Graph[Node]
+//
+// with the names given by -type and -func. The file is written next to the
+// input, named after it with a _topo.go suffix, unless -o is given; -o -
+// writes to standard output. The package defaults to $GOPACKAGE, which go
+// generate sets.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// config is what to generate.
+type config struct {
+	source   string
+	pkg      string
+	typeName string
+	funcName string
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("topogen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	input := flags.String("i", "", "read the graph from `file`")
+	output := flags.String("o", "", "write the Go source to `file`, or - for standard output")
+	var c config
+	flags.StringVar(&c.pkg, "pkg", os.Getenv("GOPACKAGE"), "package `name` of the generated file")
+	flags.StringVar(&c.typeName, "type", "Node", "`name` of the node type")
+	flags.StringVar(&c.funcName, "func", "NewGraph", "`name` of the graph constructor")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *input == "" || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: topogen -i file [-o file] [-pkg name] [-type name] [-func name]")
+		flags.PrintDefaults()
+		return 2
+	}
+	if c.pkg == "" {
+		fmt.Fprintln(stderr, "topogen: no package name; give -pkg or run from go generate")
+		return 2
+	}
+	c.source = filepath.Base(*input)
+
+	g, err := graphio.ReadFile(*input)
+	if err != nil {
+		fmt.Fprintf(stderr, "topogen: %v\n", err)
+		return 1
+	}
+	src, err := generate(g, c)
+	if err != nil {
+		fmt.Fprintf(stderr, "topogen: %s: %v\n", *input, err)
+		return 1
+	}
+
+	switch *output {
+	case "-":
+		_, err = stdout.Write(src)
+	case "":
+		base := strings.TrimSuffix(*input, filepath.Ext(*input))
+		err = os.WriteFile(base+"_topo.go", src, 0o600)
+	default:
+		err = os.WriteFile(*output, src, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "topogen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// generate returns the Go source for a graph, after checking that it can be
+// sorted and that every dependency is declared.
+func generate(g *topo.Graph[string], c config) ([]byte, error) {
+	for _, name := range []string{c.pkg, c.typeName, c.funcName} {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("%q is not a Go identifier", name)
+		}
+	}
+	var problems []string
+	for _, issue := range g.Validate() {
+		if issue.Severity == topo.SeverityError || issue.Kind == topo.IssueUndeclaredDependency {
+			problems = append(problems, issue.String())
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid graph:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+
+	nodes := g.Nodes()
+	names := make(map[string]string, len(nodes))
+	byName := make(map[string]string, len(nodes))
+	for _, value := range nodes {
+		name := c.typeName + identifier(value)
+		if other, ok := byName[name]; ok {
+			return nil, fmt.Errorf("nodes %q and %q are both named %s", other, value, name)
+		}
+		names[value], byName[name] = name, value
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by topogen from %s. DO NOT EDIT.\n\n", c.source)
+	fmt.Fprintf(&buf, "package %s\n\n", c.pkg)
+	fmt.Fprintf(&buf, "import %q\n\n", "github.com/sam-fredrickson/go-topo")
+	fmt.Fprintf(&buf, "// %s is a node of the graph in %s.\n", c.typeName, c.source)
+	fmt.Fprintf(&buf, "type %s string\n\n", c.typeName)
+	if len(nodes) > 0 {
+		fmt.Fprintf(&buf, "// The nodes of the graph in %s.\n", c.source)
+		fmt.Fprintln(&buf, "const (")
+		for _, value := range nodes {
+			fmt.Fprintf(&buf, "%s %s = %q\n", names[value], c.typeName, value)
+		}
+		fmt.Fprint(&buf, ")\n\n")
+	}
+	fmt.Fprintf(&buf, "// %s returns the graph in %s, which was checked for cycles and\n", c.funcName, c.source)
+	fmt.Fprintln(&buf, "// undeclared dependencies when this file was generated.")
+	fmt.Fprintf(&buf, "func %s() *topo.Graph[%s] {\n", c.funcName, c.typeName)
+	fmt.Fprintf(&buf, "var g topo.Graph[%s]\n", c.typeName)
+	for _, value := range nodes {
+		deps := g.Dependencies(value)
+		if len(deps) == 0 {
+			fmt.Fprintf(&buf, "g.AddNode(%s, nil)\n", names[value])
+			continue
+		}
+		refs := make([]string, len(deps))
+		for i, dep := range deps {
+			refs[i] = names[dep]
+		}
+		fmt.Fprintf(&buf, "g.AddNode(%s, []%s{%s})\n", names[value], c.typeName, strings.Join(refs, ", "))
+	}
+	fmt.Fprintln(&buf, "return &g")
+	fmt.Fprintln(&buf, "}")
+	return format.Source(buf.Bytes())
+}
+
+// identifier turns a node into the exported part of a Go identifier, like
+// "load-balancer" into "LoadBalancer".
+func identifier(value string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
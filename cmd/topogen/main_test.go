@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const graphYAML = `
+nodes:
+  - id: app
+    deps: [load-balancer, db]
+  - id: load-balancer
+  - id: db
+`
+
+const expectedSource = `// Code generated by topogen from deploy.yaml. DO NOT EDIT.
+
+package deploy
+
+import "github.com/sam-fredrickson/go-topo"
+
+// Node is a node of the graph in deploy.yaml.
+type Node string
+
+// The nodes of the graph in deploy.yaml.
+const (
+	NodeApp          Node = "app"
+	NodeLoadBalancer Node = "load-balancer"
+	NodeDb           Node = "db"
+)
+
+// NewGraph returns the graph in deploy.yaml, which was checked for cycles and
+// undeclared dependencies when this file was generated.
+func NewGraph() *topo.Graph[Node] {
+	var g topo.Graph[Node]
+	g.AddNode(NodeApp, []Node{NodeLoadBalancer, NodeDb})
+	g.AddNode(NodeLoadBalancer, nil)
+	g.AddNode(NodeDb, nil)
+	return &g
+}
+`
+
+// TestRun checks the generated source, and that graphs that would fail at
+// run time are refused.
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+		status   int
+	}{
+		{"generated", []string{"-pkg", "deploy"}, graphYAML, expectedSource, 0},
+		{
+			"names",
+			[]string{"-pkg", "deploy", "-type", "Step", "-func", "Steps"},
+			"nodes:\n  - id: build\n",
+			"Step is a node", 0,
+		},
+		{"cycle", []string{"-pkg", "deploy"}, "nodes:\n  - id: a\n    deps: [b]\n  - id: b\n    deps: [a]\n", "", 1},
+		{"typo", []string{"-pkg", "deploy"}, "nodes:\n  - id: app\n    deps: [lbi]\n  - id: lib\n", "", 1},
+		{"same name", []string{"-pkg", "deploy"}, "nodes:\n  - id: a-b\n  - id: a_b\n", "", 1},
+		{"bad package", []string{"-pkg", "de-ploy"}, graphYAML, "", 1},
+		{"no package", []string{"-pkg", ""}, graphYAML, "", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "deploy.yaml")
+			if err := os.WriteFile(path, []byte(tt.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-i", path, "-o", "-"}, tt.args...)
+			status := run(args, &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("Expected status %d, got %d (stderr: %s)", tt.status, status, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expected) {
+				t.Errorf("Expected output containing %q, got %q", tt.expected, stdout.String())
+			}
+		})
+	}
+}
+
+// TestRunOutputFile checks that the source is written next to the input by
+// default.
+func TestRunOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yaml")
+	if err := os.WriteFile(path, []byte(graphYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-i", path, "-pkg", "deploy"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("Expected status 0, got %d (stderr: %s)", status, stderr.String())
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "deploy_topo.go"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(src) != expectedSource {
+		t.Errorf("Expected %q, got %q", expectedSource, src)
+	}
+}
+
+// TestIdentifier checks how nodes are turned into Go identifiers.
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"app", "App"},
+		{"load-balancer", "LoadBalancer"},
+		{"api.v2", "ApiV2"},
+		{"my_service", "MyService"},
+		{"2fa", "2fa"},
+		{"--", "_"},
+	}
+	for _, tt := range tests {
+		if actual := identifier(tt.value); actual != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.value, actual)
+		}
+	}
+}
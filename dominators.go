@@ -0,0 +1,89 @@
+package topo
+
+// Dominators returns the immediate dominator of every value that root
+// depends on, directly or transitively. A value d dominates v when every
+// chain of dependencies from root to v passes through d, so root only
+// needs v because it needs d; the immediate dominator is the closest such
+// value to v. A value that root depends on directly is dominated only by
+// root itself.
+//
+// Dominators are chokepoints: if d fails, everything it dominates is cut
+// off from root along with it, and narrowing a dominator's dependencies is
+// the only way to change what root pulls in through it. Root has no
+// dominator and isn't included. Cycles are allowed.
+func (g *Graph[T]) Dominators(root T) map[T]T {
+	_, dependsOn := g.edges()
+
+	// number values in reverse postorder, so each value comes before
+	// the values it depends on, except around cycles
+	var postorder []T
+	seen := map[T]bool{root: true}
+	var visit func(value T)
+	visit = func(value T) {
+		for _, dep := range dependsOn[value] {
+			if !seen[dep] {
+				seen[dep] = true
+				visit(dep)
+			}
+		}
+		postorder = append(postorder, value)
+	}
+	visit(root)
+	n := len(postorder)
+	order := make([]T, n)
+	index := make(map[T]int, n)
+	for i, value := range postorder {
+		order[n-1-i] = value
+		index[value] = n - 1 - i
+	}
+	dependents := make([][]int, n)
+	for i, value := range order {
+		for _, dep := range dependsOn[value] {
+			dependents[index[dep]] = append(dependents[index[dep]], i)
+		}
+	}
+
+	// the iterative algorithm of Cooper, Harvey, and Kennedy
+	idom := make([]int, n)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[0] = 0
+	intersect := func(a, b int) int {
+		for a != b {
+			for a > b {
+				a = idom[a]
+			}
+			for b > a {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 1; i < n; i++ {
+			dom := -1
+			for _, p := range dependents[i] {
+				if idom[p] < 0 {
+					continue
+				}
+				if dom < 0 {
+					dom = p
+				} else {
+					dom = intersect(p, dom)
+				}
+			}
+			if idom[i] != dom {
+				idom[i] = dom
+				changed = true
+			}
+		}
+	}
+
+	result := make(map[T]T, n-1)
+	for i := 1; i < n; i++ {
+		result[order[i]] = order[idom[i]]
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestDominators runs some basic test cases.
+func TestDominators(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    map[string][]string
+		root     string
+		expected map[string]string
+	}{
+		{
+			name: "chokepoints",
+			nodes: map[string][]string{
+				"app":  {"lib", "db"},
+				"lib":  {"util", "json"},
+				"json": {"util"},
+				"util": {"base"},
+				"db":   {"base"},
+			},
+			root: "app",
+			expected: map[string]string{
+				"lib":  "app",
+				"db":   "app",
+				"json": "lib",
+				"util": "lib",
+				"base": "app",
+			},
+		},
+		{
+			name: "subgraph",
+			nodes: map[string][]string{
+				"app":  {"lib", "db"},
+				"lib":  {"util", "json"},
+				"json": {"util"},
+			},
+			root: "lib",
+			expected: map[string]string{
+				"json": "lib",
+				"util": "lib",
+			},
+		},
+		{
+			name: "cycle",
+			nodes: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"b", "d"},
+			},
+			root: "a",
+			expected: map[string]string{
+				"b": "a",
+				"c": "b",
+				"d": "c",
+			},
+		},
+		{
+			name:     "no dependencies",
+			nodes:    map[string][]string{"a": nil},
+			root:     "a",
+			expected: map[string]string{},
+		},
+		{
+			name:     "missing root",
+			nodes:    map[string][]string{"a": {"b"}},
+			root:     "z",
+			expected: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			for value, deps := range tt.nodes {
+				g.AddNode(value, deps)
+			}
+			dominators := g.Dominators(tt.root)
+			if !reflect.DeepEqual(dominators, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, dominators)
+			}
+		})
+	}
+}
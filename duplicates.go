@@ -0,0 +1,76 @@
+package topo
+
+import "slices"
+
+// Duplicate is a dependency listed more than once for a node, as generated
+// configuration often does.
+type Duplicate[T comparable] struct {
+	Value T
+	Kind  EdgeKind
+	Dep   T
+	// Count is how many times Dep was listed.
+	Count int
+}
+
+// Duplicates returns the dependencies that were listed more than once when
+// nodes were added. The graph only keeps each dependency once, so they
+// don't inflate counts like fan-in or add edges to exports, but they
+// usually point to a mistake upstream worth fixing. Only the dependencies
+// in effect are reported: those of the last AddNode or AddNodeOfKind call
+// for each node and kind. They're in the order the nodes were first added,
+// and then the order the dependencies were listed in.
+func (g *Graph[T]) Duplicates() []Duplicate[T] {
+	index := make(map[kindKey[T]]int, len(g.nodes))
+	var decls []node[T]
+	for _, n := range g.nodes {
+		key := kindKey[T]{n.value, n.kind}
+		if i, ok := index[key]; ok {
+			decls[i] = n
+		} else {
+			index[key] = len(decls)
+			decls = append(decls, n)
+		}
+	}
+
+	var duplicates []Duplicate[T]
+	for _, decl := range decls {
+		for _, dep := range decl.deps {
+			if n := count(decl.repeats, dep); n > 0 {
+				duplicates = append(duplicates, Duplicate[T]{
+					Value: decl.value, Kind: decl.kind, Dep: dep, Count: n + 1,
+				})
+			}
+		}
+	}
+	return duplicates
+}
+
+// unique returns values without duplicates, keeping the first of each, and
+// the duplicates removed. If there are none, values is returned as it is.
+func unique[T comparable](values []T) (kept, repeats []T) {
+	for i, value := range values {
+		if slices.Contains(values[:i], value) {
+			if kept == nil {
+				kept = slices.Clone(values[:i])
+			}
+			repeats = append(repeats, value)
+		} else if kept != nil {
+			kept = append(kept, value)
+		}
+	}
+	if kept == nil {
+		return values, nil
+	}
+	return kept, repeats
+}
+
+// count returns how many times value is in values.
+func count[T comparable](values []T, value T) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package topo_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestDuplicates checks that repeated dependencies are kept once and
+// reported.
+func TestDuplicates(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db", "lib", "lib"})
+	g.AddNodeOfKind("app", "runtime", []string{"log", "log"})
+	g.AddNode("lib", []string{"base", "base"})
+	g.AddNode("lib", []string{"base"}) // replaces the duplicates
+	g.AddNode("db", nil)
+
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db", "log"}) {
+		t.Errorf("Expected [lib db log], got %v", deps)
+	}
+	expected := []topo.Duplicate[string]{
+		{Value: "app", Dep: "lib", Count: 3},
+		{Value: "app", Kind: "runtime", Dep: "log", Count: 2},
+	}
+	if duplicates := g.Duplicates(); !reflect.DeepEqual(duplicates, expected) {
+		t.Errorf("Expected %v, got %v", expected, duplicates)
+	}
+
+	violations := g.CheckPolicies(topo.Policy{MaxFanOut: 3})
+	if len(violations) != 0 {
+		t.Errorf("Expected duplicates not to count toward fan-out, got %v", violations)
+	}
+}
+
+// TestDuplicatesNormalized checks that dependencies that are the same once
+// normalized are reported as duplicates.
+func TestDuplicatesNormalized(t *testing.T) {
+	g := new(topo.Graph[string]).WithKeyNormalizer(strings.ToLower)
+	g.AddNode("app", []string{"Lib", "lib"})
+
+	expected := []topo.Duplicate[string]{{Value: "app", Dep: "lib", Count: 2}}
+	if duplicates := g.Duplicates(); !reflect.DeepEqual(duplicates, expected) {
+		t.Errorf("Expected %v, got %v", expected, duplicates)
+	}
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib"}) {
+		t.Errorf("Expected [lib], got %v", deps)
+	}
+}
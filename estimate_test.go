@@ -0,0 +1,48 @@
+package topo_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestEstimateMemory checks the estimate against the memory a graph
+// actually takes, allowing for its roughness.
+func TestEstimateMemory(t *testing.T) {
+	const nodes, deps = 100_000, 4
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	g := denseGraph(nodes, deps)
+	if _, err := g.SortByLayers(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	used := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	runtime.KeepAlive(g)
+
+	edges := nodes*deps - deps*(deps+1)/2
+	estimate := topo.EstimateMemory[int](nodes, edges)
+	if estimate < used/2 || estimate > used*2 {
+		t.Errorf("Expected an estimate within a factor of 2 of %d bytes, got %d", used, estimate)
+	}
+}
+
+// TestEstimateGrowth checks that estimates grow with the graph and its
+// values.
+func TestEstimateGrowth(t *testing.T) {
+	if small, large := topo.EstimateMemory[int](1_000, 4_000), topo.EstimateMemory[int](2_000, 8_000); large != 2*small {
+		t.Errorf("Expected twice the graph to take twice the memory, got %d and %d", small, large)
+	}
+	if ints, strings := topo.EstimateMemory[int32](1_000, 4_000), topo.EstimateMemory[string](1_000, 4_000); ints >= strings {
+		t.Errorf("Expected int32 values to take less memory than strings, got %d and %d", ints, strings)
+	}
+	if sparse, dense := topo.EstimateSortCost(1_000, 1_000), topo.EstimateSortCost(1_000, 10_000); sparse >= dense {
+		t.Errorf("Expected more edges to cost more, got %d and %d", sparse, dense)
+	}
+	if cost := topo.EstimateSortCost(0, 0); cost != 0 {
+		t.Errorf("Expected an empty graph to cost nothing, got %d", cost)
+	}
+}
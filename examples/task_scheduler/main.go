@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"os"
 	"time"
 
 	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+	"github.com/sam-fredrickson/go-topo/exec/progress"
 )
 
-// Task represents a job with dependencies that needs to be executed.
+// Task represents a job with dependencies that needs to be executed. Its
+// topo tags let topo.FromStructs build the graph of tasks.
 type Task struct {
-	ID          string
+	ID          string   `topo:"id"`
+	Deps        []string `topo:"deps"`
 	Description string
 	Duration    time.Duration
 }
@@ -19,31 +24,28 @@ func main() {
 	fmt.Println("Task Scheduler with Layered Topological Sort")
 	fmt.Println("===========================================")
 
-	tasks := map[string]Task{
-		"setup-db":      {ID: "setup-db", Description: "Initialize database schema", Duration: 2 * time.Second},
-		"load-data":     {ID: "load-data", Description: "Load initial data", Duration: 3 * time.Second},
-		"api-server":    {ID: "api-server", Description: "Start API server", Duration: 1 * time.Second},
-		"worker":        {ID: "worker", Description: "Start background worker", Duration: 1 * time.Second},
-		"cache":         {ID: "cache", Description: "Initialize cache", Duration: 1 * time.Second},
-		"notifications": {ID: "notifications", Description: "Setup notification service", Duration: 2 * time.Second},
-		"frontend":      {ID: "frontend", Description: "Start frontend server", Duration: 1 * time.Second},
-		"monitoring":    {ID: "monitoring", Description: "Start monitoring service", Duration: 1 * time.Second},
-		"load-balancer": {ID: "load-balancer", Description: "Configure load balancer", Duration: 2 * time.Second},
-		"final-checks":  {ID: "final-checks", Description: "Run system checks", Duration: 1 * time.Second},
+	tasks := []Task{
+		{ID: "setup-db", Description: "Initialize database schema", Duration: 2 * time.Second},
+		{ID: "load-data", Deps: []string{"setup-db"}, Description: "Load initial data", Duration: 3 * time.Second},
+		{ID: "api-server", Deps: []string{"load-data"}, Description: "Start API server", Duration: 1 * time.Second},
+		{ID: "worker", Deps: []string{"load-data"}, Description: "Start background worker", Duration: 1 * time.Second},
+		{ID: "cache", Deps: []string{"setup-db"}, Description: "Initialize cache", Duration: 1 * time.Second},
+		{ID: "notifications", Deps: []string{"worker"}, Description: "Setup notification service", Duration: 2 * time.Second},
+		{ID: "frontend", Deps: []string{"api-server", "cache"}, Description: "Start frontend server", Duration: 1 * time.Second},
+		{ID: "monitoring", Deps: []string{"api-server", "worker", "cache"}, Description: "Start monitoring service", Duration: 1 * time.Second},
+		{ID: "load-balancer", Deps: []string{"api-server", "frontend"}, Description: "Configure load balancer", Duration: 2 * time.Second},
+		{ID: "final-checks", Deps: []string{"frontend", "monitoring", "load-balancer", "notifications"}, Description: "Run system checks", Duration: 1 * time.Second},
+	}
+	durations := make(map[string]time.Duration)
+	for _, task := range tasks {
+		durations[task.ID] = task.Duration
 	}
 
-	var g topo.Graph[string]
-
-	g.AddNode("setup-db", []string{})
-	g.AddNode("load-data", []string{"setup-db"})
-	g.AddNode("api-server", []string{"load-data"})
-	g.AddNode("worker", []string{"load-data"})
-	g.AddNode("cache", []string{"setup-db"})
-	g.AddNode("notifications", []string{"worker"})
-	g.AddNode("frontend", []string{"api-server", "cache"})
-	g.AddNode("monitoring", []string{"api-server", "worker", "cache"})
-	g.AddNode("load-balancer", []string{"api-server", "frontend"})
-	g.AddNode("final-checks", []string{"frontend", "monitoring", "load-balancer", "notifications"})
+	g, err := topo.FromStructs[string](tasks)
+	if err != nil {
+		fmt.Printf("Error building graph: %v\n", err)
+		return
+	}
 
 	// calculate the execution layers
 	layers, err := g.SortByLayers()
@@ -57,46 +59,32 @@ func main() {
 		fmt.Printf("Layer %d: %v\n", i+1, layer)
 	}
 
-	// execute the tasks layer by layer
+	// execute each task as soon as its dependencies are done, drawing the
+	// progress of each layer as it goes
 	fmt.Println("\nExecuting tasks:")
-	startTime := time.Now()
-
-	for i, layer := range layers {
-		fmt.Printf("\n--- Layer %d ---\n", i+1)
-		layerStart := time.Now()
-
-		// execute tasks in this layer concurrently
-		var wg sync.WaitGroup
-		for _, taskID := range layer {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				task := tasks[taskID]
-				fmt.Printf("Starting task: %s - %s\n", taskID, task.Description)
-
-				// Simulate task execution
-				time.Sleep(task.Duration)
-
-				fmt.Printf("Completed task: %s (took %v)\n", taskID, task.Duration)
-			}()
+	ctx := context.Background()
+
+	view := progress.New(os.Stdout, layers)
+	view.Start(100 * time.Millisecond)
+	var report exec.Report[string]
+	err = exec.Run(ctx, g, func(ctx context.Context, taskID string) error {
+		// simulate task execution
+		select {
+		case <-time.After(durations[taskID]):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-
-		// wait for all tasks in this layer to complete
-		wg.Wait()
-
-		layerDuration := time.Since(layerStart)
-		fmt.Printf("--- Layer %d completed in %v ---\n", i+1, layerDuration)
-	}
-
-	totalDuration := time.Since(startTime)
-	fmt.Printf("\nAll tasks completed in %v\n", totalDuration)
-
-	// calculate the theoretical sequential execution time
-	var sequentialDuration time.Duration
-	for _, task := range tasks {
-		sequentialDuration += task.Duration
+	}, exec.Options[string]{OnState: view.OnState, Report: &report})
+	view.Stop()
+	if err != nil {
+		fmt.Printf("Error executing tasks: %v\n", err)
+		return
 	}
 
-	fmt.Printf("Sequential execution would take: %v\n", sequentialDuration)
-	fmt.Printf("Parallel execution saved: %v\n", sequentialDuration-totalDuration)
+	// the report compares the run with running the tasks one at a time
+	fmt.Printf("\nAll tasks completed in %v\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Printf("Sequential execution would take: %v\n", report.Work.Round(time.Millisecond))
+	fmt.Printf("Parallel execution saved: %v\n", (report.Work - report.Elapsed).Round(time.Millisecond))
+	fmt.Println(&report)
 }
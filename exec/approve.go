@@ -0,0 +1,64 @@
+package exec
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoApprover is returned by Run for a run with Options.Gate but no
+// Options.Approver, whose gated values could never be approved. Runs
+// started with Start can be approved with Execution.Approve instead.
+var ErrNoApprover = errors.New("gated values but no approver")
+
+// Approver decides whether a gated value may run, for Options.Approver,
+// like by asking in a chat channel or checking a change ticket.
+type Approver[T comparable] interface {
+	// Approve returns nil once value may run, or an error if it may not,
+	// which skips the value and fails the run, as an error from its call
+	// would. It's called from a goroutine of its own when
+	// value is ready, and should return when ctx is done, which it is once
+	// the run stops, or the value is approved with Execution.Approve or
+	// cancelled with Execution.CancelSubtree.
+	Approve(ctx context.Context, value T) error
+}
+
+// ApproverFunc adapts a function to an Approver.
+type ApproverFunc[T comparable] func(ctx context.Context, value T) error
+
+// Approve calls f.
+func (f ApproverFunc[T]) Approve(ctx context.Context, value T) error {
+	return f(ctx, value)
+}
+
+// approval is the answer for a gated value.
+type approval[T any] struct {
+	value T
+	err   error
+}
+
+// Approve lets a gated value run, once it's ready, like when an operator
+// signs off on a deployment to production. Values can be approved before
+// they're ready, and approving a value that isn't gated does nothing. It
+// doesn't wait for the run, so it can be called from Options.OnState, as
+// a gated value becomes ready, and does nothing once the run has finished.
+func (e *Execution[T]) Approve(value T) {
+	e.ask(request[T]{value: value})
+}
+
+// Awaiting returns the gated values that are ready and waiting to be
+// approved, in the order they became ready.
+func (e *Execution[T]) Awaiting() []T {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]T(nil), e.awaiting...)
+}
+
+// await notes the gated values waiting to be approved, if e isn't nil.
+func (e *Execution[T]) await(values []T) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.awaiting = append(e.awaiting[:0], values...)
+}
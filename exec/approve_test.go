@@ -0,0 +1,280 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestExecutionApprove checks that a gated value waits for Approve while
+// the rest of the run goes on.
+func TestExecutionApprove(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("staging", []string{"build"})
+	g.AddNode("production", []string{"staging"})
+	g.AddNode("docs", nil)
+
+	var mu sync.Mutex
+	var ran []string
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, value)
+		return nil
+	}, exec.Options[string]{Limit: 1, Gate: func(value string) bool { return value == "production" }})
+
+	if err := e.WaitFor(context.Background(), "staging", "docs"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var awaiting []string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if awaiting = e.Awaiting(); len(awaiting) > 0 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(awaiting, []string{"production"}) {
+		t.Fatalf("Expected [production] awaiting approval, got %v", awaiting)
+	}
+	select {
+	case <-e.Done():
+		t.Fatal("Expected the run to wait for approval")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	e.Approve("production")
+	if err := e.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last := ran[len(ran)-1]; last != "production" || len(ran) != 4 {
+		t.Errorf("Expected 4 calls ending with production, got %v", ran)
+	}
+	if awaiting := e.Awaiting(); len(awaiting) != 0 {
+		t.Errorf("Expected nothing awaiting approval, got %v", awaiting)
+	}
+}
+
+// TestExecutionApproveOnState checks approving a gated value from
+// Options.OnState, as it becomes ready.
+func TestExecutionApproveOnState(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", []string{"staging"})
+
+	var e *exec.Execution[string]
+	started := make(chan struct{})
+	var ran []string
+	e = exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		ran = append(ran, value)
+		return nil
+	}, exec.Options[string]{
+		Gate: func(value string) bool { return value == "production" },
+		OnState: func(value string, state topo.NodeState) {
+			<-started
+			if value == "production" && state == topo.StateReady {
+				e.Approve(value)
+			}
+		},
+	})
+	close(started)
+	select {
+	case <-e.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the run to finish")
+	}
+	if err := e.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"staging", "production"}) {
+		t.Errorf("Expected [staging production], got %v", ran)
+	}
+}
+
+// TestExecutionApproveEarly checks approving a value before it's ready.
+func TestExecutionApproveEarly(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", []string{"staging"})
+
+	release := make(chan struct{})
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "staging" {
+			<-release
+		}
+		return nil
+	}, exec.Options[string]{Gate: func(value string) bool { return value == "production" }})
+	e.Approve("production")
+	close(release)
+	if err := e.Wait(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestRunApprover checks the answers of an Approver.
+func TestRunApprover(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", []string{"staging"})
+	g.AddNode("staging", nil)
+
+	errRejected := errors.New("change freeze")
+	tests := []struct {
+		name     string
+		answer   error
+		expected []string
+	}{
+		{"approved", nil, []string{"staging", "production"}},
+		{"rejected", errRejected, []string{"staging"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked []string
+			var ran []string
+			states := make(map[string]topo.NodeState)
+			err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+				ran = append(ran, value)
+				return nil
+			}, exec.Options[string]{
+				Gate: func(value string) bool { return value == "production" },
+				Approver: exec.ApproverFunc[string](func(ctx context.Context, value string) error {
+					asked = append(asked, value)
+					return tt.answer
+				}),
+				OnState: func(value string, state topo.NodeState) { states[value] = state },
+			})
+			if !errors.Is(err, tt.answer) || (tt.answer == nil) != (err == nil) {
+				t.Fatalf("Expected %v, got %v", tt.answer, err)
+			}
+			if !reflect.DeepEqual(asked, []string{"production"}) {
+				t.Errorf("Expected [production] asked, got %v", asked)
+			}
+			if !reflect.DeepEqual(ran, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ran)
+			}
+			if tt.answer != nil && states["production"] != topo.StateSkipped {
+				t.Errorf("Expected production skipped, got %v", states["production"])
+			}
+		})
+	}
+}
+
+// TestRunApproverTransitions checks that every change of state reported in
+// a run with a rejected value is one topo.NodeState allows, and that the
+// rejection is in the report.
+func TestRunApproverTransitions(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", []string{"a"})
+	g.AddNode("a", nil)
+
+	errRejected := errors.New("change freeze")
+	states := make(map[string]topo.NodeState)
+	var report exec.Report[string]
+	err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{
+		Gate: func(value string) bool { return value == "a" },
+		Approver: exec.ApproverFunc[string](func(context.Context, string) error {
+			return errRejected
+		}),
+		Report: &report,
+		OnState: func(value string, state topo.NodeState) {
+			if from, ok := states[value]; ok && !from.CanBecome(state) {
+				t.Errorf("Unexpected change of %s from %v to %v", value, from, state)
+			} else if !ok && state != topo.StateReady && state != topo.StateSkipped {
+				t.Errorf("Unexpected first state %v of %s", state, value)
+			}
+			states[value] = state
+		},
+	})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("Expected %v, got %v", errRejected, err)
+	}
+	expected := map[string]topo.NodeState{"a": topo.StateSkipped, "b": topo.StateSkipped}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected %v, got %v", expected, states)
+	}
+	if len(report.Nodes) == 0 || report.Nodes[0].Node != "a" || report.Nodes[0].Error != errRejected.Error() {
+		t.Errorf("Expected a rejected in the report, got %+v", report.Nodes)
+	}
+}
+
+// TestRunGateErrors checks runs whose gated values can't be approved.
+func TestRunGateErrors(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", []string{"staging"})
+	gate := func(value string) bool { return value == "production" }
+	noop := func(context.Context, string) error { return nil }
+
+	err := exec.Run(context.Background(), &g, noop, exec.Options[string]{Gate: gate})
+	if !errors.Is(err, exec.ErrNoApprover) {
+		t.Errorf("Expected ErrNoApprover, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var skipped []string
+	err = exec.Start(ctx, &g, noop, exec.Options[string]{
+		Gate: gate,
+		OnState: func(value string, state topo.NodeState) {
+			if state == topo.StateSkipped {
+				skipped = append(skipped, value)
+			}
+		},
+	}).Wait()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"production"}) {
+		t.Errorf("Expected [production] skipped, got %v", skipped)
+	}
+}
+
+// TestExecutionCancelGated checks cancelling a value awaiting approval.
+func TestExecutionCancelGated(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", nil)
+	e := exec.Start(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{Gate: func(string) bool { return true }})
+	for deadline := time.Now().Add(time.Second); len(e.Awaiting()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	e.CancelSubtree("production")
+	if err := e.Wait(); !errors.Is(err, exec.ErrSubtreeCanceled) {
+		t.Errorf("Expected ErrSubtreeCanceled, got %v", err)
+	}
+}
+
+// TestRunApproverDone checks that the context an Approver is asked with is
+// done once the value is approved another way.
+func TestRunApproverDone(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("production", nil)
+
+	asked := make(chan struct{})
+	answered := make(chan struct{})
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		// the run is still going, so only approving can have ended it
+		select {
+		case <-answered:
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("approver still asking")
+		}
+	}, exec.Options[string]{
+		Gate: func(string) bool { return true },
+		Approver: exec.ApproverFunc[string](func(ctx context.Context, value string) error {
+			close(asked)
+			<-ctx.Done()
+			close(answered)
+			return ctx.Err()
+		}),
+	})
+	<-asked
+	e.Approve("production")
+	if err := e.Wait(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// ArtifactStore keeps what calls produce, like their output, reports, or
+// build logs, by the value they were for and a name, through SaveArtifact.
+type ArtifactStore[T comparable] interface {
+	// Put stores data as an artifact of a value, replacing any with the
+	// same name.
+	Put(ctx context.Context, value T, name string, data []byte) error
+}
+
+// Artifacts is an ArtifactStore in memory, read once a run is over, like
+// to show the output of each value that failed on its own rather than
+// interleaved with the others':
+//
+//	artifacts := &exec.Artifacts[string]{}
+//	err := exec.RunFuncs(ctx, g, r.Funcs(), exec.Options[string]{Artifacts: artifacts})
+//	var errs exec.Errors[string]
+//	if errors.As(err, &errs) {
+//		for _, e := range errs {
+//			stderr, _ := artifacts.Get(e.Node, "stderr")
+//			fmt.Printf("%v failed:\n%s\n", e.Node, stderr)
+//		}
+//	}
+//
+// The zero value is ready to use.
+type Artifacts[T comparable] struct {
+	mu    sync.Mutex
+	nodes map[T]map[string][]byte
+}
+
+// Put stores data as an artifact of a value.
+func (a *Artifacts[T]) Put(_ context.Context, value T, name string, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nodes == nil {
+		a.nodes = make(map[T]map[string][]byte)
+	}
+	if a.nodes[value] == nil {
+		a.nodes[value] = make(map[string][]byte)
+	}
+	a.nodes[value][name] = slices.Clone(data)
+	return nil
+}
+
+// Get returns an artifact of a value, and whether there is one.
+func (a *Artifacts[T]) Get(value T, name string) ([]byte, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	data, ok := a.nodes[value][name]
+	return data, ok
+}
+
+// Names returns the names of a value's artifacts, sorted.
+func (a *Artifacts[T]) Names(value T) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var names []string
+	for name := range a.nodes[value] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// artifactSink saves artifacts for the value of a call.
+type artifactSink func(ctx context.Context, name string, data []byte) error
+
+// SaveArtifact stores data as an artifact of the value a call is
+// processing, from the context passed to it, in the run's
+// Options.Artifacts. Without one, as outside of Run, it does nothing, so
+// that calls can save artifacts whether or not anything keeps them.
+func SaveArtifact(ctx context.Context, name string, data []byte) error {
+	sink, _ := ctx.Value(artifactsKey).(artifactSink)
+	if sink == nil {
+		return nil
+	}
+	return sink(ctx, name, data)
+}
+
+// savesArtifacts reports whether artifacts saved from ctx are kept.
+func savesArtifacts(ctx context.Context) bool {
+	sink, _ := ctx.Value(artifactsKey).(artifactSink)
+	return sink != nil
+}
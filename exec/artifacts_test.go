@@ -0,0 +1,91 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestArtifacts checks that what calls save is kept by value.
+func TestArtifacts(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	artifacts := &exec.Artifacts[string]{}
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		if err := exec.SaveArtifact(ctx, "log", []byte("building "+value)); err != nil {
+			return err
+		}
+		if value == "app" {
+			if err := exec.SaveArtifact(ctx, "coverage", []byte("87%")); err != nil {
+				return err
+			}
+			return errors.New("tests failed")
+		}
+		return nil
+	}, exec.Options[string]{Artifacts: artifacts})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	tests := []struct {
+		value string
+		names []string
+		log   string
+	}{
+		{"lib", []string{"log"}, "building lib"},
+		{"app", []string{"coverage", "log"}, "building app"},
+		{"other", nil, ""},
+	}
+	for _, tt := range tests {
+		if names := artifacts.Names(tt.value); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("Expected %s artifacts %v, got %v", tt.value, tt.names, names)
+		}
+		if log, _ := artifacts.Get(tt.value, "log"); string(log) != tt.log {
+			t.Errorf("Expected %s log %q, got %q", tt.value, tt.log, log)
+		}
+	}
+
+	if err := exec.SaveArtifact(context.Background(), "log", nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestArtifactsCommands checks that the output of each command is kept
+// apart from the others'.
+func TestArtifactsCommands(t *testing.T) {
+	// the value that fails runs last, so that it doesn't cancel the others
+	var g topo.Graph[string]
+	g.AddNode("test-2", []string{"test-0", "test-1", "test-3"})
+	commands := make(map[string]exec.Command)
+	for i := range 4 {
+		value := fmt.Sprintf("test-%d", i)
+		commands[value] = exec.Command{Shell: `for i in 1 2 3; do echo "$TOPO_NODE $i"; echo "$TOPO_NODE err" >&2; done; [ "$TOPO_NODE" != test-2 ]`}
+	}
+	r := &exec.CommandRunner[string]{Commands: commands}
+	artifacts := &exec.Artifacts[string]{}
+	err := exec.RunFuncs(context.Background(), &g, r.Funcs(), exec.Options[string]{Artifacts: artifacts})
+
+	var errs exec.Errors[string]
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Node != "test-2" {
+		t.Fatalf("Expected test-2 to fail, got %v", err)
+	}
+	for value := range commands {
+		stdout, _ := artifacts.Get(value, "stdout")
+		expected := fmt.Sprintf("%[1]s 1\n%[1]s 2\n%[1]s 3\n", value)
+		if string(stdout) != expected {
+			t.Errorf("Expected %s stdout %q, got %q", value, expected, stdout)
+		}
+		stderr, _ := artifacts.Get(value, "stderr")
+		expected = fmt.Sprintf("%[1]s err\n%[1]s err\n%[1]s err\n", value)
+		if string(stderr) != expected {
+			t.Errorf("Expected %s stderr %q, got %q", value, expected, stderr)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package exec
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrCacheMiss is returned by CacheBackend.Get for a fingerprint it holds
+// nothing for.
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache records the fingerprints of calls that succeeded, so that Run can
+// skip values whose fingerprint hasn't changed since. See
+// Options.Fingerprint.
+type Cache interface {
+	// Has reports whether a call with the fingerprint succeeded.
+	Has(ctx context.Context, fingerprint string) (bool, error)
+	// Add records that a call with the fingerprint succeeded.
+	Add(ctx context.Context, fingerprint string) error
+}
+
+// CacheBackend stores data by fingerprint, where runs on other machines
+// can find it, like a remote build cache. The exec/cache package has
+// backends on disk and over HTTP.
+type CacheBackend interface {
+	// Get returns the data stored for a fingerprint, or ErrCacheMiss.
+	Get(ctx context.Context, fingerprint string) ([]byte, error)
+	// Put stores data for a fingerprint, replacing any stored before.
+	Put(ctx context.Context, fingerprint string, data []byte) error
+}
+
+// BackendCache returns a Cache that keeps its fingerprints in a backend,
+// as empty entries, so that runs sharing the backend skip the values any
+// of them has done:
+//
+//	opts.Cache = exec.BackendCache(cache.NewHTTP("https://cache.example.com/runs"))
+func BackendCache(b CacheBackend) Cache {
+	return backendCache{b}
+}
+
+// backendCache is a Cache in a CacheBackend.
+type backendCache struct {
+	b CacheBackend
+}
+
+func (c backendCache) Has(ctx context.Context, fingerprint string) (bool, error) {
+	_, err := c.b.Get(ctx, fingerprint)
+	switch {
+	case errors.Is(err, ErrCacheMiss):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+func (c backendCache) Add(ctx context.Context, fingerprint string) error {
+	return c.b.Put(ctx, fingerprint, nil)
+}
+
+// settle decides what to do with a value that's ready: StateSkipped if
+// Options.Filter leaves it out, StateCached if its fingerprint is in
+// Options.Cache, or StateReady if it needs calling. It returns the value's
+// fingerprint too, if it has one.
+func (o Options[T]) settle(ctx context.Context, value T) (topo.NodeState, string) {
+	if o.Filter != nil && !o.Filter(value) {
+		return topo.StateSkipped, ""
+	}
+	if !o.caching() {
+		return topo.StateReady, ""
+	}
+	return o.lookup(ctx, value)
+}
+
+// caching reports whether values are looked up in Options.Cache.
+func (o Options[T]) caching() bool {
+	return o.Fingerprint != nil && o.Cache != nil
+}
+
+// lookup fingerprints a value and looks it up in Options.Cache, for
+// settle. Either can be slow, so Run does it off the goroutine scheduling
+// the calls.
+func (o Options[T]) lookup(ctx context.Context, value T) (topo.NodeState, string) {
+	fingerprint, err := o.Fingerprint(ctx, value)
+	if err != nil {
+		Logger(ctx).Warn("fingerprinting", "error", err)
+		return topo.StateReady, ""
+	}
+	if fingerprint == "" {
+		return topo.StateReady, ""
+	}
+	cached, err := o.Cache.Has(ctx, fingerprint)
+	if err != nil {
+		Logger(ctx).Warn("checking cache", "fingerprint", fingerprint, "error", err)
+		return topo.StateReady, fingerprint
+	}
+	if cached {
+		return topo.StateCached, fingerprint
+	}
+	return topo.StateReady, fingerprint
+}
@@ -0,0 +1,171 @@
+// Package cache has backends for exec.CacheBackend: a directory, which CI
+// machines can share through a mounted volume, and an HTTP server, like a
+// remote build cache.
+//
+// Either is used for the fingerprints of a run through exec.BackendCache,
+// and calls can keep their outputs in the same backend, by fingerprint, to
+// restore them when they're skipped:
+//
+//	backend := cache.NewHTTP("https://cache.example.com/deploys")
+//	err := exec.Run(ctx, g, build, exec.Options[string]{
+//		Fingerprint: hashInputs,
+//		Cache:       exec.BackendCache(backend),
+//	})
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// ErrInvalidFingerprint is returned for fingerprints that can't be used as
+// a file name or URL path segment: empty ones, and those with slashes or
+// backslashes, or that start with a dot.
+var ErrInvalidFingerprint = errors.New("invalid fingerprint")
+
+// checkFingerprint returns an error for fingerprints that could escape the
+// directory or URL they're kept under.
+func checkFingerprint(fingerprint string) error {
+	if fingerprint == "" || strings.ContainsAny(fingerprint, `/\`) || strings.HasPrefix(fingerprint, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidFingerprint, fingerprint)
+	}
+	return nil
+}
+
+// Dir is a backend keeping each fingerprint's data in a file in a
+// directory, under a subdirectory named for the fingerprint's first two
+// characters, so that no directory grows too large.
+type Dir string
+
+// path returns the file holding a fingerprint's data.
+func (d Dir) path(fingerprint string) string {
+	return filepath.Join(string(d), fingerprint[:min(2, len(fingerprint))], fingerprint)
+}
+
+// Get returns the data stored for a fingerprint, or exec.ErrCacheMiss.
+func (d Dir) Get(_ context.Context, fingerprint string) ([]byte, error) {
+	if err := checkFingerprint(fingerprint); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(d.path(fingerprint))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, exec.ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put stores data for a fingerprint. The file is written under another
+// name and renamed into place, so that readers sharing the directory never
+// see it half written.
+func (d Dir) Put(_ context.Context, fingerprint string, data []byte) error {
+	if err := checkFingerprint(fingerprint); err != nil {
+		return err
+	}
+	path := d.path(fingerprint)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// HTTP is a backend on an HTTP server, keeping each fingerprint's data at
+// the URL of the fingerprint under its base URL, read with GET and written
+// with PUT. A 404 from GET is a cache miss. This is the protocol of common
+// remote build caches, and of a plain WebDAV share.
+type HTTP struct {
+	// URL is the base URL the fingerprints are kept under.
+	URL string
+	// Header is added to each request, for credentials.
+	Header http.Header
+	// Client makes the requests; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// NewHTTP returns a backend keeping fingerprints under a base URL.
+func NewHTTP(baseURL string) *HTTP {
+	return &HTTP{URL: baseURL}
+}
+
+// Get returns the data stored for a fingerprint, or exec.ErrCacheMiss.
+func (h *HTTP) Get(ctx context.Context, fingerprint string) ([]byte, error) {
+	resp, err := h.do(ctx, http.MethodGet, fingerprint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, exec.ErrCacheMiss
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("getting %s: unexpected status %s", fingerprint, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put stores data for a fingerprint.
+func (h *HTTP) Put(ctx context.Context, fingerprint string, data []byte) error {
+	resp, err := h.do(ctx, http.MethodPut, fingerprint, data)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("putting %s: unexpected status %s", fingerprint, resp.Status)
+	}
+	return nil
+}
+
+// do makes a request for a fingerprint's URL.
+func (h *HTTP) do(ctx context.Context, method, fingerprint string, body []byte) (*http.Response, error) {
+	if err := checkFingerprint(fingerprint); err != nil {
+		return nil, err
+	}
+	u, err := url.JoinPath(h.URL, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range h.Header {
+		req.Header[key] = values
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,129 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+	"github.com/sam-fredrickson/go-topo/exec/cache"
+)
+
+var (
+	_ exec.CacheBackend = cache.Dir("")
+	_ exec.CacheBackend = (*cache.HTTP)(nil)
+)
+
+// server is a remote cache in memory, requiring a token.
+type server struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/cache/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.entries[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.entries[key] = data
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// TestBackends checks storing and reading data in each backend.
+func TestBackends(t *testing.T) {
+	srv := httptest.NewServer(&server{entries: make(map[string][]byte)})
+	defer srv.Close()
+	remote := cache.NewHTTP(srv.URL + "/cache")
+	remote.Header = http.Header{"Authorization": {"Bearer token"}}
+
+	tests := []struct {
+		name    string
+		backend exec.CacheBackend
+	}{
+		{"dir", cache.Dir(t.TempDir())},
+		{"http", remote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := tt.backend.Get(ctx, "3f2a9c"); !errors.Is(err, exec.ErrCacheMiss) {
+				t.Errorf("Expected error %v, got %v", exec.ErrCacheMiss, err)
+			}
+			for _, data := range []string{"first", "second"} {
+				if err := tt.backend.Put(ctx, "3f2a9c", []byte(data)); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				actual, err := tt.backend.Get(ctx, "3f2a9c")
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if string(actual) != data {
+					t.Errorf("Expected %q, got %q", data, actual)
+				}
+			}
+			for _, fingerprint := range []string{"", "../etc", `a\b`, ".hidden"} {
+				if err := tt.backend.Put(ctx, fingerprint, nil); !errors.Is(err, cache.ErrInvalidFingerprint) {
+					t.Errorf("Expected error %v for %q, got %v", cache.ErrInvalidFingerprint, fingerprint, err)
+				}
+			}
+		})
+	}
+
+	unauthorized := cache.NewHTTP(srv.URL + "/cache")
+	if _, err := unauthorized.Get(context.Background(), "3f2a9c"); err == nil || errors.Is(err, exec.ErrCacheMiss) {
+		t.Errorf("Expected an error other than a miss, got %v", err)
+	}
+}
+
+// TestSharedRuns checks that runs sharing a backend skip the values any of
+// them has done.
+func TestSharedRuns(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	dir := cache.Dir(t.TempDir())
+	run := func() int32 {
+		var calls atomic.Int32
+		err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+			calls.Add(1)
+			return nil
+		}, exec.Options[string]{
+			Fingerprint: func(_ context.Context, value string) (string, error) { return value + "-v1", nil },
+			Cache:       exec.BackendCache(dir),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return calls.Load()
+	}
+	if calls := run(); calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+	if calls := run(); calls != 0 {
+		t.Errorf("Expected no calls, got %d", calls)
+	}
+}
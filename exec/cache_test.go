@@ -0,0 +1,181 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// mapCache is a Cache in memory.
+type mapCache struct {
+	mu  sync.Mutex
+	set map[string]bool
+	err error
+}
+
+func (c *mapCache) Has(_ context.Context, fingerprint string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set[fingerprint], c.err
+}
+
+func (c *mapCache) Add(_ context.Context, fingerprint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.set == nil {
+		c.set = make(map[string]bool)
+	}
+	c.set[fingerprint] = true
+	return c.err
+}
+
+// TestRunCache checks that values whose fingerprint is cached aren't
+// called, and that those called are cached for the next run.
+func TestRunCache(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+	g.AddNode("tool", nil)
+
+	versions := map[string]string{"app": "1", "lib": "1", "tool": ""}
+	cache := &mapCache{}
+	opts := exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) {
+			if versions[value] == "" {
+				return "", nil
+			}
+			return value + "@" + versions[value], nil
+		},
+		Cache: cache,
+	}
+	hits := make(map[string]string)
+	opts.OnCached = func(value, fingerprint string) { hits[value] = fingerprint }
+	run := func() ([]string, map[string]topo.NodeState) {
+		var mu sync.Mutex
+		var called []string
+		states := make(map[string]topo.NodeState)
+		opts.OnState = func(value string, state topo.NodeState) {
+			if state == topo.StateCached && hits[value] == "" {
+				t.Errorf("Expected OnCached before %s was reported cached", value)
+			}
+			states[value] = state
+		}
+		err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			called = append(called, value)
+			return nil
+		}, opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return called, states
+	}
+
+	if called, _ := run(); len(called) != 3 {
+		t.Errorf("Expected 3 calls, got %v", called)
+	}
+	versions["app"] = "2"
+	called, states := run()
+	if !reflect.DeepEqual(called, []string{"tool", "app"}) && !reflect.DeepEqual(called, []string{"app", "tool"}) {
+		t.Errorf("Expected calls for app and tool, got %v", called)
+	}
+	if states["lib"] != topo.StateCached || states["app"] != topo.StateSucceeded {
+		t.Errorf("Expected lib cached and app succeeded, got %v", states)
+	}
+	if !reflect.DeepEqual(hits, map[string]string{"lib": "lib@1"}) {
+		t.Errorf("Expected lib cached as lib@1, got %v", hits)
+	}
+
+	// a broken cache only loses the caching
+	cache.err = errors.New("unreachable")
+	if called, _ := run(); len(called) != 3 {
+		t.Errorf("Expected 3 calls, got %v", called)
+	}
+}
+
+// TestRunCacheSlow checks that a slow lookup in the cache doesn't hold up
+// the other values.
+func TestRunCacheSlow(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("slow", nil)
+	g.AddNode("fast", nil)
+
+	started := make(chan struct{})
+	opts := exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) {
+			if value == "slow" {
+				select {
+				case <-started:
+				case <-time.After(5 * time.Second):
+					t.Errorf("Expected fast to start while slow was fingerprinted")
+				}
+			}
+			return value, nil
+		},
+		Cache: &mapCache{},
+	}
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+		if value == "fast" {
+			close(started)
+		}
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// slowBackend is a CacheBackend whose lookups wait for release, ignoring
+// their context, like a remote cache that's stopped answering.
+type slowBackend struct {
+	release chan struct{}
+}
+
+func (b slowBackend) Get(context.Context, string) ([]byte, error) {
+	<-b.release
+	return nil, exec.ErrCacheMiss
+}
+
+func (b slowBackend) Put(context.Context, string, []byte) error {
+	return nil
+}
+
+// TestRunCacheSlowFailure checks that a value still being looked up in the
+// cache when a sibling fails is reported as skipped.
+func TestRunCacheSlowFailure(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("slow", nil)
+	g.AddNode("fail", nil)
+
+	backend := slowBackend{release: make(chan struct{})}
+	defer close(backend.release)
+	states := make(map[string]topo.NodeState)
+	opts := exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) {
+			if value == "fail" {
+				return "", nil
+			}
+			return value, nil
+		},
+		Cache:   exec.BackendCache(backend),
+		OnState: func(value string, state topo.NodeState) { states[value] = state },
+	}
+	failure := errors.New("failed")
+	err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+		return failure
+	}, opts)
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the failure, got %v", err)
+	}
+	want := map[string]topo.NodeState{"fail": topo.StateFailed, "slow": topo.StateSkipped}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Expected %v, got %v", want, states)
+	}
+}
@@ -0,0 +1,250 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+)
+
+// NodeEnv is the environment variable holding the value a command runs
+// for, as formatted by fmt.Sprint.
+const NodeEnv = "TOPO_NODE"
+
+// tailSize is how much of what a failing command wrote to stderr is kept
+// in its CommandError.
+const tailSize = 4096
+
+// Command is a command run for a value by a CommandRunner.
+type Command struct {
+	// Args is the program to run and its arguments. The program is found
+	// in PATH if it has no slashes.
+	Args []string
+	// Shell, if set in place of Args, is a command line run with "sh -c",
+	// for pipes, redirects, and variables like $TOPO_NODE.
+	Shell string
+	// Dir is the working directory of the command; the current one if
+	// empty.
+	Dir string
+	// Env is added to the environment of the command, as "KEY=value",
+	// after the runner's and NodeEnv.
+	Env []string
+}
+
+// args returns the program and arguments to run.
+func (c Command) args() []string {
+	if c.Shell != "" {
+		return []string{"sh", "-c", c.Shell}
+	}
+	return c.Args
+}
+
+// CommandError is returned by a CommandRunner for a command that fails.
+type CommandError struct {
+	// Args are the program and arguments that were run.
+	Args []string
+	// Code is the command's exit code, or -1 if it didn't exit, like when
+	// it was killed, or couldn't be started.
+	Code int
+	// Stderr is the end of what the command wrote to stderr.
+	Stderr []byte
+	// Err is the error running the command, or the one ExitCodes maps
+	// its code to.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s: %v", strings.Join(e.Args, " "), e.Err)
+	if stderr := strings.TrimSpace(string(e.Stderr)); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// CommandRunner runs a command for each value, configured in Commands, so
+// that a pipeline of programs needs no code of its own:
+//
+//	r := &exec.CommandRunner[string]{
+//		Commands: map[string]exec.Command{
+//			"base": {Args: []string{"docker", "build", "-t", "base", "."}, Dir: "images/base"},
+//			"app":  {Shell: "make image TAG=$TOPO_NODE", Dir: "app"},
+//		},
+//		Stdout: os.Stdout,
+//		Stderr: os.Stderr,
+//	}
+//	err := exec.RunFuncs(ctx, g, r.Funcs(), exec.Options[string]{})
+//
+// A command is killed if the context passed to its call is cancelled. In a
+// run with Options.Artifacts, the whole of what each command writes to
+// stdout and stderr is saved as its value's "stdout" and "stderr"
+// artifacts, even when it fails.
+type CommandRunner[T comparable] struct {
+	// Commands holds the command for each value.
+	Commands map[T]Command
+	// Env is added to the environment of every command, as "KEY=value".
+	Env []string
+	// Stdout and Stderr, if set, receive what the commands write to each,
+	// every line prefixed with the value it's for, so that the output of
+	// commands running at once can be told apart. Output is discarded
+	// otherwise.
+	Stdout, Stderr io.Writer
+	// ExitCodes maps exit codes other than zero to what they mean: a nil
+	// error for codes that mean success, like a linter's "nothing to do",
+	// or an error to fail with in place of the *os/exec.ExitError, so that
+	// callers can tell failures apart with errors.Is.
+	ExitCodes map[int]error
+
+	mu sync.Mutex
+}
+
+// Funcs returns r.Run for each value in Commands, for RunFuncs, which then
+// reports values without a command before running anything.
+func (r *CommandRunner[T]) Funcs() Funcs[T] {
+	funcs := make(Funcs[T], len(r.Commands))
+	for value := range r.Commands {
+		funcs[value] = r.Run
+	}
+	return funcs
+}
+
+// Run runs the command for a value, returning a *CommandError if it fails,
+// or a *MissingNodeError if there's none.
+func (r *CommandRunner[T]) Run(ctx context.Context, value T) error {
+	command, ok := r.Commands[value]
+	if !ok {
+		return &MissingNodeError[T]{Node: value}
+	}
+	args := command.args()
+	if len(args) == 0 {
+		return &CommandError{Args: args, Code: -1, Err: errors.New("no command")}
+	}
+	cmd := osexec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // running configured commands is the point
+	cmd.Dir = command.Dir
+	cmd.Env = append(os.Environ(), r.Env...)
+	cmd.Env = append(cmd.Env, NodeEnv+"="+fmt.Sprint(value))
+	cmd.Env = append(cmd.Env, command.Env...)
+
+	prefix := fmt.Sprintf("%v | ", value)
+	stdout := r.lines(r.Stdout, prefix)
+	stderr := r.lines(r.Stderr, prefix)
+	tail := &tail{}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderr, tail)
+	// the whole output is kept as artifacts, when the run keeps them
+	var stdoutBuf, stderrBuf bytes.Buffer
+	if savesArtifacts(ctx) {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, &stdoutBuf)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &stderrBuf)
+	}
+	err := cmd.Run()
+	stdout.flush()
+	stderr.flush()
+	var saveErr error
+	if savesArtifacts(ctx) {
+		saveErr = errors.Join(
+			SaveArtifact(ctx, "stdout", stdoutBuf.Bytes()),
+			SaveArtifact(ctx, "stderr", stderrBuf.Bytes()))
+	}
+	if err == nil {
+		return saveErr
+	}
+
+	code := -1
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		code = exitErr.ExitCode()
+		if mapped, ok := r.ExitCodes[code]; ok {
+			if mapped == nil {
+				return saveErr
+			}
+			err = mapped
+		}
+	}
+	if ctx.Err() != nil && code == -1 {
+		err = errors.Join(err, context.Cause(ctx))
+	}
+	cmdErr := &CommandError{Args: args, Code: code, Stderr: tail.bytes(), Err: err}
+	if saveErr != nil {
+		return errors.Join(cmdErr, saveErr)
+	}
+	return cmdErr
+}
+
+// lines returns a writer prefixing each line written to it before writing
+// it to w, one whole line at a time, under the runner's lock.
+func (r *CommandRunner[T]) lines(w io.Writer, prefix string) *lineWriter {
+	return &lineWriter{w: w, prefix: prefix, mu: &r.mu}
+}
+
+// lineWriter prefixes each line written to it. Partial lines are held
+// until they're finished, or flush is called.
+type lineWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	if l.w == nil {
+		return len(p), nil
+	}
+	l.buf = append(l.buf, p...)
+	end := bytes.LastIndexByte(l.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if err := l.write(l.buf[:end+1]); err != nil {
+		return 0, err
+	}
+	l.buf = append(l.buf[:0], l.buf[end+1:]...)
+	return len(p), nil
+}
+
+// flush writes a partial line left at the end of the output.
+func (l *lineWriter) flush() {
+	if l.w == nil || len(l.buf) == 0 {
+		return
+	}
+	_ = l.write(append(l.buf, '\n'))
+	l.buf = nil
+}
+
+// write writes whole lines, each with the prefix.
+func (l *lineWriter) write(lines []byte) error {
+	var b bytes.Buffer
+	for line := range bytes.Lines(lines) {
+		b.WriteString(l.prefix)
+		b.Write(line)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.w.Write(b.Bytes())
+	return err
+}
+
+// tail keeps the last tailSize bytes written to it.
+type tail struct {
+	buf []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 2*tailSize {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-tailSize:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tail) bytes() []byte {
+	return t.buf[max(0, len(t.buf)-tailSize):]
+}
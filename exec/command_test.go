@@ -0,0 +1,147 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	topoexec "github.com/sam-fredrickson/go-topo/exec"
+)
+
+// syncBuffer is a buffer safe to write from the runner's commands.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.WriteString(string(p))
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// TestCommandRunner checks running a graph of commands, with their
+// directories, environments, and output.
+func TestCommandRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	dir := t.TempDir()
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"base"})
+	g.AddNode("base", nil)
+
+	var stdout, stderr syncBuffer
+	r := &topoexec.CommandRunner[string]{
+		Commands: map[string]topoexec.Command{
+			"base": {Args: []string{"sh", "-c", "printf 'built\\nin '; basename $PWD; echo warning >&2"}, Dir: dir},
+			"app":  {Shell: `echo "$TOPO_NODE from $REGISTRY/$TAG"; printf partial`, Env: []string{"TAG=v1"}},
+		},
+		Env:    []string{"REGISTRY=registry.example.com"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	if err := topoexec.RunFuncs(context.Background(), &g, r.Funcs(), topoexec.Options[string]{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "base | built\nbase | in " + filepath.Base(dir) + "\napp | app from registry.example.com/v1\napp | partial\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected stdout %q, got %q", expected, stdout.String())
+	}
+	if stderr.String() != "base | warning\n" {
+		t.Errorf("Expected stderr %q, got %q", "base | warning\n", stderr.String())
+	}
+}
+
+// TestCommandRunnerErrors checks how failing commands are reported.
+func TestCommandRunnerErrors(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	errLocked := errors.New("locked")
+	r := &topoexec.CommandRunner[string]{
+		Commands: map[string]topoexec.Command{
+			"ok":      {Shell: "exit 3"},
+			"locked":  {Shell: "exit 4"},
+			"fails":   {Shell: "echo no space left >&2; exit 1"},
+			"missing": {Args: []string{"./no-such-program"}},
+			"empty":   {},
+		},
+		ExitCodes: map[int]error{3: nil, 4: errLocked},
+	}
+
+	tests := []struct {
+		value string
+		code  int
+		is    error
+		msg   string
+	}{
+		{"ok", 0, nil, ""},
+		{"locked", 4, errLocked, "sh -c exit 4: locked"},
+		{"fails", 1, nil, "sh -c echo no space left >&2; exit 1: exit status 1: no space left"},
+		{"missing", -1, nil, "./no-such-program: "},
+		{"empty", -1, nil, ": no command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			err := r.Run(context.Background(), tt.value)
+			if tt.msg == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var cmdErr *topoexec.CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("Expected a CommandError, got %v", err)
+			}
+			if cmdErr.Code != tt.code {
+				t.Errorf("Expected code %d, got %d", tt.code, cmdErr.Code)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Expected error %v, got %v", tt.is, err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.msg) {
+				t.Errorf("Expected error starting %q, got %q", tt.msg, err.Error())
+			}
+		})
+	}
+
+	var missing *topoexec.MissingNodeError[string]
+	if err := r.Run(context.Background(), "other"); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingNodeError, got %v", err)
+	}
+}
+
+// TestCommandRunnerCancel checks that commands are killed when their
+// context is cancelled.
+func TestCommandRunnerCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep")
+	}
+	r := &topoexec.CommandRunner[string]{Commands: map[string]topoexec.Command{
+		"slow": {Args: []string{"sleep", "10"}},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := r.Run(ctx, "slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command killed, took %v", elapsed)
+	}
+}
@@ -0,0 +1,158 @@
+package exec
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Comparison is how a run differs from an earlier one, as returned by
+// Compare: the basis for explaining why a pipeline got slower.
+type Comparison[T comparable] struct {
+	// Elapsed is how much longer the later run took; negative if it was
+	// quicker.
+	Elapsed time.Duration
+	// Failing are the values that failed in the later run but not the
+	// earlier one, and Fixed those that failed in the earlier one and
+	// succeeded in the later.
+	Failing []T
+	Fixed   []T
+	// Slower are the values whose call took longer in the later run, by
+	// more than the threshold given to Compare, and Faster those it took
+	// less time, each by how much, most first.
+	Slower []Change[T]
+	Faster []Change[T]
+	// Uncached are the values cached in the earlier run and called in the
+	// later, and Cached the reverse.
+	Uncached []T
+	Cached   []T
+	// OldPath and NewPath are the critical paths of the two runs, as
+	// returned by Report.CriticalPath, when they differ.
+	OldPath []T
+	NewPath []T
+}
+
+// Change is how long a value's call took in two runs.
+type Change[T comparable] struct {
+	Node T
+	Old  time.Duration
+	New  time.Duration
+}
+
+// Delta returns how much longer the call took in the later run.
+func (c Change[T]) Delta() time.Duration {
+	return c.New - c.Old
+}
+
+// Compare compares the report of a run with that of an earlier one, like
+// one stored as JSON from the last run of a pipeline. Durations that
+// changed by threshold or less count as the same. Values in only one of
+// the runs are left out, as are values that weren't called in both when
+// comparing durations. Lists of values are in the order of the later
+// report.
+func Compare[T comparable](before, after *Report[T], threshold time.Duration) Comparison[T] {
+	c := Comparison[T]{Elapsed: after.Elapsed - before.Elapsed}
+	old := make(map[T]NodeReport[T], len(before.Nodes))
+	for _, n := range before.Nodes {
+		old[n.Node] = n
+	}
+	for _, n := range after.Nodes {
+		o, ok := old[n.Node]
+		if !ok {
+			continue
+		}
+		switch {
+		case n.State == topo.StateFailed && o.State != topo.StateFailed:
+			c.Failing = append(c.Failing, n.Node)
+		case o.State == topo.StateFailed && n.State == topo.StateSucceeded:
+			c.Fixed = append(c.Fixed, n.Node)
+		}
+		switch {
+		case o.State == topo.StateCached && n.State != topo.StateCached && n.Duration > 0:
+			c.Uncached = append(c.Uncached, n.Node)
+		case n.State == topo.StateCached && o.State != topo.StateCached && o.Duration > 0:
+			c.Cached = append(c.Cached, n.Node)
+		}
+		if o.Duration <= 0 || n.Duration <= 0 {
+			continue
+		}
+		change := Change[T]{Node: n.Node, Old: o.Duration, New: n.Duration}
+		switch {
+		case change.Delta() > threshold:
+			c.Slower = append(c.Slower, change)
+		case -change.Delta() > threshold:
+			c.Faster = append(c.Faster, change)
+		}
+	}
+	slices.SortStableFunc(c.Slower, func(a, b Change[T]) int {
+		return cmp.Compare(b.Delta(), a.Delta())
+	})
+	slices.SortStableFunc(c.Faster, func(a, b Change[T]) int {
+		return cmp.Compare(a.Delta(), b.Delta())
+	})
+	if oldPath, newPath := before.CriticalPath(), after.CriticalPath(); !slices.Equal(oldPath, newPath) {
+		c.OldPath, c.NewPath = oldPath, newPath
+	}
+	return c
+}
+
+// String returns a summary of the comparison, a line for each kind of
+// change found:
+//
+//	3m0s slower
+//	slower: test 2m0s (1m0s to 3m0s)
+//	no longer cached: lib
+//	critical path: lib → test → app, was lib → build → app
+func (c *Comparison[T]) String() string {
+	var b strings.Builder
+	switch {
+	case c.Elapsed > 0:
+		fmt.Fprintf(&b, "%v slower", c.Elapsed.Round(time.Millisecond))
+	case c.Elapsed < 0:
+		fmt.Fprintf(&b, "%v faster", (-c.Elapsed).Round(time.Millisecond))
+	default:
+		b.WriteString("as fast")
+	}
+	values := func(label string, values []T) {
+		if len(values) > 0 {
+			fmt.Fprintf(&b, "\n%s: %s", label, joinValues(values, ", "))
+		}
+	}
+	changes := func(label string, changes []Change[T]) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:", label)
+		for i, change := range changes {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			delta := change.Delta()
+			fmt.Fprintf(&b, " %v %v (%v to %v)", change.Node, max(delta, -delta).Round(time.Millisecond),
+				change.Old.Round(time.Millisecond), change.New.Round(time.Millisecond))
+		}
+	}
+	values("newly failing", c.Failing)
+	values("fixed", c.Fixed)
+	changes("slower", c.Slower)
+	changes("faster", c.Faster)
+	values("no longer cached", c.Uncached)
+	values("newly cached", c.Cached)
+	if c.OldPath != nil || c.NewPath != nil {
+		fmt.Fprintf(&b, "\ncritical path: %s, was %s", joinValues(c.NewPath, " → "), joinValues(c.OldPath, " → "))
+	}
+	return b.String()
+}
+
+// joinValues formats values, separated by sep.
+func joinValues[T any](values []T, sep string) string {
+	s := make([]string, len(values))
+	for i, value := range values {
+		s[i] = fmt.Sprint(value)
+	}
+	return strings.Join(s, sep)
+}
@@ -0,0 +1,87 @@
+package exec_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// node returns the report of a value called from start to end, in
+// seconds.
+func node(value string, state topo.NodeState, start, end int, deps ...string) exec.NodeReport[string] {
+	return exec.NodeReport[string]{
+		Node:     value,
+		State:    state,
+		Deps:     deps,
+		Start:    time.Duration(start) * time.Second,
+		Duration: time.Duration(end-start) * time.Second,
+	}
+}
+
+// TestCompare checks comparing the report of a run with one stored from
+// an earlier run.
+func TestCompare(t *testing.T) {
+	stored, err := json.Marshal(exec.Report[string]{
+		Elapsed: 70 * time.Second,
+		Nodes: []exec.NodeReport[string]{
+			{Node: "lib", State: topo.StateCached, Fingerprint: "3f2a9c"},
+			node("db", topo.StateFailed, 0, 1),
+			node("lint", topo.StateSucceeded, 0, 5),
+			node("test", topo.StateSucceeded, 0, 40, "lib"),
+			node("build", topo.StateSucceeded, 0, 60, "lib"),
+			node("app", topo.StateSucceeded, 60, 70, "build", "test"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var before exec.Report[string]
+	if err := json.Unmarshal(stored, &before); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after := exec.Report[string]{
+		Elapsed: 200 * time.Second,
+		Nodes: []exec.NodeReport[string]{
+			node("db", topo.StateSucceeded, 0, 1),
+			node("lint", topo.StateFailed, 0, 5),
+			node("lib", topo.StateSucceeded, 0, 10),
+			node("build", topo.StateSucceeded, 10, 60, "lib"),
+			node("test", topo.StateSucceeded, 10, 190, "lib"),
+			node("app", topo.StateSucceeded, 190, 200, "build", "test"),
+			node("docs", topo.StateSucceeded, 0, 3),
+		},
+	}
+
+	c := exec.Compare(&before, &after, 5*time.Second)
+	expected := exec.Comparison[string]{
+		Elapsed:  130 * time.Second,
+		Failing:  []string{"lint"},
+		Fixed:    []string{"db"},
+		Slower:   []exec.Change[string]{{Node: "test", Old: 40 * time.Second, New: 180 * time.Second}},
+		Faster:   []exec.Change[string]{{Node: "build", Old: 60 * time.Second, New: 50 * time.Second}},
+		Uncached: []string{"lib"},
+		OldPath:  []string{"lib", "build", "app"},
+		NewPath:  []string{"lib", "test", "app"},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, c)
+	}
+	summary := "2m10s slower\n" +
+		"newly failing: lint\n" +
+		"fixed: db\n" +
+		"slower: test 2m20s (40s to 3m0s)\n" +
+		"faster: build 10s (1m0s to 50s)\n" +
+		"no longer cached: lib\n" +
+		"critical path: lib → test → app, was lib → build → app"
+	if s := c.String(); s != summary {
+		t.Errorf("Expected %q, got %q", summary, s)
+	}
+
+	if c := exec.Compare(&after, &after, 0); !reflect.DeepEqual(c, exec.Comparison[string]{}) || c.String() != "as fast" {
+		t.Errorf("Expected no changes, got %+v", c)
+	}
+}
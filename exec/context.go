@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"context"
+	"log/slog"
+)
+
+// contextKey keys the values the runners attach to the context passed to
+// each call.
+type contextKey int
+
+const (
+	nodeKey contextKey = iota
+	layerKey
+	attemptKey
+	loggerKey
+	paramsKey
+	addKey
+	nestedKey
+	artifactsKey
+	workerKey
+)
+
+// Node returns the value a call is processing, from the context passed to
+// it, and whether there is one of type T.
+func Node[T any](ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(nodeKey).(T)
+	return value, ok
+}
+
+// Layer returns the layer, from zero, of the value a call is processing,
+// from the context passed to it, and whether it's known. Run gives the
+// layer SortByLayers puts the value in, though it doesn't wait for layers;
+// RunLayer doesn't know it.
+func Layer(ctx context.Context) (int, bool) {
+	layer, ok := ctx.Value(layerKey).(int)
+	return layer, ok
+}
+
+// Attempt returns which try at the value a call is, from one, from the
+// context passed to it, or zero outside of a call.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey).(int)
+	return attempt
+}
+
+// WithLogger returns a copy of ctx carrying logger, for the runners given
+// ctx to log the run with.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the logger carried by ctx, or slog.Default. In a call,
+// it's the run's logger with the value and its layer as attributes, so
+// that what calls log can be told apart.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// callContext returns the context for a call processing value, in a layer
+// unless layer is negative.
+func callContext[T any](ctx context.Context, value T, layer int) context.Context {
+	logger := Logger(ctx).With("node", value)
+	ctx = context.WithValue(ctx, nodeKey, value)
+	if layer >= 0 {
+		logger = logger.With("layer", layer)
+		ctx = context.WithValue(ctx, layerKey, layer)
+	}
+	ctx = context.WithValue(ctx, attemptKey, 1)
+	return WithLogger(ctx, logger)
+}
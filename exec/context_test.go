@@ -0,0 +1,147 @@
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestContext checks what each runner attaches to the context passed to
+// calls.
+func TestContext(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	layers := [][]string{{"lib"}, {"app"}}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, fn exec.Func[string]) error
+		// layers is whether the layer of each value is known
+		layers bool
+	}{
+		{"RunLayers", func(ctx context.Context, fn exec.Func[string]) error {
+			return exec.RunLayers(ctx, layers, fn, 0)
+		}, true},
+		{"RunLayer", func(ctx context.Context, fn exec.Func[string]) error {
+			return exec.RunLayer(ctx, []string{"lib", "app"}, fn, 0)
+		}, false},
+		{"RunPool", func(ctx context.Context, fn exec.Func[string]) error {
+			return exec.RunPool(ctx, layers, func(ctx context.Context, _ int, value string) error {
+				return fn(ctx, value)
+			}, 2)
+		}, true},
+		{"Run", func(ctx context.Context, fn exec.Func[string]) error {
+			return exec.Run(ctx, &g, fn, exec.Options[string]{})
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			ctx := exec.WithLogger(context.Background(), logger)
+
+			var mu sync.Mutex
+			seen := make(map[string]string)
+			err := tt.run(ctx, func(ctx context.Context, value string) error {
+				node, ok := exec.Node[string](ctx)
+				if !ok || node != value {
+					t.Errorf("Expected node %s, got %q", value, node)
+				}
+				if _, ok := exec.Node[int](ctx); ok {
+					t.Error("Expected no node of another type")
+				}
+				layer, ok := exec.Layer(ctx)
+				mu.Lock()
+				seen[value] = fmt.Sprint(layer, ok, exec.Attempt(ctx))
+				mu.Unlock()
+				exec.Logger(ctx).Info("built")
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := map[string]string{"lib": "0 false 1", "app": "0 false 1"}
+			logs := []string{
+				"level=INFO msg=built node=lib",
+				"level=INFO msg=built node=app",
+			}
+			if tt.layers {
+				expected = map[string]string{"lib": "0 true 1", "app": "1 true 1"}
+				logs = []string{
+					"level=INFO msg=built node=lib layer=0",
+					"level=INFO msg=built node=app layer=1",
+				}
+			}
+			if !reflect.DeepEqual(seen, expected) {
+				t.Errorf("Expected %v, got %v", expected, seen)
+			}
+			for _, line := range logs {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("Expected %q in %q", line, out.String())
+				}
+			}
+		})
+	}
+}
+
+// TestContextOutsideCall checks the accessors outside of a call.
+func TestContextOutsideCall(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := exec.Node[string](ctx); ok {
+		t.Error("Expected no node")
+	}
+	if _, ok := exec.Layer(ctx); ok {
+		t.Error("Expected no layer")
+	}
+	if attempt := exec.Attempt(ctx); attempt != 0 {
+		t.Errorf("Expected attempt 0, got %d", attempt)
+	}
+	if logger := exec.Logger(ctx); logger != slog.Default() {
+		t.Errorf("Expected the default logger, got %v", logger)
+	}
+}
+
+// TestRunContext checks that Options.Context can add to the context of
+// each call.
+func TestRunContext(t *testing.T) {
+	type teamKey struct{}
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+
+	var mu sync.Mutex
+	teams := make(map[string]string)
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		teams[value], _ = ctx.Value(teamKey{}).(string)
+		return nil
+	}, exec.Options[string]{
+		Context: func(ctx context.Context, _ string) context.Context {
+			node, _ := exec.Node[string](ctx)
+			return context.WithValue(ctx, teamKey{}, "team-"+node)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"app": "team-app", "lib": "team-lib"}
+	if !reflect.DeepEqual(teams, expected) {
+		t.Errorf("Expected %v, got %v", expected, teams)
+	}
+}
@@ -0,0 +1,168 @@
+package exec
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// defaultKeep is how many samples a DurationHistory keeps for each value,
+// by default.
+const defaultKeep = 20
+
+// Sample is a value's estimated and actual duration in one run.
+type Sample struct {
+	// Estimate is zero when the value had none.
+	Estimate time.Duration `json:"estimate,omitempty"`
+	Actual   time.Duration `json:"actual"`
+}
+
+// DurationHistory tracks how long each value's call takes across runs,
+// against the estimate it had, like the durations given to
+// topo.Graph.SortByLayersBalanced. It's stored between runs as JSON, which
+// needs values that encoding/json takes as map keys, like strings:
+//
+//	history.Record(&report, estimates)
+//	for _, d := range history.Drift(0.25, 3) {
+//		log.Printf("%v takes %v, estimated %v", d.Node, d.Actual, d.Estimate)
+//	}
+//	estimates = history.Estimates()
+//
+// The zero value is ready to use.
+type DurationHistory[T comparable] struct {
+	// Samples holds the most recent samples of each value, oldest first.
+	Samples map[T][]Sample `json:"samples"`
+	// Keep is how many samples are kept for each value; 20 if zero.
+	Keep int `json:"keep,omitempty"`
+}
+
+// Drift is a value whose actual duration is consistently off from its
+// estimate.
+type Drift[T comparable] struct {
+	Node T
+	// Estimate is the value's latest estimate, and Actual the median of
+	// its recent durations.
+	Estimate time.Duration
+	Actual   time.Duration
+	// Ratio is the median of the value's recent durations over their
+	// estimates: above 1 for a value slower than estimated, below 1 for
+	// one faster.
+	Ratio float64
+}
+
+// Slower reports whether the value takes longer than estimated.
+func (d Drift[T]) Slower() bool {
+	return d.Ratio > 1
+}
+
+// Record adds a sample for each value whose call succeeded in a run, from
+// its report, with the estimate it had in estimates.
+func (h *DurationHistory[T]) Record(report *Report[T], estimates map[T]time.Duration) {
+	if h.Samples == nil {
+		h.Samples = make(map[T][]Sample)
+	}
+	keep := h.Keep
+	if keep <= 0 {
+		keep = defaultKeep
+	}
+	for _, n := range report.Nodes {
+		if n.State != topo.StateSucceeded {
+			continue
+		}
+		samples := append(h.Samples[n.Node], Sample{Estimate: estimates[n.Node], Actual: n.Duration})
+		h.Samples[n.Node] = samples[max(0, len(samples)-keep):]
+	}
+}
+
+// Estimates returns an estimate for each value with samples, the median
+// of its recent durations, to use in place of the estimates that drifted.
+func (h *DurationHistory[T]) Estimates() map[T]time.Duration {
+	estimates := make(map[T]time.Duration, len(h.Samples))
+	for value, samples := range h.Samples {
+		if len(samples) == 0 {
+			continue
+		}
+		actuals := make([]time.Duration, len(samples))
+		for i, sample := range samples {
+			actuals[i] = sample.Actual
+		}
+		estimates[value] = median(actuals)
+	}
+	return estimates
+}
+
+// Drift returns the values that were off from their estimate by more than
+// tolerance, as a fraction of the estimate, in the same direction in each
+// of their latest runs samples that had an estimate, so that a value that
+// was slow once doesn't count; values with fewer such samples are left
+// out. The most drifted values come first, with ties going to the slower
+// value, then the longer one, then the one that formats first.
+func (h *DurationHistory[T]) Drift(tolerance float64, runs int) []Drift[T] {
+	runs = max(runs, 1)
+	var drifts []Drift[T]
+	for value, samples := range h.Samples {
+		var recent []Sample
+		for i := len(samples) - 1; i >= 0 && len(recent) < runs; i-- {
+			if samples[i].Estimate > 0 {
+				recent = append(recent, samples[i])
+			}
+		}
+		if len(recent) < runs {
+			continue
+		}
+		ratios := make([]float64, len(recent))
+		actuals := make([]time.Duration, len(recent))
+		slower, faster := 0, 0
+		for i, sample := range recent {
+			ratios[i] = float64(sample.Actual) / float64(sample.Estimate)
+			actuals[i] = sample.Actual
+			switch {
+			case ratios[i] > 1+tolerance:
+				slower++
+			case ratios[i] < 1-tolerance:
+				faster++
+			}
+		}
+		if slower < runs && faster < runs {
+			continue
+		}
+		drifts = append(drifts, Drift[T]{
+			Node:     value,
+			Estimate: recent[0].Estimate,
+			Actual:   median(actuals),
+			Ratio:    median(ratios),
+		})
+	}
+	// how far off is the size of the log of the ratio, so that taking
+	// twice as long and half as long count the same; ties go to the
+	// slower, then the longer, then by how the values are formatted, so
+	// that the order doesn't depend on that of the map
+	slices.SortStableFunc(drifts, func(a, b Drift[T]) int {
+		if c := cmp.Compare(math.Abs(math.Log(b.Ratio)), math.Abs(math.Log(a.Ratio))); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Ratio, a.Ratio); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Actual, a.Actual); c != 0 {
+			return c
+		}
+		return cmp.Compare(fmt.Sprint(a.Node), fmt.Sprint(b.Node))
+	})
+	return drifts
+}
+
+// median returns the median of values, which it sorts, or the mean of the
+// middle two for an even number.
+func median[V time.Duration | float64](values []V) V {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
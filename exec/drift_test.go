@@ -0,0 +1,125 @@
+package exec_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// reportOf returns the report of a run where each value succeeded, taking
+// the durations given.
+func reportOf(durations map[string]time.Duration) *exec.Report[string] {
+	var r exec.Report[string]
+	for value, d := range durations {
+		r.Nodes = append(r.Nodes, exec.NodeReport[string]{Node: value, State: topo.StateSucceeded, Duration: d})
+	}
+	return &r
+}
+
+// TestDurationHistory checks finding the values whose estimates are
+// consistently off.
+func TestDurationHistory(t *testing.T) {
+	estimates := map[string]time.Duration{
+		"build": time.Second,
+		"test":  time.Second,
+		"lint":  4 * time.Second,
+		"flaky": time.Second,
+	}
+	var history exec.DurationHistory[string]
+	for run := range 4 {
+		flaky := time.Second
+		if run == 1 {
+			flaky = 3 * time.Second
+		}
+		r := reportOf(map[string]time.Duration{
+			"build": 2 * time.Second,
+			"test":  1050 * time.Millisecond,
+			"lint":  time.Second,
+			"flaky": flaky,
+			"new":   5 * time.Second,
+		})
+		r.Nodes = append(r.Nodes, exec.NodeReport[string]{Node: "broken", State: topo.StateFailed, Duration: time.Minute})
+		history.Record(r, estimates)
+	}
+
+	expected := []exec.Drift[string]{
+		{Node: "lint", Estimate: 4 * time.Second, Actual: time.Second, Ratio: 0.25},
+		{Node: "build", Estimate: time.Second, Actual: 2 * time.Second, Ratio: 2},
+	}
+	drifts := history.Drift(0.2, 3)
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, drifts)
+	}
+	if drifts[0].Slower() || !drifts[1].Slower() {
+		t.Errorf("Expected only build slower, got %+v", drifts)
+	}
+	if drifts := history.Drift(0.2, 5); len(drifts) != 0 {
+		t.Errorf("Expected no drift with too few runs, got %+v", drifts)
+	}
+
+	updated := history.Estimates()
+	expectedEstimates := map[string]time.Duration{
+		"build": 2 * time.Second,
+		"test":  1050 * time.Millisecond,
+		"lint":  time.Second,
+		"flaky": time.Second,
+		"new":   5 * time.Second,
+	}
+	if !reflect.DeepEqual(updated, expectedEstimates) {
+		t.Errorf("Expected %v, got %v", expectedEstimates, updated)
+	}
+}
+
+// TestDurationHistoryTies checks that values drifting as far are ordered
+// by value, the same in every call.
+func TestDurationHistoryTies(t *testing.T) {
+	values := []string{"e", "b", "d", "a", "c"}
+	estimates := make(map[string]time.Duration)
+	durations := make(map[string]time.Duration)
+	for _, value := range values {
+		estimates[value] = time.Second
+		durations[value] = 2 * time.Second
+	}
+	var history exec.DurationHistory[string]
+	for range 3 {
+		history.Record(reportOf(durations), estimates)
+	}
+	for range 10 {
+		var order []string
+		for _, d := range history.Drift(0.2, 3) {
+			order = append(order, d.Node)
+		}
+		if expected := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(order, expected) {
+			t.Fatalf("Expected %v, got %v", expected, order)
+		}
+	}
+}
+
+// TestDurationHistoryKeep checks that only recent samples are kept, and
+// that the history survives being stored as JSON.
+func TestDurationHistoryKeep(t *testing.T) {
+	history := exec.DurationHistory[string]{Keep: 3}
+	for i := range 5 {
+		history.Record(reportOf(map[string]time.Duration{"build": time.Duration(i+1) * time.Second}), nil)
+	}
+	expected := []exec.Sample{{Actual: 3 * time.Second}, {Actual: 4 * time.Second}, {Actual: 5 * time.Second}}
+	if !reflect.DeepEqual(history.Samples["build"], expected) {
+		t.Errorf("Expected %v, got %v", expected, history.Samples["build"])
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded exec.DurationHistory[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, history) {
+		t.Errorf("Expected %+v, got %+v", history, decoded)
+	}
+}
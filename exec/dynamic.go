@@ -0,0 +1,114 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrNotDynamic is returned by AddNode outside of a call made by Run, the
+// only runner whose graph can grow.
+var ErrNotDynamic = errors.New("nodes can only be added in calls made by Run")
+
+// addition is a node a call added.
+type addition[T any] struct {
+	value T
+	deps  []T
+}
+
+// additions collects the nodes a call adds.
+type additions[T any] struct {
+	mu    sync.Mutex
+	nodes []addition[T]
+}
+
+// take returns the nodes added so far.
+func (a *additions[T]) take() []addition[T] {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nodes
+}
+
+// AddNode adds a value, with its dependencies, to the run making a call,
+// from the context passed to it, for work that's only found by doing part
+// of it, like the packages a build turns out to need. The value runs in
+// the same run once its dependencies are done, which can be values
+// already done, values still to run, or other values added.
+//
+// Nodes are added once the call returns, if it returns nil. If a value
+// added is already in the graph, or is added twice, or the values added
+// would make a cycle, the call fails instead. The graph passed to Run isn't changed.
+func AddNode[T comparable](ctx context.Context, value T, deps []T) error {
+	a, ok := ctx.Value(addKey).(*additions[T])
+	if !ok {
+		return ErrNotDynamic
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nodes = append(a.nodes, addition[T]{value, deps})
+	return nil
+}
+
+// addedValues returns the values of the nodes a call added.
+func addedValues[T any](nodes []addition[T]) []T {
+	values := make([]T, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.value
+	}
+	return values
+}
+
+// withNodes returns a copy of g with the nodes a call added, or an error
+// if any is already in g, or added twice, or they make a cycle.
+func withNodes[T comparable](g *topo.Graph[T], nodes []addition[T]) (*topo.Graph[T], error) {
+	known := make(map[T]bool)
+	for _, value := range g.Nodes() {
+		known[value] = true
+	}
+	next := g.Clone()
+	for _, n := range nodes {
+		if known[n.value] {
+			return nil, fmt.Errorf("%v is already in the graph", n.value)
+		}
+		known[n.value] = true
+		next.AddNode(n.value, n.deps)
+	}
+	if cycles := next.Cycles(); len(cycles) > 0 {
+		return nil, fmt.Errorf("%w: %v", topo.ErrCyclicDependency, cycles)
+	}
+	return next, nil
+}
+
+// resume returns a Sorter for a graph grown from the one old sorts, in the
+// state old is in: the values old marked done are done, and those it handed
+// out but not done are handed out. It returns the values that are ready
+// and that old never handed out, which are the ones added.
+func resume[T comparable](g *topo.Graph[T], old *topo.Sorter[T]) (*topo.Sorter[T], []T) {
+	s := g.Sorter()
+	var fresh []T
+	for {
+		ready, err := s.Ready()
+		if err != nil {
+			return s, fresh
+		}
+		var done []T
+		for _, value := range ready {
+			switch old.State(value) {
+			case topo.StateSucceeded:
+				done = append(done, value)
+			case topo.StateRunning:
+				// still running, or waiting to
+			default:
+				fresh = append(fresh, value)
+			}
+		}
+		if len(done) == 0 {
+			return s, fresh
+		}
+		// every value came from Ready, so this can't fail
+		_ = s.Done(done...)
+	}
+}
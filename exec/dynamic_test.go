@@ -0,0 +1,167 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestAddNode checks that values added by calls run in the same run, after
+// their dependencies.
+func TestAddNode(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"scan"})
+	g.AddNode("scan", []string{"base"})
+
+	for _, limit := range []int{0, 1} {
+		var mu sync.Mutex
+		var order []string
+		states := make(map[string]topo.NodeState)
+		err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+			if value == "scan" {
+				// found by scanning: two packages, and a report that
+				// needs the app
+				for _, n := range []struct {
+					value string
+					deps  []string
+				}{
+					{"pkg-b", []string{"pkg-a"}},
+					{"pkg-a", []string{"base"}},
+					{"report", []string{"app", "pkg-b"}},
+				} {
+					if err := exec.AddNode(ctx, n.value, n.deps); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+				}
+			}
+			if layer, ok := exec.Layer(ctx); value == "report" && (!ok || layer != 3) {
+				t.Errorf("Expected report in layer 3, got %d", layer)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, value)
+			return nil
+		}, exec.Options[string]{
+			Limit:   limit,
+			OnState: func(value string, state topo.NodeState) { states[value] = state },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		before := func(a, b string) bool {
+			return slices.Index(order, a) < slices.Index(order, b)
+		}
+		if len(order) != 6 || !before("pkg-a", "pkg-b") || !before("pkg-b", "report") || !before("app", "report") {
+			t.Errorf("Unexpected order %v", order)
+		}
+		for _, value := range []string{"pkg-a", "pkg-b", "report"} {
+			if states[value] != topo.StateSucceeded {
+				t.Errorf("Expected %s succeeded, got %v", value, states[value])
+			}
+		}
+	}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "scan", "base"}) {
+		t.Errorf("Expected the graph unchanged, got %v", nodes)
+	}
+}
+
+// TestAddNodeInvalid checks that a call adding values that are already in
+// the graph, or adding one twice, or values that make a cycle, fails.
+func TestAddNodeInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		add   func(ctx context.Context) error
+		cycle bool
+	}{
+		{"existing", func(ctx context.Context) error {
+			return exec.AddNode(ctx, "base", nil)
+		}, false},
+		{"twice", func(ctx context.Context) error {
+			return errors.Join(exec.AddNode(ctx, "x", nil), exec.AddNode(ctx, "x", []string{"base"}))
+		}, false},
+		{"cycle", func(ctx context.Context) error {
+			return errors.Join(exec.AddNode(ctx, "x", []string{"y"}), exec.AddNode(ctx, "y", []string{"x"}))
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("scan", []string{"base"})
+			var called []string
+			err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+				called = append(called, value)
+				if value == "scan" {
+					return tt.add(ctx)
+				}
+				return nil
+			}, exec.Options[string]{Limit: 1})
+
+			var errs exec.Errors[string]
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Node != "scan" {
+				t.Fatalf("Expected scan to fail, got %v", err)
+			}
+			if errors.Is(err, topo.ErrCyclicDependency) != tt.cycle {
+				t.Errorf("Expected cycle %v, got %v", tt.cycle, err)
+			}
+			if !reflect.DeepEqual(called, []string{"base", "scan"}) {
+				t.Errorf("Expected [base scan], got %v", called)
+			}
+		})
+	}
+
+	if err := exec.AddNode(context.Background(), "x", nil); !errors.Is(err, exec.ErrNotDynamic) {
+		t.Errorf("Expected error %v, got %v", exec.ErrNotDynamic, err)
+	}
+}
+
+// TestAddNodeQueueLimit checks that values added by calls wait for room in
+// the queue like the others.
+func TestAddNodeQueueLimit(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(0, nil)
+
+	depth, deepest := 0, 0
+	var mu sync.Mutex
+	calls := 0
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value int) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if value == 0 {
+			for i := 1; i <= 20; i++ {
+				if err := exec.AddNode(ctx, i, []int{0}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}, exec.Options[int]{
+		Limit:      1,
+		QueueLimit: 4,
+		OnState: func(value int, state topo.NodeState) {
+			switch state {
+			case topo.StateReady:
+				depth++
+				deepest = max(deepest, depth)
+			case topo.StateRunning:
+				depth--
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 21 {
+		t.Errorf("Expected 21 calls, got %d", calls)
+	}
+	if deepest > 4 {
+		t.Errorf("Expected at most 4 values waiting, got %d", deepest)
+	}
+}
@@ -0,0 +1,76 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NodeError is the failure of the call for one value.
+type NodeError[T any] struct {
+	// Node is the value whose call failed.
+	Node T
+	// Attempts is how many times the value was tried. Run tries each value
+	// once.
+	Attempts int
+	// Duration is how long the value's calls took, in all.
+	Duration time.Duration
+	// Err is the error the last call returned.
+	Err error
+	// TimedOut is set when the call failed after its context was cancelled
+	// because the run's timeout passed, rather than failing on its own.
+	TimedOut bool
+	// Canceled is set when the call failed after it was cancelled with
+	// Execution.CancelSubtree.
+	Canceled bool
+}
+
+func (e *NodeError[T]) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("%v: timed out: %v", e.Node, e.Err)
+	}
+	if e.Canceled {
+		return fmt.Sprintf("%v: cancelled: %v", e.Node, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Node, e.Err)
+}
+
+func (e *NodeError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Errors is every failure of a run, in the order the calls returned, so
+// that callers can deal with each on its own, like paging for
+// infrastructure and filing a ticket for an application:
+//
+//	var errs exec.Errors[string]
+//	if errors.As(err, &errs) {
+//		for _, e := range errs {
+//			route(e.Node, e.Err)
+//		}
+//	}
+//
+// errors.Is and errors.As look through it to each NodeError and the
+// error it holds.
+type Errors[T any] []*NodeError[T]
+
+func (e Errors[T]) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d failures:", len(e))
+	for _, err := range e {
+		b.WriteString("\n\t" + err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns each NodeError.
+func (e Errors[T]) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,94 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestErrors checks the message of an Errors and what it unwraps to.
+func TestErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	errBust := errors.New("bust")
+	tests := []struct {
+		name     string
+		errs     exec.Errors[string]
+		expected string
+	}{
+		{
+			name:     "one",
+			errs:     exec.Errors[string]{{Node: "db", Attempts: 1, Err: errBoom}},
+			expected: "db: boom",
+		},
+		{
+			name: "several",
+			errs: exec.Errors[string]{
+				{Node: "db", Attempts: 1, Err: errBoom},
+				{Node: "app", Attempts: 2, Err: errBust},
+			},
+			expected: "2 failures:\n\tdb: boom\n\tapp: bust",
+		},
+		{
+			name:     "timed out",
+			errs:     exec.Errors[string]{{Node: "db", Attempts: 1, Err: context.Canceled, TimedOut: true}},
+			expected: "db: timed out: context canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.errs.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, tt.errs.Error())
+			}
+			for _, e := range tt.errs {
+				if !errors.Is(tt.errs, e.Err) {
+					t.Errorf("Expected errors.Is to find %v", e.Err)
+				}
+			}
+			var nodeErr *exec.NodeError[string]
+			if !errors.As(tt.errs, &nodeErr) || nodeErr != tt.errs[0] {
+				t.Errorf("Expected errors.As to find %v, got %v", tt.errs[0], nodeErr)
+			}
+		})
+	}
+}
+
+// TestRunErrors checks that Run attributes each failure to its value.
+func TestRunErrors(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("db", nil)
+	g.AddNode("cache", nil)
+	g.AddNode("app", []string{"db", "cache"})
+
+	errBoom := errors.New("boom")
+	dbFailed := make(chan struct{})
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "db" {
+			time.Sleep(10 * time.Millisecond)
+			close(dbFailed)
+			return errBoom
+		}
+		// cache runs until the failure of db cancels it
+		<-dbFailed
+		<-ctx.Done()
+		return ctx.Err()
+	}, exec.Options[string]{})
+
+	var errs exec.Errors[string]
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected Errors, got %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 failures, got %v", errs)
+	}
+	db, cache := errs[0], errs[1]
+	if db.Node != "db" || db.Err != errBoom || db.Attempts != 1 || db.Duration < 10*time.Millisecond {
+		t.Errorf("Unexpected failure of db: %+v", db)
+	}
+	if cache.Node != "cache" || !errors.Is(cache.Err, context.Canceled) || cache.Attempts != 1 {
+		t.Errorf("Unexpected failure of cache: %+v", cache)
+	}
+}
@@ -0,0 +1,58 @@
+// Package exec runs functions over the layers produced by a topological
+// sort, processing the values of each layer concurrently, or over a graph,
+// starting each value as soon as its dependencies are done.
+package exec
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Func processes a single value.
+type Func[T any] func(ctx context.Context, value T) error
+
+// RunLayers calls fn for every value in layers, one layer at a time. The
+// values of a layer are processed concurrently, and every call for a layer
+// returns before any call for the next layer starts.
+//
+// At most limit calls run at once; a limit of zero or less means no limit.
+// The first error returned by fn cancels the context passed to the other
+// calls, stops later layers from starting, and is returned.
+//
+// The context passed to each call carries its value, layer, and a logger;
+// see Node, Layer, and Logger.
+func RunLayers[T any](ctx context.Context, layers [][]T, fn Func[T], limit int) error {
+	for i, layer := range layers {
+		if err := runLayer(ctx, layer, i, fn, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunLayer calls fn concurrently for every value in a single layer and
+// waits for the calls to return. It behaves like [RunLayers] with one
+// layer, and is useful when the caller wants to do something between
+// layers, like reporting progress.
+func RunLayer[T any](ctx context.Context, layer []T, fn Func[T], limit int) error {
+	return runLayer(ctx, layer, -1, fn, limit)
+}
+
+// runLayer runs a layer, numbered index unless it's negative.
+func runLayer[T any](ctx context.Context, layer []T, index int, fn Func[T], limit int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	if limit > 0 {
+		group.SetLimit(limit)
+	}
+	for _, value := range layer {
+		group.Go(func() error {
+			return fn(callContext(ctx, value, index), value)
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,80 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRunLayers checks that layers run in order and concurrency is limited.
+func TestRunLayers(t *testing.T) {
+	layers := [][]int{{1, 2, 3, 4}, {5, 6}, {7}}
+	layerOf := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 1, 6: 1, 7: 2}
+
+	var mu sync.Mutex
+	var finished []int
+	var running, maxRunning atomic.Int32
+	err := exec.RunLayers(context.Background(), layers, func(_ context.Context, value int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, done := range finished {
+			if layerOf[done] > layerOf[value] {
+				t.Errorf("%d ran after %d from a later layer", value, done)
+			}
+		}
+		finished = append(finished, value)
+		return nil
+	}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(finished) != 7 {
+		t.Errorf("Expected 7 calls, got %d", len(finished))
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent calls, got %d", maxRunning.Load())
+	}
+}
+
+// TestRunLayersError checks that an error stops later layers.
+func TestRunLayersError(t *testing.T) {
+	errBoom := errors.New("boom")
+	var calls atomic.Int32
+	err := exec.RunLayers(context.Background(), [][]string{{"a", "b"}, {"c"}},
+		func(ctx context.Context, value string) error {
+			calls.Add(1)
+			if value == "a" {
+				return errBoom
+			}
+			return nil
+		}, 0)
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Expected error %v, got %v", errBoom, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected 2 calls, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = exec.RunLayer(ctx, []string{"a"}, func(context.Context, string) error {
+		t.Error("Unexpected call with cancelled context")
+		return nil
+	}, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error %v, got %v", context.Canceled, err)
+	}
+}
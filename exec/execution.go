@@ -0,0 +1,264 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrNotReached is wrapped by the errors Execution.WaitFor returns for
+// values the run never finished: those skipped, including by
+// Options.Filter, and those that were never in the run.
+var ErrNotReached = errors.New("never reached")
+
+// Execution is a run started with Start, which can be followed and waited
+// on while it goes.
+type Execution[T comparable] struct {
+	mu sync.Mutex
+	// states holds the state of each value the run has reached
+	states map[T]topo.NodeState
+	errs   map[T]*NodeError[T]
+	// changed is closed, and replaced, each time a value changes state
+	changed chan struct{}
+	// requests holds what CancelSubtree and Approve were asked, in order,
+	// for the run to take once wake wakes it; they never wait for the
+	// run, so they can be called from Options.OnState
+	requests []request[T]
+	wake     chan struct{}
+	// awaiting are the gated values waiting to be approved
+	awaiting []T
+	// depth, running, and oldest are the run's queue, for Queue
+	depth, running int
+	oldest         time.Time
+	done           chan struct{}
+	err            error
+}
+
+// Start starts running the graph as Run does, returning at once with an
+// Execution, for code that needs to follow the run while it goes, like a
+// test waiting for a service to be deployed before checking it while the
+// rest of the plan continues:
+//
+//	e := exec.Start(ctx, g, deploy, exec.Options[string]{})
+//	if err := e.WaitFor(ctx, "api-server"); err != nil {
+//		t.Fatal(err)
+//	}
+//	checkAPI(t)
+//	if err := e.Wait(); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Options.OnState and the other callbacks are called from a goroutine of
+// the Execution's own, rather than the one that called Start.
+func Start[T comparable](ctx context.Context, g *topo.Graph[T], fn Func[T], opts Options[T]) *Execution[T] {
+	e := &Execution[T]{
+		states:  make(map[T]topo.NodeState),
+		errs:    make(map[T]*NodeError[T]),
+		changed: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		err := run(ctx, g, fn, opts, e)
+		e.mu.Lock()
+		e.err = err
+		e.depth, e.running, e.oldest = 0, 0, time.Time{}
+		e.awaiting = nil
+		e.mu.Unlock()
+		close(e.done)
+	}()
+	return e
+}
+
+// Wait waits for the run to finish, returning what Run would.
+func (e *Execution[T]) Wait() error {
+	<-e.done
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// Done returns a channel closed once the run has finished.
+func (e *Execution[T]) Done() <-chan struct{} {
+	return e.done
+}
+
+// QueueStats is a snapshot of how a run's calls are keeping up, from
+// Execution.Queue.
+type QueueStats struct {
+	// Depth is how many values are ready and waiting to run, at most
+	// Options.QueueLimit.
+	Depth int
+	// Running is how many calls are running.
+	Running int
+	// Wait is how long the value waiting longest has waited.
+	Wait time.Duration
+}
+
+// Queue returns how many values are waiting to run, and for how long, as
+// of the last time a call started or returned, for watching whether the
+// calls are keeping up with the values becoming ready.
+func (e *Execution[T]) Queue() QueueStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats := QueueStats{Depth: e.depth, Running: e.running}
+	if !e.oldest.IsZero() {
+		stats.Wait = time.Since(e.oldest)
+	}
+	return stats
+}
+
+// CancelSubtree cancels a value and every value depending on it, directly
+// or transitively, that hasn't finished, while the rest of the run goes on,
+// like when an operator gives up on one branch of a deployment. Values yet
+// to start are skipped, and the contexts of those running are cancelled,
+// with ErrSubtreeCanceled as the cause; their failures are marked Canceled
+// and don't stop the run, which returns ErrSubtreeCanceled, joined with
+// an Errors if any calls failed. Values already finished are left alone.
+// It doesn't wait for the run, so it can be called from Options.OnState,
+// and does nothing once the run has finished.
+func (e *Execution[T]) CancelSubtree(value T) {
+	e.ask(request[T]{value: value, cancel: true})
+}
+
+// WaitFor waits for the values given to succeed, or be cached, while the
+// rest of the run goes on, returning nil once they all have. It returns
+// early if one of them can't: with its NodeError, if it failed, or with an
+// error wrapping ErrNotReached, if it was skipped, or the run finished
+// without it. It returns ctx's error if ctx is done first.
+func (e *Execution[T]) WaitFor(ctx context.Context, values ...T) error {
+	for {
+		e.mu.Lock()
+		waiting, err := e.check(values)
+		changed := e.changed
+		e.mu.Unlock()
+		if !waiting {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-e.done:
+			// a last look, which can't wait
+			e.mu.Lock()
+			_, err := e.check(values)
+			e.mu.Unlock()
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// check reports whether any of values is still to finish, or returns the
+// error for the first that can't succeed. The caller holds mu.
+func (e *Execution[T]) check(values []T) (bool, error) {
+	waiting := false
+	finished := e.finished()
+	for _, value := range values {
+		switch e.states[value] {
+		case topo.StateSucceeded, topo.StateCached:
+		case topo.StateFailed:
+			return false, e.errs[value]
+		case topo.StateSkipped:
+			if err, ok := e.errs[value]; ok {
+				// a gated value that wasn't approved
+				return false, err
+			}
+			return false, fmt.Errorf("%v: skipped: %w", value, ErrNotReached)
+		default:
+			if finished {
+				return false, fmt.Errorf("%v: %w", value, ErrNotReached)
+			}
+			waiting = true
+		}
+	}
+	return waiting, nil
+}
+
+// finished reports whether the run has finished.
+func (e *Execution[T]) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// request is a value given to CancelSubtree, if cancel is set, or to
+// Approve.
+type request[T any] struct {
+	value  T
+	cancel bool
+}
+
+// ask queues a request for the run and wakes it, unless the run has
+// finished.
+func (e *Execution[T]) ask(r request[T]) {
+	e.mu.Lock()
+	if e.finished() {
+		e.mu.Unlock()
+		return
+	}
+	e.requests = append(e.requests, r)
+	e.mu.Unlock()
+	select {
+	case e.wake <- struct{}{}:
+	default:
+		// already woken, and the run takes every request at once
+	}
+}
+
+// wakes returns the channel waking the run for requests, or nil if e is.
+func (e *Execution[T]) wakes() <-chan struct{} {
+	if e == nil {
+		return nil
+	}
+	return e.wake
+}
+
+// take returns the requests queued since it was last called.
+func (e *Execution[T]) take() []request[T] {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	requests := e.requests
+	e.requests = nil
+	return requests
+}
+
+// record notes a value's change of state, if e isn't nil.
+func (e *Execution[T]) record(value T, state topo.NodeState) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.states[value] = state
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// queue notes the state of the run's queue, if e isn't nil.
+func (e *Execution[T]) queue(depth, running int, oldest time.Time) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.depth, e.running, e.oldest = depth, running, oldest
+}
+
+// fail notes the failure of a value, before it's recorded as failed, if e
+// isn't nil.
+func (e *Execution[T]) fail(err *NodeError[T]) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errs[err.Node] = err
+}
@@ -0,0 +1,277 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestExecutionWaitFor checks waiting for a value while the rest of the
+// run goes on.
+func TestExecutionWaitFor(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("api-server", []string{"db"})
+	g.AddNode("batch", nil)
+
+	release := make(chan struct{})
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "batch" {
+			<-release
+		}
+		return nil
+	}, exec.Options[string]{})
+
+	if err := e.WaitFor(context.Background(), "db", "api-server"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-e.Done():
+		t.Fatal("Expected the run to go on")
+	default:
+	}
+	close(release)
+	if err := e.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := e.WaitFor(context.Background(), "batch"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestExecutionWaitForErrors checks waiting for values that never
+// succeed.
+func TestExecutionWaitForErrors(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"db"})
+	g.AddNode("docs", nil)
+	errMigration := errors.New("migration failed")
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "db" {
+			return errMigration
+		}
+		return nil
+	}, exec.Options[string]{Filter: func(value string) bool { return value != "docs" }})
+
+	tests := []struct {
+		value string
+		is    error
+	}{
+		{"db", errMigration},
+		{"app", exec.ErrNotReached},
+		{"docs", exec.ErrNotReached},
+		{"other", exec.ErrNotReached},
+	}
+	for _, tt := range tests {
+		if err := e.WaitFor(context.Background(), tt.value); !errors.Is(err, tt.is) {
+			t.Errorf("Expected %s error %v, got %v", tt.value, tt.is, err)
+		}
+	}
+	var nodeErr *exec.NodeError[string]
+	if err := e.WaitFor(context.Background(), "db"); !errors.As(err, &nodeErr) || nodeErr.Node != "db" {
+		t.Errorf("Expected a NodeError for db, got %v", err)
+	}
+	if err := e.Wait(); !errors.Is(err, errMigration) {
+		t.Errorf("Expected error %v, got %v", errMigration, err)
+	}
+}
+
+// TestExecutionWaitForContext checks that waiting stops when its context
+// is done, without stopping the run.
+func TestExecutionWaitForContext(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("slow", nil)
+	release := make(chan struct{})
+	e := exec.Start(context.Background(), &g, func(context.Context, string) error {
+		<-release
+		return nil
+	}, exec.Options[string]{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := e.WaitFor(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+	if err := e.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// TestExecutionCancelSubtree checks that cancelling a value skips or
+// cancels it and its dependents, while other branches go on.
+func TestExecutionCancelSubtree(t *testing.T) {
+	tests := []struct {
+		name string
+		// cancel is cancelled once build-eu and build-us are running
+		cancel string
+		limit  int
+		states map[string]topo.NodeState
+		failed []string
+	}{
+		{
+			name:   "running",
+			cancel: "build-eu",
+			states: map[string]topo.NodeState{
+				"build-eu":  topo.StateFailed,
+				"deploy-eu": topo.StateSkipped,
+				"deploy-us": topo.StateSucceeded,
+			},
+			failed: []string{"build-eu"},
+		},
+		{
+			name:   "pending",
+			cancel: "deploy-eu",
+			states: map[string]topo.NodeState{
+				"build-eu":  topo.StateSucceeded,
+				"deploy-eu": topo.StateSkipped,
+				"deploy-us": topo.StateSucceeded,
+			},
+		},
+		{
+			name:   "queued",
+			cancel: "docs",
+			limit:  2,
+			states: map[string]topo.NodeState{
+				"docs":      topo.StateSkipped,
+				"deploy-eu": topo.StateSucceeded,
+				"deploy-us": topo.StateSucceeded,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("deploy-eu", []string{"build-eu"})
+			g.AddNode("deploy-us", []string{"build-us"})
+			g.AddNode("build-eu", nil)
+			g.AddNode("build-us", nil)
+			g.AddNode("docs", nil)
+
+			// build-eu is only released if it isn't cancelled
+			started := make(chan string)
+			release := map[string]chan struct{}{"build-eu": make(chan struct{}), "build-us": make(chan struct{})}
+			states := make(map[string]topo.NodeState)
+			e := exec.Start(context.Background(), &g, func(ctx context.Context, value string) error {
+				if value != "build-eu" && value != "build-us" {
+					return nil
+				}
+				started <- value
+				select {
+				case <-release[value]:
+					return nil
+				case <-ctx.Done():
+					if !errors.Is(context.Cause(ctx), exec.ErrSubtreeCanceled) {
+						t.Errorf("Expected cause %v, got %v", exec.ErrSubtreeCanceled, context.Cause(ctx))
+					}
+					return ctx.Err()
+				}
+			}, exec.Options[string]{
+				Limit:   tt.limit,
+				Group:   func(string) string { return "" },
+				OnState: func(value string, state topo.NodeState) { states[value] = state },
+			})
+			<-started
+			<-started
+			e.CancelSubtree(tt.cancel)
+			close(release["build-us"])
+			if tt.cancel != "build-eu" {
+				close(release["build-eu"])
+			}
+			err := e.Wait()
+
+			if !errors.Is(err, exec.ErrSubtreeCanceled) {
+				t.Errorf("Expected error %v, got %v", exec.ErrSubtreeCanceled, err)
+			}
+			var errs exec.Errors[string]
+			errors.As(err, &errs)
+			var failed []string
+			for _, e := range errs {
+				if !e.Canceled {
+					t.Errorf("Expected %s marked cancelled", e.Node)
+				}
+				failed = append(failed, e.Node)
+			}
+			if !reflect.DeepEqual(failed, tt.failed) {
+				t.Errorf("Expected failures %v, got %v", tt.failed, failed)
+			}
+			for value, state := range tt.states {
+				if states[value] != state {
+					t.Errorf("Expected %s %v, got %v", value, state, states[value])
+				}
+			}
+		})
+	}
+}
+
+// TestExecutionQueue checks the queue reported while calls can't keep up.
+func TestExecutionQueue(t *testing.T) {
+	var g topo.Graph[int]
+	for i := range 10 {
+		g.AddNode(i, nil)
+	}
+	release := make(chan struct{})
+	e := exec.Start(context.Background(), &g, func(ctx context.Context, value int) error {
+		<-release
+		return nil
+	}, exec.Options[int]{Limit: 1, QueueLimit: 3})
+
+	var stats exec.QueueStats
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if stats = e.Queue(); stats.Running == 1 && stats.Wait > 2*time.Millisecond {
+			break
+		}
+	}
+	if stats.Depth != 3 || stats.Running != 1 {
+		t.Errorf("Expected 3 waiting and 1 running, got %+v", stats)
+	}
+	if stats.Wait <= 2*time.Millisecond {
+		t.Errorf("Expected a wait over 2ms, got %v", stats.Wait)
+	}
+	close(release)
+	if err := e.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats := e.Queue(); stats != (exec.QueueStats{}) {
+		t.Errorf("Expected nothing waiting or running, got %+v", stats)
+	}
+}
+
+// TestExecutionCancelSubtreeOnState checks that a subtree can be cancelled
+// from Options.OnState, as its value finishes.
+func TestExecutionCancelSubtreeOnState(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("deploy-eu", []string{"build-eu"})
+	g.AddNode("deploy-us", []string{"build-us"})
+
+	var e *exec.Execution[string]
+	started := make(chan struct{})
+	states := make(map[string]topo.NodeState)
+	e = exec.Start(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{
+		OnState: func(value string, state topo.NodeState) {
+			<-started
+			states[value] = state
+			if value == "build-eu" && state == topo.StateSucceeded {
+				e.CancelSubtree("deploy-eu")
+			}
+		},
+	})
+	close(started)
+	select {
+	case <-e.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the run to finish")
+	}
+	if err := e.Wait(); !errors.Is(err, exec.ErrSubtreeCanceled) {
+		t.Errorf("Expected error %v, got %v", exec.ErrSubtreeCanceled, err)
+	}
+	if states["deploy-eu"] != topo.StateSkipped || states["deploy-us"] != topo.StateSucceeded {
+		t.Errorf("Expected deploy-eu skipped and deploy-us succeeded, got %v", states)
+	}
+}
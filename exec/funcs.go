@@ -0,0 +1,75 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrMissingNode is wrapped by every MissingNodeError.
+var ErrMissingNode = errors.New("no function registered")
+
+// MissingNodeError is returned by RunFuncs for a value in the graph without
+// a function, before anything runs.
+type MissingNodeError[T any] struct {
+	// Node is the value without a function.
+	Node T
+	// Dependents are the values depending on it, which could never run,
+	// in the order they were first added to the graph.
+	Dependents []T
+}
+
+func (e *MissingNodeError[T]) Error() string {
+	if len(e.Dependents) == 0 {
+		return fmt.Sprintf("%v: %v", e.Node, ErrMissingNode)
+	}
+	return fmt.Sprintf("%v: %v, needed by %v", e.Node, ErrMissingNode, e.Dependents)
+}
+
+// Unwrap returns ErrMissingNode.
+func (e *MissingNodeError[T]) Unwrap() error {
+	return ErrMissingNode
+}
+
+// Funcs holds the function that runs each value, for graphs whose values
+// each need their own.
+type Funcs[T comparable] map[T]Func[T]
+
+// Check returns a MissingNodeError for each value in the graph without a
+// function, joined with errors.Join, or nil if every value has one.
+func (f Funcs[T]) Check(g *topo.Graph[T]) error {
+	nodes := g.Nodes()
+	dependents := make(map[T][]T)
+	for _, value := range nodes {
+		for _, dep := range g.Dependencies(value) {
+			dependents[dep] = append(dependents[dep], value)
+		}
+	}
+	var errs []error
+	for _, value := range nodes {
+		if f[value] == nil {
+			errs = append(errs, &MissingNodeError[T]{Node: value, Dependents: dependents[value]})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunFuncs runs the graph as Run does, calling each value's own function
+// from funcs. It checks first that every value has one, and returns the
+// errors from Check without running anything if not, rather than failing
+// partway through the run.
+func RunFuncs[T comparable](ctx context.Context, g *topo.Graph[T], funcs Funcs[T], opts Options[T]) error {
+	if err := funcs.Check(g); err != nil {
+		return err
+	}
+	return Run(ctx, g, func(ctx context.Context, value T) error {
+		fn := funcs[value]
+		if fn == nil {
+			// added to the run by a call, with AddNode
+			return &MissingNodeError[T]{Node: value}
+		}
+		return fn(ctx, value)
+	}, opts)
+}
@@ -0,0 +1,79 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRunFuncs checks that each value runs its own function.
+func TestRunFuncs(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) exec.Func[string] {
+		return func(_ context.Context, value string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name+" "+value)
+			return nil
+		}
+	}
+	funcs := exec.Funcs[string]{"app": record("deploy"), "lib": record("build")}
+	if err := exec.RunFuncs(context.Background(), &g, funcs, exec.Options[string]{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"build lib", "deploy app"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+// TestRunFuncsMissing checks that values without a function are reported
+// before anything runs.
+func TestRunFuncsMissing(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("api", []string{"db"})
+	g.AddNode("lib", nil)
+	g.AddNode("tool", nil)
+
+	called := false
+	run := func(context.Context, string) error {
+		called = true
+		return nil
+	}
+	funcs := exec.Funcs[string]{"app": run, "api": run, "lib": run}
+	err := exec.RunFuncs(context.Background(), &g, funcs, exec.Options[string]{})
+	if !errors.Is(err, exec.ErrMissingNode) {
+		t.Fatalf("Expected error %v, got %v", exec.ErrMissingNode, err)
+	}
+	if called {
+		t.Error("Expected no calls")
+	}
+
+	var missing *exec.MissingNodeError[string]
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingNodeError, got %v", err)
+	}
+	if missing.Node != "db" || !reflect.DeepEqual(missing.Dependents, []string{"app", "api"}) {
+		t.Errorf("Expected db needed by [app api], got %s needed by %v", missing.Node, missing.Dependents)
+	}
+	expected := "db: no function registered, needed by [app api]\ntool: no function registered"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+
+	funcs["db"], funcs["tool"] = run, run
+	if err := funcs.Check(&g); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
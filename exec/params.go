@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"context"
+	"maps"
+)
+
+// Params are named parameters for a call, like an environment, a region,
+// or a replica count.
+type Params map[string]any
+
+// ParamSet holds the parameters for the calls of a run at each level, so
+// that each value gets its own without its call closing over them all.
+// For a value, its own parameters override those of its tags, which
+// override the global ones.
+type ParamSet[T comparable] struct {
+	// Global are the parameters for every value.
+	Global Params
+	// Tags are the parameters for the values with each tag. Where a
+	// value's tags give a parameter different values, the tag listed last
+	// for it wins.
+	Tags map[string]Params
+	// TagsOf lists the tags of each value.
+	TagsOf map[T][]string
+	// Nodes are each value's own parameters.
+	Nodes map[T]Params
+}
+
+// Resolve returns the parameters for a value, merged from each level.
+func (s ParamSet[T]) Resolve(value T) Params {
+	params := maps.Clone(s.Global)
+	if params == nil {
+		params = make(Params)
+	}
+	for _, tag := range s.TagsOf[value] {
+		maps.Copy(params, s.Tags[tag])
+	}
+	maps.Copy(params, s.Nodes[value])
+	return params
+}
+
+// ParamsOf returns the parameters for a call, from the context passed to
+// it by Run, or nil outside of one. The parameters shouldn't be modified.
+func ParamsOf(ctx context.Context) Params {
+	params, _ := ctx.Value(paramsKey).(Params)
+	return params
+}
+
+// Param returns a parameter for a call, from the context passed to it by
+// Run, and whether it's set, with a value of type V:
+//
+//	replicas, ok := exec.Param[int](ctx, "replicas")
+func Param[V any](ctx context.Context, name string) (V, bool) {
+	value, ok := ParamsOf(ctx)[name].(V)
+	return value, ok
+}
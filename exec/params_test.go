@@ -0,0 +1,77 @@
+package exec_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestParamSetResolve checks that each level overrides the one before.
+func TestParamSetResolve(t *testing.T) {
+	set := exec.ParamSet[string]{
+		Global: exec.Params{"env": "prod", "replicas": 1, "region": "us"},
+		Tags: map[string]exec.Params{
+			"web": {"replicas": 3},
+			"eu":  {"region": "eu", "replicas": 2},
+		},
+		TagsOf: map[string][]string{"api": {"web", "eu"}, "site": {"eu", "web"}},
+		Nodes:  map[string]exec.Params{"api": {"env": "staging"}},
+	}
+	tests := []struct {
+		value    string
+		expected exec.Params
+	}{
+		{"api", exec.Params{"env": "staging", "replicas": 2, "region": "eu"}},
+		{"site", exec.Params{"env": "prod", "replicas": 3, "region": "eu"}},
+		{"db", exec.Params{"env": "prod", "replicas": 1, "region": "us"}},
+	}
+	for _, tt := range tests {
+		if actual := set.Resolve(tt.value); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.value, actual)
+		}
+	}
+	if set.Global["env"] != "prod" {
+		t.Errorf("Expected global params unchanged, got %v", set.Global)
+	}
+}
+
+// TestRunParams checks that each call gets its value's parameters.
+func TestRunParams(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("api", []string{"db"})
+	g.AddNode("db", nil)
+
+	var mu sync.Mutex
+	replicas := make(map[string]int)
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		n, ok := exec.Param[int](ctx, "replicas")
+		if !ok {
+			t.Errorf("Expected replicas for %s, got %v", value, exec.ParamsOf(ctx))
+		}
+		if _, ok := exec.Param[string](ctx, "replicas"); ok {
+			t.Error("Expected no string replicas")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		replicas[value] = n
+		return nil
+	}, exec.Options[string]{Params: exec.ParamSet[string]{
+		Global: exec.Params{"replicas": 1},
+		Nodes:  map[string]exec.Params{"api": {"replicas": 3}},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]int{"api": 3, "db": 1}
+	if !reflect.DeepEqual(replicas, expected) {
+		t.Errorf("Expected %v, got %v", expected, replicas)
+	}
+
+	if params := exec.ParamsOf(context.Background()); params != nil {
+		t.Errorf("Expected no params outside a call, got %v", params)
+	}
+}
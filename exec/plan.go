@@ -0,0 +1,149 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Action is what a run would do with a value.
+type Action int
+
+const (
+	// ActionRun is a value that would be called.
+	ActionRun Action = iota
+	// ActionCached is a value whose fingerprint is in Options.Cache, so it
+	// wouldn't be called.
+	ActionCached
+	// ActionSkip is a value Options.Filter leaves out.
+	ActionSkip
+)
+
+// String returns the name of the action.
+func (a Action) String() string {
+	switch a {
+	case ActionRun:
+		return "run"
+	case ActionCached:
+		return "cached"
+	case ActionSkip:
+		return "skip"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Step is what a run would do with one value.
+type Step[T any] struct {
+	Value  T
+	Action Action
+	// Layer is the layer SortByLayers puts the value in, as Layer returns
+	// in its call's context.
+	Layer int
+	// Fingerprint is the value's fingerprint, if Options.Fingerprint gave
+	// it one: the one found in the cache for a cached value, or the one
+	// that would be added to it for a value that's called.
+	Fingerprint string
+	// Gated is set for a value that would wait to be approved before
+	// it's called, as Options.Gate says.
+	Gated bool
+}
+
+// Plan is what a run would do with each value, in the order it would get
+// to them.
+type Plan[T any] []Step[T]
+
+// String returns the plan with a step on each line, numbered from one,
+// with the fingerprint of cached values, and after gated values, "needs
+// approval":
+//
+//	fmt.Print(plan)
+//	// 1. run base
+//	// 2. cached lib (3f2a9c)
+//	// 3. skip docs
+//	// 4. run app (needs approval)
+func (p Plan[T]) String() string {
+	var b strings.Builder
+	for i, step := range p {
+		fmt.Fprintf(&b, "%d. %s %v", i+1, step.Action, step.Value)
+		if step.Action == ActionCached {
+			fmt.Fprintf(&b, " (%s)", step.Fingerprint)
+		}
+		if step.Gated {
+			b.WriteString(" (needs approval)")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DryRun returns what Run would do with the same graph and options,
+// without calling anything or adding to the cache, so that a run can be
+// reviewed before it's started. Steps are in the order Run would start
+// values in if every call returned at once: those Options.Filter leaves
+// out and those cached as soon as they're ready, and the rest as many at a
+// time as Options.Limit and Options.Workers allow, in the order its other
+// options give. A graph with a cycle returns topo.ErrCyclicDependency.
+func DryRun[T comparable](ctx context.Context, g *topo.Graph[T], opts Options[T]) (Plan[T], error) {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.schedulable(g.Nodes()); err != nil {
+		return nil, err
+	}
+	layerOf := layerIndex(layers)
+
+	q := newQueue(g, opts)
+	s := g.Sorter()
+	workers := newPool(opts)
+	now := time.Now()
+	fingerprints := make(map[T]string)
+	var plan Plan[T]
+	for {
+		ready, err := s.Ready()
+		if err != nil {
+			return nil, err
+		}
+		var done []T
+		for _, value := range ready {
+			state, fingerprint := opts.settle(callContext(ctx, value, layerOf[value]), value)
+			switch state {
+			case topo.StateSkipped:
+				plan = append(plan, Step[T]{Value: value, Action: ActionSkip, Layer: layerOf[value]})
+				done = append(done, value)
+			case topo.StateCached:
+				plan = append(plan, Step[T]{Value: value, Action: ActionCached, Layer: layerOf[value], Fingerprint: fingerprint})
+				done = append(done, value)
+			default:
+				fingerprints[value] = fingerprint
+				q.push(value, now)
+			}
+		}
+		if len(done) == 0 {
+			// the values started together take workers as Run's would,
+			// and give them back once they're all done
+			var taken []int
+			for q.len() > 0 && (opts.Limit <= 0 || len(done) < opts.Limit) && workers.idle() {
+				value, ok := q.pop(workers.fits)
+				if !ok {
+					break
+				}
+				taken = append(taken, workers.take(value, true))
+				plan = append(plan, Step[T]{Value: value, Action: ActionRun, Layer: layerOf[value], Fingerprint: fingerprints[value], Gated: opts.Gate != nil && opts.Gate(value)})
+				done = append(done, value)
+			}
+			for _, worker := range taken {
+				workers.release(worker)
+			}
+		}
+		if len(done) == 0 {
+			return plan, nil
+		}
+		// every value came from Ready, so this can't fail
+		_ = s.Done(done...)
+	}
+}
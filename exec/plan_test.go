@@ -0,0 +1,114 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestDryRun checks the plan for a run, and that nothing is called or
+// cached.
+func TestDryRun(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "docs"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("docs", nil)
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("base", nil)
+
+	cache := &mapCache{set: map[string]bool{"lib-v1": true}}
+	fingerprint := func(_ context.Context, value string) (string, error) {
+		return value + "-v1", nil
+	}
+	tags := map[string][]string{"app": {"deploy"}, "lib": {"deploy"}, "base": {"deploy"}, "tool": {"deploy"}}
+	priority := map[string]int{"tool": 1}
+
+	tests := []struct {
+		name     string
+		opts     exec.Options[string]
+		expected string
+	}{
+		{
+			"unlimited",
+			exec.Options[string]{},
+			"1. run docs\n2. run base\n3. run lib\n4. run tool\n5. run app\n",
+		},
+		{
+			"limited by priority",
+			exec.Options[string]{Limit: 1, Priority: func(v string) int { return priority[v] }},
+			"1. run docs\n2. run base\n3. run tool\n4. run lib\n5. run app\n",
+		},
+		{
+			"filtered and cached",
+			exec.Options[string]{Filter: exec.Tagged(tags, "deploy"), Fingerprint: fingerprint, Cache: cache},
+			"1. skip docs\n2. run base\n3. cached lib (lib-v1)\n4. run tool\n5. run app\n",
+		},
+		{
+			"gated",
+			exec.Options[string]{Gate: func(v string) bool { return v == "app" }},
+			"1. run docs\n2. run base\n3. run lib\n4. run tool\n5. run app (needs approval)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := exec.DryRun(context.Background(), &g, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if plan.String() != tt.expected {
+				t.Errorf("Expected plan\n%s\ngot\n%s", tt.expected, plan)
+			}
+		})
+	}
+	if len(cache.set) != 1 {
+		t.Errorf("Expected nothing cached, got %v", cache.set)
+	}
+
+	plan, err := exec.DryRun(context.Background(), &g, exec.Options[string]{Fingerprint: fingerprint, Cache: cache})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, step := range plan {
+		if step.Fingerprint != step.Value+"-v1" {
+			t.Errorf("Expected fingerprint %s-v1, got %q", step.Value, step.Fingerprint)
+		}
+		if step.Value == "app" && step.Layer != 2 {
+			t.Errorf("Expected app in layer 2, got %d", step.Layer)
+		}
+	}
+
+	g.AddNode("base", []string{"app"})
+	if _, err := exec.DryRun(context.Background(), &g, exec.Options[string]{}); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected error %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestDryRunWorkers checks that the plan starts values only on workers
+// that can run them, as Run would.
+func TestDryRunWorkers(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("train", nil)
+	g.AddNode("render", nil)
+	g.AddNode("report", []string{"train"})
+
+	classes := map[string]string{"train": "gpu", "render": "gpu"}
+	priority := map[string]int{"train": 1, "report": 2}
+	opts := exec.Options[string]{
+		Priority: func(v string) int { return priority[v] },
+		Class:    func(v string) string { return classes[v] },
+		Workers:  []exec.Worker{{Name: "cpu"}, {Name: "gpu", Classes: []string{"gpu"}}},
+	}
+	plan, err := exec.DryRun(context.Background(), &g, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// render waits for the gpu worker train took, so report, ready once
+	// train is done, starts before it
+	expected := "1. run train\n2. run report\n3. run render\n"
+	if plan.String() != expected {
+		t.Errorf("Expected plan\n%s\ngot\n%s", expected, plan)
+	}
+}
@@ -0,0 +1,94 @@
+package exec
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkerFunc processes a single value on one of a pool's workers,
+// numbered from zero.
+type WorkerFunc[T any] func(ctx context.Context, worker int, value T) error
+
+// RunPool calls fn for every value in layers, one layer at a time, like
+// [RunLayers], but on a fixed number of long-lived workers rather than a
+// goroutine per value. Each worker takes the next value of the current
+// layer as soon as it's free, and keeps running until every layer is done.
+//
+// Calls with the same worker number never overlap, so fn can use a
+// resource that's expensive to set up, like a database connection or a
+// GPU context, from a slice with one per worker, without locking it.
+//
+// Fewer than one worker is treated as one. The first error returned by fn
+// cancels the context passed to the other calls, stops the values that
+// haven't started from being run, and is returned. The context passed to
+// each call carries its value, layer, and a logger, as with RunLayers.
+func RunPool[T any](ctx context.Context, layers [][]T, fn WorkerFunc[T], workers int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once  sync.Once
+		first error
+		jobs  = make(chan job[T])
+		idle  sync.WaitGroup
+	)
+	for w := range max(workers, 1) {
+		idle.Add(1)
+		go func() {
+			defer idle.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					// handed over as the run was cancelled
+					j.done.Done()
+					continue
+				}
+				if err := fn(callContext(ctx, j.value, j.layer), w, j.value); err != nil {
+					once.Do(func() {
+						first = err
+						cancel()
+					})
+				}
+				j.done.Done()
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		idle.Wait()
+	}()
+
+	for i, layer := range layers {
+		var done sync.WaitGroup
+		for _, value := range layer {
+			if ctx.Err() != nil {
+				break
+			}
+			done.Add(1)
+			select {
+			case jobs <- job[T]{value, i, &done}:
+			case <-ctx.Done():
+				done.Done()
+			}
+		}
+		done.Wait()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if first != nil {
+		return first
+	}
+	return parent.Err()
+}
+
+// job is a value handed to a worker, its layer, and the layer waiting
+// for it.
+type job[T any] struct {
+	value T
+	layer int
+	done  *sync.WaitGroup
+}
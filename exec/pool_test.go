@@ -0,0 +1,78 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRunPool checks that layers run in order, on no more than the given
+// workers, and that no worker runs two calls at once.
+func TestRunPool(t *testing.T) {
+	layers := [][]int{{1, 2, 3, 4, 5}, {6, 7}, {8}}
+	layerOf := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0, 6: 1, 7: 1, 8: 2}
+
+	var mu sync.Mutex
+	var finished []int
+	busy := make([]atomic.Bool, 3)
+	err := exec.RunPool(context.Background(), layers, func(_ context.Context, worker int, value int) error {
+		if worker < 0 || worker >= len(busy) {
+			t.Errorf("Expected a worker from 0 to 2, got %d", worker)
+			return nil
+		}
+		if !busy[worker].CompareAndSwap(false, true) {
+			t.Errorf("Expected worker %d to run one call at a time", worker)
+		}
+		defer busy[worker].Store(false)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, done := range finished {
+			if layerOf[done] > layerOf[value] {
+				t.Errorf("%d ran after %d from a later layer", value, done)
+			}
+		}
+		finished = append(finished, value)
+		return nil
+	}, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(finished) != 8 {
+		t.Errorf("Expected 8 calls, got %d", len(finished))
+	}
+}
+
+// TestRunPoolError checks that an error stops the values not yet taken.
+func TestRunPoolError(t *testing.T) {
+	errBoom := errors.New("boom")
+	var calls atomic.Int32
+	err := exec.RunPool(context.Background(), [][]string{{"a", "b", "c"}, {"d"}},
+		func(ctx context.Context, _ int, value string) error {
+			calls.Add(1)
+			if value == "a" {
+				return errBoom
+			}
+			return nil
+		}, 1)
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Expected error %v, got %v", errBoom, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = exec.RunPool(ctx, [][]string{{"a"}}, func(context.Context, int, string) error {
+		t.Error("Unexpected call with cancelled context")
+		return nil
+	}, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error %v, got %v", context.Canceled, err)
+	}
+}
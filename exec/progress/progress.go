@@ -0,0 +1,279 @@
+// Package progress draws the progress of a run in a terminal: a bar for
+// each layer, the values running, failures in red, and a summary of how
+// long each value took, and which were cached, once the run is over.
+//
+// A View follows a run through its OnState method, used as the OnState
+// option of exec.Run, or through Wrap, for exec.RunLayers:
+//
+//	v := progress.New(os.Stdout, layers)
+//	v.Start(100 * time.Millisecond)
+//	err := exec.Run(ctx, g, build, exec.Options[string]{OnState: v.OnState})
+//	v.Stop()
+package progress
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+const (
+	red   = "\x1b[31m"
+	green = "\x1b[32m"
+	reset = "\x1b[0m"
+	// clearLine erases the line the cursor is on
+	clearLine = "\x1b[2K"
+)
+
+// View draws the progress of a run.
+type View[T comparable] struct {
+	// Color, on by default, colors failures red and finished bars green.
+	Color bool
+	// Live, on by default, redraws the progress in place. Without it, as
+	// when writing to a log file, Stop writes only the summary.
+	Live bool
+	// Width is the width of each layer's bar, 30 by default.
+	Width int
+
+	w      io.Writer
+	layers [][]T
+
+	mu      sync.Mutex
+	nodes   map[T]*node
+	started time.Time
+	// lines is how many lines were drawn last, to draw over
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// node is what a View knows of a value.
+type node struct {
+	state    topo.NodeState
+	start    time.Time
+	duration time.Duration
+	// fingerprint is the one found in the cache, for a cached value
+	fingerprint string
+}
+
+// New returns a View of a run over layers, as returned by
+// topo.Graph.SortByLayers, written to w.
+func New[T comparable](w io.Writer, layers [][]T) *View[T] {
+	v := &View[T]{
+		Color:   true,
+		Live:    true,
+		Width:   30,
+		w:       w,
+		layers:  layers,
+		nodes:   make(map[T]*node),
+		started: time.Now(),
+	}
+	for _, layer := range layers {
+		for _, value := range layer {
+			v.nodes[value] = &node{}
+		}
+	}
+	return v
+}
+
+// OnState records a value's change of state.
+func (v *View[T]) OnState(value T, state topo.NodeState) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	n, ok := v.nodes[value]
+	if !ok {
+		return
+	}
+	n.state = state
+	switch state {
+	case topo.StateRunning:
+		n.start = time.Now()
+	case topo.StateSucceeded, topo.StateFailed:
+		n.duration = time.Since(n.start)
+	}
+}
+
+// OnCached records the fingerprint a value was found cached with, for the
+// summary. It's used as the OnCached option of exec.Run.
+func (v *View[T]) OnCached(value T, fingerprint string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if n, ok := v.nodes[value]; ok {
+		n.state = topo.StateCached
+		n.fingerprint = fingerprint
+	}
+}
+
+// Wrap returns fn, recording when each call starts and whether it
+// succeeds, for runners like exec.RunLayers that don't report states.
+func (v *View[T]) Wrap(fn exec.Func[T]) exec.Func[T] {
+	return func(ctx context.Context, value T) error {
+		v.OnState(value, topo.StateRunning)
+		err := fn(ctx, value)
+		if err != nil {
+			v.OnState(value, topo.StateFailed)
+		} else {
+			v.OnState(value, topo.StateSucceeded)
+		}
+		return err
+	}
+}
+
+// Start starts redrawing the progress every interval, until Stop.
+func (v *View[T]) Start(interval time.Duration) {
+	v.mu.Lock()
+	v.started = time.Now()
+	v.mu.Unlock()
+	v.stop = make(chan struct{})
+	v.done = make(chan struct{})
+	go func() {
+		defer close(v.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				v.Draw()
+			case <-v.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing, draws the progress a last time, and writes the
+// summary.
+func (v *View[T]) Stop() {
+	if v.stop != nil {
+		close(v.stop)
+		<-v.done
+		v.stop = nil
+	}
+	v.Draw()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fmt.Fprint(v.w, v.summary())
+}
+
+// Draw draws the progress, over what was drawn before.
+func (v *View[T]) Draw() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.Live {
+		return
+	}
+	var b strings.Builder
+	if v.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", v.lines)
+	}
+	lines := v.frame()
+	for _, line := range lines {
+		b.WriteString(clearLine + line + "\n")
+	}
+	v.lines = len(lines)
+	fmt.Fprint(v.w, b.String())
+}
+
+// frame returns the lines showing the progress of each layer.
+func (v *View[T]) frame() []string {
+	lines := make([]string, 0, len(v.layers))
+	for i, layer := range v.layers {
+		finished := 0
+		var running, failed []string
+		for _, value := range layer {
+			switch v.nodes[value].state {
+			case topo.StateRunning:
+				running = append(running, fmt.Sprint(value))
+			case topo.StateFailed:
+				failed = append(failed, fmt.Sprint(value))
+				finished++
+			case topo.StateSucceeded, topo.StateSkipped, topo.StateCached:
+				finished++
+			}
+		}
+		filled := v.Width * finished / max(len(layer), 1)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", v.Width-filled)
+		if finished == len(layer) && len(failed) == 0 {
+			bar = v.color(green, bar)
+		}
+		line := fmt.Sprintf("Layer %d %s %d/%d", i+1, bar, finished, len(layer))
+		if len(running) > 0 {
+			line += "  running: " + strings.Join(running, ", ")
+		}
+		if len(failed) > 0 {
+			line += "  " + v.color(red, "failed: "+strings.Join(failed, ", "))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// summary returns how long each value that ran took, longest first, and
+// the run as a whole, then the values that were cached.
+func (v *View[T]) summary() string {
+	type timing struct {
+		value    T
+		node     *node
+		duration time.Duration
+	}
+	var timings []timing
+	var cached []T
+	failed := 0
+	for _, layer := range v.layers {
+		for _, value := range layer {
+			n := v.nodes[value]
+			switch n.state {
+			case topo.StateSucceeded, topo.StateFailed:
+				timings = append(timings, timing{value, n, n.duration})
+			case topo.StateCached:
+				cached = append(cached, value)
+			}
+			if n.state == topo.StateFailed {
+				failed++
+			}
+		}
+	}
+	slices.SortStableFunc(timings, func(a, b timing) int {
+		return cmp.Compare(b.duration, a.duration)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%d of %d ran in %v", len(timings), len(v.nodes), time.Since(v.started).Round(time.Millisecond))
+	if len(cached) > 0 {
+		fmt.Fprintf(&b, ", %d cached", len(cached))
+	}
+	if failed > 0 {
+		b.WriteString(", " + v.color(red, fmt.Sprintf("%d failed", failed)))
+	}
+	b.WriteString("\n")
+	for _, t := range timings {
+		line := fmt.Sprintf("  %-30v %v", t.value, t.duration.Round(time.Millisecond))
+		if t.node.state == topo.StateFailed {
+			line = v.color(red, line+"  failed")
+		}
+		b.WriteString(line + "\n")
+	}
+	for _, value := range cached {
+		line := fmt.Sprintf("  %-30v cached", value)
+		if fingerprint := v.nodes[value].fingerprint; fingerprint != "" {
+			line += " (" + fingerprint + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func (v *View[T]) color(code, s string) string {
+	if !v.Color {
+		return s
+	}
+	return code + s + reset
+}
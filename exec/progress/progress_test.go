@@ -0,0 +1,185 @@
+package progress_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+	"github.com/sam-fredrickson/go-topo/exec/progress"
+)
+
+// TestViewDraw checks the bars, running values, and failures drawn for
+// each layer.
+func TestViewDraw(t *testing.T) {
+	var out bytes.Buffer
+	v := progress.New(&out, [][]string{{"a", "b"}, {"c", "d"}})
+	v.Color = false
+	v.Width = 4
+	v.OnState("a", topo.StateRunning)
+	v.OnState("a", topo.StateSucceeded)
+	v.OnState("b", topo.StateRunning)
+	v.Draw()
+
+	expected := "\x1b[2KLayer 1 ██░░ 1/2  running: b\n" +
+		"\x1b[2KLayer 2 ░░░░ 0/2\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	v.OnState("b", topo.StateSucceeded)
+	v.OnState("c", topo.StateRunning)
+	v.OnState("c", topo.StateFailed)
+	v.OnState("d", topo.StateSkipped)
+	v.Draw()
+
+	// the second frame is drawn over the first
+	expected = "\x1b[2A" +
+		"\x1b[2KLayer 1 ████ 2/2\n" +
+		"\x1b[2KLayer 2 ████ 2/2  failed: c\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+// TestViewColor checks that failures are red and finished layers green.
+func TestViewColor(t *testing.T) {
+	var out bytes.Buffer
+	v := progress.New(&out, [][]string{{"a"}, {"b"}})
+	v.Width = 1
+	v.OnState("a", topo.StateSucceeded)
+	v.OnState("b", topo.StateFailed)
+	v.Draw()
+
+	expected := "\x1b[2KLayer 1 \x1b[32m█\x1b[0m 1/1\n" +
+		"\x1b[2KLayer 2 █ 1/1  \x1b[31mfailed: b\x1b[0m\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+// TestViewSummary checks the summary written when a run is over.
+func TestViewSummary(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("docs", nil)
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		live   bool
+		fail   string
+		ran    string
+		failed bool
+	}{
+		{"succeeded", true, "", "3 of 3 ran", false},
+		{"failed", true, "lib", "2 of 3 ran", true},
+		{"not live", false, "", "3 of 3 ran", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			v := progress.New(&out, layers)
+			v.Color = false
+			v.Live = tt.live
+			v.Start(time.Millisecond)
+			err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+				if value == tt.fail {
+					return errors.New("boom")
+				}
+				return nil
+			}, exec.Options[string]{OnState: v.OnState})
+			v.Stop()
+			if (err != nil) != tt.failed {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			s := out.String()
+			if !strings.Contains(s, tt.ran) {
+				t.Errorf("Expected %q in %q", tt.ran, s)
+			}
+			if strings.Contains(s, "1 failed") != tt.failed {
+				t.Errorf("Expected failed %v, got %q", tt.failed, s)
+			}
+			if strings.Contains(s, "Layer") != tt.live {
+				t.Errorf("Expected layers drawn %v, got %q", tt.live, s)
+			}
+		})
+	}
+}
+
+// TestViewCached checks that cached values are counted and listed apart
+// from those that ran.
+func TestViewCached(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("docs", nil)
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	v := progress.New(&out, layers)
+	v.Color = false
+	v.Live = false
+	err = exec.Run(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) { return value + "-v1", nil },
+		Cache:       hitCache{"lib-v1": true},
+		OnState:     v.OnState,
+		OnCached:    v.OnCached,
+	})
+	v.Stop()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := out.String()
+	for _, expected := range []string{"2 of 3 ran", ", 1 cached\n", "  lib                            cached (lib-v1)\n"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("Expected %q in %q", expected, s)
+		}
+	}
+}
+
+// hitCache is a Cache holding a fixed set of fingerprints.
+type hitCache map[string]bool
+
+func (c hitCache) Has(_ context.Context, fingerprint string) (bool, error) {
+	return c[fingerprint], nil
+}
+
+func (c hitCache) Add(context.Context, string) error { return nil }
+
+// TestViewWrap checks that Wrap records the states of runners that don't
+// report them.
+func TestViewWrap(t *testing.T) {
+	var out bytes.Buffer
+	layers := [][]string{{"a", "b"}}
+	v := progress.New(&out, layers)
+	v.Color = false
+	v.Width = 2
+	err := exec.RunLayers(context.Background(), layers, v.Wrap(func(_ context.Context, value string) error {
+		if value == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	}), 0)
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	v.Draw()
+	expected := "\x1b[2KLayer 1 ██ 2/2  failed: b\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,367 @@
+package exec
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// queue holds the values ready to run, and picks which starts next. It
+// keeps them in heaps, so that pushing and popping a value costs a log of
+// the values and groups waiting, times the classes of Options.Workers,
+// however wide the graph.
+type queue[T comparable] struct {
+	opts  Options[T]
+	group map[T]int
+	// names are the names of the groups from Options.Group, by number
+	names  map[string]int
+	shares []float64
+	// pass is how far each group has come in its turns: it advances by the
+	// inverse of the group's share each time one of its values starts, and
+	// the group furthest behind goes next
+	pass []float64
+	// queued counts each group's values waiting, and active holds the
+	// groups with any, furthest behind first
+	queued []int
+	active groupHeap
+	// buckets holds each group's values waiting, by class, and classes
+	// the buckets with any, by class, the one whose value starts next
+	// first
+	buckets []map[string]*bucket[T]
+	classes map[string]*bucketHeap[T]
+	// items are the values waiting, and fifo the same in the order they
+	// became ready, along with those since started until they reach its
+	// front
+	items map[T]*waiting[T]
+	fifo  []*waiting[T]
+	seq   uint64
+	// start is when the queue was made, from which Options.Aging counts
+	start time.Time
+}
+
+// waiting is a value waiting to start.
+type waiting[T comparable] struct {
+	value    T
+	group    int
+	class    string
+	priority int
+	since    time.Time
+	// key is the priority, less the periods of Options.Aging between
+	// the start of the queue and the value becoming ready: every value
+	// waiting ages a period at once, so comparing keys compares their
+	// priorities after aging at any time
+	key int
+	// seq is the order values were pushed in, and index where the value
+	// is in its bucket
+	seq   uint64
+	index int
+	gone  bool
+}
+
+func newQueue[T comparable](g *topo.Graph[T], opts Options[T]) *queue[T] {
+	q := &queue[T]{
+		opts:    opts,
+		names:   make(map[string]int),
+		classes: make(map[string]*bucketHeap[T]),
+		items:   make(map[T]*waiting[T]),
+		start:   time.Now(),
+	}
+	q.active.pass = &q.pass
+	if opts.Group == nil {
+		// a value is in the group of the first root needing it, so the
+		// walk from each root stops at the values an earlier one needs,
+		// whose dependencies it needs too
+		q.group = make(map[T]int)
+		for i, root := range g.Roots() {
+			q.group[root] = i
+			for next := []T{root}; len(next) > 0; {
+				value := next[len(next)-1]
+				next = next[:len(next)-1]
+				for _, dep := range g.Dependencies(value) {
+					if _, ok := q.group[dep]; !ok {
+						q.group[dep] = i
+						next = append(next, dep)
+					}
+				}
+			}
+			q.addGroup(1)
+		}
+	}
+	return q
+}
+
+// addGroup adds a group with a share, returning its number.
+func (q *queue[T]) addGroup(share int) int {
+	q.shares = append(q.shares, float64(max(share, 1)))
+	q.pass = append(q.pass, 0)
+	q.queued = append(q.queued, 0)
+	q.buckets = append(q.buckets, nil)
+	q.active.pos = append(q.active.pos, -1)
+	return len(q.shares) - 1
+}
+
+func (q *queue[T]) len() int {
+	return len(q.items)
+}
+
+// push adds a value that became ready.
+func (q *queue[T]) push(value T, now time.Time) {
+	var group int
+	if q.opts.Group == nil {
+		group = q.group[value]
+	} else {
+		name := q.opts.Group(value)
+		n, ok := q.names[name]
+		if !ok {
+			n = q.addGroup(q.opts.Shares[name])
+			q.names[name] = n
+		}
+		group = n
+	}
+	if q.queued[group] == 0 {
+		// a group that had nothing waiting can't claim the turns it
+		// missed, or it would hold back the others until it caught up
+		if len(q.active.groups) > 0 {
+			q.pass[group] = max(q.pass[group], q.pass[q.active.groups[0]])
+		}
+		heap.Push(&q.active, group)
+	}
+	q.queued[group]++
+
+	w := &waiting[T]{value: value, group: group, since: now, seq: q.seq}
+	q.seq++
+	if q.opts.Priority != nil {
+		w.priority = q.opts.Priority(value)
+	}
+	w.key = w.priority
+	if q.opts.Aging > 0 {
+		w.key -= int(now.Sub(q.start) / q.opts.Aging)
+	}
+	if len(q.opts.Workers) > 0 && q.opts.Class != nil {
+		w.class = q.opts.Class(value)
+	}
+	q.items[value] = w
+	q.fifo = append(q.fifo, w)
+
+	if q.buckets[group] == nil {
+		q.buckets[group] = make(map[string]*bucket[T])
+	}
+	b, ok := q.buckets[group][w.class]
+	if !ok {
+		b = &bucket[T]{q: q, class: w.class, pos: -1}
+		q.buckets[group][w.class] = b
+	}
+	heap.Push(b, w)
+	h, ok := q.classes[w.class]
+	if !ok {
+		h = &bucketHeap[T]{q: q}
+		q.classes[w.class] = h
+	}
+	if b.pos < 0 {
+		heap.Push(h, b)
+	} else {
+		heap.Fix(h, b.pos)
+	}
+}
+
+// pop removes and returns the value to start next, of those eligible, if
+// any are: the one with the highest priority, after aging, then the one
+// whose group is furthest behind in its turns, then the one that became
+// ready first. A nil eligible makes every value eligible; otherwise, it's
+// asked about the value that would start next of each class only, since a
+// value's class decides which workers can run it.
+func (q *queue[T]) pop(eligible func(T) bool) (T, bool) {
+	var best *bucket[T]
+	for _, h := range q.classes {
+		if len(h.buckets) == 0 {
+			continue
+		}
+		b := h.buckets[0]
+		if eligible != nil && !eligible(b.values[0].value) {
+			continue
+		}
+		if best == nil || q.before(b.values[0], best.values[0]) {
+			best = b
+		}
+	}
+	if best == nil {
+		var zero T
+		return zero, false
+	}
+	w := heap.Pop(best).(*waiting[T])
+	q.drop(w)
+	q.pass[w.group] += 1 / q.shares[w.group]
+	// the group's place among the others changed with its pass
+	if q.queued[w.group] > 0 {
+		heap.Fix(&q.active, q.active.pos[w.group])
+	}
+	for _, b := range q.buckets[w.group] {
+		if b.pos >= 0 {
+			heap.Fix(q.classes[b.class], b.pos)
+		}
+	}
+	return w.value, true
+}
+
+// remove removes a value waiting to start, reporting whether it was
+// waiting.
+func (q *queue[T]) remove(value T) bool {
+	w, ok := q.items[value]
+	if !ok {
+		return false
+	}
+	b := q.buckets[w.group][w.class]
+	heap.Remove(b, w.index)
+	q.drop(w)
+	if b.pos >= 0 {
+		heap.Fix(q.classes[w.class], b.pos)
+	}
+	return true
+}
+
+// drop forgets a value taken out of its bucket, and takes the bucket out
+// of its class, and the group out of those active, once they're empty.
+func (q *queue[T]) drop(w *waiting[T]) {
+	delete(q.items, w.value)
+	w.gone = true
+	for len(q.fifo) > 0 && q.fifo[0].gone {
+		q.fifo[0] = nil
+		q.fifo = q.fifo[1:]
+	}
+	if b := q.buckets[w.group][w.class]; len(b.values) == 0 {
+		heap.Remove(q.classes[w.class], b.pos)
+	}
+	q.queued[w.group]--
+	if q.queued[w.group] == 0 {
+		heap.Remove(&q.active, q.active.pos[w.group])
+	}
+}
+
+// before reports whether a should start before b.
+func (q *queue[T]) before(a, b *waiting[T]) bool {
+	if a.key != b.key {
+		return a.key > b.key
+	}
+	if a.group != b.group && q.pass[a.group] != q.pass[b.group] {
+		return q.pass[a.group] < q.pass[b.group]
+	}
+	return a.seq < b.seq
+}
+
+// adopt puts the values a call added in the group of the value that added
+// them, when groups are the roots' subtrees.
+func (q *queue[T]) adopt(parent T, added []addition[T]) {
+	if q.opts.Group != nil {
+		return
+	}
+	for _, a := range added {
+		q.group[a.value] = q.group[parent]
+	}
+}
+
+// skip reports every value that never started as skipped, in the order
+// they were added to the graph: those still waiting in the queue, those
+// handed out by the Sorter but not queued yet, like the ones being looked
+// up in the cache, and those the Sorter never handed out.
+func (q *queue[T]) skip(g *topo.Graph[T], s *topo.Sorter[T], unqueued map[T]bool, notify func(T, topo.NodeState)) {
+	for _, value := range g.Nodes() {
+		_, queued := q.items[value]
+		if state := s.State(value); queued || unqueued[value] || state == topo.StatePending || state == topo.StateReady {
+			notify(value, topo.StateSkipped)
+		}
+	}
+}
+
+// oldest returns when the value waiting longest became ready, or the zero
+// time if none are waiting.
+func (q *queue[T]) oldest() time.Time {
+	if len(q.fifo) == 0 {
+		return time.Time{}
+	}
+	// values are pushed as they become ready, so the first waited longest
+	return q.fifo[0].since
+}
+
+// bucket is a heap of the values of one group and class waiting, the one
+// to start next first. pos is where it is in its class's heap, or -1.
+type bucket[T comparable] struct {
+	q      *queue[T]
+	class  string
+	values []*waiting[T]
+	pos    int
+}
+
+func (b *bucket[T]) Len() int           { return len(b.values) }
+func (b *bucket[T]) Less(i, j int) bool { return b.q.before(b.values[i], b.values[j]) }
+func (b *bucket[T]) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.values[i].index, b.values[j].index = i, j
+}
+func (b *bucket[T]) Push(x any) {
+	w := x.(*waiting[T])
+	w.index = len(b.values)
+	b.values = append(b.values, w)
+}
+func (b *bucket[T]) Pop() any {
+	old := b.values
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	b.values = old[:len(old)-1]
+	return w
+}
+
+// bucketHeap is a heap of the buckets of one class with values waiting,
+// the one whose first value starts next first.
+type bucketHeap[T comparable] struct {
+	q       *queue[T]
+	buckets []*bucket[T]
+}
+
+func (h *bucketHeap[T]) Len() int { return len(h.buckets) }
+func (h *bucketHeap[T]) Less(i, j int) bool {
+	return h.q.before(h.buckets[i].values[0], h.buckets[j].values[0])
+}
+func (h *bucketHeap[T]) Swap(i, j int) {
+	h.buckets[i], h.buckets[j] = h.buckets[j], h.buckets[i]
+	h.buckets[i].pos, h.buckets[j].pos = i, j
+}
+func (h *bucketHeap[T]) Push(x any) {
+	b := x.(*bucket[T])
+	b.pos = len(h.buckets)
+	h.buckets = append(h.buckets, b)
+}
+func (h *bucketHeap[T]) Pop() any {
+	old := h.buckets
+	b := old[len(old)-1]
+	old[len(old)-1] = nil
+	h.buckets = old[:len(old)-1]
+	b.pos = -1
+	return b
+}
+
+// groupHeap is a heap of the groups with values waiting, the one furthest
+// behind in its turns first. pos is where each group is in it, or -1.
+type groupHeap struct {
+	pass   *[]float64
+	groups []int
+	pos    []int
+}
+
+func (h *groupHeap) Len() int           { return len(h.groups) }
+func (h *groupHeap) Less(i, j int) bool { return (*h.pass)[h.groups[i]] < (*h.pass)[h.groups[j]] }
+func (h *groupHeap) Swap(i, j int) {
+	h.groups[i], h.groups[j] = h.groups[j], h.groups[i]
+	h.pos[h.groups[i]], h.pos[h.groups[j]] = i, j
+}
+func (h *groupHeap) Push(x any) {
+	group := x.(int)
+	h.pos[group] = len(h.groups)
+	h.groups = append(h.groups, group)
+}
+func (h *groupHeap) Pop() any {
+	group := h.groups[len(h.groups)-1]
+	h.groups = h.groups[:len(h.groups)-1]
+	h.pos[group] = -1
+	return group
+}
@@ -0,0 +1,334 @@
+package exec
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Report is what happened in a run, filled in through Options.Report once
+// the run is over: how each value went, and how well the run used its
+// parallelism. It's ready to be stored as JSON, to compare runs.
+type Report[T comparable] struct {
+	// Start is when the run started.
+	Start time.Time `json:"start"`
+	// Elapsed is how long the run took.
+	Elapsed time.Duration `json:"elapsed"`
+	// Work is the sum of the durations of the calls, what the run would
+	// have taken making them one at a time.
+	Work time.Duration `json:"work"`
+	// Active is how long at least one call was running.
+	Active time.Duration `json:"active"`
+	// Limit is the run's Options.Limit, zero for no limit.
+	Limit int `json:"limit,omitempty"`
+	// Peak is the most calls running at once.
+	Peak int `json:"peak"`
+	// QueuePeak is the most values waiting to run at once, and Wait and
+	// MaxWait the total and longest time values waited, from becoming
+	// ready to their call starting, which grow when calls can't keep up.
+	QueuePeak int           `json:"queue_peak,omitempty"`
+	Wait      time.Duration `json:"wait,omitempty"`
+	MaxWait   time.Duration `json:"max_wait,omitempty"`
+	// Nodes holds each value of the run, in the order they finished.
+	Nodes []NodeReport[T] `json:"nodes"`
+	// Layers holds how each layer's calls used the workers.
+	Layers []LayerReport `json:"layers"`
+}
+
+// NodeReport is how one value of a run went.
+type NodeReport[T comparable] struct {
+	Node  T              `json:"node"`
+	State topo.NodeState `json:"state"`
+	Layer int            `json:"layer"`
+	// Deps are the values it depends on.
+	Deps []T `json:"deps,omitempty"`
+	// Start is when the value's call started, since the start of the run,
+	// and Duration how long it took, for values that were called.
+	Start    time.Duration `json:"start,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	// Wait is how long the value waited to be called once it was ready.
+	Wait time.Duration `json:"wait,omitempty"`
+	// Fingerprint is the one found in the cache, for a cached value.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Error is the error of a value that failed, or of a gated value that
+	// wasn't approved, which is skipped.
+	Error string `json:"error,omitempty"`
+}
+
+// LayerReport is how the calls for the values of one layer used the
+// workers. Run doesn't wait for layers, so the spans of layers can
+// overlap.
+type LayerReport struct {
+	// Span is the time from the first of the layer's calls starting to
+	// the last returning.
+	Span time.Duration `json:"span"`
+	// Busy is the sum of the durations of the layer's calls.
+	Busy time.Duration `json:"busy"`
+	// Idle is the worker time in the span not spent on the layer's calls:
+	// the span times the workers, less Busy. Workers are the run's limit,
+	// or, with no limit, the most calls running at once.
+	Idle time.Duration `json:"idle"`
+}
+
+// Speedup returns how many times faster the run was than making its calls
+// one at a time: Work over Elapsed.
+func (r *Report[T]) Speedup() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Work) / float64(r.Elapsed)
+}
+
+// Parallelism returns the average number of calls running while any were:
+// Work over Active.
+func (r *Report[T]) Parallelism() float64 {
+	if r.Active <= 0 {
+		return 0
+	}
+	return float64(r.Work) / float64(r.Active)
+}
+
+// Efficiency returns the share of the workers' time, from 0 to 1, spent on
+// calls: the speedup over the workers, which are the run's limit, or, with
+// no limit, the most calls running at once.
+func (r *Report[T]) Efficiency() float64 {
+	workers := r.workers()
+	if workers == 0 {
+		return 0
+	}
+	return r.Speedup() / float64(workers)
+}
+
+func (r *Report[T]) workers() int {
+	if r.Limit > 0 {
+		return r.Limit
+	}
+	return r.Peak
+}
+
+// CriticalPath returns the chain of values that decided how long the run
+// took, first to last: the value that finished last, preceded by the one
+// of its dependencies that finished last, and so on.
+func (r *Report[T]) CriticalPath() []T {
+	nodes := make(map[T]NodeReport[T], len(r.Nodes))
+	var last *NodeReport[T]
+	for i, n := range r.Nodes {
+		nodes[n.Node] = n
+		if last == nil || n.Start+n.Duration > last.Start+last.Duration {
+			last = &r.Nodes[i]
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	path := []T{last.Node}
+	for n := *last; ; {
+		var prev *NodeReport[T]
+		for _, dep := range n.Deps {
+			if d, ok := nodes[dep]; ok && (prev == nil || d.Start+d.Duration > prev.Start+prev.Duration) {
+				prev = &d
+			}
+		}
+		if prev == nil {
+			break
+		}
+		path = append(path, prev.Node)
+		n = *prev
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// String returns a summary of the report, with a line for the run, one for
+// its queue, and one for each layer:
+//
+//	7s for 15s of work: 2.14x speedup, 2.50 parallel, 54% efficient
+//	queue: up to 6 waiting, 4s longest wait
+//	layer 0: 2s span, 2s busy, 6s idle
+func (r *Report[T]) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v for %v of work: %.2fx speedup, %.2f parallel, %.0f%% efficient",
+		r.Elapsed.Round(time.Millisecond), r.Work.Round(time.Millisecond),
+		r.Speedup(), r.Parallelism(), 100*r.Efficiency())
+	if r.QueuePeak > 0 {
+		fmt.Fprintf(&b, "\nqueue: up to %d waiting, %v longest wait", r.QueuePeak, r.MaxWait.Round(time.Millisecond))
+	}
+	for i, layer := range r.Layers {
+		fmt.Fprintf(&b, "\nlayer %d: %v span, %v busy, %v idle", i,
+			layer.Span.Round(time.Millisecond), layer.Busy.Round(time.Millisecond), layer.Idle.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+// reporter gathers the report of a run. Its methods do nothing on a nil
+// reporter, for runs without Options.Report.
+type reporter[T comparable] struct {
+	start time.Time
+	nodes []NodeReport[T]
+	// index is where each value is in nodes
+	index map[T]int
+	// finished holds the indexes of the values in the order they reached
+	// a final state
+	finished []int
+	// readyAt is when each value became ready
+	readyAt   map[T]time.Time
+	queuePeak int
+}
+
+func newReporter[T comparable](report *Report[T]) *reporter[T] {
+	if report == nil {
+		return nil
+	}
+	return &reporter[T]{start: time.Now(), index: make(map[T]int), readyAt: make(map[T]time.Time)}
+}
+
+// node returns the report of a value, adding it if it's new.
+func (r *reporter[T]) node(value T) *NodeReport[T] {
+	i, ok := r.index[value]
+	if !ok {
+		i = len(r.nodes)
+		r.index[value] = i
+		r.nodes = append(r.nodes, NodeReport[T]{Node: value})
+	}
+	return &r.nodes[i]
+}
+
+// state notes a value's change of state.
+func (r *reporter[T]) state(value T, layer int, state topo.NodeState) {
+	if r == nil {
+		return
+	}
+	n := r.node(value)
+	n.State = state
+	n.Layer = layer
+	if state == topo.StateReady {
+		r.readyAt[value] = time.Now()
+	}
+	if state.Final() {
+		r.finished = append(r.finished, r.index[value])
+	}
+}
+
+// call notes the call for a value, and its error.
+func (r *reporter[T]) call(value T, start time.Time, duration time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	n := r.node(value)
+	n.Start = start.Sub(r.start)
+	n.Duration = duration
+	if ready, ok := r.readyAt[value]; ok {
+		n.Wait = max(start.Sub(ready), 0)
+	}
+	if err != nil {
+		n.Error = err.Error()
+	}
+}
+
+// rejected notes the error a gated value wasn't approved with.
+func (r *reporter[T]) rejected(value T, err error) {
+	if r == nil {
+		return
+	}
+	r.node(value).Error = err.Error()
+}
+
+// cached notes the fingerprint a value was found cached with.
+func (r *reporter[T]) cached(value T, fingerprint string) {
+	if r == nil {
+		return
+	}
+	r.node(value).Fingerprint = fingerprint
+}
+
+// queue notes how many values are waiting to run.
+func (r *reporter[T]) queue(depth int) {
+	if r == nil {
+		return
+	}
+	r.queuePeak = max(r.queuePeak, depth)
+}
+
+// finish fills in report for a run of g with limit.
+func (r *reporter[T]) finish(report *Report[T], g *topo.Graph[T], limit int) {
+	if r == nil {
+		return
+	}
+	*report = Report[T]{Start: r.start, Elapsed: time.Since(r.start), Limit: max(limit, 0), QueuePeak: r.queuePeak}
+	nodes := make([]NodeReport[T], 0, len(r.nodes))
+	for _, i := range r.finished {
+		n := r.nodes[i]
+		n.Deps = g.Dependencies(n.Node)
+		nodes = append(nodes, n)
+	}
+	report.Nodes = nodes
+
+	// sweep the starts and ends of the calls for the peak and active time
+	type edge struct {
+		at    time.Duration
+		delta int
+	}
+	var edges []edge
+	for _, n := range nodes {
+		report.Wait += n.Wait
+		report.MaxWait = max(report.MaxWait, n.Wait)
+		if n.Duration > 0 {
+			report.Work += n.Duration
+			edges = append(edges, edge{n.Start, 1}, edge{n.Start + n.Duration, -1})
+		}
+	}
+	slices.SortFunc(edges, func(a, b edge) int {
+		if c := cmp.Compare(a.at, b.at); c != 0 {
+			return c
+		}
+		// ends before starts, so that back-to-back calls don't overlap
+		return a.delta - b.delta
+	})
+	running := 0
+	var since time.Duration
+	for _, e := range edges {
+		if running == 0 {
+			since = e.at
+		}
+		running += e.delta
+		report.Peak = max(report.Peak, running)
+		if running == 0 {
+			report.Active += e.at - since
+		}
+	}
+
+	// when each layer's first call started and its last returned
+	type span struct {
+		first, last time.Duration
+		called      bool
+	}
+	var spans []span
+	for _, n := range nodes {
+		for len(report.Layers) <= n.Layer {
+			report.Layers = append(report.Layers, LayerReport{})
+			spans = append(spans, span{})
+		}
+		if n.Duration <= 0 {
+			continue
+		}
+		sp := &spans[n.Layer]
+		if !sp.called || n.Start < sp.first {
+			sp.first = n.Start
+		}
+		sp.last = max(sp.last, n.Start+n.Duration)
+		sp.called = true
+		report.Layers[n.Layer].Busy += n.Duration
+	}
+	workers := time.Duration(report.workers())
+	for i, sp := range spans {
+		if sp.called {
+			layer := &report.Layers[i]
+			layer.Span = sp.last - sp.first
+			layer.Idle = max(workers*layer.Span-layer.Busy, 0)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestReport checks the speedup and parallelism reported for a run.
+func TestReport(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"api", "web"})
+	g.AddNode("api", nil)
+	g.AddNode("web", nil)
+
+	var report exec.Report[string]
+	err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}, exec.Options[string]{Report: &report})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	within := func(name string, actual, expected, tolerance float64) {
+		t.Helper()
+		if actual < expected-tolerance || actual > expected+tolerance {
+			t.Errorf("Expected %s around %.2f, got %.2f", name, expected, actual)
+		}
+	}
+	within("work", report.Work.Seconds(), 0.15, 0.05)
+	within("speedup", report.Speedup(), 1.5, 0.3)
+	within("parallelism", report.Parallelism(), 1.5, 0.3)
+	within("efficiency", report.Efficiency(), 0.75, 0.15)
+	if report.Peak != 2 {
+		t.Errorf("Expected a peak of 2, got %d", report.Peak)
+	}
+	if len(report.Layers) != 2 {
+		t.Fatalf("Expected 2 layers, got %+v", report.Layers)
+	}
+	within("layer 0 busy", report.Layers[0].Busy.Seconds(), 0.1, 0.03)
+	within("layer 0 idle", report.Layers[0].Idle.Seconds(), 0, 0.03)
+	within("layer 1 idle", report.Layers[1].Idle.Seconds(), 0.05, 0.03)
+	if len(report.Nodes) != 3 || report.Nodes[2].Node != "app" || report.Nodes[2].Layer != 1 {
+		t.Errorf("Expected app to finish last, in layer 1, got %+v", report.Nodes)
+	}
+	if path := report.CriticalPath(); len(path) != 2 || path[1] != "app" {
+		t.Errorf("Expected a critical path ending with app, got %v", path)
+	}
+	if s := report.String(); !strings.Contains(s, "x speedup") || strings.Count(s, "\nlayer ") != 2 {
+		t.Errorf("Unexpected summary %q", s)
+	}
+}
+
+// TestReportNodes checks how each value is reported, and that reports
+// survive being stored as JSON.
+func TestReportNodes(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", nil)
+	g.AddNode("db", []string{"lib"})
+
+	var report exec.Report[string]
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+		if value == "db" {
+			return errors.New("migration failed")
+		}
+		return nil
+	}, exec.Options[string]{
+		Limit:       1,
+		Fingerprint: func(_ context.Context, value string) (string, error) { return value + "-v1", nil },
+		Cache:       &mapCache{set: map[string]bool{"lib-v1": true}},
+		Report:      &report,
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded exec.Report[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Nodes, report.Nodes) || !decoded.Start.Equal(report.Start) {
+		t.Errorf("Expected %+v, got %+v", report, decoded)
+	}
+
+	expected := []exec.NodeReport[string]{
+		{Node: "lib", State: topo.StateCached, Fingerprint: "lib-v1"},
+		{Node: "db", State: topo.StateFailed, Layer: 1, Deps: []string{"lib"}, Error: "migration failed"},
+		{Node: "app", State: topo.StateSkipped, Layer: 2, Deps: []string{"lib", "db"}},
+	}
+	for i := range report.Nodes {
+		// the timing can't be known
+		report.Nodes[i].Start, report.Nodes[i].Duration, report.Nodes[i].Wait = 0, 0, 0
+	}
+	if !reflect.DeepEqual(report.Nodes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, report.Nodes)
+	}
+	if report.Limit != 1 {
+		t.Errorf("Expected limit 1, got %d", report.Limit)
+	}
+}
@@ -0,0 +1,666 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+var (
+	// ErrTimeout is returned by Run when Options.Timeout passes before
+	// every value has run.
+	ErrTimeout = errors.New("run timed out")
+	// ErrStopped is returned by Run when Options.Stop is closed before
+	// every value has run.
+	ErrStopped = errors.New("run stopped")
+	// ErrSubtreeCanceled is returned by a run once values were cancelled
+	// with Execution.CancelSubtree, and is the cause of the cancelled
+	// calls' contexts.
+	ErrSubtreeCanceled = errors.New("subtree cancelled")
+)
+
+// Options configures Run. The zero value starts every value as soon as its
+// dependencies are done, with no limit.
+type Options[T comparable] struct {
+	// Limit is the most calls that run at once; zero or less means no
+	// limit. The other options only matter when the limit is reached, and
+	// decide which of the values waiting to run starts next.
+	Limit int
+	// Group returns the group a value belongs to, like its team or a tag,
+	// so that groups take turns at the calls Limit allows rather than the
+	// one with most values ready taking them all. By default, the roots of
+	// the graph, the values nothing depends on, each have a group, holding
+	// every value it needs that no earlier root does, so that independent
+	// subtrees take turns.
+	Group func(T) string
+	// Shares weights the groups returned by Group: a group with a share of
+	// 2 gets twice as many calls started as a group with a share of 1,
+	// while both have values waiting. Groups missing from Shares, or with
+	// a share less than one, have a share of one.
+	Shares map[string]int
+	// Priority returns the priority of a value; values with a higher
+	// priority start before the others, regardless of their group.
+	Priority func(T) int
+	// Aging raises the priority of a value by one for every Aging it waits
+	// to start, so that a steady stream of values with a higher priority
+	// can't hold it back forever. Periods are counted from the start of
+	// the run, so the values waiting all age at once, and a value's first
+	// raise comes within Aging of it becoming ready. Zero means priorities
+	// don't change.
+	Aging time.Duration
+	// Timeout, if set, is how long the run may take. Once it passes, no
+	// more calls start, and the calls running have Grace to return before
+	// the context passed to them is cancelled, with ErrTimeout as its
+	// cause. Zero means no timeout.
+	Timeout time.Duration
+	// Grace is how long the calls running when Timeout passes, or Stop is
+	// closed, have to return. Zero cancels them at once.
+	Grace time.Duration
+	// Stop, if set, stops the run once closed, as Timeout passing does,
+	// but with ErrStopped as the cause.
+	Stop <-chan struct{}
+	// Context, if set, returns the context for the call for a value, made
+	// from the one Run would pass, to give each call what it needs, like
+	// a client scoped to the value's team.
+	Context func(ctx context.Context, value T) context.Context
+	// Filter, if set, reports whether to run a value. Values it leaves out
+	// aren't called, and are reported as skipped, but still count as done
+	// for the values depending on them. Tagged makes a filter from tags.
+	Filter func(value T) bool
+	// Fingerprint, if set, returns a fingerprint of everything the call
+	// for a value depends on, like a hash of its inputs. Values whose
+	// fingerprint is in Cache aren't called, and are reported as cached;
+	// the fingerprints of calls that succeed are added to it. An empty
+	// fingerprint means the value isn't cached. It's called for values
+	// as they become ready, for several at once, while others run.
+	Fingerprint func(ctx context.Context, value T) (string, error)
+	// Cache holds the fingerprints of calls that succeeded, in this run
+	// or earlier ones. Errors from it, or from Fingerprint, are logged to
+	// the call's logger and otherwise ignored, calling the value as if it
+	// weren't cached.
+	Cache Cache
+	// Params holds parameters for the calls, resolved for each value and
+	// carried in its call's context; see Param.
+	Params ParamSet[T]
+	// Artifacts, if set, keeps what calls save with SaveArtifact, by value;
+	// a CommandRunner saves the output of each command in it.
+	Artifacts ArtifactStore[T]
+	// QueueLimit, if above zero, is the most values kept waiting to run at
+	// once. Values whose dependencies are done beyond that are left in the
+	// graph's Sorter until there's room, so that a run over a wide graph
+	// whose calls are slow doesn't hold its whole frontier. Group, Shares,
+	// Priority, and Aging then only choose among the values waiting. Values
+	// added by calls with AddNode wait for room too, ahead of the others.
+	QueueLimit int
+	// Gate, if set, returns whether a value needs approval before it
+	// runs, like a deployment to production. Once ready, a gated value
+	// waits until Approver approves it, or Execution.Approve does for a
+	// run started with Start, while the rest of the run goes on. Values
+	// that are cached or left out by Filter aren't gated.
+	Gate func(value T) bool
+	// Approver, if set, is asked to approve each gated value once it's
+	// ready.
+	Approver Approver[T]
+	// Workers, if set, are the workers the run's calls run on, each
+	// running one call at a time, so that no more calls than there are
+	// workers run at once. A value only runs on a worker of its class;
+	// Run returns an UnschedulableError for each value none can run,
+	// before anything runs. See WorkerOf.
+	Workers []Worker
+	// Class, if set, returns the class of worker a value needs, like
+	// "gpu", or "" for any worker. It only matters with Workers.
+	Class func(value T) string
+	// Report, if set, is filled in with the report of the run once it's
+	// over, with how each value went, and the speedup and parallelism the
+	// run achieved.
+	Report *Report[T]
+	// OnState, if set, is called each time a value changes state, as laid
+	// out by topo.NodeState: when it's ready, when it starts, when it
+	// succeeds or fails, and, once the run stops after an error, a
+	// timeout, or being cancelled, for every value that never started.
+	// Calls are made one at a time, from the goroutine that called Run.
+	OnState func(value T, state topo.NodeState)
+	// OnCached, if set, is called with the fingerprint found in Cache for
+	// each value that isn't called because of it, just before OnState
+	// reports the value as cached, for reporting cache hits.
+	OnCached func(value T, fingerprint string)
+	// OnNested, if set, is called each time a value in a run nested in
+	// the call for value, with Sub, changes state. The path holds the
+	// value in the nested run, after those of any runs between, outermost
+	// first. Calls are made one at a time, and never at the same time as
+	// OnState, but from the goroutines of the calls.
+	OnNested func(value T, path []any, state topo.NodeState)
+}
+
+// Tagged returns a filter, for Options.Filter, keeping the values with any
+// of the given tags, as listed in tags.
+func Tagged[T comparable](tags map[T][]string, keep ...string) func(T) bool {
+	return func(value T) bool {
+		return slices.ContainsFunc(tags[value], func(tag string) bool {
+			return slices.Contains(keep, tag)
+		})
+	}
+}
+
+// Run calls fn for every value in the graph, starting each as soon as the
+// calls for its dependencies have returned, rather than waiting for a
+// whole layer as RunLayers does. Pins are ignored.
+//
+// The first error returned by fn cancels the context passed to the other
+// calls and stops any more from starting. Once the calls running have
+// returned, Run returns an Errors holding the first error and any the
+// others returned, often context.Canceled. A graph with a cycle returns
+// topo.ErrCyclicDependency without calling fn.
+//
+// A run that passes Options.Timeout returns ErrTimeout, joined with an
+// Errors if any calls failed, where those failing after their context was
+// cancelled for the timeout are marked TimedOut. A run stopped through
+// Options.Stop likewise returns ErrStopped.
+//
+// Calls can add values to the run as they find more work; see AddNode.
+// A value can be backed by a graph of its own, run in its call; see Sub.
+// Calls can keep what they produce, by value, in Options.Artifacts; see
+// SaveArtifact.
+//
+// Values can be left out with Options.Filter, or skipped when their
+// results are cached, with Options.Fingerprint and Options.Cache; DryRun
+// shows what a run would do.
+//
+// The context passed to each call carries its value, the layer
+// SortByLayers puts it in, its parameters, and a logger; see Node, Layer,
+// Param, and Logger.
+func Run[T comparable](ctx context.Context, g *topo.Graph[T], fn Func[T], opts Options[T]) error {
+	return run(ctx, g, fn, opts, nil)
+}
+
+// run runs the graph for Run and Start, keeping e up to date if it isn't
+// nil.
+func run[T comparable](ctx context.Context, g *topo.Graph[T], fn Func[T], opts Options[T], e *Execution[T]) error {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return err
+	}
+	if err := opts.schedulable(g.Nodes()); err != nil {
+		return err
+	}
+	if opts.Gate != nil && opts.Approver == nil && e == nil {
+		return ErrNoApprover
+	}
+	layerOf := layerIndex(layers)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	rep := newReporter(opts.Report)
+	// g grows as calls add values, so it's read once the run is over
+	defer func() { rep.finish(opts.Report, g, opts.Limit) }()
+
+	q := newQueue(g, opts)
+	s := g.Sorter()
+	workers := newPool(opts)
+	// mu keeps OnNested from being called at the same time as the others
+	var mu sync.Mutex
+	notify := func(value T, state topo.NodeState) {
+		e.record(value, state)
+		rep.state(value, layerOf[value], state)
+		if opts.OnState != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			opts.OnState(value, state)
+		}
+	}
+	results := make(chan result[T])
+	running := 0
+	var returned []result[T]
+	var done, settled []T
+	// fresh are the values that became ready when the graph grew, which
+	// wait here for room in the queue
+	var fresh []T
+	// fingerprints are those of the values to be called, to cache
+	fingerprints := make(map[T]string)
+	var errs Errors[T]
+	// first is the first error, stopping the run
+	var first error
+	// halt is ErrTimeout or ErrStopped once the run is stopped by either,
+	// and expired is set when the grace period after it passes, cancelling
+	// the calls; TimedOut marks the calls that fail after that
+	var halt error
+	var expired bool
+	// calls cancel the calls running, by value, and canceled holds the
+	// values cancelled with Execution.CancelSubtree, which are skipped
+	// rather than started, and whose failures don't stop the run
+	calls := make(map[T]context.CancelCauseFunc)
+	canceled := make(map[T]bool)
+	// dropped are the values done without a call since the last wait:
+	// those cancelled, and those skipped or cached once looked up
+	var dropped []T
+	var wasCanceled bool
+	// looking holds the values being looked up in Options.Cache, off this
+	// goroutine, and checks takes what's found; those still being looked
+	// up once the run stops are skipped
+	looking := make(map[T]bool)
+	checks := make(chan check[T])
+	// held are the gated values waiting to be approved, in the order they
+	// became ready, approved those approved so far, answers takes what
+	// Options.Approver answers, and asking cancels the contexts it's asked
+	// with, by value
+	var held []T
+	approved := make(map[T]bool)
+	answers := make(chan approval[T])
+	asking := make(map[T]context.CancelCauseFunc)
+	// unhold stops holding a value, and asking Options.Approver about it
+	// for cause, reporting whether it was held
+	unhold := func(value T, cause error) bool {
+		i := slices.Index(held, value)
+		if i < 0 {
+			return false
+		}
+		held = slices.Delete(held, i, i+1)
+		e.await(held)
+		if cancel, ok := asking[value]; ok {
+			cancel(cause)
+			delete(asking, value)
+		}
+		return true
+	}
+	// admit deals with a value once it's settled: values skipped or cached
+	// are reported, and admit returns true for them to be marked done;
+	// gated values are held, and the rest queued
+	admit := func(value T, state topo.NodeState, fingerprint string, now time.Time) bool {
+		if state != topo.StateReady {
+			if state == topo.StateCached {
+				rep.cached(value, fingerprint)
+			}
+			if state == topo.StateCached && opts.OnCached != nil {
+				mu.Lock()
+				opts.OnCached(value, fingerprint)
+				mu.Unlock()
+			}
+			notify(value, state)
+			return true
+		}
+		if fingerprint != "" {
+			fingerprints[value] = fingerprint
+		}
+		if opts.Gate != nil && !approved[value] && opts.Gate(value) {
+			held = append(held, value)
+			e.await(held)
+			if opts.Approver != nil {
+				approveCtx, cancel := context.WithCancelCause(callContext(ctx, value, layerOf[value]))
+				asking[value] = cancel
+				go func() {
+					err := opts.Approver.Approve(approveCtx, value)
+					select {
+					case answers <- approval[T]{value, err}:
+					case <-ctx.Done():
+					}
+				}()
+			}
+			return false
+		}
+		q.push(value, now)
+		return false
+	}
+	// answer deals with what was asked of e: values cancelled are skipped,
+	// or have their calls cancelled, along with their dependents, and
+	// values approved stop being held
+	answer := func(requests []request[T]) {
+		for _, r := range requests {
+			if !r.cancel {
+				approved[r.value] = true
+				if unhold(r.value, nil) {
+					q.push(r.value, time.Now())
+				}
+				continue
+			}
+			for _, value := range append([]T{r.value}, g.Descendants(r.value)...) {
+				canceled[value] = true
+				if call, ok := calls[value]; ok {
+					wasCanceled = true
+					call(ErrSubtreeCanceled)
+				} else if q.remove(value) || unhold(value, ErrSubtreeCanceled) {
+					wasCanceled = true
+					notify(value, topo.StateSkipped)
+					dropped = append(dropped, value)
+				}
+			}
+		}
+	}
+	var deadline, grace <-chan time.Time
+	stop := opts.Stop
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		now := time.Now()
+		// values added by calls are admitted first, then those left in
+		// the Sorter, as many as there's room for; values being looked up
+		// will be queued, so they count too
+		ready, more := fresh, []T(nil)
+		fresh = nil
+		var err error
+		if opts.QueueLimit <= 0 {
+			more, err = s.ReadyN(0)
+		} else {
+			room := max(opts.QueueLimit-q.len()-len(looking), 0)
+			n := min(room, len(ready))
+			ready, fresh = ready[:n:n], ready[n:]
+			if room > n {
+				more, err = s.ReadyN(room - n)
+			}
+		}
+		if err != nil && first == nil {
+			first = err
+			cancel(nil)
+		}
+		ready = append(ready, more...)
+		settled = settled[:0]
+		for _, value := range ready {
+			notify(value, topo.StateReady)
+			if canceled[value] {
+				wasCanceled = true
+				notify(value, topo.StateSkipped)
+				settled = append(settled, value)
+				continue
+			}
+			if first == nil && halt == nil && ctx.Err() == nil {
+				state := topo.StateReady
+				if opts.Filter != nil && !opts.Filter(value) {
+					state = topo.StateSkipped
+				} else if opts.caching() {
+					// fingerprinting and looking up the cache can be slow,
+					// so they're done without holding up the other values
+					looking[value] = true
+					checkCtx := callContext(ctx, value, layerOf[value])
+					go func() {
+						state, fingerprint := opts.lookup(checkCtx, value)
+						select {
+						case checks <- check[T]{value, state, fingerprint}:
+						case <-ctx.Done():
+						}
+					}()
+					continue
+				}
+				if admit(value, state, "", now) {
+					settled = append(settled, value)
+				}
+				continue
+			}
+			q.push(value, now)
+		}
+		rep.queue(q.len())
+		if len(settled) > 0 {
+			// values left out or cached are done without a call
+			_ = s.Done(settled...)
+			continue
+		}
+		started := 0
+		for first == nil && halt == nil && ctx.Err() == nil && q.len() > 0 && (opts.Limit <= 0 || running < opts.Limit) && workers.idle() {
+			value, ok := q.pop(workers.fits)
+			if !ok {
+				// no free worker can run any of the values waiting
+				break
+			}
+			worker := workers.take(value, true)
+			notify(value, topo.StateRunning)
+			running++
+			started++
+			callCtx := context.WithValue(callContext(ctx, value, layerOf[value]), paramsKey, opts.Params.Resolve(value))
+			callCtx = workers.withWorker(callCtx, worker)
+			if opts.Context != nil {
+				callCtx = opts.Context(callCtx, value)
+			}
+			fingerprint := fingerprints[value]
+			added := &additions[T]{}
+			callCtx = context.WithValue(callCtx, addKey, added)
+			// set even when nil, so that runs nested in the call don't
+			// report to this one's parent
+			var report nested
+			if opts.OnNested != nil {
+				report = func(path []any, state topo.NodeState) {
+					mu.Lock()
+					defer mu.Unlock()
+					opts.OnNested(value, path, state)
+				}
+			}
+			callCtx = context.WithValue(callCtx, nestedKey, report)
+			var sink artifactSink
+			if opts.Artifacts != nil {
+				sink = func(ctx context.Context, name string, data []byte) error {
+					return opts.Artifacts.Put(ctx, value, name, data)
+				}
+			}
+			callCtx = context.WithValue(callCtx, artifactsKey, sink)
+			callCtx, calls[value] = context.WithCancelCause(callCtx)
+			go func() {
+				start := time.Now()
+				err := fn(callCtx, value)
+				r := result[T]{value: value, worker: worker, err: err, start: start, duration: time.Since(start)}
+				if err == nil {
+					r.added = added.take()
+				}
+				// a value that adds nodes isn't cached, since skipping it
+				// would leave them out
+				if err == nil && len(r.added) == 0 && fingerprint != "" {
+					if err := opts.Cache.Add(callCtx, fingerprint); err != nil {
+						Logger(callCtx).Warn("caching result", "fingerprint", fingerprint, "error", err)
+					}
+				}
+				results <- r
+			}()
+		}
+		if running == 0 && ((len(held) == 0 && len(looking) == 0 && len(fresh) == 0) || first != nil || halt != nil || ctx.Err() != nil) {
+			break
+		}
+		if started > 0 && opts.QueueLimit > 0 && q.len() < opts.QueueLimit {
+			// make room for the values left in the Sorter before waiting
+			continue
+		}
+		e.queue(q.len(), running, q.oldest())
+		// with only gated values left, the run waits for them to be
+		// approved, unless it's cancelled
+		var stopped <-chan struct{}
+		if running == 0 {
+			stopped = ctx.Done()
+		}
+		// take every call that has returned, and mark them done at once,
+		// which is cheaper when many small calls finish together; anything
+		// else that happens is dealt with before waiting again
+		returned = returned[:0]
+		select {
+		case r := <-results:
+			returned = append(returned, r)
+			running--
+		case <-deadline:
+			deadline = nil
+			if first == nil && halt == nil {
+				halt = ErrTimeout
+				grace = time.After(opts.Grace)
+			}
+		case <-stop:
+			stop = nil
+			if first == nil && halt == nil {
+				halt = ErrStopped
+				grace = time.After(opts.Grace)
+			}
+		case <-grace:
+			grace = nil
+			expired = true
+			cancel(halt)
+		case <-stopped:
+		case <-e.wakes():
+			answer(e.take())
+		case c := <-checks:
+			delete(looking, c.value)
+			switch {
+			case canceled[c.value]:
+				wasCanceled = true
+				notify(c.value, topo.StateSkipped)
+				dropped = append(dropped, c.value)
+			case first != nil || halt != nil || ctx.Err() != nil:
+				q.push(c.value, time.Now())
+			case admit(c.value, c.state, c.fingerprint, time.Now()):
+				dropped = append(dropped, c.value)
+			}
+		case a := <-answers:
+			if !unhold(a.value, nil) {
+				// approved already, or cancelled
+				break
+			}
+			if a.err == nil {
+				q.push(a.value, time.Now())
+				break
+			}
+			// a value that isn't approved never starts, so it's skipped,
+			// but its error fails the run as a call's would
+			nodeErr := &NodeError[T]{Node: a.value, Err: a.err}
+			errs = append(errs, nodeErr)
+			e.fail(nodeErr)
+			rep.rejected(a.value, a.err)
+			notify(a.value, topo.StateSkipped)
+			if first == nil && !expired {
+				first = a.err
+				cancel(nil)
+			}
+		}
+		for waiting := true; waiting && running > 0; {
+			select {
+			case r := <-results:
+				returned = append(returned, r)
+				running--
+			default:
+				waiting = false
+			}
+		}
+		done = append(done[:0], dropped...)
+		dropped = dropped[:0]
+		grew := false
+		for _, r := range returned {
+			calls[r.value](nil)
+			delete(calls, r.value)
+			workers.release(r.worker)
+			if len(r.added) > 0 {
+				if next, err := withNodes(g, r.added); err != nil {
+					r.err = fmt.Errorf("adding nodes: %w", err)
+				} else if err := opts.schedulable(addedValues(r.added)); err != nil {
+					r.err = fmt.Errorf("adding nodes: %w", err)
+				} else {
+					g, grew = next, true
+					q.adopt(r.value, r.added)
+				}
+			}
+			rep.call(r.value, r.start, r.duration, r.err)
+			if r.err != nil {
+				nodeErr := &NodeError[T]{
+					Node:     r.value,
+					Attempts: 1,
+					Duration: r.duration,
+					Err:      r.err,
+					TimedOut: expired && halt == ErrTimeout,
+					Canceled: canceled[r.value],
+				}
+				errs = append(errs, nodeErr)
+				e.fail(nodeErr)
+				notify(r.value, topo.StateFailed)
+				if first == nil && !expired && !canceled[r.value] {
+					first = r.err
+					cancel(nil)
+				}
+				continue
+			}
+			notify(r.value, topo.StateSucceeded)
+			done = append(done, r.value)
+		}
+		// what was asked from OnState as the calls returned is dealt with
+		// before their dependents start
+		select {
+		case <-e.wakes():
+			answer(e.take())
+			done = append(done, dropped...)
+			dropped = dropped[:0]
+		default:
+		}
+		// every value started came from Ready, so this can't fail
+		_ = s.Done(done...)
+		if grew {
+			var added []T
+			s, added = resume(g, s)
+			fresh = append(fresh, added...)
+			// values added only depend on others, so no layers change,
+			// and this can't fail either
+			layers, _ = g.SortByLayers()
+			layerOf = layerIndex(layers)
+		}
+	}
+	for _, value := range held {
+		notify(value, topo.StateSkipped)
+	}
+	e.await(nil)
+	if s.Active() {
+		for _, value := range fresh {
+			looking[value] = true
+		}
+		q.skip(g, s, looking, notify)
+	}
+	if halt != nil && s.Active() {
+		if len(errs) > 0 {
+			return errors.Join(halt, errs)
+		}
+		return halt
+	}
+	if wasCanceled {
+		if len(errs) > 0 {
+			return errors.Join(ErrSubtreeCanceled, errs)
+		}
+		return ErrSubtreeCanceled
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if first != nil {
+		return first
+	}
+	if s.Active() {
+		return parent.Err()
+	}
+	return nil
+}
+
+// result is what a call returned, how long it took, and the nodes it
+// added.
+type result[T any] struct {
+	value T
+	// worker is the worker the call ran on, or -1
+	worker   int
+	err      error
+	start    time.Time
+	duration time.Duration
+	added    []addition[T]
+}
+
+// check is what looking a value up in Options.Cache found.
+type check[T any] struct {
+	value       T
+	state       topo.NodeState
+	fingerprint string
+}
+
+// layerIndex maps each value to its layer.
+func layerIndex[T comparable](layers [][]T) map[T]int {
+	layerOf := make(map[T]int)
+	for i, layer := range layers {
+		for _, value := range layer {
+			layerOf[value] = i
+		}
+	}
+	return layerOf
+}
@@ -0,0 +1,433 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRun checks that each value starts once its dependencies are done,
+// without waiting for the rest of its layer.
+func TestRun(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("slow", nil)
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("app", []string{"lib", "slow"})
+
+	var mu sync.Mutex
+	done := make(map[string]bool)
+	libDone := make(chan struct{})
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+		if value == "slow" {
+			// lib is in the next layer, but only depends on base
+			<-libDone
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range g.Dependencies(value) {
+			if !done[dep] {
+				t.Errorf("%s started before its dependency %s was done", value, dep)
+			}
+		}
+		done[value] = true
+		if value == "lib" {
+			close(libDone)
+		}
+		return nil
+	}, exec.Options[string]{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(done) != 4 {
+		t.Errorf("Expected 4 calls, got %v", done)
+	}
+}
+
+// TestRunError checks that an error stops more calls from starting, and
+// that a cycle is reported without any calls.
+func TestRunError(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", []string{"a"})
+	g.AddNode("c", []string{"b"})
+
+	errBoom := errors.New("boom")
+	var calls []string
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+		calls = append(calls, value)
+		if value == "b" {
+			return errBoom
+		}
+		return nil
+	}, exec.Options[string]{Limit: 1})
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Expected error %v, got %v", errBoom, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b"}) {
+		t.Errorf("Expected calls [a b], got %v", calls)
+	}
+
+	g.AddNode("a", []string{"c"})
+	err = exec.Run(context.Background(), &g, func(context.Context, string) error {
+		t.Error("Unexpected call with a cycle")
+		return nil
+	}, exec.Options[string]{})
+	if !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected ErrCyclicDependency, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var h topo.Graph[string]
+	h.AddNode("a", nil)
+	err = exec.Run(ctx, &h, func(context.Context, string) error {
+		t.Error("Unexpected call with cancelled context")
+		return nil
+	}, exec.Options[string]{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error %v, got %v", context.Canceled, err)
+	}
+}
+
+// TestRunFairness checks the order values start in when only one call
+// can run at a time.
+func TestRunFairness(t *testing.T) {
+	// a big subtree added before a small one
+	var g topo.Graph[string]
+	g.AddNode("big", []string{"b1", "b2", "b3", "b4", "b5"})
+	g.AddNode("small", []string{"s1", "s2"})
+
+	tag := func(value string) string { return value[:1] }
+	tests := []struct {
+		name     string
+		opts     exec.Options[string]
+		expected []string
+	}{
+		{
+			name:     "subtrees take turns",
+			opts:     exec.Options[string]{Limit: 1},
+			expected: []string{"b1", "s1", "b2", "s2", "b3", "small", "b4", "b5", "big"},
+		},
+		{
+			name: "weighted shares",
+			opts: exec.Options[string]{
+				Limit:  1,
+				Group:  tag,
+				Shares: map[string]int{"s": 2},
+			},
+			expected: []string{"b1", "s1", "s2", "b2", "small", "b3", "b4", "b5", "big"},
+		},
+		{
+			name: "priority",
+			opts: exec.Options[string]{
+				Limit:    1,
+				Priority: func(value string) int { return strings.Count(value, "s") },
+			},
+			expected: []string{"s1", "s2", "small", "b1", "b2", "b3", "b4", "b5", "big"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var started []string
+			err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+				started = append(started, value)
+				return nil
+			}, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(started, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, started)
+			}
+		})
+	}
+}
+
+// TestRunAging checks that a value with a low priority isn't held back
+// forever by values with higher ones.
+func TestRunAging(t *testing.T) {
+	// a chain of urgent values, each ready as the last is done
+	var g topo.Graph[string]
+	g.AddNode("u1", nil)
+	g.AddNode("u2", []string{"u1"})
+	g.AddNode("u3", []string{"u2"})
+	g.AddNode("later", nil)
+	priority := func(value string) int {
+		if value[0] == 'u' {
+			return 3
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name     string
+		aging    time.Duration
+		expected []string
+	}{
+		{"no aging", 0, []string{"u1", "u2", "u3", "later"}},
+		// later has waited 5ms once u1 is done, and has aged past u2
+		{"aging", time.Millisecond, []string{"u1", "later", "u2", "u3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var started []string
+			err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+				started = append(started, value)
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			}, exec.Options[string]{Limit: 1, Priority: priority, Aging: tt.aging})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(started, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, started)
+			}
+		})
+	}
+}
+
+// TestRunQueueLimit checks that no more values wait to run than
+// Options.QueueLimit allows, and that every value still runs.
+func TestRunQueueLimit(t *testing.T) {
+	var g topo.Graph[int]
+	for i := range 100 {
+		g.AddNode(i, nil)
+	}
+	g.AddNode(100, []int{0, 50, 99})
+
+	var mu sync.Mutex
+	calls, depth, deepest := 0, 0, 0
+	var report exec.Report[int]
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value int) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	}, exec.Options[int]{
+		Limit:      4,
+		QueueLimit: 8,
+		Report:     &report,
+		OnState: func(value int, state topo.NodeState) {
+			switch state {
+			case topo.StateReady:
+				depth++
+				deepest = max(deepest, depth)
+			case topo.StateRunning:
+				depth--
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 101 {
+		t.Errorf("Expected 101 calls, got %d", calls)
+	}
+	if deepest > 8 {
+		t.Errorf("Expected at most 8 values waiting, got %d", deepest)
+	}
+	if report.QueuePeak == 0 || report.QueuePeak > 8 {
+		t.Errorf("Expected a queue peak of 1 to 8, got %d", report.QueuePeak)
+	}
+	if report.Peak != 4 {
+		t.Errorf("Expected 4 calls running at once, got %d", report.Peak)
+	}
+	if report.MaxWait <= 0 || report.Wait < report.MaxWait {
+		t.Errorf("Expected waits, got %v in all, %v longest", report.Wait, report.MaxWait)
+	}
+}
+
+// TestRunStates checks the states reported for each value, and that each
+// change is one NodeState allows.
+func TestRunStates(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", []string{"a"})
+	g.AddNode("c", []string{"b"})
+	g.AddNode("x", nil)
+
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name     string
+		fail     string
+		expected map[string]topo.NodeState
+	}{
+		{"succeeded", "", map[string]topo.NodeState{
+			"a": topo.StateSucceeded, "b": topo.StateSucceeded, "c": topo.StateSucceeded, "x": topo.StateSucceeded,
+		}},
+		{"failed", "b", map[string]topo.NodeState{
+			"a": topo.StateSucceeded, "b": topo.StateFailed, "c": topo.StateSkipped, "x": topo.StateSucceeded,
+		}},
+		{"skipped when ready", "a", map[string]topo.NodeState{
+			"a": topo.StateFailed, "b": topo.StateSkipped, "c": topo.StateSkipped, "x": topo.StateSkipped,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := make(map[string]topo.NodeState)
+			onState := func(value string, state topo.NodeState) {
+				if from := states[value]; !from.CanBecome(state) {
+					t.Errorf("Unexpected change of %s from %v to %v", value, from, state)
+				}
+				states[value] = state
+			}
+			err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+				if value == tt.fail {
+					return errBoom
+				}
+				return nil
+			}, exec.Options[string]{Limit: 1, OnState: onState})
+			if tt.fail != "" && !errors.Is(err, errBoom) {
+				t.Errorf("Expected error %v, got %v", errBoom, err)
+			}
+			if !reflect.DeepEqual(states, tt.expected) {
+				t.Errorf("Expected states %v, got %v", tt.expected, states)
+			}
+		})
+	}
+}
+
+// TestRunTimeout checks that once the timeout passes no more calls start,
+// and that the calls running are cancelled after the grace period.
+func TestRunTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		grace   time.Duration
+		// work is how long the call for a takes, if it isn't cancelled
+		work     time.Duration
+		timedOut bool
+		// expired is whether a is cancelled for the timeout
+		expired bool
+		states  map[string]topo.NodeState
+	}{
+		{
+			name:    "in time",
+			timeout: time.Second,
+			work:    time.Millisecond,
+			states:  map[string]topo.NodeState{"a": topo.StateSucceeded, "b": topo.StateSucceeded},
+		},
+		{
+			name:     "drained",
+			timeout:  10 * time.Millisecond,
+			grace:    time.Second,
+			work:     50 * time.Millisecond,
+			timedOut: true,
+			states:   map[string]topo.NodeState{"a": topo.StateSucceeded, "b": topo.StateSkipped},
+		},
+		{
+			name:     "expired",
+			timeout:  10 * time.Millisecond,
+			grace:    10 * time.Millisecond,
+			work:     time.Second,
+			timedOut: true,
+			expired:  true,
+			states:   map[string]topo.NodeState{"a": topo.StateFailed, "b": topo.StateSkipped},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("b", []string{"a"})
+
+			states := make(map[string]topo.NodeState)
+			var cause error
+			err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+				if value != "a" {
+					return nil
+				}
+				select {
+				case <-time.After(tt.work):
+					return nil
+				case <-ctx.Done():
+					cause = context.Cause(ctx)
+					return ctx.Err()
+				}
+			}, exec.Options[string]{
+				Timeout: tt.timeout,
+				Grace:   tt.grace,
+				OnState: func(value string, state topo.NodeState) { states[value] = state },
+			})
+
+			if errors.Is(err, exec.ErrTimeout) != tt.timedOut {
+				t.Errorf("Expected timed out %v, got %v", tt.timedOut, err)
+			}
+			var errs exec.Errors[string]
+			if errors.As(err, &errs) != tt.expired {
+				t.Fatalf("Expected failures %v, got %v", tt.expired, err)
+			}
+			if tt.expired {
+				if len(errs) != 1 || errs[0].Node != "a" || !errs[0].TimedOut {
+					t.Errorf("Expected a to time out, got %v", errs)
+				}
+				if !errors.Is(cause, exec.ErrTimeout) {
+					t.Errorf("Expected cause %v, got %v", exec.ErrTimeout, cause)
+				}
+			}
+			if !reflect.DeepEqual(states, tt.states) {
+				t.Errorf("Expected states %v, got %v", tt.states, states)
+			}
+		})
+	}
+}
+
+// TestRunFilter checks that values left out aren't called, but don't hold
+// back the values depending on them.
+func TestRunFilter(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"docs", "lib"})
+	g.AddNode("docs", nil)
+	g.AddNode("lib", nil)
+
+	var mu sync.Mutex
+	var called []string
+	states := make(map[string]topo.NodeState)
+	tags := map[string][]string{"app": {"deploy"}, "lib": {"build", "deploy"}}
+	err := exec.Run(context.Background(), &g, func(_ context.Context, value string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, value)
+		return nil
+	}, exec.Options[string]{
+		Filter:  exec.Tagged(tags, "deploy"),
+		OnState: func(value string, state topo.NodeState) { states[value] = state },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(called, []string{"lib", "app"}) {
+		t.Errorf("Expected [lib app], got %v", called)
+	}
+	if states["docs"] != topo.StateSkipped {
+		t.Errorf("Expected docs skipped, got %v", states["docs"])
+	}
+}
+
+// BenchmarkRunWide runs a layer of independent values, each its own
+// group, on a few calls at a time, so that the queue holds most of them.
+func BenchmarkRunWide(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		var g topo.Graph[int]
+		for i := range n {
+			g.AddNode(i, nil)
+		}
+		noop := func(context.Context, int) error { return nil }
+		for _, aging := range []time.Duration{0, time.Millisecond} {
+			b.Run(fmt.Sprintf("%d aging %v", n, aging), func(b *testing.B) {
+				opts := exec.Options[int]{Limit: 8, Priority: func(i int) int { return i % 3 }, Aging: aging}
+				for b.Loop() {
+					if err := exec.Run(context.Background(), &g, noop, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Shutdown configures how RunWithSignals shuts a run down.
+type Shutdown[T comparable] struct {
+	// Signals are the signals that stop the run; os.Interrupt and
+	// syscall.SIGTERM by default.
+	Signals []os.Signal
+	// Teardown, if set, is called once a stopped run has drained, to undo
+	// what it had set up.
+	Teardown func(ctx context.Context) error
+	// Checkpoint, if set, is called once the run is over, however it
+	// ended, with the values that succeeded or were cached, in the order
+	// they were, to be saved so that the next run can skip them.
+	Checkpoint func(done []T) error
+}
+
+// RunWithSignals is Run for command-line tools: the first of
+// Shutdown.Signals stops the run as Options.Stop does, letting the calls
+// running drain for Options.Grace, and a second cancels them at once.
+// Once the run is over it calls Shutdown.Teardown, if the run was stopped,
+// and Shutdown.Checkpoint, joining any errors they return to Run's.
+//
+//	err := exec.RunWithSignals(ctx, g, deploy, exec.Options[string]{Grace: time.Minute},
+//		exec.Shutdown[string]{Checkpoint: save})
+func RunWithSignals[T comparable](ctx context.Context, g *topo.Graph[T], fn Func[T], opts Options[T], shutdown Shutdown[T]) error {
+	signals := shutdown.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, signals...)
+	defer signal.Stop(caught)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop, stopped := make(chan struct{}), opts.Stop
+	go func() {
+		select {
+		case <-caught:
+		case <-stopped:
+		case <-runCtx.Done():
+			return
+		}
+		close(stop)
+		// a second signal doesn't wait for the calls to drain
+		select {
+		case <-caught:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+	opts.Stop = stop
+
+	var done []T
+	onState := opts.OnState
+	opts.OnState = func(value T, state topo.NodeState) {
+		if state == topo.StateSucceeded || state == topo.StateCached {
+			done = append(done, value)
+		}
+		if onState != nil {
+			onState(value, state)
+		}
+	}
+
+	err := Run(runCtx, g, fn, opts)
+	errs := []error{err}
+	if shutdown.Teardown != nil && errors.Is(err, ErrStopped) {
+		if err := shutdown.Teardown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("teardown: %w", err))
+		}
+	}
+	if shutdown.Checkpoint != nil {
+		if err := shutdown.Checkpoint(done); err != nil {
+			errs = append(errs, fmt.Errorf("checkpoint: %w", err))
+		}
+	}
+	if len(errs) == 1 {
+		return err
+	}
+	return errors.Join(errs...)
+}
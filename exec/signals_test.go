@@ -0,0 +1,137 @@
+//go:build unix
+
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRunWithSignals checks that a signal drains the calls running, then
+// tears down and checkpoints what succeeded.
+func TestRunWithSignals(t *testing.T) {
+	errTeardown := errors.New("teardown failed")
+	tests := []struct {
+		name     string
+		signals  int
+		teardown error
+		done     []string
+		// cancelled is whether the call for b is cancelled
+		cancelled bool
+	}{
+		{"drained", 1, nil, []string{"a", "b"}, false},
+		{"second signal", 2, nil, []string{"a"}, true},
+		{"teardown error", 1, errTeardown, []string{"a", "b"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("b", []string{"a"})
+			g.AddNode("c", []string{"b"})
+
+			var tornDown bool
+			var checkpoint []string
+			err := exec.RunWithSignals(context.Background(), &g, func(ctx context.Context, value string) error {
+				if value != "b" {
+					return nil
+				}
+				for range tt.signals {
+					if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				select {
+				case <-time.After(20 * time.Millisecond):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}, exec.Options[string]{Grace: time.Second}, exec.Shutdown[string]{
+				Signals: []os.Signal{syscall.SIGUSR1},
+				Teardown: func(context.Context) error {
+					tornDown = true
+					return tt.teardown
+				},
+				Checkpoint: func(done []string) error {
+					checkpoint = done
+					return nil
+				},
+			})
+
+			if !errors.Is(err, exec.ErrStopped) {
+				t.Errorf("Expected error %v, got %v", exec.ErrStopped, err)
+			}
+			if tt.teardown != nil && !errors.Is(err, tt.teardown) {
+				t.Errorf("Expected error %v, got %v", tt.teardown, err)
+			}
+			if errors.Is(err, context.Canceled) != tt.cancelled {
+				t.Errorf("Expected cancelled %v, got %v", tt.cancelled, err)
+			}
+			if !tornDown {
+				t.Error("Expected teardown")
+			}
+			if !reflect.DeepEqual(checkpoint, tt.done) {
+				t.Errorf("Expected checkpoint %v, got %v", tt.done, checkpoint)
+			}
+		})
+	}
+}
+
+// TestRunWithSignalsResume checks that the checkpoint of a failed run
+// has the values that were cached as well as those that succeeded, so
+// that a run resumed from it only calls the rest.
+func TestRunWithSignalsResume(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", []string{"a"})
+	g.AddNode("c", []string{"b"})
+
+	errFailed := errors.New("failed")
+	var checkpoint []string
+	err := exec.RunWithSignals(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "c" {
+			return errFailed
+		}
+		return nil
+	}, exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) {
+			return value + "-v1", nil
+		},
+		Cache: &mapCache{set: map[string]bool{"a-v1": true}},
+	}, exec.Shutdown[string]{
+		Signals: []os.Signal{syscall.SIGUSR1},
+		Checkpoint: func(done []string) error {
+			checkpoint = done
+			return nil
+		},
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected error %v, got %v", errFailed, err)
+	}
+	if expected := []string{"a", "b"}; !reflect.DeepEqual(checkpoint, expected) {
+		t.Fatalf("Expected checkpoint %v, got %v", expected, checkpoint)
+	}
+
+	var called []string
+	err = exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		called = append(called, value)
+		return nil
+	}, exec.Options[string]{Filter: func(value string) bool {
+		return !slices.Contains(checkpoint, value)
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"c"}; !reflect.DeepEqual(called, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, called)
+	}
+}
@@ -0,0 +1,49 @@
+package exec
+
+import (
+	"context"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// nested reports a state change in a nested run to the run above it.
+type nested func(path []any, state topo.NodeState)
+
+// Sub returns a function for a value of type P backed by a graph of its
+// own, which its call runs with Run, fn, and opts, so that large pipelines
+// can be composed of smaller ones, like a release made of each service's
+// build. The value succeeds once the nested run does, and fails with the
+// error the nested run returns, so that the nested Errors are found
+// through the NodeError of the value:
+//
+//	err := exec.RunFuncs(ctx, release, exec.Funcs[string]{
+//		"frontend": exec.Sub[string](frontend, build, exec.Options[string]{}),
+//		"backend":  exec.Sub[string](backend, build, exec.Options[string]{}),
+//	}, exec.Options[string]{OnNested: report})
+//
+// The state changes of the nested run go to the OnNested option of the run
+// above it, as well as to opts.OnState, and runs nested deeper report
+// through each run between. The nested run logs with the logger of the
+// call, its attributes grouped under "sub".
+func Sub[P, T comparable](g *topo.Graph[T], fn Func[T], opts Options[T]) Func[P] {
+	return func(ctx context.Context, _ P) error {
+		opts := opts
+		if report, _ := ctx.Value(nestedKey).(nested); report != nil {
+			onState, onNested := opts.OnState, opts.OnNested
+			opts.OnState = func(value T, state topo.NodeState) {
+				if onState != nil {
+					onState(value, state)
+				}
+				report([]any{value}, state)
+			}
+			opts.OnNested = func(value T, path []any, state topo.NodeState) {
+				if onNested != nil {
+					onNested(value, path, state)
+				}
+				report(append([]any{value}, path...), state)
+			}
+		}
+		ctx = WithLogger(ctx, Logger(ctx).WithGroup("sub"))
+		return Run(ctx, g, fn, opts)
+	}
+}
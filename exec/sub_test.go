@@ -0,0 +1,106 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestSub checks that a value backed by a nested run succeeds or fails with
+// it, and that the nested run's state changes reach the run above it.
+func TestSub(t *testing.T) {
+	var web topo.Graph[string]
+	web.AddNode("bundle", []string{"npm"})
+	var cluster topo.Graph[string]
+	cluster.AddNode("chart", nil)
+
+	tests := []struct {
+		name   string
+		fail   string
+		events []string
+	}{
+		{"succeeds", "", []string{
+			"web/npm ready", "web/npm running", "web/npm succeeded",
+			"web/bundle ready", "web/bundle running", "web/bundle succeeded",
+			"deploy/chart ready", "deploy/chart running", "deploy/chart succeeded",
+		}},
+		{"fails", "bundle", []string{
+			"web/npm ready", "web/npm running", "web/npm succeeded",
+			"web/bundle ready", "web/bundle running", "web/bundle failed",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := func(_ context.Context, step string) error {
+				if step == tt.fail {
+					return errors.New("build failed")
+				}
+				return nil
+			}
+			var release topo.Graph[string]
+			release.AddNode("deploy", []string{"web"})
+			release.AddNode("web", nil)
+
+			var events []string
+			err := exec.RunFuncs(context.Background(), &release, exec.Funcs[string]{
+				"web":    exec.Sub[string](&web, build, exec.Options[string]{}),
+				"deploy": exec.Sub[string](&cluster, build, exec.Options[string]{}),
+			}, exec.Options[string]{
+				OnNested: func(value string, path []any, state topo.NodeState) {
+					events = append(events, fmt.Sprintf("%s/%v %v", value, path[0], state))
+				},
+			})
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Errorf("Expected events %v, got %v", tt.events, events)
+			}
+			if tt.fail == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs exec.Errors[string]
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Node != "web" {
+				t.Fatalf("Expected web to fail, got %v", err)
+			}
+			var nested exec.Errors[string]
+			if !errors.As(errs[0].Err, &nested) || len(nested) != 1 || nested[0].Node != tt.fail {
+				t.Errorf("Expected %s to fail in the nested run, got %v", tt.fail, errs[0].Err)
+			}
+		})
+	}
+}
+
+// TestSubDeep checks that runs nested in nested runs report their path
+// through each run between.
+func TestSubDeep(t *testing.T) {
+	var inner topo.Graph[string]
+	inner.AddNode("compile", nil)
+	var middle topo.Graph[string]
+	middle.AddNode("lib", nil)
+	var outer topo.Graph[string]
+	outer.AddNode("app", nil)
+
+	noop := func(context.Context, string) error { return nil }
+	var paths [][]any
+	err := exec.Run(context.Background(), &outer, exec.Sub[string](&middle, exec.Sub[string](&inner, noop, exec.Options[string]{}), exec.Options[string]{}), exec.Options[string]{
+		OnNested: func(value string, path []any, state topo.NodeState) {
+			if state == topo.StateSucceeded {
+				paths = append(paths, append([]any{value}, path...))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]any{{"app", "lib", "compile"}, {"app", "lib"}}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
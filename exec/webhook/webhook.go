@@ -0,0 +1,287 @@
+// Package webhook posts the state changes of a run to HTTP endpoints as
+// JSON, so that chat bots, audit logs, and dashboards can follow a run
+// without polling it.
+//
+// A Notifier's OnState method is used as the OnState option of exec.Run.
+// Events are delivered in order from a goroutine of the Notifier's own, so
+// slow endpoints don't hold up the run:
+//
+//	n := webhook.New[string](webhook.Config{
+//		URLs:   []string{"https://hooks.example.com/deploys"},
+//		Secret: []byte(os.Getenv("WEBHOOK_SECRET")),
+//	})
+//	err := exec.Run(ctx, g, deploy, exec.Options[string]{OnState: n.OnState, OnCached: n.OnCached})
+//	n.Close(ctx)
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// SignatureHeader is the header holding the signature of an event's body,
+// when Config.Secret is set: "sha256=" and the hex HMAC-SHA256 of the
+// body, keyed with the secret.
+const SignatureHeader = "X-Topo-Signature"
+
+// Config configures a Notifier.
+type Config struct {
+	// URLs are the endpoints each event is posted to.
+	URLs []string
+	// Secret, if set, signs each event; see SignatureHeader.
+	Secret []byte
+	// Run, if set, is included in each event, to tell runs apart.
+	Run string
+	// Client posts the events; http.DefaultClient if nil.
+	Client *http.Client
+	// Retries is how many more times an event is posted to an endpoint
+	// after a failure: an error, a 5xx status, or a 429. Other statuses
+	// aren't retried. The default is 3; a negative number means none.
+	Retries int
+	// Backoff is how long to wait before the first retry, doubling for
+	// each after. The default is one second.
+	Backoff time.Duration
+	// OnError, if set, is called with the error when an event can't be
+	// delivered to an endpoint, after any retries.
+	OnError func(error)
+}
+
+// Event is the JSON body posted for a state change.
+type Event[T any] struct {
+	// Seq numbers the events of a Notifier from 1, in the order they
+	// happened, so that receivers can spot duplicates and gaps.
+	Seq   uint64         `json:"seq"`
+	Run   string         `json:"run,omitempty"`
+	Node  T              `json:"node"`
+	State topo.NodeState `json:"state"`
+	// Path is the value in a nested run that changed state, after those
+	// of any runs between, for events reported through OnNested; Node is
+	// then the value the nested run is for, and State the nested value's.
+	Path []any `json:"path,omitempty"`
+	// Fingerprint is the fingerprint found in the cache, for a value
+	// reported cached through OnCached.
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Notifier posts events for state changes. Create one with New, and Close
+// it once the run is over.
+type Notifier[T any] struct {
+	config Config
+
+	mu      sync.Mutex
+	seq     uint64
+	pending []Event[T]
+	// fingerprints is set once OnCached is used, which then queues the
+	// events for cached values in place of OnState
+	fingerprints bool
+	closed       bool
+	wake         chan struct{}
+	done         chan struct{}
+	// stop cancels deliveries when Close gives up waiting for them
+	stop context.CancelFunc
+	ctx  context.Context
+}
+
+// New returns a Notifier, and starts the goroutine that delivers its
+// events.
+func New[T any](config Config) *Notifier[T] {
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Retries == 0 {
+		config.Retries = 3
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	n := &Notifier[T]{
+		config: config,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		stop:   stop,
+	}
+	go n.deliver()
+	return n
+}
+
+// OnState queues an event for a value's change of state. It doesn't wait
+// for the event to be delivered. Events after Close are dropped.
+func (n *Notifier[T]) OnState(value T, state topo.NodeState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if state == topo.StateCached && n.fingerprints {
+		return
+	}
+	n.queue(Event[T]{Node: value, State: state})
+}
+
+// OnCached queues the event for a value found cached, with the fingerprint
+// it was found with. It's used as the OnCached option of exec.Run, which
+// calls it just before OnState reports the value cached; once it has been
+// called, OnState leaves the events for cached values to it.
+func (n *Notifier[T]) OnCached(value T, fingerprint string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fingerprints = true
+	n.queue(Event[T]{Node: value, State: topo.StateCached, Fingerprint: fingerprint})
+}
+
+// OnNested queues an event for the change of state of a value in a run
+// nested in the call for value. It's used as the OnNested option of
+// exec.Run.
+func (n *Notifier[T]) OnNested(value T, path []any, state topo.NodeState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.queue(Event[T]{Node: value, Path: path, State: state})
+}
+
+// queue numbers an event and queues it for delivery. The caller holds mu.
+func (n *Notifier[T]) queue(event Event[T]) {
+	if n.closed {
+		return
+	}
+	n.seq++
+	event.Seq = n.seq
+	event.Run = n.config.Run
+	event.Time = time.Now().UTC()
+	n.pending = append(n.pending, event)
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close waits for the events queued so far to be delivered, or for ctx to
+// be done, in which case the rest are dropped and its error returned.
+func (n *Notifier[T]) Close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.wake)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		n.stop()
+		<-n.done
+		return ctx.Err()
+	}
+}
+
+// deliver posts events as they're queued, until the Notifier is closed
+// and every event is delivered.
+func (n *Notifier[T]) deliver() {
+	defer close(n.done)
+	defer n.stop()
+	for {
+		n.mu.Lock()
+		events := n.pending
+		n.pending = nil
+		n.mu.Unlock()
+		for _, event := range events {
+			if n.ctx.Err() != nil {
+				return
+			}
+			n.post(event)
+		}
+		if _, ok := <-n.wake; !ok {
+			n.mu.Lock()
+			empty := len(n.pending) == 0
+			n.mu.Unlock()
+			if empty {
+				return
+			}
+		}
+	}
+}
+
+// post delivers an event to every endpoint.
+func (n *Notifier[T]) post(event Event[T]) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.fail(fmt.Errorf("encoding event %d: %w", event.Seq, err))
+		return
+	}
+	for _, url := range n.config.URLs {
+		if err := n.postTo(url, body); err != nil {
+			n.fail(fmt.Errorf("posting event %d to %s: %w", event.Seq, url, err))
+		}
+	}
+}
+
+// postTo posts an event's body to an endpoint, retrying failures.
+func (n *Notifier[T]) postTo(url string, body []byte) error {
+	backoff := n.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.send(url, body)
+		if err == nil || !retry || attempt >= n.config.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+			return errors.Join(err, n.ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// send posts an event's body once, reporting whether a failure is worth
+// retrying.
+func (n *Notifier[T]) send(url string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.config.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.config.Secret, body))
+	}
+	resp, err := n.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+func (n *Notifier[T]) fail(err error) {
+	if n.config.OnError != nil {
+		n.config.OnError(err)
+	}
+}
+
+// Sign returns the signature of a body, as sent in SignatureHeader, for
+// receivers to check with Verify.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a signature from SignatureHeader matches a body.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, body)))
+}
@@ -0,0 +1,245 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+	"github.com/sam-fredrickson/go-topo/exec/webhook"
+)
+
+// receiver records the events posted to it, failing the first fail
+// requests with status, and checking signatures if it has a secret.
+type receiver struct {
+	t      *testing.T
+	secret []byte
+	status int
+	fail   int
+
+	mu       sync.Mutex
+	requests int
+	events   []webhook.Event[string]
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("Unexpected error: %v", err)
+	}
+	signature := r.Header.Get(webhook.SignatureHeader)
+	if rc.secret != nil && !webhook.Verify(rc.secret, body, signature) {
+		rc.t.Errorf("Expected a valid signature for %s", body)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests++
+	if rc.requests <= rc.fail {
+		w.WriteHeader(rc.status)
+		return
+	}
+	var event webhook.Event[string]
+	if err := json.Unmarshal(body, &event); err != nil {
+		rc.t.Errorf("Unexpected error: %v", err)
+	}
+	rc.events = append(rc.events, event)
+}
+
+// TestNotifier checks that the state changes of a run are posted in order,
+// signed.
+func TestNotifier(t *testing.T) {
+	rc := &receiver{t: t, secret: []byte("secret")}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	n := webhook.New[string](webhook.Config{
+		URLs:   []string{server.URL},
+		Secret: rc.secret,
+		Run:    "deploy-1",
+	})
+	err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{OnState: n.OnState})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type change struct {
+		Node  string
+		State topo.NodeState
+	}
+	var changes []change
+	for i, event := range rc.events {
+		if event.Seq != uint64(i+1) || event.Run != "deploy-1" || event.Time.IsZero() {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		changes = append(changes, change{event.Node, event.State})
+	}
+	expected := []change{
+		{"lib", topo.StateReady}, {"lib", topo.StateRunning}, {"lib", topo.StateSucceeded},
+		{"app", topo.StateReady}, {"app", topo.StateRunning}, {"app", topo.StateSucceeded},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+// TestNotifierCached checks that a value found cached is posted once, with
+// its fingerprint.
+func TestNotifierCached(t *testing.T) {
+	rc := &receiver{t: t}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	n := webhook.New[string](webhook.Config{URLs: []string{server.URL}})
+	err := exec.Run(context.Background(), &g, func(context.Context, string) error {
+		return nil
+	}, exec.Options[string]{
+		Fingerprint: func(_ context.Context, value string) (string, error) { return value + "-v1", nil },
+		Cache:       hitCache{"lib-v1": true},
+		OnState:     n.OnState,
+		OnCached:    n.OnCached,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var cached []webhook.Event[string]
+	for _, event := range rc.events {
+		if event.State == topo.StateCached {
+			cached = append(cached, event)
+		}
+	}
+	if len(cached) != 1 || cached[0].Node != "lib" || cached[0].Fingerprint != "lib-v1" {
+		t.Errorf("Expected one cached event for lib with fingerprint lib-v1, got %+v", cached)
+	}
+	if len(rc.events) != 5 {
+		t.Errorf("Expected 5 events, got %+v", rc.events)
+	}
+}
+
+// TestNotifierNested checks that the state changes of a nested run are
+// posted with their path.
+func TestNotifierNested(t *testing.T) {
+	rc := &receiver{t: t}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	var web topo.Graph[string]
+	web.AddNode("bundle", nil)
+	var g topo.Graph[string]
+	g.AddNode("web", nil)
+	n := webhook.New[string](webhook.Config{URLs: []string{server.URL}})
+	build := func(context.Context, string) error { return nil }
+	err := exec.Run(context.Background(), &g, exec.Sub[string](&web, build, exec.Options[string]{}), exec.Options[string]{
+		OnState:  n.OnState,
+		OnNested: n.OnNested,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var nested []string
+	for _, event := range rc.events {
+		if len(event.Path) > 0 {
+			nested = append(nested, fmt.Sprintf("%s/%v %v", event.Node, event.Path[0], event.State))
+		}
+	}
+	expected := []string{"web/bundle ready", "web/bundle running", "web/bundle succeeded"}
+	if !reflect.DeepEqual(nested, expected) {
+		t.Errorf("Expected %v, got %v", expected, nested)
+	}
+	if len(rc.events) != 6 {
+		t.Errorf("Expected 6 events, got %+v", rc.events)
+	}
+}
+
+// hitCache is a Cache holding a fixed set of fingerprints.
+type hitCache map[string]bool
+
+func (c hitCache) Has(_ context.Context, fingerprint string) (bool, error) {
+	return c[fingerprint], nil
+}
+
+func (c hitCache) Add(context.Context, string) error { return nil }
+
+// TestNotifierRetries checks which failures are retried.
+func TestNotifierRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		fail     int
+		requests int
+		errors   int
+	}{
+		{"server error", http.StatusInternalServerError, 2, 3, 0},
+		{"too many requests", http.StatusTooManyRequests, 1, 2, 0},
+		{"out of retries", http.StatusBadGateway, 10, 3, 1},
+		{"client error", http.StatusBadRequest, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{t: t, status: tt.status, fail: tt.fail}
+			server := httptest.NewServer(rc)
+			defer server.Close()
+
+			var errs []error
+			n := webhook.New[string](webhook.Config{
+				URLs:    []string{server.URL},
+				Retries: 2,
+				Backoff: time.Millisecond,
+				OnError: func(err error) { errs = append(errs, err) },
+			})
+			n.OnState("app", topo.StateRunning)
+			if err := n.Close(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if rc.requests != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, rc.requests)
+			}
+			if len(errs) != tt.errors {
+				t.Errorf("Expected %d errors, got %v", tt.errors, errs)
+			}
+		})
+	}
+}
+
+// TestNotifierClose checks that Close gives up when its context is done.
+func TestNotifierClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	n := webhook.New[string](webhook.Config{URLs: []string{server.URL}, Backoff: time.Hour})
+	n.OnState("app", topo.StateRunning)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := n.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	// events after Close are dropped
+	n.OnState("app", topo.StateSucceeded)
+}
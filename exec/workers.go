@@ -0,0 +1,147 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrUnschedulable is wrapped by every UnschedulableError.
+var ErrUnschedulable = errors.New("no worker can run it")
+
+// Worker is one of the workers of a run, for Options.Workers.
+type Worker struct {
+	// Name names the worker in logs; its index in Options.Workers if
+	// empty.
+	Name string
+	// Classes are the classes of values the worker can run, like "gpu"
+	// or "arm64", as returned by Options.Class. Every worker can run
+	// values without a class.
+	Classes []string
+}
+
+// UnschedulableError is returned by Run for a value whose class none of
+// Options.Workers has, before anything runs.
+type UnschedulableError[T any] struct {
+	// Node is the value no worker can run.
+	Node T
+	// Class is its class.
+	Class string
+}
+
+func (e *UnschedulableError[T]) Error() string {
+	return fmt.Sprintf("%v: no worker of class %s", e.Node, e.Class)
+}
+
+// Unwrap returns ErrUnschedulable.
+func (e *UnschedulableError[T]) Unwrap() error {
+	return ErrUnschedulable
+}
+
+// WorkerOf returns the index in Options.Workers of the worker a call is
+// running on, from the context passed to it, and whether it's known, which
+// it is only in runs with Options.Workers. Calls with the same worker
+// never overlap, so calls can use a resource per worker without locking
+// it, as with RunPool.
+func WorkerOf(ctx context.Context) (int, bool) {
+	worker, ok := ctx.Value(workerKey).(int)
+	return worker, ok
+}
+
+// schedulable returns an UnschedulableError for each of values no worker
+// can run, joined with errors.Join, or nil if workers can run them all.
+func (o Options[T]) schedulable(values []T) error {
+	if len(o.Workers) == 0 || o.Class == nil {
+		return nil
+	}
+	var errs []error
+	for _, value := range values {
+		class := o.Class(value)
+		if !slices.ContainsFunc(o.Workers, func(w Worker) bool { return w.can(class) }) {
+			errs = append(errs, &UnschedulableError[T]{Node: value, Class: class})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// can reports whether the worker can run values of a class.
+func (w Worker) can(class string) bool {
+	return class == "" || slices.Contains(w.Classes, class)
+}
+
+// pool tracks which of a run's workers are free. A nil pool, for runs
+// without Options.Workers, has a free worker for every value.
+type pool[T comparable] struct {
+	opts Options[T]
+	busy []bool
+}
+
+func newPool[T comparable](opts Options[T]) *pool[T] {
+	if len(opts.Workers) == 0 {
+		return nil
+	}
+	return &pool[T]{opts: opts, busy: make([]bool, len(opts.Workers))}
+}
+
+// class returns the class of a value.
+func (p *pool[T]) class(value T) string {
+	if p.opts.Class == nil {
+		return ""
+	}
+	return p.opts.Class(value)
+}
+
+// idle reports whether any worker is free.
+func (p *pool[T]) idle() bool {
+	return p == nil || slices.Contains(p.busy, false)
+}
+
+// fits reports whether a free worker can run a value.
+func (p *pool[T]) fits(value T) bool {
+	return p.take(value, false) >= 0 || p == nil
+}
+
+// take returns a free worker that can run a value, and marks it busy if
+// claim is set, or returns -1 if there's none, or no pool. Of the workers
+// that can, it picks the one with fewest classes, keeping the others free
+// for the values only they can run.
+func (p *pool[T]) take(value T, claim bool) int {
+	if p == nil {
+		return -1
+	}
+	class := p.class(value)
+	best := -1
+	for i, w := range p.opts.Workers {
+		if p.busy[i] || !w.can(class) {
+			continue
+		}
+		if best < 0 || len(w.Classes) < len(p.opts.Workers[best].Classes) {
+			best = i
+		}
+	}
+	if best >= 0 && claim {
+		p.busy[best] = true
+	}
+	return best
+}
+
+// release frees a worker taken with take.
+func (p *pool[T]) release(worker int) {
+	if p != nil && worker >= 0 {
+		p.busy[worker] = false
+	}
+}
+
+// withWorker returns the context for a call on a worker, if it's on one.
+func (p *pool[T]) withWorker(ctx context.Context, worker int) context.Context {
+	if worker < 0 {
+		return ctx
+	}
+	var name any = worker
+	if n := p.opts.Workers[worker].Name; n != "" {
+		name = n
+	}
+	ctx = context.WithValue(ctx, workerKey, worker)
+	return WithLogger(ctx, Logger(ctx).With("worker", name))
+}
@@ -0,0 +1,154 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/exec"
+)
+
+// TestRunWorkers checks that values only run on workers of their class,
+// one call per worker at a time.
+func TestRunWorkers(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("train-a", []string{"prep"})
+	g.AddNode("train-b", []string{"prep"})
+	g.AddNode("lint", nil)
+	g.AddNode("test", nil)
+	g.AddNode("package", []string{"train-a", "train-b", "lint", "test"})
+
+	classes := map[string]string{"train-a": "gpu", "train-b": "gpu"}
+	workers := []exec.Worker{
+		{Name: "cpu"},
+		{Name: "gpu", Classes: []string{"gpu"}},
+	}
+	var mu sync.Mutex
+	ran := make(map[string]int)
+	busy := make(map[int]bool)
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		worker, ok := exec.WorkerOf(ctx)
+		if !ok {
+			t.Errorf("Expected a worker for %v", value)
+			return nil
+		}
+		mu.Lock()
+		if busy[worker] {
+			t.Errorf("Expected worker %d to run one call at a time", worker)
+		}
+		busy[worker] = true
+		ran[value] = worker
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		busy[worker] = false
+		mu.Unlock()
+		return nil
+	}, exec.Options[string]{
+		Workers: workers,
+		Class:   func(value string) string { return classes[value] },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ran) != len(g.Nodes()) {
+		t.Errorf("Expected %d calls, got %d", len(g.Nodes()), len(ran))
+	}
+	for _, value := range []string{"train-a", "train-b"} {
+		if ran[value] != 1 {
+			t.Errorf("Expected %v on worker 1, got %d", value, ran[value])
+		}
+	}
+}
+
+// TestRunWorkersPreferFewestClasses checks that a value any worker can run
+// is given to the least capable free worker, leaving the others free.
+func TestRunWorkersPreferFewestClasses(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("lint", nil)
+
+	var got int
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		got, _ = exec.WorkerOf(ctx)
+		return nil
+	}, exec.Options[string]{
+		Workers: []exec.Worker{
+			{Name: "big", Classes: []string{"gpu", "arm64"}},
+			{Name: "small"},
+		},
+		Class: func(string) string { return "" },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Expected worker 1, got %d", got)
+	}
+}
+
+// TestRunUnschedulable checks that values no worker can run are reported
+// before anything runs.
+func TestRunUnschedulable(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("train", []string{"prep"})
+	g.AddNode("build-arm", nil)
+
+	classes := map[string]string{"train": "gpu", "build-arm": "arm64"}
+	opts := exec.Options[string]{
+		Workers: []exec.Worker{{Name: "cpu"}, {Name: "gpu", Classes: []string{"gpu"}}},
+		Class:   func(value string) string { return classes[value] },
+	}
+	called := false
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		called = true
+		return nil
+	}, opts)
+	if !errors.Is(err, exec.ErrUnschedulable) {
+		t.Fatalf("Expected ErrUnschedulable, got %v", err)
+	}
+	if called {
+		t.Error("Expected nothing to run")
+	}
+	var unschedulable *exec.UnschedulableError[string]
+	if !errors.As(err, &unschedulable) {
+		t.Fatalf("Expected an UnschedulableError, got %v", err)
+	}
+	if unschedulable.Node != "build-arm" || unschedulable.Class != "arm64" {
+		t.Errorf("Expected build-arm of class arm64, got %v of class %v", unschedulable.Node, unschedulable.Class)
+	}
+	if expected := "build-arm: no worker of class arm64"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+
+	if _, err := exec.DryRun(context.Background(), &g, opts); !errors.Is(err, exec.ErrUnschedulable) {
+		t.Errorf("Expected ErrUnschedulable from DryRun, got %v", err)
+	}
+}
+
+// TestAddNodeUnschedulable checks that adding a value no worker can run
+// fails the call that added it.
+func TestAddNodeUnschedulable(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("scan", nil)
+
+	err := exec.Run(context.Background(), &g, func(ctx context.Context, value string) error {
+		if value == "scan" {
+			return exec.AddNode(ctx, "train", nil)
+		}
+		return nil
+	}, exec.Options[string]{
+		Workers: []exec.Worker{{Name: "cpu"}},
+		Class: func(value string) string {
+			if value == "train" {
+				return "gpu"
+			}
+			return ""
+		},
+	})
+	if !errors.Is(err, exec.ErrUnschedulable) {
+		t.Errorf("Expected ErrUnschedulable, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package topo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String returns the graph as text, for logs and test failures: a line for
+// each value, in the order they were added, with its dependencies, if any,
+// after an arrow:
+//
+//	lib
+//	app -> lib, db
+func (g *Graph[T]) String() string {
+	order, deps := g.edges()
+	var b strings.Builder
+	for i, value := range order {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprint(&b, value)
+		for j, dep := range deps[value] {
+			if j == 0 {
+				b.WriteString(" -> ")
+			} else {
+				b.WriteString(", ")
+			}
+			fmt.Fprint(&b, dep)
+		}
+	}
+	return b.String()
+}
+
+// Layers is the result of a layered sort, as returned by SortByLayers,
+// made readable when printed:
+//
+//	fmt.Printf("%v", topo.Layers[string](layers))  // [db lib] [app]
+//	fmt.Printf("%+v", topo.Layers[string](layers)) // one line for each layer:
+//	                                               // 1: db lib
+//	                                               // 2: app
+//
+// Other verbs, like %q, are applied to each value.
+type Layers[T any] [][]T
+
+// String returns the layers as %v prints them.
+func (l Layers[T]) String() string {
+	return fmt.Sprintf("%v", l)
+}
+
+// Format implements fmt.Formatter.
+func (l Layers[T]) Format(f fmt.State, verb rune) {
+	lines := f.Flag('+') && verb == 'v'
+	// the format for each value, with any flags but the one for lines
+	format := fmt.FormatString(f, verb)
+	if lines {
+		format = strings.Replace(format, "+", "", 1)
+	}
+	for i, layer := range l {
+		switch {
+		case lines && i > 0:
+			fmt.Fprintf(f, "\n%d: ", i+1)
+		case lines:
+			fmt.Fprintf(f, "%d: ", i+1)
+		case i > 0:
+			fmt.Fprint(f, " [")
+		default:
+			fmt.Fprint(f, "[")
+		}
+		for j, value := range layer {
+			if j > 0 {
+				fmt.Fprint(f, " ")
+			}
+			fmt.Fprintf(f, format, value)
+		}
+		if !lines {
+			fmt.Fprint(f, "]")
+		}
+	}
+}
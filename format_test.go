@@ -0,0 +1,68 @@
+package topo_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestGraphString checks the text of a graph.
+func TestGraphString(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(g *topo.Graph[string])
+		expected string
+	}{
+		{"empty", func(*topo.Graph[string]) {}, ""},
+		{
+			name: "dependencies",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("lib", nil)
+				g.AddNode("app", []string{"lib", "db"})
+			},
+			expected: "lib\napp -> lib, db\ndb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			tt.build(&g)
+			if s := fmt.Sprint(&g); s != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, s)
+			}
+		})
+	}
+}
+
+// TestLayersFormat checks how layers are printed with each verb.
+func TestLayersFormat(t *testing.T) {
+	layers := topo.Layers[string]{{"db", "lib"}, {"app"}}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"%v", "[db lib] [app]"},
+		{"%s", "[db lib] [app]"},
+		{"%+v", "1: db lib\n2: app"},
+		{"%q", `["db" "lib"] ["app"]`},
+		{"%5s", "[   db   lib] [  app]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if s := fmt.Sprintf(tt.format, layers); s != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, s)
+			}
+		})
+	}
+
+	if s := layers.String(); s != "[db lib] [app]" {
+		t.Errorf("Expected %q, got %q", "[db lib] [app]", s)
+	}
+	if s := fmt.Sprintf("%d", topo.Layers[int]{{1, 2}, {3}}); s != "[1 2] [3]" {
+		t.Errorf("Expected %q, got %q", "[1 2] [3]", s)
+	}
+	if s := fmt.Sprint(topo.Layers[string]{}); s != "" {
+		t.Errorf("Expected %q, got %q", "", s)
+	}
+}
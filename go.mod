@@ -1,3 +1,13 @@
 module github.com/sam-fredrickson/go-topo
 
 go 1.24.2
+
+require (
+	github.com/dominikbraun/graph v0.23.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/sync v0.18.0
+	gonum.org/v1/gonum v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.29.0 // indirect
@@ -0,0 +1,129 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Canonical reads and writes graphs in the text written by
+// topo.Graph.EncodeCanonical, a line for each value and each dependency,
+// sorted, for graph files kept in version control.
+var Canonical Format = canonicalFormat{}
+
+type canonicalFormat struct{}
+
+// Extensions implements Format.
+func (canonicalFormat) Extensions() []string {
+	return []string{".topo"}
+}
+
+// Read implements Reader.
+func (canonicalFormat) Read(r io.Reader) (*topo.Graph[string], error) {
+	type key struct {
+		value string
+		kind  topo.EdgeKind
+	}
+	var order []string
+	var keys []key
+	seen := make(map[string]bool)
+	deps := make(map[key][]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		value, dep, kind, err := parseCanonical(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing canonical graph: line %d: %w", line, err)
+		}
+		if !seen[value] {
+			seen[value] = true
+			order = append(order, value)
+		}
+		if dep == nil {
+			continue
+		}
+		k := key{value, topo.EdgeKind(kind)}
+		if _, ok := deps[k]; !ok {
+			keys = append(keys, k)
+		}
+		deps[k] = append(deps[k], *dep)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parsing canonical graph: %w", err)
+	}
+
+	var g topo.Graph[string]
+	for _, value := range order {
+		g.AddNode(value, nil)
+	}
+	for _, k := range keys {
+		g.AddNodeOfKind(k.value, k.kind, deps[k])
+	}
+	return &g, nil
+}
+
+// Write implements Writer.
+func (canonicalFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	return g.EncodeCanonical(w)
+}
+
+// parseCanonical parses a line of canonical text: a value on its own, or
+// followed by a dependency and, optionally, its kind.
+func parseCanonical(line string) (value string, dep *string, kind string, err error) {
+	value, rest, err := canonicalToken(line)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if rest == "" {
+		return value, nil, "", nil
+	}
+	rest, ok := strings.CutPrefix(rest, " -> ")
+	if !ok {
+		return "", nil, "", fmt.Errorf("expected \" -> \" after %q", value)
+	}
+	d, rest, err := canonicalToken(rest)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if rest != "" {
+		inner, ok := strings.CutPrefix(rest, " (")
+		if ok {
+			inner, ok = strings.CutSuffix(inner, ")")
+		}
+		if !ok {
+			return "", nil, "", fmt.Errorf("unexpected %q", rest)
+		}
+		if kind, rest, err = canonicalToken(inner); err != nil || rest != "" {
+			return "", nil, "", fmt.Errorf("invalid kind %q", inner)
+		}
+	}
+	return value, &d, kind, nil
+}
+
+// canonicalToken parses a value, quoted or not, from the start of s.
+func canonicalToken(s string) (token, rest string, err error) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted value %s", s)
+		}
+		token, err = strconv.Unquote(quoted)
+		return token, s[len(quoted):], err
+	}
+	end := strings.IndexAny(s, " ()")
+	if end < 0 {
+		end = len(s)
+	}
+	if end == 0 {
+		return "", "", fmt.Errorf("expected a value at %q", s)
+	}
+	return s[:end], s[end:], nil
+}
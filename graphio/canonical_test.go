@@ -0,0 +1,55 @@
+package graphio_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestCanonical checks reading canonical text back into the same graph.
+func TestCanonical(t *testing.T) {
+	input := "\"my lib\"\n\"my lib\" -> base (\"run time\")\napp\napp -> db (runtime)\napp -> lib\nbase\ndb\nlib\n"
+	g, err := graphio.Canonical.(graphio.Reader).Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected [lib db], got %v", deps)
+	}
+	if kinds := g.Kinds(); len(kinds) != 3 {
+		t.Errorf("Expected 3 kinds, got %q", kinds)
+	}
+
+	var out bytes.Buffer
+	if err := graphio.Canonical.(graphio.Writer).Write(&out, g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != input {
+		t.Errorf("Expected %q, got %q", input, out.String())
+	}
+}
+
+// TestCanonicalErrors checks the lines that can't be read.
+func TestCanonicalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no arrow", "app lib\n"},
+		{"no dependency", "app -> \n"},
+		{"unterminated quote", "\"app\n"},
+		{"unclosed kind", "app -> lib (runtime\n"},
+		{"trailing text", "app -> lib (runtime) x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := graphio.Canonical.(graphio.Reader).Read(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("Expected an error for line 1, got %v", err)
+			}
+		})
+	}
+}
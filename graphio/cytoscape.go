@@ -0,0 +1,98 @@
+package graphio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Cytoscape reads and writes graphs as Cytoscape.js elements JSON, which
+// can be passed as is to cytoscape({elements}):
+//
+//	{
+//	  "nodes": [{"data": {"id": "app", "layer": 1}}, {"data": {"id": "lib", "layer": 0}}],
+//	  "edges": [{"data": {"id": "app->lib", "source": "app", "target": "lib"}}]
+//	}
+//
+// Each edge goes from a node to one of its dependencies. The layer of each
+// node is the one it would be in after sorting, counting from 0, so
+// frontends can use it for a layered layout; it's ignored when reading.
+// Since Cytoscape desktop's .cyjs files hold more than the elements, the
+// format has no file extension.
+var Cytoscape cytoscapeFormat
+
+type cytoscapeFormat struct{}
+
+type cytoscapeElements struct {
+	Nodes []cytoscapeNode `json:"nodes"`
+	Edges []cytoscapeEdge `json:"edges"`
+}
+
+type cytoscapeNode struct {
+	Data struct {
+		ID    string `json:"id"`
+		Layer int    `json:"layer"`
+	} `json:"data"`
+}
+
+type cytoscapeEdge struct {
+	Data struct {
+		ID     string `json:"id"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+	} `json:"data"`
+}
+
+// Extensions implements Format.
+func (cytoscapeFormat) Extensions() []string {
+	return nil
+}
+
+// Read implements Reader.
+func (cytoscapeFormat) Read(r io.Reader) (*topo.Graph[string], error) {
+	var elements cytoscapeElements
+	if err := json.NewDecoder(r).Decode(&elements); err != nil {
+		return nil, fmt.Errorf("parsing Cytoscape JSON: %w", err)
+	}
+
+	deps := make(map[string][]string)
+	for _, edge := range elements.Edges {
+		deps[edge.Data.Source] = append(deps[edge.Data.Source], edge.Data.Target)
+	}
+	var g topo.Graph[string]
+	for _, node := range elements.Nodes {
+		if node.Data.ID == "" {
+			return nil, ErrMissingID
+		}
+		g.AddNode(node.Data.ID, deps[node.Data.ID])
+	}
+	return &g, nil
+}
+
+// Write implements Writer.
+func (cytoscapeFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	_, layerOf := layered(g)
+	elements := cytoscapeElements{
+		Nodes: []cytoscapeNode{},
+		Edges: []cytoscapeEdge{},
+	}
+	for _, value := range g.Nodes() {
+		var node cytoscapeNode
+		node.Data.ID = value
+		node.Data.Layer = layerOf[value]
+		elements.Nodes = append(elements.Nodes, node)
+		for _, dep := range g.Dependencies(value) {
+			var edge cytoscapeEdge
+			edge.Data.ID = value + "->" + dep
+			edge.Data.Source = value
+			edge.Data.Target = dep
+			elements.Edges = append(elements.Edges, edge)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(elements)
+}
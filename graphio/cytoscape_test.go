@@ -0,0 +1,59 @@
+package graphio_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestCytoscape checks the elements written for a graph.
+func TestCytoscape(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	var buf strings.Builder
+	if err := graphio.Cytoscape.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "nodes": [
+    {
+      "data": {
+        "id": "app",
+        "layer": 1
+      }
+    },
+    {
+      "data": {
+        "id": "lib",
+        "layer": 0
+      }
+    }
+  ],
+  "edges": [
+    {
+      "data": {
+        "id": "app->lib",
+        "source": "app",
+        "target": "lib"
+      }
+    }
+  ]
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestCytoscapeMissingID checks that nodes must have IDs.
+func TestCytoscapeMissingID(t *testing.T) {
+	_, err := graphio.Cytoscape.Read(strings.NewReader(`{"nodes": [{"data": {}}]}`))
+	if !errors.Is(err, graphio.ErrMissingID) {
+		t.Errorf("Expected error %v, got %v", graphio.ErrMissingID, err)
+	}
+}
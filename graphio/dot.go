@@ -0,0 +1,106 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/internal/dot"
+)
+
+// DOTGraph reads and writes graphs in the Graphviz DOT language. An edge
+// from "a" to "b" means a depends on b. Only node and edge statements are
+// read; attributes are ignored. Node attributes set with SetAttr are
+// written as DOT attributes.
+type DOTGraph struct {
+	// HighlightCycles colors the nodes and edges that form cycles red and
+	// draws a box around each cycle.
+	HighlightCycles bool
+	// CyclesOnly writes only the nodes and edges that form cycles.
+	CyclesOnly bool
+}
+
+// DOT reads and writes DOT graphs without highlighting.
+var DOT DOTGraph
+
+// Extensions implements Format.
+func (DOTGraph) Extensions() []string {
+	return []string{".dot", ".gv"}
+}
+
+// Read implements Reader.
+func (DOTGraph) Read(r io.Reader) (*topo.Graph[string], error) {
+	parsed, err := dot.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DOT: %w", err)
+	}
+	deps := make(map[string][]string, len(parsed.Nodes))
+	for _, edge := range parsed.Edges {
+		deps[edge.From] = append(deps[edge.From], edge.To)
+	}
+	var g topo.Graph[string]
+	for _, node := range parsed.Nodes {
+		g.AddNode(node, deps[node])
+	}
+	return &g, nil
+}
+
+// Write implements Writer.
+func (d DOTGraph) Write(w io.Writer, g *topo.Graph[string]) error {
+	inCycle := cyclic(g)
+	highlight := d.HighlightCycles && len(inCycle) > 0
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph {")
+	if highlight {
+		for i, cycle := range g.Cycles() {
+			fmt.Fprintf(bw, "\tsubgraph cluster_cycle%d {\n", i+1)
+			fmt.Fprintf(bw, "\t\tlabel=\"cycle %d\";\n\t\tcolor=red;\n", i+1)
+			for _, value := range cycle {
+				fmt.Fprintf(bw, "\t\t%s [color=red];\n", dotID(value))
+			}
+			fmt.Fprintln(bw, "\t}")
+		}
+	}
+	for _, value := range g.Nodes() {
+		if d.CyclesOnly && !inCycleNode(inCycle, value) {
+			continue
+		}
+		deps := g.Dependencies(value)
+		if attrs := g.Attrs(value); len(attrs) > 0 {
+			fmt.Fprintf(bw, "\t%s [%s];\n", dotID(value), dotAttrs(attrs))
+		} else if len(deps) == 0 {
+			fmt.Fprintf(bw, "\t%s;\n", dotID(value))
+		}
+		for _, dep := range deps {
+			cycleEdge := inCycleEdge(inCycle, value, dep)
+			switch {
+			case d.CyclesOnly && !cycleEdge:
+				continue
+			case highlight && cycleEdge:
+				fmt.Fprintf(bw, "\t%s -> %s [color=red];\n", dotID(value), dotID(dep))
+			default:
+				fmt.Fprintf(bw, "\t%s -> %s;\n", dotID(value), dotID(dep))
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotAttrs formats attributes as a DOT attribute list, sorted by key.
+func dotAttrs(attrs map[string]string) string {
+	var list []string
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		list = append(list, dotID(key)+"="+dotID(attrs[key]))
+	}
+	return strings.Join(list, ", ")
+}
+
+func dotID(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,112 @@
+package graphio_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestDOTRead checks reading node and edge statements from DOT.
+func TestDOTRead(t *testing.T) {
+	input := `digraph deps {
+		rankdir=LR;
+		app -> lib [color=red];
+		app -> db;
+		lib -> base;
+		"solo";
+	}`
+	g, err := graphio.DOT.Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"lib", "db"}
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected app deps %v, got %v", expected, deps)
+	}
+	if nodes := g.Nodes(); len(nodes) != 5 {
+		t.Errorf("Expected 5 nodes, got %v", nodes)
+	}
+}
+
+func cyclicGraph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"a", "lib"})
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+	return &g
+}
+
+// TestDOTCycles checks highlighting cycles and writing only cycles.
+func TestDOTCycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   graphio.DOTGraph
+		expected string
+	}{
+		{
+			name:   "highlight",
+			format: graphio.DOTGraph{HighlightCycles: true},
+			expected: `digraph {
+	subgraph cluster_cycle1 {
+		label="cycle 1";
+		color=red;
+		"a" [color=red];
+		"b" [color=red];
+	}
+	"app" -> "a";
+	"app" -> "lib";
+	"a" -> "b" [color=red];
+	"lib";
+	"b" -> "a" [color=red];
+}
+`,
+		},
+		{
+			name:   "cycles only",
+			format: graphio.DOTGraph{CyclesOnly: true},
+			expected: `digraph {
+	"a" -> "b";
+	"b" -> "a";
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := tt.format.Write(&buf, cyclicGraph()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+// TestDOTAttrs checks writing node attributes.
+func TestDOTAttrs(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.SetAttr("app", "owner", "team-x")
+	g.SetAttr("app", "color", "blue")
+	g.SetAttr("lib", "owner", `say "hi"`)
+
+	var buf strings.Builder
+	if err := graphio.DOT.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `digraph {
+	"app" ["color"="blue", "owner"="team-x"];
+	"app" -> "lib";
+	"lib" ["owner"="say \"hi\""];
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,134 @@
+package graphio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// DrawIO writes graphs as draw.io (diagrams.net) diagrams, laid out like
+// [SVG]. Edges are connected to their nodes, so the diagram can be
+// rearranged by hand afterwards. It can only be written, not read.
+var DrawIO drawioFormat
+
+type drawioFormat struct{}
+
+type drawioFile struct {
+	XMLName xml.Name      `xml:"mxfile"`
+	Host    string        `xml:"host,attr"`
+	Diagram drawioDiagram `xml:"diagram"`
+}
+
+type drawioDiagram struct {
+	ID    string      `xml:"id,attr"`
+	Name  string      `xml:"name,attr"`
+	Model drawioModel `xml:"mxGraphModel"`
+}
+
+type drawioModel struct {
+	Cells []drawioCell `xml:"root>mxCell"`
+}
+
+type drawioCell struct {
+	ID       string          `xml:"id,attr"`
+	Value    string          `xml:"value,attr,omitempty"`
+	Style    string          `xml:"style,attr,omitempty"`
+	Vertex   string          `xml:"vertex,attr,omitempty"`
+	Edge     string          `xml:"edge,attr,omitempty"`
+	Parent   string          `xml:"parent,attr,omitempty"`
+	Source   string          `xml:"source,attr,omitempty"`
+	Target   string          `xml:"target,attr,omitempty"`
+	Geometry *drawioGeometry `xml:"mxGeometry"`
+}
+
+type drawioGeometry struct {
+	X        float64       `xml:"x,attr,omitempty"`
+	Y        float64       `xml:"y,attr,omitempty"`
+	Width    float64       `xml:"width,attr,omitempty"`
+	Height   float64       `xml:"height,attr,omitempty"`
+	Relative string        `xml:"relative,attr,omitempty"`
+	As       string        `xml:"as,attr"`
+	Points   *drawioPoints `xml:"Array"`
+}
+
+type drawioPoints struct {
+	As     string        `xml:"as,attr"`
+	Points []drawioPoint `xml:"mxPoint"`
+}
+
+type drawioPoint struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+// Extensions implements Format.
+func (drawioFormat) Extensions() []string {
+	return []string{".drawio"}
+}
+
+// Write implements Writer.
+func (drawioFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	boxes, _, edges := layoutGraph(g)
+
+	cells := []drawioCell{{ID: "0"}, {ID: "1", Parent: "0"}}
+	cellID := func(i int) string { return fmt.Sprintf("node-%d", i) }
+	for i, box := range boxes {
+		if box.dummy {
+			continue
+		}
+		cells = append(cells, drawioCell{
+			ID:     cellID(i),
+			Value:  box.label,
+			Style:  "rounded=1;whiteSpace=wrap;fontFamily=Courier New;fontSize=12;",
+			Vertex: "1",
+			Parent: "1",
+			Geometry: &drawioGeometry{
+				X: box.x, Y: layerY(box.layer), Width: box.width, Height: boxHeight,
+				As: "geometry",
+			},
+		})
+	}
+	for i, edge := range edges {
+		geometry := &drawioGeometry{Relative: "1", As: "geometry"}
+		// route the edge through its dummies
+		if dummies := edge.chain[1 : len(edge.chain)-1]; len(dummies) > 0 {
+			geometry.Points = &drawioPoints{As: "points"}
+			for _, id := range dummies {
+				geometry.Points.Points = append(geometry.Points.Points, drawioPoint{
+					X: boxes[id].center(),
+					Y: layerY(boxes[id].layer) + boxHeight/2,
+				})
+			}
+		}
+		cells = append(cells, drawioCell{
+			ID:       fmt.Sprintf("edge-%d", i),
+			Style:    "endArrow=classic;curved=1;",
+			Edge:     "1",
+			Parent:   "1",
+			Source:   cellID(edge.chain[0]),
+			Target:   cellID(edge.chain[len(edge.chain)-1]),
+			Geometry: geometry,
+		})
+	}
+
+	file := drawioFile{
+		Host: "go-topo",
+		Diagram: drawioDiagram{
+			ID:    "graph",
+			Name:  "Dependencies",
+			Model: drawioModel{Cells: cells},
+		},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
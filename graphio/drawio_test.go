@@ -0,0 +1,66 @@
+package graphio_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestDrawIO checks that every node becomes a vertex and every edge an edge
+// connected to the vertices at its ends.
+func TestDrawIO(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "a&b"})
+	g.AddNode("lib", []string{"a&b"})
+
+	var buf strings.Builder
+	if err := graphio.DrawIO.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var file struct {
+		Cells []struct {
+			ID     string `xml:"id,attr"`
+			Value  string `xml:"value,attr"`
+			Vertex string `xml:"vertex,attr"`
+			Edge   string `xml:"edge,attr"`
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+			Points []struct {
+				X float64 `xml:"x,attr"`
+			} `xml:"mxGeometry>Array>mxPoint"`
+		} `xml:"diagram>mxGraphModel>root>mxCell"`
+	}
+	if err := xml.Unmarshal([]byte(buf.String()), &file); err != nil {
+		t.Fatalf("Invalid XML: %v", err)
+	}
+
+	labels := make(map[string]string)
+	for _, c := range file.Cells {
+		if c.Vertex == "1" {
+			labels[c.ID] = c.Value
+		}
+	}
+	var edges []string
+	routed := 0
+	for _, c := range file.Cells {
+		if c.Edge == "1" {
+			edges = append(edges, labels[c.Source]+"->"+labels[c.Target])
+			routed += len(c.Points)
+		}
+	}
+
+	if len(labels) != 3 {
+		t.Errorf("Expected 3 vertices, got %v", labels)
+	}
+	expected := "app->lib app->a&b lib->a&b"
+	if strings.Join(edges, " ") != expected {
+		t.Errorf("Expected edges %s, got %v", expected, edges)
+	}
+	// app to a&b spans two layers, so it's routed through a waypoint
+	if routed != 1 {
+		t.Errorf("Expected 1 waypoint, got %d", routed)
+	}
+}
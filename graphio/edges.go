@@ -0,0 +1,138 @@
+package graphio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// EdgeList reads and writes graphs as lists of edges, one per row. Each row
+// holds a node followed by one of its dependencies. A row with a single
+// field declares a node without any dependencies. Lines starting with "#"
+// are comments.
+type EdgeList struct {
+	// Comma is the field separator. It defaults to ','. A space separates
+	// fields by any amount of whitespace.
+	Comma rune
+	// Header is true if the first row is a header, like "from,to", which
+	// is skipped when reading and written when writing.
+	Header bool
+}
+
+// Common edge list formats.
+var (
+	CSV = EdgeList{Comma: ','}
+	TSV = EdgeList{Comma: '\t'}
+)
+
+// Extensions implements Format, returning ".csv" for comma-separated and
+// ".tsv" for tab-separated lists.
+func (e EdgeList) Extensions() []string {
+	switch e.comma() {
+	case ',':
+		return []string{".csv"}
+	case '\t':
+		return []string{".tsv"}
+	default:
+		return nil
+	}
+}
+
+// Read reads a graph from an edge list.
+func (e EdgeList) Read(r io.Reader) (*topo.Graph[string], error) {
+	cr := csv.NewReader(r)
+	cr.Comma = e.comma()
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var order []string
+	deps := make(map[string][]string)
+	first := true
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing edge list: %w", err)
+		}
+		if first && e.Header {
+			first = false
+			continue
+		}
+		first = false
+
+		record = trimEmpty(record)
+		if len(record) == 0 {
+			continue
+		}
+		if len(record) > 2 {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("parsing edge list: line %d: expected at most two fields", line)
+		}
+		if _, exists := deps[record[0]]; !exists {
+			deps[record[0]] = nil
+			order = append(order, record[0])
+		}
+		if len(record) == 2 {
+			deps[record[0]] = append(deps[record[0]], record[1])
+		}
+	}
+
+	var g topo.Graph[string]
+	for _, value := range order {
+		g.AddNode(value, deps[value])
+	}
+	return &g, nil
+}
+
+// Write writes a graph as an edge list, listing nodes in the order they
+// were added to the graph.
+func (e EdgeList) Write(w io.Writer, g *topo.Graph[string]) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = e.comma()
+	if e.Header {
+		if err := cw.Write([]string{"from", "to"}); err != nil {
+			return err
+		}
+	}
+	for _, value := range g.Nodes() {
+		deps := g.Dependencies(value)
+		if len(deps) == 0 {
+			if err := cw.Write([]string{value}); err != nil {
+				return err
+			}
+		}
+		for _, dep := range deps {
+			if err := cw.Write([]string{value, dep}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (e EdgeList) comma() rune {
+	if e.Comma == 0 {
+		return ','
+	}
+	return e.Comma
+}
+
+// trimEmpty drops empty fields, which appear when fields are separated by
+// runs of whitespace.
+func trimEmpty(record []string) []string {
+	fields := record[:0]
+	for _, field := range record {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
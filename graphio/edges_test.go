@@ -0,0 +1,71 @@
+package graphio_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestEdgeList checks reading and writing edge lists.
+func TestEdgeList(t *testing.T) {
+	tests := []struct {
+		name   string
+		format graphio.EdgeList
+		input  string
+		output string
+	}{
+		{
+			name:   "csv",
+			format: graphio.CSV,
+			input:  "# edges\napp,lib\napp, \"db, primary\"\nlib,base\nbase\n",
+			output: "app,lib\napp,\"db, primary\"\nlib,base\n\"db, primary\"\nbase\n",
+		},
+		{
+			name:   "tsv with header",
+			format: graphio.EdgeList{Comma: '\t', Header: true},
+			input:  "from\tto\napp\tlib\nlib\tbase\n",
+			output: "from\tto\napp\tlib\nlib\tbase\nbase\n",
+		},
+		{
+			name:   "whitespace",
+			format: graphio.EdgeList{Comma: ' '},
+			input:  "app   lib\n  lib base\n\nbase\n",
+			output: "app lib\nlib base\nbase\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := tt.format.Read(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tt.format.Write(&buf, g); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.output {
+				t.Errorf("Expected %q, got %q", tt.output, buf.String())
+			}
+
+			// what was written reads back as the same graph
+			again, err := tt.format.Read(&buf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, value := range g.Nodes() {
+				if !reflect.DeepEqual(g.Dependencies(value), again.Dependencies(value)) {
+					t.Errorf("Expected %s to depend on %v, got %v",
+						value, g.Dependencies(value), again.Dependencies(value))
+				}
+			}
+		})
+	}
+
+	if _, err := graphio.CSV.Read(strings.NewReader("a,b,c\n")); err == nil {
+		t.Errorf("Expected error for a row with three fields")
+	}
+}
@@ -0,0 +1,170 @@
+package graphio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Excalidraw writes graphs as Excalidraw scenes, laid out like [SVG], with
+// arrows bound to the boxes so the diagram stays connected when edited by
+// hand. It can only be written, not read.
+var Excalidraw excalidrawFormat
+
+type excalidrawFormat struct{}
+
+type excalidrawScene struct {
+	Type     string              `json:"type"`
+	Version  int                 `json:"version"`
+	Source   string              `json:"source"`
+	Elements []excalidrawElement `json:"elements"`
+	AppState map[string]any      `json:"appState"`
+	Files    map[string]any      `json:"files"`
+}
+
+// excalidrawElement has the fields of every element type; those that don't
+// apply are omitted.
+type excalidrawElement struct {
+	ID              string             `json:"id"`
+	Type            string             `json:"type"`
+	X               float64            `json:"x"`
+	Y               float64            `json:"y"`
+	Width           float64            `json:"width"`
+	Height          float64            `json:"height"`
+	Angle           float64            `json:"angle"`
+	StrokeColor     string             `json:"strokeColor"`
+	BackgroundColor string             `json:"backgroundColor"`
+	FillStyle       string             `json:"fillStyle"`
+	StrokeWidth     int                `json:"strokeWidth"`
+	StrokeStyle     string             `json:"strokeStyle"`
+	Roughness       int                `json:"roughness"`
+	Opacity         int                `json:"opacity"`
+	GroupIDs        []string           `json:"groupIds"`
+	Roundness       *excalidrawRound   `json:"roundness"`
+	Seed            int                `json:"seed"`
+	Version         int                `json:"version"`
+	IsDeleted       bool               `json:"isDeleted"`
+	BoundElements   []excalidrawBound  `json:"boundElements"`
+	Locked          bool               `json:"locked"`
+	Text            string             `json:"text,omitempty"`
+	OriginalText    string             `json:"originalText,omitempty"`
+	FontSize        int                `json:"fontSize,omitempty"`
+	FontFamily      int                `json:"fontFamily,omitempty"`
+	TextAlign       string             `json:"textAlign,omitempty"`
+	VerticalAlign   string             `json:"verticalAlign,omitempty"`
+	ContainerID     string             `json:"containerId,omitempty"`
+	LineHeight      float64            `json:"lineHeight,omitempty"`
+	Points          [][2]float64       `json:"points,omitempty"`
+	StartBinding    *excalidrawBinding `json:"startBinding,omitempty"`
+	EndBinding      *excalidrawBinding `json:"endBinding,omitempty"`
+	EndArrowhead    string             `json:"endArrowhead,omitempty"`
+}
+
+type excalidrawRound struct {
+	Type int `json:"type"`
+}
+
+type excalidrawBound struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type excalidrawBinding struct {
+	ElementID string  `json:"elementId"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+// Extensions implements Format.
+func (excalidrawFormat) Extensions() []string {
+	return []string{".excalidraw"}
+}
+
+// Write implements Writer.
+func (excalidrawFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	boxes, _, edges := layoutGraph(g)
+
+	element := func(id, kind string, x, y, width, height float64) excalidrawElement {
+		return excalidrawElement{
+			ID: id, Type: kind,
+			X: x, Y: y, Width: width, Height: height,
+			StrokeColor: "#1e1e1e", BackgroundColor: "transparent",
+			FillStyle: "solid", StrokeWidth: 1, StrokeStyle: "solid",
+			Roughness: 1, Opacity: 100, GroupIDs: []string{},
+			Seed: 1, Version: 1,
+		}
+	}
+	boxID := func(i int) string { return fmt.Sprintf("node-%d", i) }
+
+	// boxes first, so they can be bound to the arrows and text
+	var elements []excalidrawElement
+	rect := make(map[int]int)
+	for i, box := range boxes {
+		if box.dummy {
+			continue
+		}
+		y := layerY(box.layer)
+		r := element(boxID(i), "rectangle", box.x, y, box.width, boxHeight)
+		r.Roundness = &excalidrawRound{Type: 3}
+		r.Seed = len(elements) + 1
+		r.BoundElements = []excalidrawBound{{ID: fmt.Sprintf("text-%d", i), Type: "text"}}
+		rect[i] = len(elements)
+		elements = append(elements, r)
+
+		t := element(fmt.Sprintf("text-%d", i), "text", box.x, y+(boxHeight-15)/2, box.width, 15)
+		t.Seed = len(elements) + 1
+		t.Text, t.OriginalText = box.label, box.label
+		t.FontSize, t.FontFamily, t.LineHeight = 12, 3, 1.25
+		t.TextAlign, t.VerticalAlign = "center", "middle"
+		t.ContainerID = boxID(i)
+		elements = append(elements, t)
+	}
+
+	for i, edge := range edges {
+		from, to := edge.chain[0], edge.chain[len(edge.chain)-1]
+		var points []point
+		if from == to {
+			// a node depending on itself loops around its right side
+			box := &boxes[from]
+			x, y := box.x+box.width, layerY(box.layer)
+			points = []point{{x, y + 6}, {x + 30, y + boxHeight/2}, {x, y + boxHeight - 6}}
+		} else {
+			points = edgePoints(boxes, edge.chain)
+		}
+
+		id := fmt.Sprintf("edge-%d", i)
+		a := element(id, "arrow", points[0].x, points[0].y, 0, 0)
+		a.Seed = len(elements) + 1
+		a.Roundness = &excalidrawRound{Type: 2}
+		a.EndArrowhead = "arrow"
+		a.StartBinding = &excalidrawBinding{ElementID: boxID(from), Gap: 1}
+		a.EndBinding = &excalidrawBinding{ElementID: boxID(to), Gap: 1}
+		minX, maxX, minY, maxY := points[0].x, points[0].x, points[0].y, points[0].y
+		for _, p := range points {
+			a.Points = append(a.Points, [2]float64{p.x - points[0].x, p.y - points[0].y})
+			minX, maxX = min(minX, p.x), max(maxX, p.x)
+			minY, maxY = min(minY, p.y), max(maxY, p.y)
+		}
+		a.Width, a.Height = maxX-minX, maxY-minY
+		elements = append(elements, a)
+
+		bound := excalidrawBound{ID: id, Type: "arrow"}
+		elements[rect[from]].BoundElements = append(elements[rect[from]].BoundElements, bound)
+		if to != from {
+			elements[rect[to]].BoundElements = append(elements[rect[to]].BoundElements, bound)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(excalidrawScene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "https://github.com/sam-fredrickson/go-topo",
+		Elements: elements,
+		AppState: map[string]any{"viewBackgroundColor": "#ffffff"},
+		Files:    map[string]any{},
+	})
+}
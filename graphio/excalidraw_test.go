@@ -0,0 +1,85 @@
+package graphio_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestExcalidraw checks that every node gets a labeled box and every edge
+// an arrow bound to the boxes at its ends.
+func TestExcalidraw(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "base"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("loop", []string{"loop"})
+
+	var buf strings.Builder
+	if err := graphio.Excalidraw.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var scene struct {
+		Type     string `json:"type"`
+		Elements []struct {
+			ID            string `json:"id"`
+			Type          string `json:"type"`
+			Text          string `json:"text"`
+			ContainerID   string `json:"containerId"`
+			BoundElements []struct {
+				ID string `json:"id"`
+			} `json:"boundElements"`
+			StartBinding struct {
+				ElementID string `json:"elementId"`
+			} `json:"startBinding"`
+			EndBinding struct {
+				ElementID string `json:"elementId"`
+			} `json:"endBinding"`
+			Points [][2]float64 `json:"points"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &scene); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if scene.Type != "excalidraw" {
+		t.Errorf("Expected type excalidraw, got %q", scene.Type)
+	}
+
+	labels := make(map[string]string) // box ID to label
+	bound := make(map[string][]string)
+	counts := make(map[string]int)
+	for _, e := range scene.Elements {
+		counts[e.Type]++
+		for _, b := range e.BoundElements {
+			bound[e.ID] = append(bound[e.ID], b.ID)
+		}
+		if e.Type == "text" {
+			labels[e.ContainerID] = e.Text
+		}
+	}
+	if counts["rectangle"] != 4 || counts["text"] != 4 || counts["arrow"] != 4 {
+		t.Errorf("Expected 4 boxes, labels, and arrows, got %v", counts)
+	}
+
+	var edges []string
+	for _, e := range scene.Elements {
+		if e.Type != "arrow" {
+			continue
+		}
+		if len(e.Points) < 2 || e.Points[0] != [2]float64{0, 0} {
+			t.Errorf("Expected arrow points relative to its start, got %v", e.Points)
+		}
+		for _, end := range []string{e.StartBinding.ElementID, e.EndBinding.ElementID} {
+			if !strings.Contains(strings.Join(bound[end], " "), e.ID) {
+				t.Errorf("Expected %s to be bound to %s", end, e.ID)
+			}
+		}
+		edges = append(edges, labels[e.StartBinding.ElementID]+"->"+labels[e.EndBinding.ElementID])
+	}
+	expected := "app->lib app->base lib->base loop->loop"
+	if strings.Join(edges, " ") != expected {
+		t.Errorf("Expected edges %s, got %v", expected, edges)
+	}
+}
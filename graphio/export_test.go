@@ -0,0 +1,9 @@
+package graphio
+
+// Unregister removes a format registered by a test, so that it doesn't
+// leak into the other tests, or into the next run with -count.
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, name)
+}
@@ -0,0 +1,597 @@
+package graphio
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ExternalSort sorts edge lists that are too large to hold in memory into
+// layers, like those exported from large monorepos and package registries.
+// Rather than building a graph, it keeps the edges, the number of
+// dependencies each node is waiting on, and each layer in sorted temporary
+// files, and writes each layer as soon as it's found.
+//
+// Each layer reads through the edges and the waiting nodes once, so sorting
+// takes time proportional to the size of the edge list times the number of
+// layers, and disk space a few times the size of the edge list.
+type ExternalSort struct {
+	// EdgeList is the format of the edge list read, and of the layers
+	// written.
+	EdgeList
+	// Dir is the directory for temporary files. It defaults to the
+	// directory returned by os.TempDir.
+	Dir string
+	// MaxLines is how many lines of the temporary files are sorted in
+	// memory at once. It defaults to 1,000,000.
+	MaxLines int
+}
+
+// maxMerge is how many sorted runs are merged at once.
+const maxMerge = 64
+
+// Sort reads an edge list from r, and writes the nodes to w layer by layer,
+// each as a row of its layer's index, starting from 0, and the node. With
+// Header set, the first row is "layer,node". Within a layer, nodes are
+// sorted, rather than in the order they were first listed as SortByLayers
+// would have them. It returns the number of layers.
+//
+// If the edges have a cycle, an error wrapping topo.ErrCyclicDependency is
+// returned, after the layers before the cycle have been written.
+func (s ExternalSort) Sort(r io.Reader, w io.Writer) (int, error) {
+	dir, err := os.MkdirTemp(s.Dir, "topo-sort-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	files := &tempFiles{dir: dir, maxLines: s.MaxLines}
+	if files.maxLines <= 0 {
+		files.maxLines = 1_000_000
+	}
+
+	nodes, byNode, byDep, err := s.spill(r, files)
+	if err != nil {
+		return 0, err
+	}
+	ready, waiting, err := files.waiting(nodes, byNode)
+	if err != nil {
+		return 0, err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = s.comma()
+	if s.Header {
+		if err := cw.Write([]string{"layer", "node"}); err != nil {
+			return 0, err
+		}
+	}
+	layers := 0
+	for {
+		empty, err := files.writeLayer(cw, ready, layers)
+		if err != nil {
+			return layers, err
+		}
+		if empty {
+			break
+		}
+		layers++
+		oldReady, oldWaiting := ready, waiting
+		ready, waiting, err = files.nextLayer(ready, waiting, byDep)
+		if err != nil {
+			return layers, err
+		}
+		os.Remove(oldReady)
+		os.Remove(oldWaiting)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return layers, err
+	}
+
+	if left, err := files.first(waiting, 10); err != nil {
+		return layers, err
+	} else if len(left) > 0 {
+		return layers, fmt.Errorf("%w: involving %v", topo.ErrCyclicDependency, left)
+	}
+	return layers, nil
+}
+
+// spill reads the edge list into sorted files of every node, of each
+// node's dependencies, and of each dependency's dependents, without
+// duplicates.
+func (s ExternalSort) spill(r io.Reader, files *tempFiles) (nodes, byNode, byDep string, err error) {
+	cr := csv.NewReader(r)
+	cr.Comma = s.comma()
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	nodeLines := files.sorter()
+	nodeDeps := files.sorter()
+	depNodes := files.sorter()
+	first := true
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", "", "", fmt.Errorf("parsing edge list: %w", err)
+		}
+		if first && s.Header {
+			first = false
+			continue
+		}
+		first = false
+
+		record = trimEmpty(record)
+		if len(record) == 0 {
+			continue
+		}
+		if len(record) > 2 {
+			line, _ := cr.FieldPos(0)
+			return "", "", "", fmt.Errorf("parsing edge list: line %d: expected at most two fields", line)
+		}
+		node := escapeField(record[0])
+		if err := nodeLines.add(node); err != nil {
+			return "", "", "", err
+		}
+		if len(record) == 2 {
+			dep := escapeField(record[1])
+			for _, err := range []error{
+				nodeLines.add(dep),
+				nodeDeps.add(node + "\t" + dep),
+				depNodes.add(dep + "\t" + node),
+			} {
+				if err != nil {
+					return "", "", "", err
+				}
+			}
+		}
+	}
+
+	if nodes, err = nodeLines.finish(); err != nil {
+		return "", "", "", err
+	}
+	if byNode, err = nodeDeps.finish(); err != nil {
+		return "", "", "", err
+	}
+	if byDep, err = depNodes.finish(); err != nil {
+		return "", "", "", err
+	}
+	return nodes, byNode, byDep, nil
+}
+
+// tempFiles makes the sorted temporary files of an ExternalSort.
+type tempFiles struct {
+	dir      string
+	maxLines int
+	count    int
+}
+
+// create creates a new temporary file.
+func (f *tempFiles) create() (*os.File, error) {
+	f.count++
+	return os.Create(filepath.Join(f.dir, strconv.Itoa(f.count)))
+}
+
+// waiting splits the nodes into those without dependencies, which form the
+// first layer, and the rest, each with the number of dependencies it's
+// waiting on.
+func (f *tempFiles) waiting(nodes, byNode string) (ready, waiting string, err error) {
+	deps, err := openLines(byNode)
+	if err != nil {
+		return "", "", err
+	}
+	defer deps.close()
+	return f.split(nodes, func(node string) (int, error) {
+		n := 0
+		for deps.ok && key(deps.line) == node {
+			n++
+			deps.next()
+		}
+		return n, deps.err
+	})
+}
+
+// nextLayer finds the layer after ready: the waiting nodes whose last
+// dependencies were in it.
+func (f *tempFiles) nextLayer(ready, waiting, byDep string) (string, string, error) {
+	// the nodes that depended on the layer, once for each dependency
+	resolved := f.sorter()
+	resolved.duplicates = true
+	err := join(ready, byDep, func(dependents *lineReader, dep string) error {
+		for dependents.ok && key(dependents.line) == dep {
+			if err := resolved.add(value(dependents.line)); err != nil {
+				return err
+			}
+			dependents.next()
+		}
+		return dependents.err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	resolvedPath, err := resolved.finish()
+	if err != nil {
+		return "", "", err
+	}
+
+	defer os.Remove(resolvedPath)
+	counts, err := openLines(resolvedPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer counts.close()
+	return f.splitWaiting(waiting, func(node string) (int, error) {
+		for counts.ok && counts.line < node {
+			counts.next()
+		}
+		n := 0
+		for counts.ok && counts.line == node {
+			n++
+			counts.next()
+		}
+		return n, counts.err
+	})
+}
+
+// split writes each node with no dependencies to the ready file, and each
+// node with dependencies, and how many, to the waiting file.
+func (f *tempFiles) split(nodes string, deps func(node string) (int, error)) (string, string, error) {
+	in, err := openLines(nodes)
+	if err != nil {
+		return "", "", err
+	}
+	defer in.close()
+	return f.write2(func(ready, waiting *bufio.Writer) error {
+		for ; in.ok; in.next() {
+			n, err := deps(in.line)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				ready.WriteString(in.line + "\n")
+			} else {
+				waiting.WriteString(in.line + "\t" + strconv.Itoa(n) + "\n")
+			}
+		}
+		return in.err
+	})
+}
+
+// splitWaiting takes the dependencies resolved from each waiting node,
+// writing those with none left to the ready file and the rest back to the
+// waiting file.
+func (f *tempFiles) splitWaiting(waiting string, resolved func(node string) (int, error)) (string, string, error) {
+	in, err := openLines(waiting)
+	if err != nil {
+		return "", "", err
+	}
+	defer in.close()
+	return f.write2(func(ready, waiting *bufio.Writer) error {
+		for ; in.ok; in.next() {
+			node := key(in.line)
+			n, err := strconv.Atoi(value(in.line))
+			if err != nil {
+				return err
+			}
+			r, err := resolved(node)
+			if err != nil {
+				return err
+			}
+			if n -= r; n == 0 {
+				ready.WriteString(node + "\n")
+			} else {
+				waiting.WriteString(node + "\t" + strconv.Itoa(n) + "\n")
+			}
+		}
+		return in.err
+	})
+}
+
+// write2 creates two files and writes them with fn.
+func (f *tempFiles) write2(fn func(a, b *bufio.Writer) error) (string, string, error) {
+	a, err := f.create()
+	if err != nil {
+		return "", "", err
+	}
+	defer a.Close()
+	b, err := f.create()
+	if err != nil {
+		return "", "", err
+	}
+	defer b.Close()
+	aw, bw := bufio.NewWriter(a), bufio.NewWriter(b)
+	if err := fn(aw, bw); err != nil {
+		return "", "", err
+	}
+	if err := errors.Join(aw.Flush(), bw.Flush(), a.Close(), b.Close()); err != nil {
+		return "", "", err
+	}
+	return a.Name(), b.Name(), nil
+}
+
+// writeLayer writes the nodes of a layer, reporting whether it's empty.
+func (f *tempFiles) writeLayer(cw *csv.Writer, ready string, layer int) (bool, error) {
+	in, err := openLines(ready)
+	if err != nil {
+		return false, err
+	}
+	defer in.close()
+	empty := true
+	index := strconv.Itoa(layer)
+	for ; in.ok; in.next() {
+		empty = false
+		if err := cw.Write([]string{index, unescapeField(in.line)}); err != nil {
+			return false, err
+		}
+	}
+	return empty, in.err
+}
+
+// first returns up to n of the nodes in a file of waiting nodes.
+func (f *tempFiles) first(waiting string, n int) ([]string, error) {
+	in, err := openLines(waiting)
+	if err != nil {
+		return nil, err
+	}
+	defer in.close()
+	var nodes []string
+	for ; in.ok && len(nodes) < n; in.next() {
+		nodes = append(nodes, unescapeField(key(in.line)))
+	}
+	return nodes, in.err
+}
+
+// join calls fn for each line of a sorted file of keys, with a reader of a
+// sorted file of key and value lines positioned at the first line with
+// that key or after.
+func join(keys, pairs string, fn func(pairs *lineReader, key string) error) error {
+	k, err := openLines(keys)
+	if err != nil {
+		return err
+	}
+	defer k.close()
+	p, err := openLines(pairs)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+	for ; k.ok; k.next() {
+		for p.ok && key(p.line) < k.line {
+			p.next()
+		}
+		if err := fn(p, k.line); err != nil {
+			return err
+		}
+	}
+	return errors.Join(k.err, p.err)
+}
+
+// sorter sorts lines too many to hold in memory, by sorting them in runs
+// that are written to files and then merged.
+type sorter struct {
+	files      *tempFiles
+	lines      []string
+	runs       []string
+	duplicates bool
+}
+
+func (f *tempFiles) sorter() *sorter {
+	return &sorter{files: f}
+}
+
+// add adds a line, writing a run once enough lines are held.
+func (s *sorter) add(line string) error {
+	s.lines = append(s.lines, line)
+	if len(s.lines) >= s.files.maxLines {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the lines held as a sorted run.
+func (s *sorter) spill() error {
+	slices.Sort(s.lines)
+	if !s.duplicates {
+		s.lines = slices.Compact(s.lines)
+	}
+	file, err := s.files.create()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	for _, line := range s.lines {
+		w.WriteString(line + "\n")
+	}
+	if err := errors.Join(w.Flush(), file.Close()); err != nil {
+		return err
+	}
+	s.runs = append(s.runs, file.Name())
+	s.lines = s.lines[:0]
+	return nil
+}
+
+// finish merges the runs into a single sorted file, returning its path.
+func (s *sorter) finish() (string, error) {
+	if len(s.lines) > 0 || len(s.runs) == 0 {
+		if err := s.spill(); err != nil {
+			return "", err
+		}
+	}
+	for len(s.runs) > 1 {
+		n := min(len(s.runs), maxMerge)
+		merged, err := s.merge(s.runs[:n])
+		if err != nil {
+			return "", err
+		}
+		for _, run := range s.runs[:n] {
+			os.Remove(run)
+		}
+		s.runs = append(s.runs[n:], merged)
+	}
+	return s.runs[0], nil
+}
+
+// merge merges sorted runs into one.
+func (s *sorter) merge(runs []string) (string, error) {
+	var h readerHeap
+	defer func() {
+		for _, r := range h {
+			r.close()
+		}
+	}()
+	for _, run := range runs {
+		r, err := openLines(run)
+		if err != nil {
+			return "", err
+		}
+		if r.ok {
+			h = append(h, r)
+		} else if err := r.close(); err != nil {
+			return "", err
+		}
+	}
+	heap.Init(&h)
+
+	file, err := s.files.create()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	last, wrote := "", false
+	for h.Len() > 0 {
+		r := h[0]
+		if s.duplicates || !wrote || r.line != last {
+			w.WriteString(r.line + "\n")
+			last, wrote = r.line, true
+		}
+		if r.next(); r.ok {
+			heap.Fix(&h, 0)
+			continue
+		}
+		heap.Pop(&h)
+		if err := r.close(); err != nil {
+			return "", err
+		}
+	}
+	if err := errors.Join(w.Flush(), file.Close()); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// readerHeap is a min-heap of line readers by their current lines.
+type readerHeap []*lineReader
+
+func (h readerHeap) Len() int           { return len(h) }
+func (h readerHeap) Less(i, j int) bool { return h[i].line < h[j].line }
+func (h readerHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *readerHeap) Push(x any)        { *h = append(*h, x.(*lineReader)) }
+func (h *readerHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// lineReader reads a file a line at a time. line is the current line, if
+// ok; once the file is read or fails, ok is false and err holds any error.
+type lineReader struct {
+	file *os.File
+	r    *bufio.Reader
+	line string
+	ok   bool
+	err  error
+}
+
+// openLines opens a file and reads its first line.
+func openLines(path string) (*lineReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &lineReader{file: file, r: bufio.NewReader(file)}
+	r.next()
+	return r, r.err
+}
+
+func (r *lineReader) next() {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		r.ok = false
+		if !errors.Is(err, io.EOF) {
+			r.err = err
+		}
+		return
+	}
+	r.line, r.ok = strings.TrimSuffix(line, "\n"), true
+}
+
+func (r *lineReader) close() error {
+	return r.file.Close()
+}
+
+// key returns the first field of a line of a temporary file.
+func key(line string) string {
+	k, _, _ := strings.Cut(line, "\t")
+	return k
+}
+
+// value returns the second field of a line of a temporary file.
+func value(line string) string {
+	_, v, _ := strings.Cut(line, "\t")
+	return v
+}
+
+// escapeField writes control characters and backslashes as \xHH, keeping
+// tabs and newlines, which separate the fields and lines of temporary
+// files, out of the fields written to them. As every character left sorts
+// after a tab, sorting lines also sorts them by their first field.
+func escapeField(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r < ' ' || r == '\\' }) {
+		return s
+	}
+	var b strings.Builder
+	for i := range len(s) {
+		if c := s[i]; c < ' ' || c == '\\' {
+			fmt.Fprintf(&b, `\x%02x`, c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// unescapeField reverses escapeField.
+func unescapeField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package graphio_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// externalLayers sorts an edge list with an ExternalSort, and reads the
+// layers back from what it writes.
+func externalLayers(t *testing.T, s graphio.ExternalSort, input string) ([][]string, error) {
+	t.Helper()
+	s.Dir = t.TempDir()
+	var out strings.Builder
+	n, sortErr := s.Sort(strings.NewReader(input), &out)
+	if left, _ := os.ReadDir(s.Dir); len(left) > 0 {
+		t.Errorf("Expected temporary files to be removed, found %v", left)
+	}
+
+	rows, err := graphio.EdgeList{Comma: s.Comma, Header: s.Header}.Read(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("Unexpected error reading output: %v\n%s", err, out.String())
+	}
+	layers := make([][]string, n)
+	for _, layer := range rows.Nodes() {
+		for _, node := range rows.Dependencies(layer) {
+			var i int
+			fmt.Sscan(layer, &i)
+			layers[i] = append(layers[i], node)
+		}
+	}
+	return layers, sortErr
+}
+
+// TestExternalSort checks that sorting on disk gives the same layers as
+// sorting in memory, including when the temporary files take many runs
+// and merges to sort.
+func TestExternalSort(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var g topo.Graph[string]
+	var input strings.Builder
+	for i := range 500 {
+		node := fmt.Sprintf("n%d", i)
+		var deps []string
+		for range min(i, 3) {
+			deps = append(deps, fmt.Sprintf("n%d", r.IntN(i)))
+		}
+		g.AddNode(node, deps)
+		if len(deps) == 0 {
+			fmt.Fprintln(&input, node)
+		}
+		for _, dep := range deps {
+			fmt.Fprintf(&input, "%s,%s\n", node, dep)
+		}
+	}
+	expected, err := topo.SortedLayers(&g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, maxLines := range []int{0, 100, 3} {
+		t.Run(fmt.Sprint(maxLines), func(t *testing.T) {
+			layers, err := externalLayers(t, graphio.ExternalSort{EdgeList: graphio.CSV, MaxLines: maxLines}, input.String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.EqualFunc(layers, expected, slices.Equal) {
+				t.Errorf("Expected %v, got %v", expected, layers)
+			}
+		})
+	}
+}
+
+// TestExternalSortFields checks headers, duplicates, and names with the
+// characters used in temporary files.
+func TestExternalSortFields(t *testing.T) {
+	input := "from\tto\napp\tlib\napp\tlib\n\"a\tb\"\t\"x\\y\"\n\"x\\y\"\t\"c\x01\"\nlib\n"
+	s := graphio.ExternalSort{EdgeList: graphio.EdgeList{Comma: '\t', Header: true}, MaxLines: 2}
+	layers, err := externalLayers(t, s, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"c\x01", "lib"}, {"app", "x\\y"}, {"a\tb"}}
+	if !slices.EqualFunc(layers, expected, slices.Equal) {
+		t.Errorf("Expected %q, got %q", expected, layers)
+	}
+}
+
+// TestExternalSortCycle checks that the layers before a cycle are written,
+// and the cycle reported.
+func TestExternalSortCycle(t *testing.T) {
+	input := "app,a\na,b\nb,a\na,base\n"
+	layers, err := externalLayers(t, graphio.ExternalSort{EdgeList: graphio.CSV}, input)
+	if !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Fatalf("Expected ErrCyclicDependency, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "[a app b]") {
+		t.Errorf("Expected the nodes left to be reported, got %v", err)
+	}
+	expected := [][]string{{"base"}}
+	if !slices.EqualFunc(layers, expected, slices.Equal) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
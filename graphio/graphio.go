@@ -0,0 +1,243 @@
+// Package graphio reads and writes dependency graphs in file formats.
+//
+// # Definition files
+//
+// Graphs and their metadata can be kept in JSON or YAML files using the
+// following schema:
+//
+//	version: 1           # the schema version; see SchemaVersion
+//	nodes:
+//	  - id: app          # required, unique
+//	    deps: [lib, db]  # optional, IDs of the nodes this node depends on
+//	    tags: [backend]  # optional, free-form labels
+//	    duration: 1m30s  # optional, in time.ParseDuration format
+//	    attrs:           # optional, set on the graph with SetAttr
+//	      owner: team-x
+//	  - id: lib
+//	  - id: db
+//
+// The same structure is used for JSON, with "nodes" as the top-level key.
+// Dependencies don't have to be declared as nodes themselves.
+//
+// Files written with an older schema are migrated to SchemaVersion as
+// they're read, so files kept for years still load; files written with a
+// newer one return ErrUnsupportedVersion.
+package graphio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+var (
+	// ErrMissingID is returned when a node in a definition has no ID.
+	ErrMissingID = errors.New("node without an id")
+	// ErrDuplicateID is returned when two nodes in a definition have the
+	// same ID.
+	ErrDuplicateID = errors.New("duplicate node id")
+)
+
+// Document is the schema of a definition file.
+type Document struct {
+	// Version is the schema version the file was written with; see
+	// SchemaVersion.
+	Version int    `json:"version,omitempty" yaml:"version,omitempty"`
+	Nodes   []Node `json:"nodes" yaml:"nodes"`
+}
+
+// Node is a single node of a definition file.
+type Node struct {
+	ID       string            `json:"id" yaml:"id"`
+	Deps     []string          `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Tags     []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Duration Duration          `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Attrs    map[string]string `json:"attrs,omitempty" yaml:"attrs,omitempty"`
+}
+
+// Duration is a time.Duration written as a string like "1m30s".
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// IsZero reports whether the duration is zero, so it's omitted when
+// marshaling to YAML.
+func (d Duration) IsZero() bool {
+	return d == 0
+}
+
+// JSON and YAML read and write definition files as formats, ignoring tags
+// and durations. Attributes are kept.
+var (
+	JSON Format = definitionFormat{json: true}
+	YAML Format = definitionFormat{}
+)
+
+type definitionFormat struct {
+	json bool
+}
+
+// Extensions implements Format.
+func (f definitionFormat) Extensions() []string {
+	if f.json {
+		return []string{".json"}
+	}
+	return []string{".yaml", ".yml"}
+}
+
+// Read implements Reader.
+func (f definitionFormat) Read(r io.Reader) (*topo.Graph[string], error) {
+	read := ReadYAML
+	if f.json {
+		read = ReadJSON
+	}
+	def, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	return def.Graph, nil
+}
+
+// Write implements Writer.
+func (f definitionFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	doc := Document{Version: SchemaVersion}
+	for _, value := range g.Nodes() {
+		doc.Nodes = append(doc.Nodes, Node{ID: value, Deps: g.Dependencies(value), Attrs: g.Attrs(value)})
+	}
+	if f.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Definition is a graph loaded from a definition file, with its metadata.
+type Definition struct {
+	Graph *topo.Graph[string]
+	// Tags maps node IDs to their tags. Nodes without tags are omitted.
+	Tags map[string][]string
+	// Durations maps node IDs to their durations. Nodes without a
+	// duration are omitted.
+	Durations map[string]time.Duration
+}
+
+// ReadJSON reads a definition in JSON format, migrating it from an older
+// schema version if needed.
+func ReadJSON(r io.Reader) (*Definition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	err = decodeVersioned(func(v any) error {
+		return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+	}, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+	return doc.Definition()
+}
+
+// ReadYAML reads a definition in YAML format, migrating it from an older
+// schema version if needed.
+func ReadYAML(r io.Reader) (*Definition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	err = decodeVersioned(func(v any) error {
+		if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(v); !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	return doc.Definition()
+}
+
+// Load reads a definition file, choosing the format by its extension:
+// ".json" for JSON, and ".yaml" or ".yml" for YAML.
+func Load(path string) (*Definition, error) {
+	var read func(io.Reader) (*Definition, error)
+	switch filepath.Ext(path) {
+	case ".json":
+		read = ReadJSON
+	case ".yaml", ".yml":
+		read = ReadYAML
+	default:
+		return nil, fmt.Errorf("%s: unknown file extension", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	def, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return def, nil
+}
+
+// Definition validates the document and builds its graph and metadata.
+func (doc *Document) Definition() (*Definition, error) {
+	def := &Definition{
+		Graph:     &topo.Graph[string]{},
+		Tags:      make(map[string][]string),
+		Durations: make(map[string]time.Duration),
+	}
+	seen := make(map[string]bool, len(doc.Nodes))
+	for i, node := range doc.Nodes {
+		if node.ID == "" {
+			return nil, fmt.Errorf("%w: node %d", ErrMissingID, i+1)
+		}
+		if seen[node.ID] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateID, node.ID)
+		}
+		seen[node.ID] = true
+
+		def.Graph.AddNode(node.ID, node.Deps)
+		for key, attr := range node.Attrs {
+			def.Graph.SetAttr(node.ID, key, attr)
+		}
+		if len(node.Tags) > 0 {
+			def.Tags[node.ID] = node.Tags
+		}
+		if node.Duration != 0 {
+			def.Durations[node.ID] = time.Duration(node.Duration)
+		}
+	}
+	return def, nil
+}
@@ -0,0 +1,125 @@
+package graphio_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+const definitionYAML = `
+nodes:
+  - id: app
+    deps: [lib, db]
+    tags: [backend]
+    duration: 1m30s
+  - id: lib
+    duration: 10s
+  - id: db
+    tags: [infra, stateful]
+`
+
+const definitionJSON = `{"nodes": [
+	{"id": "app", "deps": ["lib", "db"], "tags": ["backend"], "duration": "1m30s"},
+	{"id": "lib", "duration": "10s"},
+	{"id": "db", "tags": ["infra", "stateful"]}
+]}`
+
+// TestRead checks that both formats produce the same definition.
+func TestRead(t *testing.T) {
+	fromYAML, err := graphio.ReadYAML(strings.NewReader(definitionYAML))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fromJSON, err := graphio.ReadJSON(strings.NewReader(definitionJSON))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, def := range []*graphio.Definition{fromYAML, fromJSON} {
+		layers, err := topo.SortedLayers(def.Graph)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectedLayers := [][]string{{"db", "lib"}, {"app"}}
+		if !reflect.DeepEqual(layers, expectedLayers) {
+			t.Errorf("Expected %v, got %v", expectedLayers, layers)
+		}
+
+		expectedTags := map[string][]string{"app": {"backend"}, "db": {"infra", "stateful"}}
+		if !reflect.DeepEqual(def.Tags, expectedTags) {
+			t.Errorf("Expected tags %v, got %v", expectedTags, def.Tags)
+		}
+		expectedDurations := map[string]time.Duration{"app": 90 * time.Second, "lib": 10 * time.Second}
+		if !reflect.DeepEqual(def.Durations, expectedDurations) {
+			t.Errorf("Expected durations %v, got %v", expectedDurations, def.Durations)
+		}
+	}
+}
+
+// TestReadErrors checks validation of definitions.
+func TestReadErrors(t *testing.T) {
+	_, err := graphio.ReadYAML(strings.NewReader("nodes:\n  - deps: [a]\n"))
+	if !errors.Is(err, graphio.ErrMissingID) {
+		t.Errorf("Expected error %v, got %v", graphio.ErrMissingID, err)
+	}
+	_, err = graphio.ReadJSON(strings.NewReader(`{"nodes": [{"id": "a"}, {"id": "a"}]}`))
+	if !errors.Is(err, graphio.ErrDuplicateID) {
+		t.Errorf("Expected error %v, got %v", graphio.ErrDuplicateID, err)
+	}
+	_, err = graphio.ReadYAML(strings.NewReader("nodes:\n  - id: a\n    duration: soon\n"))
+	if err == nil {
+		t.Errorf("Expected error for invalid duration")
+	}
+}
+
+// TestLoad checks choosing the format by file extension.
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.yml")
+	if err := os.WriteFile(path, []byte(definitionYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	def, err := graphio.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if nodes := def.Graph.Nodes(); len(nodes) != 3 {
+		t.Errorf("Expected 3 nodes, got %v", nodes)
+	}
+
+	if _, err := graphio.Load(filepath.Join(dir, "graph.txt")); err == nil {
+		t.Errorf("Expected error for unknown extension")
+	}
+}
+
+// TestDefinitionAttrs checks that attributes are written to definitions and
+// read back.
+func TestDefinitionAttrs(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.SetAttr("app", "owner", "team-x")
+	g.SetAttr("lib", "docs", "https://example.com/lib")
+
+	for _, f := range []graphio.Format{graphio.JSON, graphio.YAML} {
+		var buf strings.Builder
+		if err := f.(graphio.Writer).Write(&buf, &g); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		again, err := f.(graphio.Reader).Read(strings.NewReader(buf.String()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v\n%s", err, buf.String())
+		}
+		for _, value := range []string{"app", "lib"} {
+			if attrs := again.Attrs(value); !reflect.DeepEqual(attrs, g.Attrs(value)) {
+				t.Errorf("Expected %v, got %v\n%s", g.Attrs(value), attrs, buf.String())
+			}
+		}
+	}
+}
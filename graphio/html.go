@@ -0,0 +1,67 @@
+package graphio
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlPage = template.Must(template.New("graph").Parse(htmlTemplate))
+
+// HTMLPage writes a graph as a single, self-contained HTML file for viewing
+// in a browser. The page lays the graph out by layer and can be panned and
+// zoomed; nodes can be searched for by name, and clicking one highlights
+// everything it depends on and everything that depends on it.
+//
+// The page has no external dependencies, so it can be attached to a build
+// or mailed around as is. It can only be written, not read.
+type HTMLPage struct {
+	// Title is the page title. If empty, "Dependency graph" is used.
+	Title string
+}
+
+// HTML writes graphs as HTML pages with the default title.
+var HTML HTMLPage
+
+type htmlNode struct {
+	ID    string `json:"id"`
+	Layer int    `json:"layer"`
+}
+
+type htmlEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Extensions implements Format.
+func (HTMLPage) Extensions() []string {
+	return []string{".html", ".htm"}
+}
+
+// Write implements Writer.
+func (p HTMLPage) Write(w io.Writer, g *topo.Graph[string]) error {
+	_, layerOf := layered(g)
+	var data struct {
+		Title string
+		Nodes []htmlNode
+		Edges []htmlEdge
+	}
+	data.Title = p.Title
+	if data.Title == "" {
+		data.Title = "Dependency graph"
+	}
+	data.Nodes = []htmlNode{}
+	data.Edges = []htmlEdge{}
+	for _, value := range g.Nodes() {
+		data.Nodes = append(data.Nodes, htmlNode{ID: value, Layer: layerOf[value]})
+		for _, dep := range g.Dependencies(value) {
+			data.Edges = append(data.Edges, htmlEdge{From: value, To: dep})
+		}
+	}
+	return htmlPage.Execute(w, data)
+}
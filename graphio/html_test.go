@@ -0,0 +1,35 @@
+package graphio_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestHTML checks that the page embeds the graph safely.
+func TestHTML(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("</script><b>", []string{"app"})
+
+	var buf strings.Builder
+	if err := (graphio.HTMLPage{Title: "Deps & more"}).Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	page := buf.String()
+
+	for _, expected := range []string{
+		"<title>Deps &amp; more</title>",
+		`{"id":"lib","layer":0}`,
+		`{"from":"app","to":"lib"}`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected page to contain %q", expected)
+		}
+	}
+	if strings.Count(page, "</script>") != 1 {
+		t.Error("Expected node names to be escaped inside the script")
+	}
+}
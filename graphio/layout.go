@@ -0,0 +1,340 @@
+package graphio
+
+import (
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// layered assigns every node of the graph to a layer, like
+// topo.Graph.SortByLayers, but without failing on cycles: an edge that
+// closes a cycle is ignored when placing its node. Nodes within a layer are
+// in the order they were first added to the graph. It also returns each
+// node's layer index.
+func layered[T comparable](g *topo.Graph[T]) ([][]T, map[T]int) {
+	nodes := g.Nodes()
+	layerOf := make(map[T]int, len(nodes))
+	onStack := make(map[T]bool)
+	var place func(value T) int
+	place = func(value T) int {
+		if layer, ok := layerOf[value]; ok {
+			return layer
+		}
+		onStack[value] = true
+		layer := 0
+		for _, dep := range g.Dependencies(value) {
+			if onStack[dep] {
+				continue
+			}
+			layer = max(layer, place(dep)+1)
+		}
+		onStack[value] = false
+		layerOf[value] = layer
+		return layer
+	}
+
+	var layers [][]T
+	for _, value := range nodes {
+		place(value)
+	}
+	for _, value := range nodes {
+		layer := layerOf[value]
+		for len(layers) <= layer {
+			layers = append(layers, nil)
+		}
+		layers[layer] = append(layers[layer], value)
+	}
+	return layers, layerOf
+}
+
+// cyclic maps each node that's part of a cycle to the index of its cycle in
+// g.Cycles(). An edge is part of a cycle if both its ends map to the same
+// index.
+func cyclic[T comparable](g *topo.Graph[T]) map[T]int {
+	inCycle := make(map[T]int)
+	for i, cycle := range g.Cycles() {
+		for _, value := range cycle {
+			inCycle[value] = i
+		}
+	}
+	return inCycle
+}
+
+func inCycleNode[T comparable](inCycle map[T]int, value T) bool {
+	_, ok := inCycle[value]
+	return ok
+}
+
+// inCycleEdge reports whether the edge from value to dep is part of a cycle.
+func inCycleEdge[T comparable](inCycle map[T]int, value, dep T) bool {
+	c, ok := inCycle[value]
+	if !ok {
+		return false
+	}
+	d, ok := inCycle[dep]
+	return ok && c == d
+}
+
+// Diagram layout constants, in pixels. Label widths are estimated for a
+// 12px monospace font.
+const (
+	charWidth  = 7.2
+	boxHeight  = 28
+	boxPadding = 10
+	nodeGap    = 24
+	layerGap   = 56
+	margin     = 20
+)
+
+// layoutBox is a node in the layout. Edges spanning several layers get dummy
+// boxes in the layers between their ends, so they can be routed around
+// other nodes.
+type layoutBox struct {
+	label string
+	layer int
+	dummy bool
+	width float64
+	x     float64 // left edge
+	up    []int   // neighbors in the layer above
+	down  []int   // neighbors in the layer below
+}
+
+func (b *layoutBox) center() float64 {
+	return b.x + b.width/2
+}
+
+// layoutEdge is an edge of the graph, as a chain of boxes from the node to
+// its dependency.
+type layoutEdge struct {
+	chain []int
+}
+
+// point is a position in a diagram.
+type point struct{ x, y float64 }
+
+// edgePoints returns the points an edge passes through, from the node to
+// its dependency: the near sides of the boxes at its ends, and the centers
+// of the dummies between them. The ends must be in different layers.
+func edgePoints(boxes []layoutBox, chain []int) []point {
+	points := make([]point, len(chain))
+	downward := boxes[chain[len(chain)-1]].layer > boxes[chain[0]].layer
+	for i, id := range chain {
+		box := &boxes[id]
+		y := layerY(box.layer) + boxHeight/2
+		if !box.dummy {
+			if (i == 0) == downward {
+				y += boxHeight / 2
+			} else {
+				y -= boxHeight / 2
+			}
+		}
+		points[i] = point{box.center(), y}
+	}
+	return points
+}
+
+// layerY returns the top of the boxes in a layer.
+func layerY(layer int) float64 {
+	return margin + float64(layer)*(boxHeight+layerGap)
+}
+
+// layoutGraph lays out the graph. It returns the boxes, the box indexes in
+// each layer from left to right, and the edges.
+func layoutGraph(g *topo.Graph[string]) ([]layoutBox, [][]int, []layoutEdge) {
+	nodeLayers, layerOf := layered(g)
+
+	var boxes []layoutBox
+	layers := make([][]int, len(nodeLayers))
+	index := make(map[string]int)
+	addBox := func(box layoutBox) int {
+		boxes = append(boxes, box)
+		layers[box.layer] = append(layers[box.layer], len(boxes)-1)
+		return len(boxes) - 1
+	}
+	for layer, values := range nodeLayers {
+		for _, value := range values {
+			width := math.Round((float64(utf8.RuneCountInString(value))*charWidth+2*boxPadding)*10) / 10
+			index[value] = addBox(layoutBox{label: value, layer: layer, width: width})
+		}
+	}
+
+	// edges go from each node to its dependency, usually upward; edges that
+	// close a cycle go downward
+	var edges []layoutEdge
+	for _, value := range g.Nodes() {
+		for _, dep := range g.Dependencies(value) {
+			from, to := index[value], index[dep]
+			chain := []int{from}
+			step := 1
+			if layerOf[dep] < layerOf[value] {
+				step = -1
+			}
+			for layer := layerOf[value] + step; layer != layerOf[dep] && from != to; layer += step {
+				chain = append(chain, addBox(layoutBox{layer: layer, dummy: true}))
+			}
+			chain = append(chain, to)
+			for i := 1; i < len(chain); i++ {
+				a, b := &boxes[chain[i-1]], &boxes[chain[i]]
+				switch {
+				case a.layer < b.layer:
+					a.down = append(a.down, chain[i])
+					b.up = append(b.up, chain[i-1])
+				case a.layer > b.layer:
+					a.up = append(a.up, chain[i])
+					b.down = append(b.down, chain[i-1])
+				}
+			}
+			edges = append(edges, layoutEdge{chain: chain})
+		}
+	}
+
+	orderBoxes(boxes, layers)
+	placeBoxes(boxes, layers)
+	return boxes, layers, edges
+}
+
+// orderBoxes reorders the boxes within each layer to reduce edge crossings,
+// using the barycenter heuristic: sweeping down and up the layers, each box
+// is moved to the average position of its neighbors in the layer before.
+// The best ordering seen is kept.
+func orderBoxes(boxes []layoutBox, layers [][]int) {
+	position := make([]float64, len(boxes))
+	setPositions := func(layer []int) {
+		for i, id := range layer {
+			position[id] = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		setPositions(layer)
+	}
+
+	sortLayer := func(layer []int, neighbors func(*layoutBox) []int) {
+		bary := make(map[int]float64, len(layer))
+		for _, id := range layer {
+			ns := neighbors(&boxes[id])
+			if len(ns) == 0 {
+				bary[id] = position[id]
+				continue
+			}
+			sum := 0.0
+			for _, n := range ns {
+				sum += position[n]
+			}
+			bary[id] = sum / float64(len(ns))
+		}
+		slices.SortStableFunc(layer, func(a, b int) int {
+			switch {
+			case bary[a] < bary[b]:
+				return -1
+			case bary[a] > bary[b]:
+				return 1
+			}
+			return 0
+		})
+		setPositions(layer)
+	}
+
+	best := crossings(boxes, layers, position)
+	bestLayers := cloneLayers(layers)
+	for range 8 {
+		if best == 0 {
+			break
+		}
+		for i := 1; i < len(layers); i++ {
+			sortLayer(layers[i], func(b *layoutBox) []int { return b.up })
+		}
+		for i := len(layers) - 2; i >= 0; i-- {
+			sortLayer(layers[i], func(b *layoutBox) []int { return b.down })
+		}
+		if c := crossings(boxes, layers, position); c < best {
+			best = c
+			bestLayers = cloneLayers(layers)
+		}
+	}
+	copy(layers, bestLayers)
+}
+
+func cloneLayers(layers [][]int) [][]int {
+	clone := make([][]int, len(layers))
+	for i, layer := range layers {
+		clone[i] = slices.Clone(layer)
+	}
+	return clone
+}
+
+// crossings counts the pairs of edge segments that cross between
+// adjacent layers.
+func crossings(boxes []layoutBox, layers [][]int, position []float64) int {
+	crossings := 0
+	for i := 0; i+1 < len(layers); i++ {
+		var segments [][2]float64
+		for _, id := range layers[i] {
+			for _, n := range boxes[id].down {
+				segments = append(segments, [2]float64{position[id], position[n]})
+			}
+		}
+		for a := range segments {
+			for b := a + 1; b < len(segments); b++ {
+				s, t := segments[a], segments[b]
+				if (s[0] < t[0] && s[1] > t[1]) || (s[0] > t[0] && s[1] < t[1]) {
+					crossings++
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+// placeBoxes assigns x coordinates, keeping the order within each layer. It
+// starts with the boxes packed to the left and then repeatedly pulls each
+// box toward the average center of its neighbors, keeping boxes apart.
+func placeBoxes(boxes []layoutBox, layers [][]int) {
+	gap := func(id int) float64 {
+		if boxes[id].dummy {
+			return nodeGap / 2
+		}
+		return nodeGap
+	}
+	for _, layer := range layers {
+		x := 0.0
+		for _, id := range layer {
+			boxes[id].x = x
+			x += boxes[id].width + gap(id)
+		}
+	}
+
+	pull := func(layer []int, neighbors func(*layoutBox) []int) {
+		right := -1e18
+		for _, id := range layer {
+			box := &boxes[id]
+			desired := box.x
+			if ns := neighbors(box); len(ns) > 0 {
+				sum := 0.0
+				for _, n := range ns {
+					sum += boxes[n].center()
+				}
+				desired = sum/float64(len(ns)) - box.width/2
+			}
+			box.x = max(desired, right)
+			right = box.x + box.width + gap(id)
+		}
+	}
+	for range 4 {
+		for i := 1; i < len(layers); i++ {
+			pull(layers[i], func(b *layoutBox) []int { return b.up })
+		}
+		for i := len(layers) - 2; i >= 0; i-- {
+			pull(layers[i], func(b *layoutBox) []int { return b.down })
+		}
+	}
+
+	left := 1e18
+	for _, box := range boxes {
+		left = min(left, box.x)
+	}
+	for i := range boxes {
+		boxes[i].x = math.Round((boxes[i].x+margin-left)*10) / 10
+	}
+}
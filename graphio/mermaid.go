@@ -0,0 +1,80 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// MermaidChart writes graphs as Mermaid flowcharts, which render in GitHub
+// and GitLab Markdown, among others. An arrow from "a" to "b" means a
+// depends on b. It can only be written, not read.
+type MermaidChart struct {
+	// HighlightCycles colors the nodes and edges that form cycles red.
+	HighlightCycles bool
+	// CyclesOnly writes only the nodes and edges that form cycles.
+	CyclesOnly bool
+}
+
+// Mermaid writes Mermaid flowcharts without highlighting.
+var Mermaid MermaidChart
+
+// Extensions implements Format.
+func (MermaidChart) Extensions() []string {
+	return []string{".mmd", ".mermaid"}
+}
+
+// Write implements Writer.
+func (m MermaidChart) Write(w io.Writer, g *topo.Graph[string]) error {
+	inCycle := cyclic(g)
+	var nodes []string
+	for _, value := range g.Nodes() {
+		if !m.CyclesOnly || inCycleNode(inCycle, value) {
+			nodes = append(nodes, value)
+		}
+	}
+
+	// node IDs are generated, since Mermaid is picky about which names are
+	// allowed, and the names are used as labels
+	ids := make(map[string]string, len(nodes))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart TD")
+	for i, value := range nodes {
+		ids[value] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(bw, "    %s[\"%s\"]\n", ids[value], mermaidLabel(value))
+	}
+
+	var cycleNodes []string
+	var cycleLinks []string
+	link := 0
+	for _, value := range nodes {
+		if inCycleNode(inCycle, value) {
+			cycleNodes = append(cycleNodes, ids[value])
+		}
+		for _, dep := range g.Dependencies(value) {
+			cycleEdge := inCycleEdge(inCycle, value, dep)
+			if m.CyclesOnly && !cycleEdge {
+				continue
+			}
+			fmt.Fprintf(bw, "    %s --> %s\n", ids[value], ids[dep])
+			if cycleEdge {
+				cycleLinks = append(cycleLinks, fmt.Sprint(link))
+			}
+			link++
+		}
+	}
+
+	if m.HighlightCycles && len(cycleNodes) > 0 {
+		fmt.Fprintln(bw, "    classDef cycle fill:#fdd,stroke:#c00,color:#900")
+		fmt.Fprintf(bw, "    class %s cycle\n", strings.Join(cycleNodes, ","))
+		fmt.Fprintf(bw, "    linkStyle %s stroke:#c00,stroke-width:2px\n", strings.Join(cycleLinks, ","))
+	}
+	return bw.Flush()
+}
+
+func mermaidLabel(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,72 @@
+package graphio_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestMermaid checks writing flowcharts, with and without cycles
+// highlighted.
+func TestMermaid(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   graphio.MermaidChart
+		expected string
+	}{
+		{
+			name:   "plain",
+			format: graphio.Mermaid,
+			expected: `flowchart TD
+    n0["app"]
+    n1["a"]
+    n2["lib"]
+    n3["b"]
+    n0 --> n1
+    n0 --> n2
+    n1 --> n3
+    n3 --> n1
+`,
+		},
+		{
+			name:   "highlight",
+			format: graphio.MermaidChart{HighlightCycles: true},
+			expected: `flowchart TD
+    n0["app"]
+    n1["a"]
+    n2["lib"]
+    n3["b"]
+    n0 --> n1
+    n0 --> n2
+    n1 --> n3
+    n3 --> n1
+    classDef cycle fill:#fdd,stroke:#c00,color:#900
+    class n1,n3 cycle
+    linkStyle 2,3 stroke:#c00,stroke-width:2px
+`,
+		},
+		{
+			name:   "cycles only",
+			format: graphio.MermaidChart{CyclesOnly: true},
+			expected: `flowchart TD
+    n0["a"]
+    n1["b"]
+    n0 --> n1
+    n1 --> n0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := tt.format.Write(&buf, cyclicGraph()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package graphio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Format is a file format for graphs. Formats that can read graphs also
+// implement [Reader], and formats that can write them implement [Writer].
+type Format interface {
+	// Extensions returns the file extensions used for the format,
+	// including the leading dot, like ".csv".
+	Extensions() []string
+}
+
+// Reader is a format that can read graphs.
+type Reader interface {
+	Format
+	Read(r io.Reader) (*topo.Graph[string], error)
+}
+
+// Writer is a format that can write graphs.
+type Writer interface {
+	Format
+	Write(w io.Writer, g *topo.Graph[string]) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Format)
+)
+
+func init() {
+	Register("json", JSON)
+	Register("yaml", YAML)
+	Register("csv", CSV)
+	Register("tsv", TSV)
+	Register("edges", EdgeList{Comma: ' '})
+	Register("dot", DOT)
+	Register("mermaid", Mermaid)
+	Register("html", HTML)
+	Register("svg", SVG)
+	Register("excalidraw", Excalidraw)
+	Register("drawio", DrawIO)
+	Register("cytoscape", Cytoscape)
+	Register("canonical", Canonical)
+}
+
+// Register makes a format available by name, and by its extensions to
+// [ReadFile] and [WriteFile]. It panics if a format with the same name is
+// already registered, so it's typically called from an init function.
+func Register(name string, f Format) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic("graphio: format " + name + " registered twice")
+	}
+	registry[name] = f
+}
+
+// Lookup returns the format registered with the given name.
+func Lookup(name string) (Format, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[name]
+	return f, ok
+}
+
+// ForExtension returns the name of the format registered for a file
+// extension, like ".csv". Extensions are matched case-insensitively. If
+// several formats claim the extension, the first by name wins.
+func ForExtension(ext string) (string, Format, bool) {
+	for _, name := range Formats() {
+		f, _ := Lookup(name)
+		if slices.ContainsFunc(f.Extensions(), func(e string) bool {
+			return strings.EqualFold(e, ext)
+		}) {
+			return name, f, true
+		}
+	}
+	return "", nil, false
+}
+
+// Formats returns the names of all registered formats, sorted.
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ReadFile reads a graph from a file, in the format registered for its
+// extension.
+func ReadFile(path string) (*topo.Graph[string], error) {
+	_, f, ok := ForExtension(filepath.Ext(path))
+	if !ok {
+		return nil, fmt.Errorf("%s: no format for extension", path)
+	}
+	r, ok := f.(Reader)
+	if !ok {
+		return nil, fmt.Errorf("%s: format can't be read", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	g, err := r.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// WriteFile writes a graph to a file, in the format registered for its
+// extension.
+func WriteFile(path string, g *topo.Graph[string]) error {
+	_, f, ok := ForExtension(filepath.Ext(path))
+	if !ok {
+		return fmt.Errorf("%s: no format for extension", path)
+	}
+	w, ok := f.(Writer)
+	if !ok {
+		return fmt.Errorf("%s: format can't be written", path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(file, g); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return file.Close()
+}
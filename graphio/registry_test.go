@@ -0,0 +1,117 @@
+package graphio_test
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestFormats checks that every built-in format writes a graph that reads
+// back the same.
+func TestFormats(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("base", nil)
+	g.SetAttr("app", "owner", "team-x")
+
+	for _, name := range []string{"json", "yaml", "csv", "tsv", "edges", "dot", "cytoscape"} {
+		t.Run(name, func(t *testing.T) {
+			f, ok := graphio.Lookup(name)
+			if !ok {
+				t.Fatalf("Format %q not registered", name)
+			}
+			var buf strings.Builder
+			if err := f.(graphio.Writer).Write(&buf, &g); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			again, err := f.(graphio.Reader).Read(strings.NewReader(buf.String()))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\n%s", err, buf.String())
+			}
+			assertSameGraph(t, &g, again)
+		})
+	}
+}
+
+type lineFormat struct{}
+
+func (lineFormat) Extensions() []string { return []string{".lines"} }
+
+func (lineFormat) Read(r io.Reader) (*topo.Graph[string], error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var g topo.Graph[string]
+	for _, line := range strings.Fields(string(data)) {
+		node, deps, _ := strings.Cut(line, ":")
+		g.AddNode(node, strings.Split(deps, ";"))
+	}
+	return &g, nil
+}
+
+// TestRegister checks that a custom format can be registered and is found
+// by extension.
+func TestRegister(t *testing.T) {
+	graphio.Register("lines", lineFormat{})
+	t.Cleanup(func() { graphio.Unregister("lines") })
+
+	name, _, ok := graphio.ForExtension(".LINES")
+	if !ok || name != "lines" {
+		t.Errorf("Expected lines format for extension, got %q", name)
+	}
+	if _, _, ok := graphio.ForExtension(".nope"); ok {
+		t.Error("Expected no format for unknown extension")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering a format twice")
+		}
+	}()
+	graphio.Register("lines", lineFormat{})
+}
+
+// TestReadWriteFile checks that files are read and written in the format
+// for their extension.
+func TestReadWriteFile(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+
+	dir := t.TempDir()
+	for _, name := range []string{"graph.yml", "graph.json", "graph.csv", "graph.gv"} {
+		path := filepath.Join(dir, name)
+		if err := graphio.WriteFile(path, &g); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		again, err := graphio.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertSameGraph(t, &g, again)
+	}
+
+	if err := graphio.WriteFile(filepath.Join(dir, "graph.xyz"), &g); err == nil {
+		t.Error("Expected error for unknown extension")
+	}
+}
+
+func assertSameGraph(t *testing.T, expected, actual *topo.Graph[string]) {
+	t.Helper()
+	if !reflect.DeepEqual(expected.Nodes(), actual.Nodes()) {
+		t.Errorf("Expected nodes %v, got %v", expected.Nodes(), actual.Nodes())
+	}
+	for _, value := range expected.Nodes() {
+		if !reflect.DeepEqual(expected.Dependencies(value), actual.Dependencies(value)) {
+			t.Errorf("Expected %s deps %v, got %v",
+				value, expected.Dependencies(value), actual.Dependencies(value))
+		}
+	}
+}
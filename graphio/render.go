@@ -0,0 +1,80 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+type charset struct {
+	rule, arrow, cycle string
+}
+
+var (
+	unicodeChars = charset{rule: "─", arrow: "──▶", cycle: "⟲"}
+	asciiChars   = charset{rule: "-", arrow: "-->", cycle: "(cycle)"}
+)
+
+// Render draws the graph for a terminal using Unicode box-drawing
+// characters. Nodes are grouped by layer, each followed by arrows to its
+// dependencies:
+//
+//	── layer 1 ──
+//	  base
+//	  config
+//	── layer 2 ──
+//	  lib  ──▶ base
+//	  tool ──▶ base
+//	── layer 3 ──
+//	  app ──▶ lib, config
+//
+// Graphs with cycles are drawn too; nodes that close a cycle are placed as
+// if the closing edge weren't there, and dependencies that are part of a
+// cycle are marked with ⟲. Values are formatted with fmt.Sprint.
+func Render[T comparable](w io.Writer, g *topo.Graph[T]) error {
+	return render(w, g, unicodeChars)
+}
+
+// RenderASCII is like Render, but draws with ASCII characters only.
+func RenderASCII[T comparable](w io.Writer, g *topo.Graph[T]) error {
+	return render(w, g, asciiChars)
+}
+
+func render[T comparable](w io.Writer, g *topo.Graph[T], chars charset) error {
+	layers, _ := layered(g)
+	inCycle := cyclic(g)
+	rule := strings.Repeat(chars.rule, 2)
+
+	bw := bufio.NewWriter(w)
+	for i, layer := range layers {
+		fmt.Fprintf(bw, "%s layer %d %s\n", rule, i+1, rule)
+
+		// line up the arrows within the layer
+		width := 0
+		for _, value := range layer {
+			width = max(width, utf8.RuneCountInString(fmt.Sprint(value)))
+		}
+		for _, value := range layer {
+			label := fmt.Sprint(value)
+			deps := g.Dependencies(value)
+			if len(deps) == 0 {
+				fmt.Fprintf(bw, "  %s\n", label)
+				continue
+			}
+			names := make([]string, len(deps))
+			for j, dep := range deps {
+				names[j] = fmt.Sprint(dep)
+				if inCycleEdge(inCycle, value, dep) {
+					names[j] += " " + chars.cycle
+				}
+			}
+			pad := strings.Repeat(" ", width-utf8.RuneCountInString(label))
+			fmt.Fprintf(bw, "  %s%s %s %s\n", label, pad, chars.arrow, strings.Join(names, ", "))
+		}
+	}
+	return bw.Flush()
+}
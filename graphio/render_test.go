@@ -0,0 +1,60 @@
+package graphio_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestRender checks the terminal rendering of a graph.
+func TestRender(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "config"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+
+	var buf strings.Builder
+	if err := graphio.Render(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `── layer 1 ──
+  config
+  base
+── layer 2 ──
+  lib  ──▶ base
+  tool ──▶ base
+── layer 3 ──
+  app ──▶ lib, config
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestRenderCycles checks that graphs with cycles are rendered, marking the
+// dependencies that form a cycle.
+func TestRenderCycles(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(1, []int{2})
+	g.AddNode(2, []int{3})
+	g.AddNode(3, []int{1, 4})
+
+	var buf strings.Builder
+	if err := graphio.RenderASCII(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `-- layer 1 --
+  4
+-- layer 2 --
+  3 --> 1 (cycle), 4
+-- layer 3 --
+  2 --> 3 (cycle)
+-- layer 4 --
+  1 --> 2 (cycle)
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
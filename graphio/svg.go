@@ -0,0 +1,94 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// SVG writes graphs as SVG images, laid out in layers from top to bottom
+// with each node's dependencies above it. The layout is computed in Go, in
+// the style of Sugiyama's layered drawings, so no Graphviz installation is
+// needed. It can only be written, not read.
+var SVG svgFormat
+
+type svgFormat struct{}
+
+// svgSelfLoop is how far the loop of a node depending on itself sticks out.
+const svgSelfLoop = 30
+
+// Extensions implements Format.
+func (svgFormat) Extensions() []string {
+	return []string{".svg"}
+}
+
+// Write implements Writer.
+func (svgFormat) Write(w io.Writer, g *topo.Graph[string]) error {
+	boxes, layers, edges := layoutGraph(g)
+
+	width, height := 0.0, 0.0
+	for _, box := range boxes {
+		width = max(width, box.x+box.width)
+	}
+	for _, edge := range edges {
+		if len(edge.chain) == 2 && edge.chain[0] == edge.chain[1] {
+			box := &boxes[edge.chain[0]]
+			width = max(width, box.x+box.width+svgSelfLoop)
+		}
+	}
+	if len(layers) > 0 {
+		height = layerY(len(layers)-1) + boxHeight
+	}
+	width += margin
+	height += margin
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n",
+		width, height, width, height)
+	fmt.Fprintln(bw, `<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#666"/></marker></defs>`)
+	fmt.Fprintln(bw, `<style>.node rect{fill:#fff;stroke:#444}.node text{font:12px monospace;text-anchor:middle;dominant-baseline:central}.edge{fill:none;stroke:#666;marker-end:url(#arrow)}</style>`)
+
+	for _, edge := range edges {
+		fmt.Fprintf(bw, `<path class="edge" d="%s"/>`+"\n", svgPath(boxes, edge.chain))
+	}
+	for _, box := range boxes {
+		if box.dummy {
+			continue
+		}
+		y := layerY(box.layer)
+		fmt.Fprintf(bw, `<g class="node"><rect x="%.1f" y="%.1f" width="%.1f" height="%d" rx="4"/><text x="%.1f" y="%.1f">%s</text></g>`+"\n",
+			box.x, y, box.width, boxHeight, box.center(), y+boxHeight/2, html.EscapeString(box.label))
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// svgPath draws an edge through its chain of boxes, ending in an arrow at
+// the dependency.
+func svgPath(boxes []layoutBox, chain []int) string {
+	first, last := &boxes[chain[0]], &boxes[chain[len(chain)-1]]
+	if len(chain) == 2 && chain[0] == chain[1] {
+		// a node depending on itself loops around its right side
+		x, y := first.x+first.width, layerY(first.layer)
+		return fmt.Sprintf("M%.1f,%.1f C%.1f,%.1f %.1f,%.1f %.1f,%.1f",
+			x, y+6, x+svgSelfLoop, y-10, x+svgSelfLoop, y+boxHeight+10, x, y+boxHeight-6)
+	}
+	if first.layer == last.layer {
+		// nodes in the same layer are joined by an arc above them
+		y := layerY(first.layer)
+		return fmt.Sprintf("M%.1f,%.1f C%.1f,%.1f %.1f,%.1f %.1f,%.1f",
+			first.center(), y, first.center(), y-layerGap/2, last.center(), y-layerGap/2, last.center(), y)
+	}
+
+	points := edgePoints(boxes, chain)
+	d := fmt.Sprintf("M%.1f,%.1f", points[0].x, points[0].y)
+	for i := 1; i < len(points); i++ {
+		p, q := points[i-1], points[i]
+		mid := (p.y + q.y) / 2
+		d += fmt.Sprintf(" C%.1f,%.1f %.1f,%.1f %.1f,%.1f", p.x, mid, q.x, mid, q.x, q.y)
+	}
+	return d
+}
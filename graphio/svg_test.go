@@ -0,0 +1,123 @@
+package graphio_test
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+type svgRect struct {
+	X, Y, Width float64
+	Label       string
+}
+
+// parseSVG checks that the output is well-formed XML and returns its node
+// boxes and the number of edges.
+func parseSVG(t *testing.T, data string) ([]svgRect, int) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(data))
+	var rects []svgRect
+	edges := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, data)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rect":
+			var r struct {
+				X     float64 `xml:"x,attr"`
+				Y     float64 `xml:"y,attr"`
+				Width float64 `xml:"width,attr"`
+			}
+			if err := dec.DecodeElement(&r, &start); err != nil {
+				t.Fatal(err)
+			}
+			rects = append(rects, svgRect{X: r.X, Y: r.Y, Width: r.Width})
+		case "text":
+			var text string
+			if err := dec.DecodeElement(&text, &start); err != nil {
+				t.Fatal(err)
+			}
+			rects[len(rects)-1].Label = text
+		case "path":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" && strings.Contains(attr.Value, "edge") {
+					edges++
+				}
+			}
+		}
+	}
+	return rects, edges
+}
+
+// TestSVG checks that every node and edge is drawn, with nodes in layers
+// from top to bottom that don't overlap.
+func TestSVG(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "config", "base"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("a<b", []string{"a<b"})
+	g.AddNode("x", []string{"y"})
+	g.AddNode("y", []string{"x"})
+
+	var buf strings.Builder
+	if err := graphio.SVG.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rects, edges := parseSVG(t, buf.String())
+
+	if len(rects) != len(g.Nodes()) {
+		t.Errorf("Expected %d nodes, got %d", len(g.Nodes()), len(rects))
+	}
+	if edges != 8 {
+		t.Errorf("Expected 8 edges, got %d", edges)
+	}
+	y := make(map[string]float64)
+	for i, a := range rects {
+		y[a.Label] = a.Y
+		for _, b := range rects[i+1:] {
+			if a.Y == b.Y && a.X < b.X+b.Width && b.X < a.X+a.Width {
+				t.Errorf("Nodes %q and %q overlap", a.Label, b.Label)
+			}
+		}
+	}
+	if !(y["base"] < y["lib"] && y["lib"] < y["app"]) {
+		t.Errorf("Expected dependencies above their dependents, got %v", y)
+	}
+}
+
+// TestSVGCrossings checks that nodes are reordered to untangle edges.
+func TestSVGCrossings(t *testing.T) {
+	// in insertion order, a1-b2 and a2-b1 would cross
+	var g topo.Graph[string]
+	g.AddNode("a1", nil)
+	g.AddNode("a2", nil)
+	g.AddNode("b1", []string{"a2"})
+	g.AddNode("b2", []string{"a1"})
+
+	var buf strings.Builder
+	if err := graphio.SVG.Write(&buf, &g); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rects, _ := parseSVG(t, buf.String())
+	x := make(map[string]float64)
+	for _, r := range rects {
+		x[r.Label] = r.X
+	}
+	if (x["a1"] < x["a2"]) != (x["b2"] < x["b1"]) {
+		t.Errorf("Expected edges not to cross, got positions %v", x)
+	}
+}
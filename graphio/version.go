@@ -0,0 +1,105 @@
+package graphio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SchemaVersion is the version of the definition file schema this package
+// writes, in each file's "version" field. Files from before versions were
+// written have none, and are read as version 0.
+const SchemaVersion = 1
+
+// ErrUnsupportedVersion is returned when reading a definition file written
+// with a newer schema than this package knows.
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// Migration upgrades a definition file, decoded as it would be into an
+// any by encoding/json, from one schema version to the next.
+type Migration func(doc map[string]any) error
+
+// migrations upgrade definition files from each version to the next. When
+// the schema changes, SchemaVersion goes up, and a migration from the
+// version before is added here, so that files kept for years still load.
+var migrations = map[int]Migration{
+	// version 0 had the same schema, without the version
+	0: func(map[string]any) error { return nil },
+}
+
+// Migrate upgrades a definition file, decoded as it would be into an any by
+// encoding/json, to SchemaVersion, applying the migration from each
+// version in turn, and returns its version before.
+func Migrate(doc map[string]any) (int, error) {
+	version, err := docVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return version, fmt.Errorf("%w: %d, newest known is %d", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+	for v := version; v < SchemaVersion; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return version, fmt.Errorf("%w: no migration from %d", ErrUnsupportedVersion, v)
+		}
+		if err := migrate(doc); err != nil {
+			return version, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	doc["version"] = SchemaVersion
+	return version, nil
+}
+
+// docVersion returns the version of a decoded definition file.
+func docVersion(doc map[string]any) (int, error) {
+	var version float64
+	switch v := doc["version"].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		version = v
+	case int:
+		version = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %v", v)
+		}
+		version = f
+	default:
+		return 0, fmt.Errorf("invalid version %v", v)
+	}
+	if version < 0 || version != math.Trunc(version) {
+		return 0, fmt.Errorf("invalid version %v", version)
+	}
+	return int(version), nil
+}
+
+// decodeVersioned decodes a definition file into doc, migrating it first if
+// it's older than SchemaVersion. decode decodes the file into a value, as
+// json.Unmarshal does.
+func decodeVersioned(decode func(v any) error, doc *Document) error {
+	var raw map[string]any
+	if err := decode(&raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+	version, err := Migrate(raw)
+	if err != nil {
+		return err
+	}
+	if version == SchemaVersion {
+		return decode(doc)
+	}
+	// decode the migrated file through JSON, which the schema's tags match
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(doc)
+}
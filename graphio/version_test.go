@@ -0,0 +1,112 @@
+package graphio_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/graphio"
+)
+
+// TestReadVersions checks reading definitions of each schema version.
+func TestReadVersions(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		yaml  string
+		err   error
+		valid bool
+	}{
+		{
+			name:  "unversioned",
+			json:  `{"nodes": [{"id": "app", "deps": ["lib"], "duration": "1s"}]}`,
+			yaml:  "nodes:\n  - id: app\n    deps: [lib]\n    duration: 1s\n",
+			valid: true,
+		},
+		{
+			name:  "current",
+			json:  `{"version": 1, "nodes": [{"id": "app", "deps": ["lib"], "duration": "1s"}]}`,
+			yaml:  "version: 1\nnodes:\n  - id: app\n    deps: [lib]\n    duration: 1s\n",
+			valid: true,
+		},
+		{
+			name: "newer",
+			json: `{"version": 2, "nodes": []}`,
+			yaml: "version: 2\nnodes: []\n",
+			err:  graphio.ErrUnsupportedVersion,
+		},
+		{
+			name: "invalid",
+			json: `{"version": "one", "nodes": []}`,
+			yaml: "version: 1.5\nnodes: []\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromJSON, jsonErr := graphio.ReadJSON(strings.NewReader(tt.json))
+			fromYAML, yamlErr := graphio.ReadYAML(strings.NewReader(tt.yaml))
+			for _, err := range []error{jsonErr, yamlErr} {
+				switch {
+				case tt.valid && err != nil:
+					t.Errorf("Unexpected error: %v", err)
+				case !tt.valid && err == nil:
+					t.Error("Expected an error")
+				case tt.err != nil && !errors.Is(err, tt.err):
+					t.Errorf("Expected error %v, got %v", tt.err, err)
+				}
+			}
+			if !tt.valid {
+				return
+			}
+			for _, def := range []*graphio.Definition{fromJSON, fromYAML} {
+				if deps := def.Graph.Dependencies("app"); len(deps) != 1 || deps[0] != "lib" {
+					t.Errorf("Expected [lib], got %v", deps)
+				}
+				if d := def.Durations["app"]; d.Seconds() != 1 {
+					t.Errorf("Expected 1s, got %v", d)
+				}
+			}
+		})
+	}
+}
+
+// TestWriteVersion checks that definitions are written with the current
+// schema version.
+func TestWriteVersion(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", nil)
+	tests := []struct {
+		format   graphio.Format
+		expected string
+	}{
+		{graphio.JSON, `"version": 1,`},
+		{graphio.YAML, "version: 1\n"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := tt.format.(graphio.Writer).Write(&buf, &g); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), tt.expected) {
+			t.Errorf("Expected %q in %q", tt.expected, buf.String())
+		}
+	}
+}
+
+// TestMigrate checks upgrading decoded definitions.
+func TestMigrate(t *testing.T) {
+	doc := map[string]any{"nodes": []any{}}
+	version, err := graphio.Migrate(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != 0 || doc["version"] != graphio.SchemaVersion {
+		t.Errorf("Expected version 0 migrated to %d, got %d and %v", graphio.SchemaVersion, version, doc["version"])
+	}
+
+	doc = map[string]any{"version": float64(graphio.SchemaVersion + 1)}
+	if _, err := graphio.Migrate(doc); !errors.Is(err, graphio.ErrUnsupportedVersion) {
+		t.Errorf("Expected error %v, got %v", graphio.ErrUnsupportedVersion, err)
+	}
+}
@@ -0,0 +1,81 @@
+package topo
+
+import "container/heap"
+
+// SortByLayersGrouped is like SortByLayers, but orders the nodes within
+// each layer by group, as returned by the group function, so that each
+// group's nodes are together. Groups are ordered by when their first node
+// was added to the graph, and nodes within a group keep their order.
+// Grouping by team, repository, or environment makes plans easier to read
+// by their owners.
+func (g *Graph[T]) SortByLayersGrouped(group func(T) string) ([][]T, error) {
+	index := g.groupIndexes(group)
+	return g.SortByLayersFunc(func(a, b T) int {
+		return index[group(a)] - index[group(b)]
+	})
+}
+
+// SortGrouped returns every value in the graph in a single order where
+// each value comes after its dependencies, keeping the values of each
+// group, as returned by the group function, together wherever dependencies
+// allow. It stays with one group while any of its values are ready, and
+// then moves to the group with the earliest-added ready value. Within a
+// group, values keep the order they were first added in, as in SortStable.
+func (g *Graph[T]) SortGrouped(group func(T) string) ([]T, error) {
+	order, dependsOn := g.edges()
+	index := g.groupIndexes(group)
+	waiting := make(map[T]int, len(order))
+	dependedOnBy := make(map[T][]T)
+	ready := make([]positions, len(index))
+	for i, value := range order {
+		deps := dependsOn[value]
+		for _, dep := range deps {
+			dependedOnBy[dep] = append(dependedOnBy[dep], value)
+		}
+		waiting[value] = len(deps)
+		if len(deps) == 0 {
+			heap.Push(&ready[index[group(value)]], i)
+		}
+	}
+	position := g.positions()
+
+	result := make([]T, 0, len(order))
+	current := -1
+	for {
+		if current < 0 || ready[current].Len() == 0 {
+			current = -1
+			for i, r := range ready {
+				if r.Len() > 0 && (current < 0 || r[0] < ready[current][0]) {
+					current = i
+				}
+			}
+			if current < 0 {
+				break
+			}
+		}
+		value := order[heap.Pop(&ready[current]).(int)]
+		result = append(result, value)
+		for _, dependent := range dependedOnBy[value] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				heap.Push(&ready[index[group(dependent)]], position[dependent])
+			}
+		}
+	}
+	if len(result) < len(order) {
+		return nil, ErrCyclicDependency
+	}
+	return result, nil
+}
+
+// groupIndexes numbers the groups of the graph's values, in the order they
+// first appear.
+func (g *Graph[T]) groupIndexes(group func(T) string) map[string]int {
+	index := make(map[string]int)
+	for _, value := range g.Nodes() {
+		if _, ok := index[group(value)]; !ok {
+			index[group(value)] = len(index)
+		}
+	}
+	return index
+}
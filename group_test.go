@@ -0,0 +1,60 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// team groups values like "web/app" by the part before the slash.
+func team(value string) string {
+	name, _, _ := strings.Cut(value, "/")
+	return name
+}
+
+// groupGraph returns a graph of values owned by several teams.
+func groupGraph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	g.AddNode("web/app", []string{"db/postgres", "web/lib"})
+	g.AddNode("db/postgres", nil)
+	g.AddNode("web/lib", []string{"base/os"})
+	g.AddNode("db/backup", []string{"db/postgres"})
+	return &g
+}
+
+// TestSortByLayersGrouped checks that each layer keeps groups together.
+func TestSortByLayersGrouped(t *testing.T) {
+	layers, err := groupGraph().SortByLayersGrouped(team)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{
+		{"db/postgres", "base/os"},
+		{"web/lib", "db/backup"},
+		{"web/app"},
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestSortGrouped checks that a single order keeps groups together.
+func TestSortGrouped(t *testing.T) {
+	g := groupGraph()
+	order, err := g.SortGrouped(team)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"db/postgres", "db/backup", "base/os", "web/lib", "web/app"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected %v, got %v", expected, order)
+	}
+
+	g.AddNode("base/os", []string{"web/app"})
+	if _, err := g.SortGrouped(team); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
@@ -0,0 +1,98 @@
+package topo
+
+import (
+	"maps"
+	"slices"
+)
+
+// history is the undo and redo stacks of a graph with EnableHistory.
+type history[T comparable] struct {
+	limit int
+	undo  []snapshot[T]
+	redo  []snapshot[T]
+}
+
+// snapshot is the state of a graph at one point in its history.
+type snapshot[T comparable] struct {
+	nodes []node[T]
+	pins  map[T]layerPin
+	attrs map[T]map[string]string
+}
+
+// EnableHistory starts recording changes to the graph, so that they can be
+// undone with Undo and redone with Redo, as an editor would. Only the last
+// limit changes are kept, or every change if limit is zero. Each call to a
+// method that changes the graph, including Apply and Changeset.ApplyTo, is
+// one change. Enabling history again clears it.
+func (g *Graph[T]) EnableHistory(limit int) {
+	g.history = &history[T]{limit: limit}
+}
+
+// DisableHistory stops recording changes and forgets those recorded.
+func (g *Graph[T]) DisableHistory() {
+	g.history = nil
+}
+
+// Undo reverts the last change to the graph, reporting whether there was
+// one to undo. Observers are told of what it changes, as a Changeset would
+// do it.
+func (g *Graph[T]) Undo() bool {
+	h := g.history
+	if h == nil || len(h.undo) == 0 {
+		return false
+	}
+	h.redo = append(h.redo, g.snapshot())
+	g.restore(h.undo[len(h.undo)-1])
+	h.undo = h.undo[:len(h.undo)-1]
+	return true
+}
+
+// Redo makes the last change undone by Undo again, reporting whether there
+// was one to redo. Any other change to the graph clears what can be redone.
+func (g *Graph[T]) Redo() bool {
+	h := g.history
+	if h == nil || len(h.redo) == 0 {
+		return false
+	}
+	h.undo = append(h.undo, g.snapshot())
+	g.restore(h.redo[len(h.redo)-1])
+	h.redo = h.redo[:len(h.redo)-1]
+	return true
+}
+
+// record saves the state of the graph before a change, if history is
+// enabled.
+func (g *Graph[T]) record() {
+	h := g.history
+	if h == nil {
+		return
+	}
+	h.undo = append(h.undo, g.snapshot())
+	if h.limit > 0 && len(h.undo) > h.limit {
+		h.undo = slices.Delete(h.undo, 0, len(h.undo)-h.limit)
+	}
+	h.redo = nil
+}
+
+// snapshot returns the current state of the graph. Nodes are only ever
+// appended or replaced by new slices, so they can be shared; pins and
+// attributes are changed in place, so they're copied.
+func (g *Graph[T]) snapshot() snapshot[T] {
+	return snapshot[T]{
+		nodes: slices.Clip(g.nodes),
+		pins:  maps.Clone(g.pins),
+		attrs: cloneAttrs(g.attrs, nil),
+	}
+}
+
+// restore returns the graph to an earlier state, notifying observers.
+func (g *Graph[T]) restore(s snapshot[T]) {
+	before := &Graph[T]{nodes: g.nodes}
+	g.nodes = s.nodes
+	g.pins = maps.Clone(s.pins)
+	g.attrs = cloneAttrs(s.attrs, nil)
+	g.invalidate()
+	for _, c := range Diff(before, g) {
+		g.notify(c)
+	}
+}
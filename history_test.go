@@ -0,0 +1,133 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestUndoRedo checks stepping back and forth through changes.
+func TestUndoRedo(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.EnableHistory(0)
+
+	if g.Redo() {
+		t.Errorf("Expected nothing to redo")
+	}
+	g.AddNode("lib", []string{"base"})
+	g.AddDependency("app", "db")
+	g.RemoveNode("base")
+	err := g.Apply(func(tx *topo.Tx[string]) error {
+		tx.AddNode("db", nil)
+		tx.AddNode("cache", nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	states := [][]string{
+		{"app", "lib", "db", "cache"},
+		{"app", "lib", "db"},
+		{"app", "lib", "db", "base"},
+		{"app", "lib", "base"},
+		{"app", "lib"},
+	}
+	for i, expected := range states {
+		if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+			t.Errorf("Expected %v after %d undos, got %v", expected, i, nodes)
+		}
+		if undone := g.Undo(); undone != (i < len(states)-1) {
+			t.Errorf("Expected Undo to return %v after %d undos", !undone, i)
+		}
+	}
+
+	g.Redo()
+	g.Redo()
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected [lib db] after redoing, got %v", deps)
+	}
+
+	// a new change can't be followed by a redo
+	g.AddNode("docs", nil)
+	if g.Redo() {
+		t.Errorf("Expected nothing to redo after a new change")
+	}
+	g.Undo()
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib", "db", "base"}) {
+		t.Errorf("Expected docs undone, got %v", nodes)
+	}
+}
+
+// TestHistoryLimit checks that only the last changes are kept.
+func TestHistoryLimit(t *testing.T) {
+	var g topo.Graph[int]
+	g.EnableHistory(2)
+	for i := range 5 {
+		g.AddNode(i, nil)
+	}
+	undos := 0
+	for g.Undo() {
+		undos++
+	}
+	if undos != 2 {
+		t.Errorf("Expected 2 undos, got %d", undos)
+	}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []int{0, 1, 2}) {
+		t.Errorf("Expected [0 1 2], got %v", nodes)
+	}
+
+	g.DisableHistory()
+	g.AddNode(9, nil)
+	if g.Undo() {
+		t.Errorf("Expected nothing to undo without history")
+	}
+}
+
+// TestUndoObserved checks that observers are told what Undo changes.
+func TestUndoObserved(t *testing.T) {
+	var g topo.Graph[string]
+	g.EnableHistory(0)
+	g.AddNode("app", []string{"lib"})
+	g.PinLayer("app", 3)
+
+	var changes []string
+	g.Observe(func(c topo.Change[string]) { changes = append(changes, c.String()) })
+	g.Undo()
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes from undoing a pin, got %v", changes)
+	}
+	if layers, _ := g.SortByLayers(); len(layers) != 2 {
+		t.Errorf("Expected pin undone, got %v", layers)
+	}
+	g.Undo()
+	expected := []string{"remove dependency app [lib]", "remove node app"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %q, got %q", expected, changes)
+	}
+}
+
+// TestUndoAttrs checks that undoing RemoveNode puts back the node's
+// attributes.
+func TestUndoAttrs(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.SetAttr("app", "owner", "platform")
+	g.EnableHistory(0)
+	g.RemoveNode("app")
+	if attrs := g.Attrs("app"); attrs != nil {
+		t.Errorf("Expected attributes removed, got %v", attrs)
+	}
+
+	expected := map[string]string{"owner": "platform"}
+	g.Undo()
+	if attrs := g.Attrs("app"); !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected %v after Undo, got %v", expected, attrs)
+	}
+	g.Redo()
+	if attrs := g.Attrs("app"); attrs != nil {
+		t.Errorf("Expected attributes removed after Redo, got %v", attrs)
+	}
+}
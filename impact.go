@@ -0,0 +1,85 @@
+package topo
+
+import "slices"
+
+// Impact describes what is affected when a node fails or is removed.
+type Impact[T comparable] struct {
+	// Node is the node whose impact is described.
+	Node T
+	// Direct are the nodes that depend on Node directly.
+	Direct []T
+	// Affected are all the nodes that depend on Node, directly or
+	// transitively, and so can't run without it.
+	Affected []T
+	// LongestChain is the longest path of dependents starting at Node,
+	// which bounds how many steps are held up by it.
+	LongestChain []T
+}
+
+// BlastRadius returns everything that becomes unrunnable if the given node
+// fails or is removed. Direct and Affected are in the order nodes were
+// first added to the graph; their lengths give the counts. If the node
+// isn't in the graph, nothing is affected and LongestChain is empty.
+//
+// In a graph with cycles, LongestChain doesn't go around a cycle more than
+// once.
+func (g *Graph[T]) BlastRadius(value T) Impact[T] {
+	impact := Impact[T]{Node: value}
+	order, dependsOn := g.edges()
+	if !slices.Contains(order, value) {
+		return impact
+	}
+	dependedOnBy := make(map[T][]T)
+	for _, v := range order {
+		for _, dep := range dependsOn[v] {
+			if !slices.Contains(dependedOnBy[dep], v) {
+				dependedOnBy[dep] = append(dependedOnBy[dep], v)
+			}
+		}
+	}
+
+	impact.Direct = slices.DeleteFunc(slices.Clone(dependedOnBy[value]), func(v T) bool {
+		return v == value
+	})
+	impact.Affected = reachable(order, dependedOnBy, []T{value})
+
+	// longest path by depth-first search, memoizing the longest chain from
+	// each node and skipping edges back onto the current path
+	longest := make(map[T][]T)
+	onPath := make(map[T]bool)
+	var chain func(v T) []T
+	chain = func(v T) []T {
+		if c, ok := longest[v]; ok {
+			return c
+		}
+		onPath[v] = true
+		var best []T
+		for _, next := range dependedOnBy[v] {
+			if onPath[next] {
+				continue
+			}
+			if c := chain(next); len(c) > len(best) {
+				best = c
+			}
+		}
+		onPath[v] = false
+		longest[v] = append([]T{v}, best...)
+		return longest[v]
+	}
+	impact.LongestChain = chain(value)
+	return impact
+}
+
+// Orphans returns the nodes that could no longer be built if the given
+// nodes were removed: those that depend on any of them, and so would lose
+// a dependency they require, and then those that depend on those in turn.
+// It answers what breaks if the nodes are removed, where RemoveNode would
+// instead quietly drop the dependencies on them. Unlike BlastRadius, it
+// considers several nodes removed together.
+//
+// Every dependency is taken as required; use ExcludeKinds first to leave
+// out kinds of dependencies that aren't. The given nodes themselves aren't
+// included, and nodes are in the order they were first added.
+func (g *Graph[T]) Orphans(after []T) []T {
+	return g.Descendants(after...)
+}
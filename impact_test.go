@@ -0,0 +1,112 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestBlastRadius checks the impact of removing nodes.
+func TestBlastRadius(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("db", nil)
+	g.AddNode("api", []string{"db"})
+	g.AddNode("worker", []string{"db"})
+	g.AddNode("web", []string{"api"})
+	g.AddNode("e2e", []string{"web", "worker"})
+	g.AddNode("docs", nil)
+
+	tests := []struct {
+		node     string
+		expected topo.Impact[string]
+	}{
+		{
+			node: "db",
+			expected: topo.Impact[string]{
+				Node:         "db",
+				Direct:       []string{"api", "worker"},
+				Affected:     []string{"api", "worker", "web", "e2e"},
+				LongestChain: []string{"db", "api", "web", "e2e"},
+			},
+		},
+		{
+			node: "worker",
+			expected: topo.Impact[string]{
+				Node:         "worker",
+				Direct:       []string{"e2e"},
+				Affected:     []string{"e2e"},
+				LongestChain: []string{"worker", "e2e"},
+			},
+		},
+		{
+			node: "docs",
+			expected: topo.Impact[string]{
+				Node:         "docs",
+				LongestChain: []string{"docs"},
+			},
+		},
+		{
+			node:     "unknown",
+			expected: topo.Impact[string]{Node: "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			impact := g.BlastRadius(tt.node)
+			if !reflect.DeepEqual(impact, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, impact)
+			}
+		})
+	}
+}
+
+// TestBlastRadiusCycle checks that chains don't loop forever in cycles.
+func TestBlastRadiusCycle(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(2, []int{1, 3})
+	g.AddNode(3, []int{2})
+	g.AddNode(4, []int{3})
+
+	impact := g.BlastRadius(1)
+	if expected := []int{2, 3, 4}; !reflect.DeepEqual(impact.Affected, expected) {
+		t.Errorf("Expected affected %v, got %v", expected, impact.Affected)
+	}
+	if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(impact.LongestChain, expected) {
+		t.Errorf("Expected longest chain %v, got %v", expected, impact.LongestChain)
+	}
+}
+
+// TestOrphans checks what can't be built after removing nodes.
+func TestOrphans(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("db", nil)
+	g.AddNode("cache", nil)
+	g.AddNode("api", []string{"db"})
+	g.AddNodeOfKind("api", "optional", []string{"cache"})
+	g.AddNode("web", []string{"api"})
+	g.AddNode("e2e", []string{"web"})
+	g.AddNode("docs", nil)
+
+	tests := []struct {
+		name     string
+		graph    *topo.Graph[string]
+		after    []string
+		expected []string
+	}{
+		{"one", &g, []string{"db"}, []string{"api", "web", "e2e"}},
+		{"several", &g, []string{"api", "web"}, []string{"e2e"}},
+		{"none affected", &g, []string{"docs"}, nil},
+		{"unknown", &g, []string{"unknown"}, nil},
+		{"every kind required", &g, []string{"cache"}, []string{"api", "web", "e2e"}},
+		{"optional kind excluded", g.ExcludeKinds("optional"), []string{"cache"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if orphans := tt.graph.Orphans(tt.after); !reflect.DeepEqual(orphans, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, orphans)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Package bazel builds dependency graphs from the output of Bazel and Buck
+// queries.
+//
+// Graphs can be read from the DOT output of "bazel query --output=graph"
+// or "buck2 query --output-format=dot", or from the output of
+// "bazel query --output=streamed_proto". Nodes are target labels, such as
+// "//app:server".
+package bazel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/internal/dot"
+)
+
+// ParseGraph reads a query result in DOT format.
+//
+// Bazel factors targets with identical dependencies into a single node
+// whose name lists them separated by "\n"; these are split back into
+// separate targets. Bazel also truncates node names in large graphs, so
+// queries should be run with --graph:node_limit=-1.
+func ParseGraph(r io.Reader) (*topo.Graph[string], error) {
+	parsed, err := dot.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing query graph: %w", err)
+	}
+
+	b := newBuilder()
+	for _, node := range parsed.Nodes {
+		for _, label := range strings.Split(node, `\n`) {
+			b.addTarget(label)
+		}
+	}
+	for _, edge := range parsed.Edges {
+		for _, from := range strings.Split(edge.From, `\n`) {
+			for _, to := range strings.Split(edge.To, `\n`) {
+				b.addDep(from, to)
+			}
+		}
+	}
+	return b.graph(), nil
+}
+
+// ParseStreamedProto reads a query result in the streamed_proto format:
+// a sequence of length-delimited Target messages.
+//
+// Rules depend on their inputs, and generated files depend on the rule
+// that generates them. Source files have no dependencies.
+func ParseStreamedProto(r io.Reader) (*topo.Graph[string], error) {
+	br := bufio.NewReader(r)
+	b := newBuilder()
+	for {
+		size, err := readVarint(br)
+		if errors.Is(err, io.EOF) {
+			return b.graph(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading target: %w", err)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			return nil, fmt.Errorf("reading target: %w", err)
+		}
+		if err := b.addProtoTarget(msg); err != nil {
+			return nil, fmt.Errorf("decoding target: %w", err)
+		}
+	}
+}
+
+// Field numbers from Bazel's build.proto.
+const (
+	targetRule           = 2
+	targetSourceFile     = 3
+	targetGeneratedFile  = 4
+	targetPackageGroup   = 5
+	targetEnvGroup       = 6
+	ruleName             = 1
+	ruleInput            = 5
+	fileName             = 1
+	generatedFileRuleRef = 2
+)
+
+func (b *builder) addProtoTarget(msg []byte) error {
+	return eachField(msg, func(num int, value []byte) error {
+		switch num {
+		case targetRule:
+			var name string
+			var inputs []string
+			err := eachField(value, func(num int, value []byte) error {
+				switch num {
+				case ruleName:
+					name = string(value)
+				case ruleInput:
+					inputs = append(inputs, string(value))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			b.addTarget(name)
+			for _, input := range inputs {
+				b.addDep(name, input)
+			}
+		case targetGeneratedFile:
+			var name, rule string
+			err := eachField(value, func(num int, value []byte) error {
+				switch num {
+				case fileName:
+					name = string(value)
+				case generatedFileRuleRef:
+					rule = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			b.addTarget(name)
+			if rule != "" {
+				b.addDep(name, rule)
+			}
+		case targetSourceFile, targetPackageGroup, targetEnvGroup:
+			return eachField(value, func(num int, value []byte) error {
+				if num == fileName {
+					b.addTarget(string(value))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+// eachField calls fn for every length-delimited field of a protobuf
+// message, skipping fields of other wire types.
+func eachField(msg []byte, fn func(num int, value []byte) error) error {
+	r := bytes.NewReader(msg)
+	for {
+		key, err := readVarint(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		num, wireType := int(key>>3), key&7
+		switch wireType {
+		case 0:
+			if _, err := readVarint(r); err != nil {
+				return err
+			}
+		case 1:
+			if _, err := r.Seek(8, io.SeekCurrent); err != nil {
+				return err
+			}
+		case 2:
+			size, err := readVarint(r)
+			if err != nil {
+				return err
+			}
+			value := make([]byte, size)
+			if _, err := io.ReadFull(r, value); err != nil {
+				return err
+			}
+			if err := fn(num, value); err != nil {
+				return err
+			}
+		case 5:
+			if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+	}
+}
+
+// readVarint reads a protobuf base-128 varint. It returns io.EOF only if
+// no bytes could be read.
+func readVarint(r io.ByteReader) (uint64, error) {
+	var x uint64
+	for shift := 0; shift < 64; shift += 7 {
+		c, err := r.ReadByte()
+		if err != nil {
+			if shift > 0 && errors.Is(err, io.EOF) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		x |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return x, nil
+		}
+	}
+	return 0, errors.New("varint overflow")
+}
+
+// builder accumulates targets and dependencies in order of appearance.
+type builder struct {
+	order []string
+	deps  map[string][]string
+}
+
+func newBuilder() *builder {
+	return &builder{deps: make(map[string][]string)}
+}
+
+func (b *builder) addTarget(label string) {
+	if _, exists := b.deps[label]; !exists {
+		b.deps[label] = nil
+		b.order = append(b.order, label)
+	}
+}
+
+func (b *builder) addDep(from, to string) {
+	b.addTarget(from)
+	b.addTarget(to)
+	if from != to && !slices.Contains(b.deps[from], to) {
+		b.deps[from] = append(b.deps[from], to)
+	}
+}
+
+func (b *builder) graph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	for _, label := range b.order {
+		g.AddNode(label, b.deps[label])
+	}
+	return &g
+}
@@ -0,0 +1,96 @@
+package bazel_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/bazel"
+)
+
+// TestParseGraph checks reading factored DOT query output.
+func TestParseGraph(t *testing.T) {
+	const graph = `digraph mygraph {
+  node [shape=box];
+  "//app:server"
+  "//app:server" -> "//lib:http\n//lib:json"
+  "//lib:http\n//lib:json" -> "//base:core"
+  "//base:core"
+}
+`
+	g, err := bazel.ParseGraph(strings.NewReader(graph))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkLayers(t, g, [][]string{
+		{"//base:core"},
+		{"//lib:http", "//lib:json"},
+		{"//app:server"},
+	})
+}
+
+// TestParseStreamedProto checks decoding streamed Target messages.
+func TestParseStreamedProto(t *testing.T) {
+	var buf bytes.Buffer
+	writeTarget(&buf, 3, message(field(1, "//lib:core.go")))
+	writeTarget(&buf, 2, message(field(1, "//lib:core"), field(2, "go_library"), field(5, "//lib:core.go")))
+	writeTarget(&buf, 2, message(field(1, "//lib:gen"), field(5, "//lib:core")))
+	writeTarget(&buf, 4, message(field(1, "//lib:gen.txt"), field(2, "//lib:gen")))
+	writeTarget(&buf, 2, message(field(1, "//app:bin"), field(5, "//lib:gen.txt"), field(5, "//lib:core")))
+
+	g, err := bazel.ParseStreamedProto(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkLayers(t, g, [][]string{
+		{"//lib:core.go"},
+		{"//lib:core"},
+		{"//lib:gen"},
+		{"//lib:gen.txt"},
+		{"//app:bin"},
+	})
+}
+
+// writeTarget writes a length-delimited Target message with the given
+// discriminator and body field.
+func writeTarget(buf *bytes.Buffer, kind int, body []byte) {
+	target := append([]byte{1 << 3, byte(kind - 1)}, fieldBytes(kind, body)...)
+	buf.Write(varint(len(target)))
+	buf.Write(target)
+}
+
+func message(fields ...[]byte) []byte {
+	return bytes.Join(fields, nil)
+}
+
+func field(num int, value string) []byte {
+	return fieldBytes(num, []byte(value))
+}
+
+func fieldBytes(num int, value []byte) []byte {
+	b := varint(num<<3 | 2)
+	b = append(b, varint(len(value))...)
+	return append(b, value...)
+}
+
+func varint(x int) []byte {
+	var b []byte
+	for x >= 0x80 {
+		b = append(b, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(b, byte(x))
+}
+
+func checkLayers(t *testing.T, g *topo.Graph[string], expected [][]string) {
+	t.Helper()
+	layers, err := topo.SortedLayers(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
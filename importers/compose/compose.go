@@ -0,0 +1,167 @@
+// Package compose builds dependency graphs from docker-compose files.
+//
+// Services become nodes and their depends_on entries become dependencies,
+// so the layers produced by [topo.Graph.SortByLayers] give a valid startup
+// ordering for the project.
+package compose
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrUndefinedService is returned when a service depends on a service that
+// is not defined in the file.
+var ErrUndefinedService = errors.New("undefined service")
+
+// Condition is the state a dependency must reach before the dependent
+// service may start.
+type Condition string
+
+// The conditions supported by the depends_on long syntax.
+const (
+	ServiceStarted               Condition = "service_started"
+	ServiceHealthy               Condition = "service_healthy"
+	ServiceCompletedSuccessfully Condition = "service_completed_successfully"
+)
+
+// Dependency is a single depends_on entry of a service.
+type Dependency struct {
+	Service   string
+	Condition Condition
+	Required  bool
+}
+
+// Project holds the services of a compose file and their dependencies.
+type Project struct {
+	// Services maps each service name to its dependencies.
+	Services map[string][]Dependency
+}
+
+// file mirrors the subset of the compose file format we care about.
+type file struct {
+	Services map[string]struct {
+		DependsOn dependsOn `yaml:"depends_on"`
+	} `yaml:"services"`
+}
+
+// dependsOn accepts both the short (list) and long (map) syntax.
+type dependsOn []Dependency
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *dependsOn) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		var names []string
+		if err := value.Decode(&names); err != nil {
+			return err
+		}
+		for _, name := range names {
+			*d = append(*d, Dependency{
+				Service:   name,
+				Condition: ServiceStarted,
+				Required:  true,
+			})
+		}
+	case yaml.MappingNode:
+		var entries map[string]struct {
+			Condition Condition `yaml:"condition"`
+			Required  *bool     `yaml:"required"`
+		}
+		if err := value.Decode(&entries); err != nil {
+			return err
+		}
+		for name, entry := range entries {
+			dep := Dependency{
+				Service:   name,
+				Condition: entry.Condition,
+				Required:  entry.Required == nil || *entry.Required,
+			}
+			if dep.Condition == "" {
+				dep.Condition = ServiceStarted
+			}
+			*d = append(*d, dep)
+		}
+		slices.SortFunc(*d, func(a, b Dependency) int {
+			return cmp.Compare(a.Service, b.Service)
+		})
+	default:
+		return fmt.Errorf("line %d: depends_on must be a list or a map", value.Line)
+	}
+	return nil
+}
+
+// Parse reads a compose file.
+//
+// Dependencies on services that are not defined in the file are an error,
+// unless they are marked as not required, in which case they are dropped.
+func Parse(r io.Reader) (*Project, error) {
+	var f file
+	if err := yaml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("parsing compose file: %w", err)
+	}
+
+	p := &Project{Services: make(map[string][]Dependency, len(f.Services))}
+	for name, svc := range f.Services {
+		var deps []Dependency
+		for _, dep := range svc.DependsOn {
+			if _, exists := f.Services[dep.Service]; !exists {
+				if !dep.Required {
+					continue
+				}
+				return nil, fmt.Errorf("%w: %s depends on %s",
+					ErrUndefinedService, name, dep.Service)
+			}
+			deps = append(deps, dep)
+		}
+		p.Services[name] = deps
+	}
+	return p, nil
+}
+
+// Graph returns the startup dependency graph of the project.
+func (p *Project) Graph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	for _, name := range p.names() {
+		deps := make([]string, 0, len(p.Services[name]))
+		for _, dep := range p.Services[name] {
+			deps = append(deps, dep.Service)
+		}
+		g.AddNode(name, deps)
+	}
+	return &g
+}
+
+// HealthGates returns, for each service, the dependencies that must report
+// healthy (rather than merely started) before the service may start.
+//
+// Services without such dependencies are omitted.
+func (p *Project) HealthGates() map[string][]string {
+	gates := make(map[string][]string)
+	for _, name := range p.names() {
+		for _, dep := range p.Services[name] {
+			if dep.Condition == ServiceHealthy {
+				gates[name] = append(gates[name], dep.Service)
+			}
+		}
+	}
+	return gates
+}
+
+// names returns the service names in sorted order, so that graphs built
+// from the same file are always built the same way.
+func (p *Project) names() []string {
+	names := make([]string, 0, len(p.Services))
+	for name := range p.Services {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
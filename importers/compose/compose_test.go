@@ -0,0 +1,90 @@
+package compose_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/compose"
+)
+
+const project = `
+services:
+  db:
+    image: postgres
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+  cache:
+    image: redis
+  migrate:
+    depends_on:
+      db:
+        condition: service_healthy
+  api:
+    depends_on:
+      db:
+        condition: service_healthy
+      migrate:
+        condition: service_completed_successfully
+      cache:
+        condition: service_started
+  web:
+    depends_on:
+      - api
+`
+
+// TestParse checks that both depends_on syntaxes produce the expected graph.
+func TestParse(t *testing.T) {
+	p, err := compose.Parse(strings.NewReader(project))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	layers, err := topo.SortedLayers(p.Graph())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"cache", "db"}, {"migrate"}, {"api"}, {"web"}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+
+	gates := p.HealthGates()
+	expectedGates := map[string][]string{
+		"api":     {"db"},
+		"migrate": {"db"},
+	}
+	if !reflect.DeepEqual(gates, expectedGates) {
+		t.Errorf("Expected gates %v, got %v", expectedGates, gates)
+	}
+}
+
+// TestParseUndefinedService checks handling of dependencies on services
+// that don't exist.
+func TestParseUndefinedService(t *testing.T) {
+	_, err := compose.Parse(strings.NewReader(`
+services:
+  web:
+    depends_on: [api]
+`))
+	if !errors.Is(err, compose.ErrUndefinedService) {
+		t.Errorf("Expected error %v, got %v", compose.ErrUndefinedService, err)
+	}
+
+	p, err := compose.Parse(strings.NewReader(`
+services:
+  web:
+    depends_on:
+      api:
+        condition: service_started
+        required: false
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := p.Services["web"]; len(deps) != 0 {
+		t.Errorf("Expected optional dependency to be dropped, got %v", deps)
+	}
+}
@@ -0,0 +1,288 @@
+// Package dockerfile builds dependency graphs from trees of Dockerfiles.
+//
+// Each Dockerfile produces one image, and the images it references in FROM
+// instructions and COPY --from flags become its dependencies, provided they
+// are themselves built from a Dockerfile in the tree.
+package dockerfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrDuplicateImage is returned by Scan when two Dockerfiles build images
+// with the same name.
+var ErrDuplicateImage = errors.New("duplicate image")
+
+// Image is an image built from a Dockerfile found while scanning.
+type Image struct {
+	// Name is the name of the image.
+	Name string
+	// Path is the directory containing the Dockerfile.
+	Path string
+	// Dockerfile is the path to the Dockerfile itself.
+	Dockerfile string
+	// Dependencies are the names of the other local images this image is
+	// built from.
+	Dependencies []string
+}
+
+// NameFunc returns the name of the image built by the Dockerfile at the
+// given path.
+type NameFunc func(dockerfile string) string
+
+// DefaultName names images after the directory containing their Dockerfile,
+// or for files named like "app.Dockerfile", after the file itself. Names
+// are lowercased, as the references Parse returns are.
+func DefaultName(dockerfile string) string {
+	base := path.Base(dockerfile)
+	if name, ok := strings.CutSuffix(base, ".Dockerfile"); ok {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(path.Base(path.Dir(dockerfile)))
+}
+
+// Scan walks fsys starting at root, parsing every file named "Dockerfile" or
+// ending in ".Dockerfile". Images are returned in the order their
+// Dockerfiles were found. Two Dockerfiles naming the same image are
+// reported with ErrDuplicateImage.
+//
+// If name is nil, [DefaultName] is used.
+func Scan(fsys fs.FS, root string, name NameFunc) ([]Image, error) {
+	if name == nil {
+		name = DefaultName
+	}
+
+	var images []Image
+	refs := make(map[string][]string)
+	// found holds the Dockerfile of each image, by name
+	found := make(map[string]string)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isDockerfile(d.Name()) {
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		imageRefs, err := Parse(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		img := Image{
+			Name:       name(p),
+			Path:       path.Dir(p),
+			Dockerfile: p,
+		}
+		if other, exists := found[img.Name]; exists {
+			return fmt.Errorf("%w: %s (%s and %s)", ErrDuplicateImage, img.Name, other, p)
+		}
+		found[img.Name] = p
+		refs[img.Name] = imageRefs
+		images = append(images, img)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// only references to images built in this tree are dependencies;
+	// everything else is pulled from a registry
+	for i := range images {
+		for _, ref := range refs[images[i].Name] {
+			if _, local := refs[ref]; local {
+				images[i].Dependencies = append(images[i].Dependencies, ref)
+			}
+		}
+	}
+	return images, nil
+}
+
+// Graph returns the dependency graph of the given images.
+func Graph(images []Image) *topo.Graph[string] {
+	var g topo.Graph[string]
+	for _, img := range images {
+		g.AddNode(img.Name, img.Dependencies)
+	}
+	return &g
+}
+
+// Parse returns the images referenced by a Dockerfile, in the order they
+// first appear. References to earlier build stages and to scratch are
+// omitted, and tags and digests are stripped.
+func Parse(r io.Reader) ([]string, error) {
+	var refs []string
+	args := make(map[string]string)
+	stages := make(map[string]bool)
+	addRef := func(ref string) {
+		ref = strings.ToLower(stripTag(ref))
+		if ref == "" || ref == "scratch" || stages[ref] || slices.Contains(refs, ref) {
+			return
+		}
+		refs = append(refs, ref)
+	}
+
+	seenFrom := false
+	lines, err := instructions(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range lines {
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// only global args, declared before the first FROM,
+			// are in scope for FROM instructions
+			if seenFrom {
+				continue
+			}
+			for _, arg := range fields[1:] {
+				key, value, _ := strings.Cut(arg, "=")
+				args[key] = strings.Trim(value, `"'`)
+			}
+		case "FROM":
+			seenFrom = true
+			operands := withoutFlags(fields[1:])
+			if len(operands) == 0 {
+				return nil, fmt.Errorf("FROM without an image")
+			}
+			addRef(expand(operands[0], args))
+			if len(operands) >= 3 && strings.EqualFold(operands[1], "AS") {
+				stages[strings.ToLower(operands[2])] = true
+			}
+		case "COPY":
+			for _, flag := range fields[1:] {
+				if from, ok := strings.CutPrefix(flag, "--from="); ok {
+					if !isStageIndex(from) {
+						addRef(from)
+					}
+				}
+			}
+		}
+	}
+	return refs, nil
+}
+
+// instructions splits a Dockerfile into the fields of each instruction,
+// joining continuation lines and dropping comments.
+func instructions(r io.Reader) ([][]string, error) {
+	var result [][]string
+	var current strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if cont, ok := strings.CutSuffix(line, `\`); ok {
+			current.WriteString(cont)
+			current.WriteByte(' ')
+			continue
+		}
+		current.WriteString(line)
+		if fields := strings.Fields(current.String()); len(fields) > 0 {
+			result = append(result, fields)
+		}
+		current.Reset()
+	}
+	if fields := strings.Fields(current.String()); len(fields) > 0 {
+		result = append(result, fields)
+	}
+	return result, scanner.Err()
+}
+
+func isDockerfile(name string) bool {
+	return name == "Dockerfile" || strings.HasSuffix(name, ".Dockerfile")
+}
+
+func isStageIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func withoutFlags(fields []string) []string {
+	var operands []string
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "--") {
+			operands = append(operands, f)
+		}
+	}
+	return operands
+}
+
+// expand substitutes $VAR and ${VAR} references using global args. A $
+// not followed by a name is kept as it is.
+func expand(s string, args map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		var name string
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			name = s[i+2 : i+end]
+			i += end
+		} else {
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || isAlnum(s[j])) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			name = s[i+1 : j]
+			i = j - 1
+		}
+		// support ${VAR:-default}
+		name, def, hasDef := strings.Cut(name, ":-")
+		if v, ok := args[name]; ok && v != "" {
+			b.WriteString(v)
+		} else if hasDef {
+			b.WriteString(def)
+		}
+	}
+	return b.String()
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// stripTag removes any tag or digest from an image reference, taking care
+// not to mistake a registry port for a tag.
+func stripTag(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	slash := strings.LastIndexByte(ref, '/')
+	if colon := strings.LastIndexByte(ref, ':'); colon > slash {
+		ref = ref[:colon]
+	}
+	return ref
+}
@@ -0,0 +1,148 @@
+package dockerfile_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/dockerfile"
+)
+
+// TestParse checks which image references are extracted from a Dockerfile.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{
+			name:     "single stage",
+			contents: "FROM debian:bookworm-slim\nRUN apt-get update\n",
+			expected: []string{"debian"},
+		},
+		{
+			name: "multi-stage",
+			contents: `
+FROM --platform=$BUILDPLATFORM golang:1.24 AS build
+RUN go build -o /app .
+
+FROM build AS test
+RUN go test ./...
+
+FROM gcr.io/distroless/static@sha256:abcd
+COPY --from=build /app /app
+COPY --from=0 /etc/passwd /etc/passwd
+COPY --from=localhost:5000/assets:v1 /assets /assets
+`,
+			expected: []string{"golang", "gcr.io/distroless/static", "localhost:5000/assets"},
+		},
+		{
+			name: "global args and continuations",
+			contents: `
+# the base image can be overridden
+ARG BASE=base-image
+ARG TAG
+FROM ${BASE}:${TAG:-latest} \
+    AS final
+FROM scratch
+`,
+			expected: []string{"base-image"},
+		},
+		{
+			name:     "literal dollar signs",
+			contents: "FROM registry.local/$$/img-$:${TAG:-1}\n",
+			expected: []string{"registry.local/$$/img-$"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := dockerfile.Parse(strings.NewReader(tt.contents))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(refs, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, refs)
+			}
+		})
+	}
+}
+
+// TestScan checks that only local images become dependencies.
+func TestScan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/base/Dockerfile":   {Data: []byte("FROM debian\n")},
+		"images/app/Dockerfile":    {Data: []byte("FROM golang AS build\nFROM base\nCOPY --from=build /a /a\n")},
+		"images/tools.Dockerfile":  {Data: []byte("FROM base\n")},
+		"images/test/Dockerfile":   {Data: []byte("FROM app\nCOPY --from=tools /bin /bin\n")},
+		"images/test/README.md":    {Data: []byte("not a Dockerfile")},
+		"other/ignored/Dockerfile": {Data: []byte("FROM app\n")},
+	}
+
+	images, err := dockerfile.Scan(fsys, "images", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	deps := make(map[string][]string)
+	for _, img := range images {
+		deps[img.Name] = img.Dependencies
+	}
+	expected := map[string][]string{
+		"base":  nil,
+		"app":   {"base"},
+		"tools": {"base"},
+		"test":  {"app", "tools"},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected %v, got %v", expected, deps)
+	}
+
+	layers, err := topo.SortedLayers(dockerfile.Graph(images))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedLayers := [][]string{{"base"}, {"app", "tools"}, {"test"}}
+	if !reflect.DeepEqual(layers, expectedLayers) {
+		t.Errorf("Expected %v, got %v", expectedLayers, layers)
+	}
+}
+
+// TestScanMixedCase checks that images in directories named with capitals
+// are found by the lowercased references to them.
+func TestScanMixedCase(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/Base/Dockerfile": {Data: []byte("FROM debian\n")},
+		"images/App.Dockerfile":  {Data: []byte("FROM Base\n")},
+		"images/test/Dockerfile": {Data: []byte("FROM app\n")},
+	}
+
+	images, err := dockerfile.Scan(fsys, "images", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	layers, err := topo.SortedLayers(dockerfile.Graph(images))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"base"}, {"app"}, {"test"}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestScanDuplicate checks that two Dockerfiles building the same image are
+// reported rather than merged.
+func TestScanDuplicate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/app/Dockerfile":        {Data: []byte("FROM debian\n")},
+		"images/legacy/app.Dockerfile": {Data: []byte("FROM alpine\n")},
+	}
+	_, err := dockerfile.Scan(fsys, "images", nil)
+	if !errors.Is(err, dockerfile.ErrDuplicateImage) {
+		t.Errorf("Expected ErrDuplicateImage, got %v", err)
+	}
+}
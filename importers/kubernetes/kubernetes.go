@@ -0,0 +1,336 @@
+// Package kubernetes orders Kubernetes manifests for applying.
+//
+// Objects become nodes, with dependencies derived from three sources:
+//
+//   - Kind ordering: objects are applied in the same kind order Helm uses,
+//     so namespaces come before the service accounts, config maps, and
+//     custom resource definitions that workloads need. Objects of unknown
+//     kinds, typically custom resources, come last.
+//   - Owner references: an object depends on the owners it lists.
+//   - Annotations: an object may name additional dependencies in the
+//     [DependsOnAnnotation] annotation.
+//
+// Explicit dependencies add to kind ordering: an object is applied after
+// the kinds before its own, and the objects of later kinds wait for it. An
+// object whose explicit dependencies are of a later kind takes their place
+// in the kind order instead, so that a secret owned by a custom resource,
+// say, comes after the custom resource rather than making a cycle.
+package kubernetes
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// DependsOnAnnotation lists extra dependencies of an object, as a
+// comma-separated list of "Kind/name" references to objects in the same
+// namespace, or "Kind/namespace/name" references to objects in any
+// namespace. Cluster-scoped objects are referenced as "Kind//name".
+const DependsOnAnnotation = "go-topo/depends-on"
+
+// ErrUnknownObject is returned when an annotation refers to an object that
+// is not among the manifests.
+var ErrUnknownObject = errors.New("unknown object")
+
+// kindOrder is the order in which kinds are applied.
+var kindOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+}
+
+// Key identifies an object.
+type Key struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String returns the key as "Kind/namespace/name", or "Kind/name" for
+// objects without a namespace.
+func (k Key) String() string {
+	if k.Namespace == "" {
+		return k.Kind + "/" + k.Name
+	}
+	return k.Kind + "/" + k.Namespace + "/" + k.Name
+}
+
+// OwnerReference is an entry of an object's metadata.ownerReferences.
+type OwnerReference struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+	UID  string `yaml:"uid"`
+}
+
+// Object is a single Kubernetes object from a manifest.
+type Object struct {
+	Key
+	APIVersion      string
+	UID             string
+	Annotations     map[string]string
+	OwnerReferences []OwnerReference
+	// Manifest is the full decoded object.
+	Manifest map[string]any
+}
+
+// header mirrors the fields of an object we need.
+type header struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name            string            `yaml:"name"`
+		Namespace       string            `yaml:"namespace"`
+		UID             string            `yaml:"uid"`
+		Annotations     map[string]string `yaml:"annotations"`
+		OwnerReferences []OwnerReference  `yaml:"ownerReferences"`
+	} `yaml:"metadata"`
+	Items []yaml.Node `yaml:"items"`
+}
+
+// Parse reads a stream of YAML documents, expanding any List objects into
+// their items. Empty documents are skipped.
+func Parse(r io.Reader) ([]Object, error) {
+	var objs []Object
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifests: %w", err)
+		}
+		objs, err = appendObjects(objs, &doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func appendObjects(objs []Object, node *yaml.Node) ([]Object, error) {
+	var h header
+	if err := node.Decode(&h); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if h.Kind == "" && h.Metadata.Name == "" {
+		return objs, nil
+	}
+	if strings.HasSuffix(h.Kind, "List") && h.Items != nil {
+		for i := range h.Items {
+			var err error
+			if objs, err = appendObjects(objs, &h.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+		return objs, nil
+	}
+
+	var manifest map[string]any
+	if err := node.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return append(objs, Object{
+		Key: Key{
+			Kind:      h.Kind,
+			Namespace: h.Metadata.Namespace,
+			Name:      h.Metadata.Name,
+		},
+		APIVersion:      h.APIVersion,
+		UID:             h.Metadata.UID,
+		Annotations:     h.Metadata.Annotations,
+		OwnerReferences: h.Metadata.OwnerReferences,
+		Manifest:        manifest,
+	}), nil
+}
+
+// Graph returns the apply ordering graph of the given objects.
+func Graph(objs []Object) (*topo.Graph[Key], error) {
+	byKey := make(map[Key]bool, len(objs))
+	byUID := make(map[string]Key)
+	for _, obj := range objs {
+		byKey[obj.Key] = true
+		if obj.UID != "" {
+			byUID[obj.UID] = obj.Key
+		}
+	}
+
+	// explicit holds the owners and annotated dependencies of each object
+	explicit := make(map[Key][]Key, len(objs))
+	kinds := make(map[Key]string, len(objs))
+	for _, obj := range objs {
+		kinds[obj.Key] = obj.Kind
+		var deps []Key
+		addDep := func(key Key) {
+			if key != obj.Key && !slices.Contains(deps, key) {
+				deps = append(deps, key)
+			}
+		}
+		for _, owner := range obj.OwnerReferences {
+			if key, exists := byUID[owner.UID]; exists && owner.UID != "" {
+				addDep(key)
+				continue
+			}
+			// owners are always in the same namespace
+			key := Key{Kind: owner.Kind, Namespace: obj.Namespace, Name: owner.Name}
+			if byKey[key] {
+				addDep(key)
+			}
+		}
+		for _, ref := range splitList(obj.Annotations[DependsOnAnnotation]) {
+			key, err := parseRef(ref, obj.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", obj.Key, err)
+			}
+			if !byKey[key] {
+				return nil, fmt.Errorf("%w: %v depends on %v", ErrUnknownObject, obj.Key, key)
+			}
+			addDep(key)
+		}
+		explicit[obj.Key] = deps
+	}
+
+	// an object is ordered by its kind, or by that of its explicit
+	// dependencies if later, since it can't be applied before them
+	ranks := make(map[Key]int, len(objs))
+	var rankOf func(key Key) int
+	rankOf = func(key Key) int {
+		if r, ok := ranks[key]; ok {
+			return r
+		}
+		// a placeholder, in case explicit dependencies form a cycle,
+		// which sorting the graph reports
+		r := rank(kinds[key])
+		ranks[key] = r
+		for _, dep := range explicit[key] {
+			r = max(r, rankOf(dep))
+		}
+		ranks[key] = r
+		return r
+	}
+	byRank := make(map[int][]Key)
+	for _, obj := range objs {
+		r := rankOf(obj.Key)
+		byRank[r] = append(byRank[r], obj.Key)
+	}
+
+	var g topo.Graph[Key]
+	for _, obj := range objs {
+		var deps []Key
+		// depending on the nearest earlier rank is enough, since those
+		// objects in turn depend on the ranks before them
+		for r := ranks[obj.Key] - 1; r >= 0; r-- {
+			if earlier, exists := byRank[r]; exists {
+				deps = append(deps, earlier...)
+				break
+			}
+		}
+		for _, dep := range explicit[obj.Key] {
+			if !slices.Contains(deps, dep) {
+				deps = append(deps, dep)
+			}
+		}
+		g.AddNode(obj.Key, deps)
+	}
+	return &g, nil
+}
+
+// Batches returns the objects grouped into batches that can each be applied
+// at once, in the order the batches must be applied. Within a batch,
+// objects are sorted by kind order and then by key.
+func Batches(objs []Object) ([][]Object, error) {
+	g, err := Graph(objs)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[Key]Object, len(objs))
+	for _, obj := range objs {
+		byKey[obj.Key] = obj
+	}
+
+	batches := make([][]Object, len(layers))
+	for i, layer := range layers {
+		for _, key := range layer {
+			batches[i] = append(batches[i], byKey[key])
+		}
+		slices.SortFunc(batches[i], func(a, b Object) int {
+			return cmp.Or(
+				cmp.Compare(rank(a.Kind), rank(b.Kind)),
+				cmp.Compare(a.String(), b.String()),
+			)
+		})
+	}
+	return batches, nil
+}
+
+// rank returns the position of kind in the apply order.
+func rank(kind string) int {
+	if i := slices.Index(kindOrder, kind); i >= 0 {
+		return i
+	}
+	return len(kindOrder)
+}
+
+// parseRef parses a "Kind/name", "Kind/namespace/name" or "Kind//name"
+// reference made from an object in the given namespace.
+func parseRef(ref, namespace string) (Key, error) {
+	parts := strings.Split(ref, "/")
+	switch len(parts) {
+	case 2:
+		return Key{Kind: parts[0], Namespace: namespace, Name: parts[1]}, nil
+	case 3:
+		return Key{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+	default:
+		return Key{}, fmt.Errorf("invalid reference %q", ref)
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
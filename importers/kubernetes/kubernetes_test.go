@@ -0,0 +1,151 @@
+package kubernetes_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/importers/kubernetes"
+)
+
+const manifests = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: shop
+  annotations:
+    go-topo/depends-on: StatefulSet/db
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: shop
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: api-config
+      namespace: shop
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: api
+      namespace: shop
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: shop
+  uid: 1234
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: widget-secret
+  namespace: shop
+  ownerReferences:
+    - kind: Widget
+      name: w
+      uid: 1234
+---
+`
+
+// TestBatches checks the apply order of a set of manifests.
+func TestBatches(t *testing.T) {
+	objs, err := kubernetes.Parse(strings.NewReader(manifests))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(objs) != 7 {
+		t.Fatalf("Expected 7 objects, got %d", len(objs))
+	}
+
+	batches, err := kubernetes.Batches(objs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result [][]string
+	for _, batch := range batches {
+		var keys []string
+		for _, obj := range batch {
+			keys = append(keys, obj.String())
+		}
+		result = append(result, keys)
+	}
+	expected := [][]string{
+		{"Namespace/shop"},
+		{"ConfigMap/shop/api-config"},
+		{"Service/shop/api"},
+		{"StatefulSet/shop/db"},
+		{"Deployment/shop/api"},
+		{"Widget/shop/w"},
+		{"Secret/shop/widget-secret"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// TestGraphAnnotatedConfigMap checks that objects of later kinds wait for
+// an object with explicit dependencies.
+func TestGraphAnnotatedConfigMap(t *testing.T) {
+	objs, err := kubernetes.Parse(strings.NewReader(`
+kind: Deployment
+metadata:
+  name: api
+---
+kind: ConfigMap
+metadata:
+  name: api-config
+  annotations:
+    go-topo/depends-on: Secret/api-key
+---
+kind: Secret
+metadata:
+  name: api-key
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g, err := kubernetes.Graph(objs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deployment := kubernetes.Key{Kind: "Deployment", Name: "api"}
+	configMap := kubernetes.Key{Kind: "ConfigMap", Name: "api-config"}
+	if deps := g.Dependencies(deployment); !slices.Contains(deps, configMap) {
+		t.Errorf("Expected %v to depend on %v, got %v", deployment, configMap, deps)
+	}
+}
+
+// TestGraphUnknownObject checks that annotations must name known objects.
+func TestGraphUnknownObject(t *testing.T) {
+	objs, err := kubernetes.Parse(strings.NewReader(`
+kind: Deployment
+metadata:
+  name: api
+  annotations:
+    go-topo/depends-on: Service/missing
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = kubernetes.Graph(objs)
+	if !errors.Is(err, kubernetes.ErrUnknownObject) {
+		t.Errorf("Expected error %v, got %v", kubernetes.ErrUnknownObject, err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package makefile builds dependency graphs from Makefiles.
+//
+// Targets become nodes and their prerequisites become dependencies. Only
+// explicit rules are considered; pattern rules, suffix rules and recipes
+// are ignored.
+package makefile
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Rule is the combined set of rules for a single target.
+type Rule struct {
+	Target string
+	// Prerequisites are the normal prerequisites of the target.
+	Prerequisites []string
+	// OrderOnly are the prerequisites listed after a "|".
+	OrderOnly []string
+}
+
+// Makefile holds the explicit rules of a Makefile.
+type Makefile struct {
+	// Rules are the rules of the Makefile, in the order their targets
+	// first appear. Rules for the same target are merged.
+	Rules []Rule
+	// Phony is the set of targets declared as prerequisites of .PHONY.
+	Phony map[string]bool
+}
+
+// Parse reads a Makefile.
+//
+// Variables defined earlier in the file are expanded in targets and
+// prerequisites, following make's rules for each operator: = defers
+// expanding its value until the variable is used, := and ::= expand it at
+// once, += appends to the value with a space, and ?= only assigns a
+// variable that isn't set. Anything else that make would evaluate, such as
+// functions or conditionals, is left as-is or ignored.
+func Parse(r io.Reader) (*Makefile, error) {
+	m := &Makefile{Phony: make(map[string]bool)}
+	index := make(map[string]int)
+	vars := make(map[string]variable)
+
+	lines, err := logicalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	inDefine := false
+	for _, line := range lines {
+		// recipes
+		if strings.HasPrefix(line, "\t") {
+			continue
+		}
+		line = stripComment(line)
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		directive, _, _ := strings.Cut(trimmed, " ")
+		switch {
+		case inDefine:
+			inDefine = directive != "endef"
+			continue
+		case directive == "define":
+			inDefine = true
+			continue
+		case isDirective(directive):
+			continue
+		}
+
+		if name, op, value, ok := parseAssignment(trimmed); ok {
+			if name != "" {
+				assign(vars, name, op, value)
+			}
+			continue
+		}
+
+		targetsPart, prereqsPart, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		// double-colon rules
+		prereqsPart = strings.TrimPrefix(prereqsPart, ":")
+		// inline recipe
+		prereqsPart, _, _ = strings.Cut(prereqsPart, ";")
+		// target-specific variable assignment
+		if strings.Contains(prereqsPart, "=") {
+			continue
+		}
+
+		normalPart, orderOnlyPart, _ := strings.Cut(prereqsPart, "|")
+		targets := strings.Fields(expand(targetsPart, vars))
+		prereqs := strings.Fields(expand(normalPart, vars))
+		orderOnly := strings.Fields(expand(orderOnlyPart, vars))
+
+		for _, target := range targets {
+			if target == ".PHONY" {
+				for _, p := range prereqs {
+					m.Phony[p] = true
+				}
+				continue
+			}
+			if strings.HasPrefix(target, ".") || strings.Contains(target, "%") {
+				continue
+			}
+
+			i, exists := index[target]
+			if !exists {
+				i = len(m.Rules)
+				index[target] = i
+				m.Rules = append(m.Rules, Rule{Target: target})
+			}
+			rule := &m.Rules[i]
+			rule.Prerequisites = appendUnique(rule.Prerequisites, prereqs)
+			rule.OrderOnly = appendUnique(rule.OrderOnly, orderOnly)
+		}
+	}
+	return m, nil
+}
+
+// Graph returns the dependency graph of the Makefile's targets.
+//
+// Prerequisites that are not themselves targets, typically source files,
+// are left out of the graph since they never need to be built.
+func (m *Makefile) Graph() *topo.Graph[string] {
+	targets := make(map[string]bool, len(m.Rules))
+	for _, rule := range m.Rules {
+		targets[rule.Target] = true
+	}
+
+	var g topo.Graph[string]
+	for _, rule := range m.Rules {
+		var deps []string
+		for _, p := range slices.Concat(rule.Prerequisites, rule.OrderOnly) {
+			if targets[p] && !slices.Contains(deps, p) {
+				deps = append(deps, p)
+			}
+		}
+		g.AddNode(rule.Target, deps)
+	}
+	return &g
+}
+
+// logicalLines reads lines, joining those ending in a backslash.
+func logicalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	var current strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if cont, ok := strings.CutSuffix(line, `\`); ok {
+			current.WriteString(cont)
+			current.WriteByte(' ')
+			continue
+		}
+		current.WriteString(line)
+		lines = append(lines, current.String())
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		lines = append(lines, current.String())
+	}
+	return lines, scanner.Err()
+}
+
+func stripComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func isDirective(word string) bool {
+	switch word {
+	case "include", "-include", "sinclude", "export", "unexport", "override",
+		"ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "vpath":
+		return true
+	}
+	return false
+}
+
+// variable is the value of a variable. A simple variable's value was
+// expanded when it was assigned; a recursive one's is expanded each time
+// it's used.
+type variable struct {
+	value  string
+	simple bool
+}
+
+// parseAssignment recognizes variable assignments, returning the operator
+// without its "=". An assignment is any line whose first "=" comes before
+// its first ":" other than one that is part of the assignment operator
+// itself.
+func parseAssignment(line string) (name, op, value string, ok bool) {
+	eq := strings.IndexByte(line, '=')
+	if eq < 0 {
+		return "", "", "", false
+	}
+	lhs := line[:eq]
+	for _, prefix := range []string{"::", ":", "+", "?", "!"} {
+		if cut, found := strings.CutSuffix(lhs, prefix); found {
+			lhs, op = cut, prefix
+			break
+		}
+	}
+	if strings.Contains(lhs, ":") {
+		return "", "", "", false
+	}
+	return strings.TrimSpace(lhs), op, strings.TrimSpace(line[eq+1:]), true
+}
+
+// assign assigns a variable with an operator from parseAssignment.
+func assign(vars map[string]variable, name, op, value string) {
+	old, set := vars[name]
+	switch op {
+	case "":
+		vars[name] = variable{value: value}
+	case "?":
+		if !set {
+			vars[name] = variable{value: value}
+		}
+	case "+":
+		switch {
+		case !set:
+			vars[name] = variable{value: value}
+			return
+		case old.simple:
+			value = expand(value, vars)
+		}
+		if old.value != "" {
+			value = old.value + " " + value
+		}
+		vars[name] = variable{value: value, simple: old.simple}
+	default:
+		// ":", "::", and "!", whose command isn't run
+		vars[name] = variable{value: expand(value, vars), simple: true}
+	}
+}
+
+// expand substitutes $(VAR) and ${VAR} references to known variables.
+func expand(s string, vars map[string]variable) string {
+	return expandWith(s, vars, nil)
+}
+
+// expandWith is expand, leaving references to the recursive variables
+// being expanded as they are, since make would refuse them.
+func expandWith(s string, vars map[string]variable, expanding []string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		var closer byte
+		switch s[i+1] {
+		case '(':
+			closer = ')'
+		case '{':
+			closer = '}'
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		default:
+			b.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i:], closer)
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		name := s[i+2 : i+end]
+		switch v, ok := vars[name]; {
+		case ok && v.simple:
+			b.WriteString(v.value)
+		case ok && !slices.Contains(expanding, name):
+			b.WriteString(expandWith(v.value, vars, append(expanding, name)))
+		default:
+			b.WriteString(s[i : i+end+1])
+		}
+		i += end
+	}
+	return b.String()
+}
+
+func appendUnique(dst, src []string) []string {
+	for _, s := range src {
+		if !slices.Contains(dst, s) {
+			dst = append(dst, s)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,129 @@
+package makefile_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/makefile"
+)
+
+const contents = `
+# build settings
+BIN := bin
+OBJS = main.o \
+       util.o
+
+.PHONY: all clean test
+
+all: $(BIN)/app docs
+
+$(BIN)/app: $(OBJS) | $(BIN)
+	$(CC) -o $@ $^
+
+$(BIN):
+	mkdir -p $@
+
+main.o: main.c util.h
+util.o: util.c util.h
+
+%.o: %.c
+	$(CC) -c $<
+
+docs: ; mkdocs build
+
+test: all
+test: CFLAGS += -g
+
+ifdef DEBUG
+debug: all
+endif
+
+define HELP
+help: text
+endef
+
+clean:
+	rm -rf $(BIN) *.o
+`
+
+// TestParse checks rule extraction and graph construction.
+func TestParse(t *testing.T) {
+	m, err := makefile.Parse(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var targets []string
+	for _, rule := range m.Rules {
+		targets = append(targets, rule.Target)
+	}
+	expectedTargets := []string{
+		"all", "bin/app", "bin", "main.o", "util.o", "docs", "test", "debug", "clean",
+	}
+	if !slices.Equal(targets, expectedTargets) {
+		t.Errorf("Expected targets %v, got %v", expectedTargets, targets)
+	}
+
+	app := m.Rules[1]
+	if !slices.Equal(app.Prerequisites, []string{"main.o", "util.o"}) {
+		t.Errorf("Unexpected prerequisites %v", app.Prerequisites)
+	}
+	if !slices.Equal(app.OrderOnly, []string{"bin"}) {
+		t.Errorf("Unexpected order-only prerequisites %v", app.OrderOnly)
+	}
+	if !m.Phony["clean"] || m.Phony["bin"] {
+		t.Errorf("Unexpected phony targets %v", m.Phony)
+	}
+
+	layers, err := topo.SortedLayers(m.Graph())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedLayers := [][]string{
+		{"bin", "clean", "docs", "main.o", "util.o"},
+		{"bin/app"},
+		{"all"},
+		{"debug", "test"},
+	}
+	if !reflect.DeepEqual(layers, expectedLayers) {
+		t.Errorf("Expected %v, got %v", expectedLayers, layers)
+	}
+}
+
+// TestParseAssignments checks that each assignment operator sets variables
+// as make does.
+func TestParseAssignments(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{"append", "OBJS = a.o\nOBJS += b.o\nall: $(OBJS)\n", []string{"a.o", "b.o"}},
+		{"append to unset", "OBJS += b.o\nall: $(OBJS)\n", []string{"b.o"}},
+		{"conditional when unset", "OBJS ?= a.o\nall: $(OBJS)\n", []string{"a.o"}},
+		{"conditional when set", "OBJS = a.o\nOBJS ?= b.o\nall: $(OBJS)\n", []string{"a.o"}},
+		{"deferred", "OBJS = $(SRC).o\nSRC = main\nall: $(OBJS)\n", []string{"main.o"}},
+		{"immediate", "OBJS := $(SRC).o\nSRC = main\nall: $(OBJS)\n", []string{"$(SRC).o"}},
+		{"posix immediate", "SRC = a\nOBJS ::= $(SRC).o\nSRC = b\nall: $(OBJS)\n", []string{"a.o"}},
+		{"append to immediate", "SRC = a\nOBJS := $(SRC).o\nOBJS += $(SRC).x\nSRC = b\nall: $(OBJS)\n", []string{"a.o", "a.x"}},
+		{"append to deferred", "SRC = a\nOBJS = $(SRC).o\nOBJS += $(SRC).x\nSRC = b\nall: $(OBJS)\n", []string{"b.o", "b.x"}},
+		{"self reference", "OBJS = $(OBJS) a.o\nall: $(OBJS)\n", []string{"$(OBJS)", "a.o"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := makefile.Parse(strings.NewReader(tt.contents))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(m.Rules) != 1 {
+				t.Fatalf("Expected 1 rule, got %v", m.Rules)
+			}
+			if got := m.Rules[0].Prerequisites; !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
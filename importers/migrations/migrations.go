@@ -0,0 +1,167 @@
+// Package migrations orders SQL migration files that declare their
+// dependencies in header comments:
+//
+//	-- requires: 0005_users
+//	-- requires: 0007_orders, 0008_products
+//	ALTER TABLE ...
+//
+// Migrations are identified by their file name without the ".sql" or
+// ".up.sql" extension. Down migrations (".down.sql") are ignored.
+package migrations
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+var (
+	// ErrDuplicateMigration is returned when two files have the same ID.
+	ErrDuplicateMigration = errors.New("duplicate migration")
+	// ErrUnknownMigration is returned when a migration requires a
+	// migration that doesn't exist.
+	ErrUnknownMigration = errors.New("unknown migration")
+)
+
+// Migration is a single migration file.
+type Migration struct {
+	ID       string
+	Path     string
+	Requires []string
+}
+
+// ParseHeader returns the migrations required by the header of a migration
+// file. The header is the comments before the first statement.
+func ParseHeader(r io.Reader) ([]string, error) {
+	var requires []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		comment, ok := strings.CutPrefix(line, "--")
+		if !ok {
+			break
+		}
+		key, value, ok := strings.Cut(comment, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "requires") {
+			continue
+		}
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" && !slices.Contains(requires, id) {
+				requires = append(requires, id)
+			}
+		}
+	}
+	return requires, scanner.Err()
+}
+
+// Scan reads the headers of all migrations in the directory dir of fsys.
+// Migrations are returned sorted by ID.
+func Scan(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migs []Migration
+	for _, entry := range entries {
+		id, ok := migrationID(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		p := path.Join(dir, entry.Name())
+		requires, err := parseFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		migs = append(migs, Migration{ID: id, Path: p, Requires: requires})
+	}
+	return migs, nil
+}
+
+// Graph returns the dependency graph of the given migrations, after
+// checking that IDs are unique and every required migration exists.
+func Graph(migs []Migration) (*topo.Graph[string], error) {
+	seen := make(map[string]string, len(migs))
+	for _, m := range migs {
+		if other, exists := seen[m.ID]; exists {
+			return nil, fmt.Errorf("%w: %s (%s and %s)", ErrDuplicateMigration, m.ID, other, m.Path)
+		}
+		seen[m.ID] = m.Path
+	}
+
+	var g topo.Graph[string]
+	for _, m := range migs {
+		for _, req := range m.Requires {
+			if _, exists := seen[req]; !exists {
+				return nil, fmt.Errorf("%w: %s requires %s", ErrUnknownMigration, m.ID, req)
+			}
+		}
+		g.AddNode(m.ID, m.Requires)
+	}
+	return &g, nil
+}
+
+// Plan returns the migrations grouped into layers, where each layer can be
+// applied once the layers before it have been. Within a layer, migrations
+// are sorted by ID.
+//
+// Migrations whose requirements form a cycle are reported with
+// [topo.ErrCyclicDependency].
+func Plan(migs []Migration) ([][]Migration, error) {
+	g, err := Graph(migs)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return nil, fmt.Errorf("ordering migrations: %w", err)
+	}
+
+	byID := make(map[string]Migration, len(migs))
+	for _, m := range migs {
+		byID[m.ID] = m
+	}
+	plan := make([][]Migration, len(layers))
+	for i, layer := range layers {
+		slices.Sort(layer)
+		for _, id := range layer {
+			plan[i] = append(plan[i], byID[id])
+		}
+	}
+	return plan, nil
+}
+
+func parseFile(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	requires, err := ParseHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return requires, nil
+}
+
+// migrationID returns the ID of the migration in the named file, or false
+// if the file is not an up migration.
+func migrationID(name string) (string, bool) {
+	if strings.HasSuffix(name, ".down.sql") {
+		return "", false
+	}
+	if id, ok := strings.CutSuffix(name, ".up.sql"); ok {
+		return id, true
+	}
+	return strings.CutSuffix(name, ".sql")
+}
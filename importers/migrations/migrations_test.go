@@ -0,0 +1,97 @@
+package migrations_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/migrations"
+)
+
+// TestPlan checks ordering a directory of migrations.
+func TestPlan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/0001_init.sql":          {Data: []byte("CREATE SCHEMA app;\n")},
+		"db/0002_users.up.sql":      {Data: []byte("-- requires: 0001_init\nCREATE TABLE users ();\n")},
+		"db/0002_users.down.sql":    {Data: []byte("DROP TABLE users;\n")},
+		"db/0003_products.sql":      {Data: []byte("-- Products catalog.\n-- Requires: 0001_init\n\nCREATE TABLE products ();\n")},
+		"db/0004_orders.sql":        {Data: []byte("-- requires: 0002_users, 0003_products\nCREATE TABLE orders ();\n-- requires: 0005_late\n")},
+		"db/0005_late.sql":          {Data: []byte("-- requires: 0001_init\n")},
+		"db/notes.txt":              {Data: []byte("not a migration")},
+		"db/archive/0000_old.sql":   {Data: []byte("")},
+		"other/0006_elsewhere.sql":  {Data: []byte("")},
+		"db/0007_orders_index.sql":  {Data: []byte("-- requires: 0004_orders\n")},
+		"db/0006_products_seed.sql": {Data: []byte("-- requires: 0003_products\n")},
+	}
+
+	migs, err := migrations.Scan(fsys, "db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	plan, err := migrations.Plan(migs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result [][]string
+	for _, layer := range plan {
+		var ids []string
+		for _, m := range layer {
+			ids = append(ids, m.ID)
+		}
+		result = append(result, ids)
+	}
+	expected := [][]string{
+		{"0001_init"},
+		{"0002_users", "0003_products", "0005_late"},
+		{"0004_orders", "0006_products_seed"},
+		{"0007_orders_index"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// TestPlanErrors checks that broken migration sets are rejected.
+func TestPlanErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		migs     []migrations.Migration
+		expected error
+	}{
+		{
+			name: "duplicate",
+			migs: []migrations.Migration{
+				{ID: "0001_init", Path: "a/0001_init.sql"},
+				{ID: "0001_init", Path: "b/0001_init.sql"},
+			},
+			expected: migrations.ErrDuplicateMigration,
+		},
+		{
+			name: "unknown",
+			migs: []migrations.Migration{
+				{ID: "0002_users", Requires: []string{"0001_init"}},
+			},
+			expected: migrations.ErrUnknownMigration,
+		},
+		{
+			name: "cyclic",
+			migs: []migrations.Migration{
+				{ID: "0001_a", Requires: []string{"0002_b"}},
+				{ID: "0002_b", Requires: []string{"0001_a"}},
+			},
+			expected: topo.ErrCyclicDependency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := migrations.Plan(tt.migs)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
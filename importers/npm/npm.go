@@ -0,0 +1,338 @@
+// Package npm builds dependency graphs from npm and pnpm lockfiles.
+//
+// Workspace packages are identified by their path relative to the
+// repository root, with "." for the root package itself. Installed
+// packages are identified as "name@version". A [Lockfile] can produce
+// either the full graph of everything installed, or just the graph of
+// workspace packages, which is usually what monorepo build tooling wants.
+package npm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ErrUnsupportedVersion is returned for lockfile versions that can't be
+// read.
+var ErrUnsupportedVersion = errors.New("unsupported lockfile version")
+
+// Package is a package recorded in a lockfile.
+type Package struct {
+	// ID identifies the package in graphs.
+	ID string
+	// Name is the package name, if the lockfile records it.
+	Name    string
+	Version string
+	// Workspace is true for packages that are part of the repository.
+	Workspace bool
+	// Dependencies are the IDs of the packages this package depends on,
+	// including dev, optional and peer dependencies.
+	Dependencies []string
+}
+
+// Lockfile holds the resolved packages of a lockfile.
+type Lockfile struct {
+	// Packages are sorted by ID.
+	Packages []Package
+}
+
+// Graph returns the dependency graph of every package in the lockfile.
+func (l *Lockfile) Graph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	for _, pkg := range l.Packages {
+		g.AddNode(pkg.ID, pkg.Dependencies)
+	}
+	return &g
+}
+
+// WorkspaceGraph returns the dependency graph of the workspace packages,
+// leaving out everything installed from a registry.
+func (l *Lockfile) WorkspaceGraph() *topo.Graph[string] {
+	workspace := make(map[string]bool)
+	for _, pkg := range l.Packages {
+		workspace[pkg.ID] = pkg.Workspace
+	}
+
+	var g topo.Graph[string]
+	for _, pkg := range l.Packages {
+		if !pkg.Workspace {
+			continue
+		}
+		var deps []string
+		for _, dep := range pkg.Dependencies {
+			if workspace[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		g.AddNode(pkg.ID, deps)
+	}
+	return &g
+}
+
+// packageLock mirrors package-lock.json.
+type packageLock struct {
+	LockfileVersion int                       `json:"lockfileVersion"`
+	Packages        map[string]packageLockPkg `json:"packages"`
+}
+
+type packageLockPkg struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Resolved             string            `json:"resolved"`
+	Link                 bool              `json:"link"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+// ParsePackageLock reads an npm package-lock.json, version 2 or later.
+//
+// Dependencies are resolved the way Node does, by searching node_modules
+// directories from the depending package up to the root. Dependencies
+// that aren't installed, such as optional ones for other platforms, are
+// left out.
+func ParsePackageLock(r io.Reader) (*Lockfile, error) {
+	var lock packageLock
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, fmt.Errorf("parsing package-lock.json: %w", err)
+	}
+	if lock.LockfileVersion < 2 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, lock.LockfileVersion)
+	}
+
+	// resolve links to the workspace directories they point at
+	target := func(loc string) string {
+		if pkg := lock.Packages[loc]; pkg.Link {
+			return pkg.Resolved
+		}
+		return loc
+	}
+	id := func(loc string) string {
+		if isWorkspacePath(loc) {
+			return workspaceID(loc)
+		}
+		return packageName(loc, lock.Packages[loc]) + "@" + lock.Packages[loc].Version
+	}
+
+	var l Lockfile
+	seen := make(map[string]bool)
+	for _, loc := range sortedKeys(lock.Packages) {
+		pkg := lock.Packages[loc]
+		if pkg.Link || seen[id(loc)] {
+			continue
+		}
+		seen[id(loc)] = true
+
+		var deps []string
+		for _, depMap := range []map[string]string{
+			pkg.Dependencies, pkg.DevDependencies, pkg.OptionalDependencies, pkg.PeerDependencies,
+		} {
+			for _, name := range sortedKeys(depMap) {
+				depLoc, ok := resolve(lock.Packages, loc, name)
+				if !ok {
+					continue
+				}
+				if depID := id(target(depLoc)); !slices.Contains(deps, depID) {
+					deps = append(deps, depID)
+				}
+			}
+		}
+
+		l.Packages = append(l.Packages, Package{
+			ID:           id(loc),
+			Name:         packageName(loc, pkg),
+			Version:      pkg.Version,
+			Workspace:    isWorkspacePath(loc),
+			Dependencies: deps,
+		})
+	}
+	sortPackages(l.Packages)
+	return &l, nil
+}
+
+// resolve finds where the named dependency of the package at loc is
+// installed.
+func resolve(pkgs map[string]packageLockPkg, loc, name string) (string, bool) {
+	dir := loc
+	for {
+		candidate := path.Join(dir, "node_modules", name)
+		if _, exists := pkgs[candidate]; exists {
+			return candidate, true
+		}
+		if dir == "" || dir == "." {
+			return "", false
+		}
+		// step out of this package, and out of the node_modules
+		// directory containing it
+		dir = path.Dir(dir)
+		if strings.HasPrefix(path.Base(dir), "@") {
+			dir = path.Dir(dir)
+		}
+		if path.Base(dir) == "node_modules" {
+			dir = path.Dir(dir)
+		}
+		if dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// pnpmLock mirrors pnpm-lock.yaml, versions 6 and 9.
+type pnpmLock struct {
+	LockfileVersion string                  `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter `yaml:"importers"`
+	// Packages holds dependencies in version 6; version 9 moved them
+	// to Snapshots.
+	Packages  map[string]pnpmSnapshot `yaml:"packages"`
+	Snapshots map[string]pnpmSnapshot `yaml:"snapshots"`
+}
+
+type pnpmImporter struct {
+	Dependencies         map[string]pnpmImporterDep `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmImporterDep `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmImporterDep `yaml:"optionalDependencies"`
+}
+
+type pnpmImporterDep struct {
+	Version string `yaml:"version"`
+}
+
+type pnpmSnapshot struct {
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// ParsePNPMLock reads a pnpm-lock.yaml, version 6 or later.
+//
+// pnpm doesn't record the names of workspace packages, so their Name is
+// left empty.
+func ParsePNPMLock(r io.Reader) (*Lockfile, error) {
+	var lock pnpmLock
+	if err := yaml.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, fmt.Errorf("parsing pnpm-lock.yaml: %w", err)
+	}
+	major, _, _ := strings.Cut(lock.LockfileVersion, ".")
+	if major != "6" && major != "9" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, lock.LockfileVersion)
+	}
+
+	snapshots := lock.Snapshots
+	if major == "6" {
+		snapshots = make(map[string]pnpmSnapshot, len(lock.Packages))
+		for key, snap := range lock.Packages {
+			snapshots[strings.TrimPrefix(key, "/")] = snap
+		}
+	}
+
+	// id converts a dependency version as written in the lockfile, which
+	// may be a link to another importer, to a package ID
+	id := func(importer, name, version string) string {
+		if dir, ok := strings.CutPrefix(version, "link:"); ok {
+			return workspaceID(path.Join(importer, dir))
+		}
+		if base, _, _ := strings.Cut(version, "("); strings.Contains(base, "@") {
+			// an alias like "npm:other@1.0.0" is written as "other@1.0.0"
+			return version
+		}
+		return name + "@" + version
+	}
+
+	var l Lockfile
+	for _, importer := range sortedKeys(lock.Importers) {
+		imp := lock.Importers[importer]
+		var deps []string
+		for _, depMap := range []map[string]pnpmImporterDep{
+			imp.Dependencies, imp.DevDependencies, imp.OptionalDependencies,
+		} {
+			for _, name := range sortedKeys(depMap) {
+				if depID := id(importer, name, depMap[name].Version); !slices.Contains(deps, depID) {
+					deps = append(deps, depID)
+				}
+			}
+		}
+		l.Packages = append(l.Packages, Package{
+			ID:           workspaceID(importer),
+			Workspace:    true,
+			Dependencies: deps,
+		})
+	}
+
+	for _, key := range sortedKeys(snapshots) {
+		snap := snapshots[key]
+		var deps []string
+		for _, depMap := range []map[string]string{snap.Dependencies, snap.OptionalDependencies} {
+			for _, name := range sortedKeys(depMap) {
+				if depID := id(".", name, depMap[name]); !slices.Contains(deps, depID) {
+					deps = append(deps, depID)
+				}
+			}
+		}
+		name, version := splitID(key)
+		l.Packages = append(l.Packages, Package{
+			ID:           key,
+			Name:         name,
+			Version:      version,
+			Dependencies: deps,
+		})
+	}
+	sortPackages(l.Packages)
+	return &l, nil
+}
+
+// isWorkspacePath reports whether a package-lock.json location is a
+// workspace package rather than something installed into node_modules.
+func isWorkspacePath(loc string) bool {
+	return !strings.HasPrefix(loc, "node_modules/") && !strings.Contains(loc, "/node_modules/")
+}
+
+func workspaceID(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return path.Clean(dir)
+}
+
+// packageName returns the name of the package at a package-lock.json
+// location, which is implied by the location for installed packages.
+func packageName(loc string, pkg packageLockPkg) string {
+	if pkg.Name != "" {
+		return pkg.Name
+	}
+	if i := strings.LastIndex(loc, "node_modules/"); i >= 0 {
+		return loc[i+len("node_modules/"):]
+	}
+	return ""
+}
+
+// splitID splits a "name@version" ID, where name may be scoped.
+func splitID(id string) (string, string) {
+	if i := strings.LastIndexByte(strings.SplitN(id, "(", 2)[0], '@'); i > 0 {
+		return id[:i], id[i+1:]
+	}
+	return id, ""
+}
+
+func sortPackages(pkgs []Package) {
+	slices.SortFunc(pkgs, func(a, b Package) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
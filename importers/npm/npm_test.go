@@ -0,0 +1,140 @@
+package npm_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/npm"
+)
+
+const packageLock = `{
+  "name": "monorepo",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "monorepo",
+      "workspaces": ["packages/*"],
+      "devDependencies": {"typescript": "^5.0.0"}
+    },
+    "node_modules/@acme/app": {"resolved": "packages/app", "link": true},
+    "node_modules/@acme/lib": {"resolved": "packages/lib", "link": true},
+    "node_modules/left-pad": {"version": "1.3.0"},
+    "node_modules/typescript": {"version": "5.4.5", "dev": true},
+    "node_modules/chalk": {
+      "version": "5.3.0",
+      "dependencies": {"ansi-styles": "^6.0.0", "fsevents": "*"}
+    },
+    "node_modules/ansi-styles": {"version": "6.2.1"},
+    "packages/app": {
+      "name": "@acme/app",
+      "version": "1.0.0",
+      "dependencies": {"@acme/lib": "*", "chalk": "^5.0.0", "left-pad": "^2.0.0"}
+    },
+    "packages/app/node_modules/left-pad": {"version": "2.0.0"},
+    "packages/lib": {
+      "name": "@acme/lib",
+      "version": "1.0.0",
+      "dependencies": {"left-pad": "^1.0.0"}
+    }
+  }
+}`
+
+// TestParsePackageLock checks resolution of npm lockfile dependencies.
+func TestParsePackageLock(t *testing.T) {
+	l, err := npm.ParsePackageLock(strings.NewReader(packageLock))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	deps := make(map[string][]string)
+	for _, pkg := range l.Packages {
+		deps[pkg.ID] = pkg.Dependencies
+	}
+	expected := map[string][]string{
+		".":                 {"typescript@5.4.5"},
+		"ansi-styles@6.2.1": nil,
+		"chalk@5.3.0":       {"ansi-styles@6.2.1"},
+		"left-pad@1.3.0":    nil,
+		"left-pad@2.0.0":    nil,
+		"packages/app":      {"packages/lib", "chalk@5.3.0", "left-pad@2.0.0"},
+		"packages/lib":      {"left-pad@1.3.0"},
+		"typescript@5.4.5":  nil,
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected %v, got %v", expected, deps)
+	}
+
+	checkLayers(t, l.WorkspaceGraph(), [][]string{{".", "packages/lib"}, {"packages/app"}})
+}
+
+const pnpmLock = `
+lockfileVersion: '9.0'
+importers:
+  .:
+    devDependencies:
+      typescript:
+        specifier: ^5.0.0
+        version: 5.4.5
+  packages/app:
+    dependencies:
+      '@acme/lib':
+        specifier: workspace:*
+        version: link:../lib
+      react-dom:
+        specifier: ^18.0.0
+        version: 18.2.0(react@18.2.0)
+  packages/lib:
+    dependencies:
+      react:
+        specifier: ^18.0.0
+        version: 18.2.0
+packages:
+  react@18.2.0:
+    resolution: {integrity: sha512-abc}
+  react-dom@18.2.0:
+    resolution: {integrity: sha512-def}
+  typescript@5.4.5:
+    resolution: {integrity: sha512-ghi}
+snapshots:
+  react@18.2.0: {}
+  react-dom@18.2.0(react@18.2.0):
+    dependencies:
+      react: 18.2.0
+  typescript@5.4.5: {}
+`
+
+// TestParsePNPMLock checks reading a pnpm lockfile.
+func TestParsePNPMLock(t *testing.T) {
+	l, err := npm.ParsePNPMLock(strings.NewReader(pnpmLock))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checkLayers(t, l.Graph(), [][]string{
+		{"react@18.2.0", "typescript@5.4.5"},
+		{".", "packages/lib", "react-dom@18.2.0(react@18.2.0)"},
+		{"packages/app"},
+	})
+	checkLayers(t, l.WorkspaceGraph(), [][]string{{".", "packages/lib"}, {"packages/app"}})
+
+	i := slices.IndexFunc(l.Packages, func(p npm.Package) bool {
+		return p.ID == "react-dom@18.2.0(react@18.2.0)"
+	})
+	if pkg := l.Packages[i]; pkg.Name != "react-dom" || pkg.Version != "18.2.0(react@18.2.0)" {
+		t.Errorf("Unexpected name and version %q %q", pkg.Name, pkg.Version)
+	}
+}
+
+func checkLayers(t *testing.T, g *topo.Graph[string], expected [][]string) {
+	t.Helper()
+	layers, err := topo.SortedLayers(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
@@ -0,0 +1,109 @@
+// Package sqlschema builds dependency graphs of database tables from their
+// foreign keys.
+//
+// A table depends on every table it references, so sorting the graph gives
+// an order for creating and seeding tables, and sorting its
+// [topo.Graph.Reverse] gives an order for truncating or dropping them.
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// ForeignKey is a reference from one table to another.
+type ForeignKey struct {
+	Table           string
+	ReferencedTable string
+}
+
+// Queries for [Load] that list the foreign keys of the tables in a schema.
+// The schema name is the only parameter.
+const (
+	PostgresQuery = `
+SELECT t.table_name, pk.table_name
+FROM information_schema.tables t
+LEFT JOIN information_schema.table_constraints fk
+  ON fk.table_schema = t.table_schema
+  AND fk.table_name = t.table_name
+  AND fk.constraint_type = 'FOREIGN KEY'
+LEFT JOIN information_schema.referential_constraints rc
+  ON rc.constraint_schema = fk.constraint_schema
+  AND rc.constraint_name = fk.constraint_name
+LEFT JOIN information_schema.table_constraints pk
+  ON pk.constraint_schema = rc.unique_constraint_schema
+  AND pk.constraint_name = rc.unique_constraint_name
+WHERE t.table_schema = $1 AND t.table_type = 'BASE TABLE'
+ORDER BY t.table_name`
+
+	MySQLQuery = `
+SELECT t.table_name, rc.referenced_table_name
+FROM information_schema.tables t
+LEFT JOIN information_schema.referential_constraints rc
+  ON rc.constraint_schema = t.table_schema
+  AND rc.table_name = t.table_name
+WHERE t.table_schema = ? AND t.table_type = 'BASE TABLE'
+ORDER BY t.table_name`
+)
+
+// Load runs a query returning rows of (table, referenced table) and
+// collects them into a list of tables and their foreign keys. Tables
+// without foreign keys should be returned with a NULL referenced table so
+// they are still included, as [PostgresQuery] and [MySQLQuery] do.
+func Load(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, []ForeignKey, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	var fks []ForeignKey
+	for rows.Next() {
+		var table string
+		var referenced sql.NullString
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, nil, fmt.Errorf("querying foreign keys: %w", err)
+		}
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+		if referenced.Valid {
+			fks = append(fks, ForeignKey{Table: table, ReferencedTable: referenced.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("querying foreign keys: %w", err)
+	}
+	return tables, fks, nil
+}
+
+// Graph returns the dependency graph of the given tables.
+//
+// Self-references, such as an employee table referencing its own manager
+// column, don't constrain the order of tables and are left out. Tables
+// that are referenced but not listed are included as nodes without
+// dependencies.
+func Graph(tables []string, fks []ForeignKey) *topo.Graph[string] {
+	deps := make(map[string][]string, len(tables))
+	order := slices.Clone(tables)
+	for _, fk := range fks {
+		if !slices.Contains(order, fk.Table) {
+			order = append(order, fk.Table)
+		}
+		if fk.Table == fk.ReferencedTable || slices.Contains(deps[fk.Table], fk.ReferencedTable) {
+			continue
+		}
+		deps[fk.Table] = append(deps[fk.Table], fk.ReferencedTable)
+	}
+
+	var g topo.Graph[string]
+	for _, table := range order {
+		g.AddNode(table, deps[table])
+	}
+	return &g
+}
@@ -0,0 +1,103 @@
+package sqlschema_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/importers/sqlschema"
+)
+
+// TestLoad checks building a graph from query results.
+func TestLoad(t *testing.T) {
+	db := sql.OpenDB(fakeConnector{rows: [][2]any{
+		{"customers", nil},
+		{"employees", "employees"},
+		{"order_items", "orders"},
+		{"order_items", "products"},
+		{"orders", "customers"},
+		{"orders", "employees"},
+		{"products", nil},
+	}})
+	defer db.Close()
+
+	tables, fks, err := sqlschema.Load(context.Background(), db, sqlschema.PostgresQuery, "public")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedTables := []string{"customers", "employees", "order_items", "orders", "products"}
+	if !slices.Equal(tables, expectedTables) {
+		t.Errorf("Expected tables %v, got %v", expectedTables, tables)
+	}
+	if len(fks) != 5 {
+		t.Errorf("Expected 5 foreign keys, got %d", len(fks))
+	}
+
+	g := sqlschema.Graph(tables, fks)
+	seed, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range seed {
+		slices.Sort(seed[i])
+	}
+	expected := [][]string{{"customers", "employees", "products"}, {"orders"}, {"order_items"}}
+	if !reflect.DeepEqual(seed, expected) {
+		t.Errorf("Expected %v, got %v", expected, seed)
+	}
+
+	truncate, err := g.Reverse().SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range truncate {
+		slices.Sort(truncate[i])
+	}
+	expected = [][]string{{"order_items"}, {"orders", "products"}, {"customers", "employees"}}
+	if !reflect.DeepEqual(truncate, expected) {
+		t.Errorf("Expected %v, got %v", expected, truncate)
+	}
+}
+
+// fakeConnector is a database/sql driver that answers every query with
+// the same rows.
+type fakeConnector struct {
+	rows [][2]any
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn fakeConnector
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type fakeStmt fakeConnector
+
+func (s fakeStmt) Close() error                               { return nil }
+func (s fakeStmt) NumInput() int                              { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][2]any
+}
+
+func (r *fakeRows) Columns() []string { return []string{"table_name", "referenced_table_name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package systemd builds dependency graphs from systemd unit files.
+//
+// Units become nodes. A unit depends on every unit it is ordered after
+// (After=, or the other unit's Before=), since systemd only orders units
+// by those: a unit that requires another (Requires=, Requisite=,
+// BindsTo=) without being ordered after it starts alongside it. Those
+// requirements are reported separately, as are Wants= dependencies, which
+// are weaker still: the unit starts even if the wanted unit fails.
+package systemd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// Unit holds the dependency settings from the [Unit] section of a unit.
+type Unit struct {
+	Name      string
+	Requires  []string
+	Requisite []string
+	BindsTo   []string
+	Wants     []string
+	After     []string
+	Before    []string
+}
+
+// unitTypes are the file extensions of unit files.
+var unitTypes = []string{
+	".service", ".socket", ".target", ".timer", ".mount", ".automount",
+	".path", ".device", ".swap", ".slice", ".scope",
+}
+
+// Parse reads a unit file, or a drop-in for one, into u. Settings are
+// accumulated onto those already in u, and an empty assignment such as
+// "After=" clears any earlier values, just as systemd does.
+func Parse(u *Unit, r io.Reader) error {
+	section := ""
+	var current strings.Builder
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if current.Len() == 0 && (strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")) {
+			continue
+		}
+		if cont, ok := strings.CutSuffix(line, `\`); ok {
+			current.WriteString(cont)
+			current.WriteByte(' ')
+			continue
+		}
+		current.WriteString(line)
+		line = current.String()
+		current.Reset()
+
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		if section != "Unit" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected key=value", lineNo)
+		}
+		var list *[]string
+		switch strings.TrimSpace(key) {
+		case "Requires":
+			list = &u.Requires
+		case "Requisite":
+			list = &u.Requisite
+		case "BindsTo":
+			list = &u.BindsTo
+		case "Wants":
+			list = &u.Wants
+		case "After":
+			list = &u.After
+		case "Before":
+			list = &u.Before
+		default:
+			continue
+		}
+		values := strings.Fields(value)
+		if len(values) == 0 {
+			*list = nil
+			continue
+		}
+		for _, v := range values {
+			if !slices.Contains(*list, v) {
+				*list = append(*list, v)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Scan reads every unit file in the directory root of fsys, along with any
+// drop-ins in "<unit>.d/*.conf" directories. Units are returned sorted by
+// name.
+func Scan(fsys fs.FS, root string) ([]Unit, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []Unit
+	for _, entry := range entries {
+		if entry.IsDir() || !isUnitFile(entry.Name()) {
+			continue
+		}
+		u := Unit{Name: entry.Name()}
+		if err := parseFile(fsys, &u, path.Join(root, entry.Name())); err != nil {
+			return nil, err
+		}
+
+		// drop-ins are applied in lexical order, after the unit itself
+		dropIns, err := fs.Glob(fsys, path.Join(root, entry.Name()+".d", "*.conf"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(dropIns)
+		for _, dropIn := range dropIns {
+			if err := parseFile(fsys, &u, dropIn); err != nil {
+				return nil, err
+			}
+		}
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+// Graph returns the boot ordering graph of the given units.
+//
+// Units that are referenced but not among those given, such as targets
+// shipped by the distribution, are included as nodes without dependencies,
+// as are units only required by others; see Requirements.
+func Graph(units []Unit) *topo.Graph[string] {
+	deps := make(map[string][]string)
+	for _, u := range units {
+		deps[u.Name] = appendUnique(deps[u.Name], u.After)
+		for _, before := range u.Before {
+			deps[before] = appendUnique(deps[before], []string{u.Name})
+		}
+		for _, required := range slices.Concat(u.Requires, u.Requisite, u.BindsTo) {
+			deps[required] = appendUnique(deps[required])
+		}
+	}
+
+	var g topo.Graph[string]
+	for _, name := range sortedKeys(deps) {
+		g.AddNode(name, deps[name])
+	}
+	return &g
+}
+
+// Requirements returns the units each unit that has any requires, with
+// Requires=, Requisite=, or BindsTo=. They don't order the units; see
+// Graph.
+func Requirements(units []Unit) map[string][]string {
+	required := make(map[string][]string)
+	for _, u := range units {
+		if deps := appendUnique(nil, u.Requires, u.Requisite, u.BindsTo); len(deps) > 0 {
+			required[u.Name] = deps
+		}
+	}
+	return required
+}
+
+// SoftEdges returns the Wants= dependencies of each unit that has any.
+func SoftEdges(units []Unit) map[string][]string {
+	soft := make(map[string][]string)
+	for _, u := range units {
+		if len(u.Wants) > 0 {
+			soft[u.Name] = slices.Clone(u.Wants)
+		}
+	}
+	return soft
+}
+
+func parseFile(fsys fs.FS, u *Unit, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := Parse(u, f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func isUnitFile(name string) bool {
+	return slices.Contains(unitTypes, path.Ext(name))
+}
+
+func appendUnique(dst []string, lists ...[]string) []string {
+	for _, list := range lists {
+		for _, s := range list {
+			if !slices.Contains(dst, s) {
+				dst = append(dst, s)
+			}
+		}
+	}
+	return dst
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package systemd_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/systemd"
+)
+
+// TestScan checks unit parsing, drop-ins, and graph construction.
+func TestScan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"units/network.target": {Data: []byte("[Unit]\nDescription=Network\n")},
+		"units/postgres.service": {Data: []byte(`
+[Unit]
+Description=PostgreSQL
+After=network.target
+Before=app.service
+
+[Service]
+ExecStart=/usr/bin/postgres
+`)},
+		"units/app.service": {Data: []byte(`
+[Unit]
+# the app needs its database
+Requires=postgres.service
+Wants=metrics.service \
+      logs.service
+After=old.service
+
+[Install]
+WantedBy=multi-user.target
+`)},
+		"units/app.service.d/10-order.conf": {Data: []byte("[Unit]\nAfter=\nAfter=network.target\n")},
+		"units/metrics.service":             {Data: []byte("[Unit]\nAfter=network.target\n")},
+		"units/README":                      {Data: []byte("not a unit")},
+	}
+
+	units, err := systemd.Scan(fsys, "units")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+	expectedNames := []string{"app.service", "metrics.service", "network.target", "postgres.service"}
+	if !slices.Equal(names, expectedNames) {
+		t.Errorf("Expected units %v, got %v", expectedNames, names)
+	}
+
+	app := units[0]
+	if !slices.Equal(app.After, []string{"network.target"}) {
+		t.Errorf("Expected drop-in to reset After=, got %v", app.After)
+	}
+
+	layers, err := topo.SortedLayers(systemd.Graph(units))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedLayers := [][]string{
+		{"network.target"},
+		{"metrics.service", "postgres.service"},
+		{"app.service"},
+	}
+	if !reflect.DeepEqual(layers, expectedLayers) {
+		t.Errorf("Expected %v, got %v", expectedLayers, layers)
+	}
+
+	soft := systemd.SoftEdges(units)
+	expectedSoft := map[string][]string{
+		"app.service": {"metrics.service", "logs.service"},
+	}
+	if !reflect.DeepEqual(soft, expectedSoft) {
+		t.Errorf("Expected soft edges %v, got %v", expectedSoft, soft)
+	}
+}
+
+// TestGraphRequiresWithoutAfter checks that requiring a unit without being
+// ordered after it doesn't order the units, as with systemd.
+func TestGraphRequiresWithoutAfter(t *testing.T) {
+	units := []systemd.Unit{
+		{Name: "app.service", Requires: []string{"cache.service"}, BindsTo: []string{"db.service"}, After: []string{"db.service"}},
+		{Name: "cache.service"},
+	}
+	layers, err := topo.SortedLayers(systemd.Graph(units))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"cache.service", "db.service"}, {"app.service"}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+	if deps := systemd.Graph(units).Dependencies("app.service"); !reflect.DeepEqual(deps, []string{"db.service"}) {
+		t.Errorf("Expected app.service ordered after [db.service], got %v", deps)
+	}
+
+	required := systemd.Requirements(units)
+	expectedRequired := map[string][]string{"app.service": {"cache.service", "db.service"}}
+	if !reflect.DeepEqual(required, expectedRequired) {
+		t.Errorf("Expected requirements %v, got %v", expectedRequired, required)
+	}
+}
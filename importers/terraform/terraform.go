@@ -0,0 +1,387 @@
+// Package terraform builds dependency graphs of Terraform resources.
+//
+// Graphs can be read from the DOT output of "terraform graph", or from the
+// JSON output of "terraform show -json" for either a state or a plan. To
+// order destroys, sort the [topo.Graph.Reverse] of the graph.
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/internal/dot"
+)
+
+// ParseDOT reads the output of "terraform graph".
+//
+// Node names are normalized to plain addresses, dropping the "[root] "
+// prefix and suffixes such as " (expand)" used by older Terraform versions,
+// so that "[root] aws_instance.web (expand)" becomes "aws_instance.web".
+// Terraform's internal bookkeeping nodes, like "root" and "meta.*", are
+// left out.
+func ParseDOT(r io.Reader) (*topo.Graph[string], error) {
+	parsed, err := dot.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing terraform graph: %w", err)
+	}
+
+	var order []string
+	deps := make(map[string][]string)
+	for _, node := range parsed.Nodes {
+		if name, keep := normalize(node); keep {
+			if _, exists := deps[name]; !exists {
+				deps[name] = nil
+				order = append(order, name)
+			}
+		}
+	}
+	for _, edge := range parsed.Edges {
+		from, keepFrom := normalize(edge.From)
+		to, keepTo := normalize(edge.To)
+		if !keepFrom || !keepTo || from == to || slices.Contains(deps[from], to) {
+			continue
+		}
+		deps[from] = append(deps[from], to)
+	}
+
+	var g topo.Graph[string]
+	for _, name := range order {
+		g.AddNode(name, deps[name])
+	}
+	return &g, nil
+}
+
+// state mirrors the parts of the JSON state and plan representations
+// that record dependencies.
+type state struct {
+	Values        *stateValues `json:"values"`
+	PlannedValues *stateValues `json:"planned_values"`
+	PriorState    *struct {
+		Values *stateValues `json:"values"`
+	} `json:"prior_state"`
+	Configuration *struct {
+		RootModule configModule `json:"root_module"`
+	} `json:"configuration"`
+}
+
+type stateValues struct {
+	RootModule module `json:"root_module"`
+}
+
+type module struct {
+	Resources []struct {
+		Address   string   `json:"address"`
+		DependsOn []string `json:"depends_on"`
+	} `json:"resources"`
+	ChildModules []module `json:"child_modules"`
+}
+
+// configModule mirrors the parts of a plan's configuration that record
+// dependencies: the explicit depends_on of each resource, and the
+// references in its expressions.
+type configModule struct {
+	Resources []struct {
+		Address     string          `json:"address"`
+		DependsOn   []string        `json:"depends_on"`
+		Expressions json.RawMessage `json:"expressions"`
+	} `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module configModule `json:"module"`
+	} `json:"module_calls"`
+	Outputs map[string]struct {
+		Expression json.RawMessage `json:"expression"`
+	} `json:"outputs"`
+}
+
+// ParseJSON reads the output of "terraform show -json", for either a state
+// file or a saved plan.
+//
+// Nodes are resource instance addresses. Terraform records dependencies
+// on resources rather than instances, so an instance depends on every
+// instance of the resources it references. For a plan, the nodes are the
+// resources in its planned values, including those it would create, and
+// their dependencies come from its configuration: the resources each
+// depends on explicitly or refers to in its arguments. A reference to a
+// module's output is to the resources the output refers to, and one to
+// the whole module, like depends_on = [module.net], is to every resource
+// in it. Plans without planned values fall back on the dependencies
+// recorded in their prior state.
+func ParseJSON(r io.Reader) (*topo.Graph[string], error) {
+	var s state
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("parsing terraform JSON: %w", err)
+	}
+	if s.PlannedValues != nil {
+		return planGraph(s), nil
+	}
+
+	values := s.Values
+	if s.PriorState != nil && s.PriorState.Values != nil {
+		values = s.PriorState.Values
+	}
+
+	var g topo.Graph[string]
+	if values == nil {
+		return &g, nil
+	}
+
+	// depends_on holds configuration addresses, without the instance keys
+	// of the resource or of the modules it's in
+	resources := collect(values.RootModule)
+	instances := make(map[string][]string)
+	for _, res := range resources {
+		base := configAddress(res.address)
+		instances[base] = append(instances[base], res.address)
+	}
+
+	for _, res := range resources {
+		var deps []string
+		for _, dep := range res.dependsOn {
+			deps = appendInstances(deps, instances[configAddress(dep)])
+		}
+		g.AddNode(res.address, deps)
+	}
+	return &g, nil
+}
+
+// resource is a resource instance, and the resources it depends on, as
+// recorded in a state.
+type resource struct {
+	address   string
+	dependsOn []string
+}
+
+// collect returns the resources of a module and its children, in
+// depth-first module order.
+func collect(m module) []resource {
+	var resources []resource
+	for _, res := range m.Resources {
+		resources = append(resources, resource{res.Address, res.DependsOn})
+	}
+	for _, child := range m.ChildModules {
+		resources = append(resources, collect(child)...)
+	}
+	return resources
+}
+
+// planGraph returns the graph of a plan's planned values, with the
+// dependencies in its configuration.
+func planGraph(s state) *topo.Graph[string] {
+	resources := collect(s.PlannedValues.RootModule)
+	// instances are grouped by their configuration address, without the
+	// instance keys of the resource or of the modules it's in
+	instances := make(map[string][]string)
+	for _, res := range resources {
+		base := configAddress(res.address)
+		instances[base] = append(instances[base], res.address)
+	}
+
+	// modules are the configurations of the modules, by their prefix
+	modules := make(map[string]configModule)
+	var index func(m configModule, prefix string)
+	index = func(m configModule, prefix string) {
+		modules[prefix] = m
+		for name, call := range m.ModuleCalls {
+			index(call.Module, prefix+"module."+name+".")
+		}
+	}
+	if s.Configuration != nil {
+		index(s.Configuration.RootModule, "")
+	}
+
+	deps := make(map[string][]string)
+	var walk func(m configModule, prefix string)
+	walk = func(m configModule, prefix string) {
+		for _, res := range m.Resources {
+			address := prefix + res.Address
+			for _, ref := range slices.Concat(res.DependsOn, specific(references(res.Expressions))) {
+				for _, dep := range resolveAll(ref, prefix, instances, modules) {
+					if dep != address && !slices.Contains(deps[address], dep) {
+						deps[address] = append(deps[address], dep)
+					}
+				}
+			}
+		}
+		// module calls are walked in name order, for stable dependencies
+		names := make([]string, 0, len(m.ModuleCalls))
+		for name := range m.ModuleCalls {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			walk(m.ModuleCalls[name].Module, prefix+"module."+name+".")
+		}
+	}
+	if s.Configuration != nil {
+		walk(s.Configuration.RootModule, "")
+	}
+
+	var g topo.Graph[string]
+	for _, res := range resources {
+		var resDeps []string
+		for _, dep := range deps[configAddress(res.address)] {
+			resDeps = appendInstances(resDeps, instances[dep])
+		}
+		g.AddNode(res.address, resDeps)
+	}
+	return &g
+}
+
+// references returns every reference in a resource's expressions, as
+// listed in the "references" of each, like "aws_vpc.main.id".
+func references(expressions json.RawMessage) []string {
+	if len(expressions) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(expressions, &v); err != nil {
+		return nil
+	}
+	var refs []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if list, ok := v["references"].([]any); ok {
+				for _, ref := range list {
+					if ref, ok := ref.(string); ok {
+						refs = append(refs, ref)
+					}
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(v)
+	return refs
+}
+
+// specific drops the references that another in refs is more specific
+// than, like "module.net" alongside "module.net.subnet_id", which
+// Terraform lists for every reference to a module's output.
+func specific(refs []string) []string {
+	return slices.DeleteFunc(slices.Clone(refs), func(ref string) bool {
+		return slices.ContainsFunc(refs, func(other string) bool {
+			return strings.HasPrefix(other, ref+".") || strings.HasPrefix(other, ref+"[")
+		})
+	})
+}
+
+// resolveAll returns the configuration addresses of the resources a
+// reference made in the module at prefix is to: the resource it names, or
+// for a module's output, those the output refers to, or for a whole
+// module, every resource in it.
+func resolveAll(ref, prefix string, instances map[string][]string, modules map[string]configModule) []string {
+	if dep, ok := resolve(prefix+ref, instances); ok {
+		return []string{dep}
+	}
+	parts := strings.SplitN(configAddress(ref), ".", 4)
+	if len(parts) < 2 || parts[0] != "module" {
+		return nil
+	}
+	child := prefix + "module." + parts[1] + "."
+	m, known := modules[child]
+	if len(parts) > 2 && known {
+		if output, ok := m.Outputs[parts[2]]; ok {
+			var deps []string
+			for _, ref := range specific(references(output.Expression)) {
+				deps = append(deps, resolveAll(ref, child, instances, modules)...)
+			}
+			return deps
+		}
+	}
+	// the whole module, or an output the configuration doesn't show
+	var deps []string
+	for address := range instances {
+		if strings.HasPrefix(address, child) {
+			deps = append(deps, address)
+		}
+	}
+	slices.Sort(deps)
+	return deps
+}
+
+// resolve returns the configuration address of the resource a reference
+// is to, dropping the attributes after it, like ".id", reporting whether
+// it's to a resource in the plan. References to variables, locals, and
+// module outputs aren't.
+func resolve(ref string, instances map[string][]string) (string, bool) {
+	ref = configAddress(ref)
+	for {
+		if _, ok := instances[ref]; ok {
+			return ref, true
+		}
+		i := strings.LastIndexByte(ref, '.')
+		if i < 0 {
+			return "", false
+		}
+		ref = ref[:i]
+	}
+}
+
+// appendInstances appends the instances not already in deps.
+func appendInstances(deps, instances []string) []string {
+	for _, inst := range instances {
+		if !slices.Contains(deps, inst) {
+			deps = append(deps, inst)
+		}
+	}
+	return deps
+}
+
+// normalize converts a DOT node name into a resource address, reporting
+// whether the node should be kept.
+func normalize(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "[root] ")
+	if i := strings.LastIndex(name, " ("); i >= 0 && strings.HasSuffix(name, ")") {
+		name = name[:i]
+	}
+	if name == "root" || strings.HasPrefix(name, "meta.") {
+		return "", false
+	}
+	return name, true
+}
+
+// configAddress strips every instance key from an address, of the resource
+// and of the modules it's in, so that "module.net[0].aws_subnet.a["x"]"
+// becomes "module.net.aws_subnet.a".
+func configAddress(address string) string {
+	var b strings.Builder
+	depth := 0
+	inString := false
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		case c == '"' && depth > 0:
+			inString = true
+			continue
+		case c == '[':
+			depth++
+			continue
+		case c == ']' && depth > 0:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,262 @@
+package terraform_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/importers/terraform"
+)
+
+// TestParseDOT checks parsing the output of terraform graph.
+func TestParseDOT(t *testing.T) {
+	const graph = `digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] aws_instance.web (expand)" [label = "aws_instance.web", shape = "box"]
+		"[root] aws_security_group.web (expand)" [label = "aws_security_group.web", shape = "box"]
+		"[root] aws_vpc.main (expand)" [label = "aws_vpc.main", shape = "box"]
+		"[root] provider[\"registry.terraform.io/hashicorp/aws\"]" [label = "provider[\"registry.terraform.io/hashicorp/aws\"]", shape = "diamond"]
+		"[root] aws_instance.web (expand)" -> "[root] aws_security_group.web (expand)"
+		"[root] aws_security_group.web (expand)" -> "[root] aws_vpc.main (expand)"
+		"[root] aws_vpc.main (expand)" -> "[root] provider[\"registry.terraform.io/hashicorp/aws\"]"
+		"[root] meta.count-boundary (EachMode fixup)" -> "[root] aws_instance.web (expand)"
+		"[root] root" -> "[root] meta.count-boundary (EachMode fixup)"
+	}
+}
+`
+	g, err := terraform.ParseDOT(strings.NewReader(graph))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{
+		{`provider["registry.terraform.io/hashicorp/aws"]`},
+		{"aws_vpc.main"},
+		{"aws_security_group.web"},
+		{"aws_instance.web"},
+	}
+	checkLayers(t, g, expected)
+
+	// destroys happen in the opposite order
+	slices.Reverse(expected)
+	checkLayers(t, g.Reverse(), expected)
+}
+
+// TestParseJSON checks parsing the JSON representation of a plan.
+func TestParseJSON(t *testing.T) {
+	const plan = `{
+  "format_version": "1.2",
+  "prior_state": {
+    "values": {
+      "root_module": {
+        "resources": [
+          {"address": "aws_vpc.main"},
+          {"address": "aws_instance.web[0]", "depends_on": ["module.net.aws_subnet.a", "aws_vpc.main"]},
+          {"address": "aws_instance.web[1]", "depends_on": ["module.net.aws_subnet.a", "aws_vpc.main"]}
+        ],
+        "child_modules": [
+          {
+            "address": "module.net",
+            "resources": [
+              {"address": "module.net.aws_subnet.a", "depends_on": ["aws_vpc.main"]}
+            ]
+          }
+        ]
+      }
+    }
+  }
+}`
+	g, err := terraform.ParseJSON(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkLayers(t, g, [][]string{
+		{"aws_vpc.main"},
+		{"module.net.aws_subnet.a"},
+		{"aws_instance.web[0]", "aws_instance.web[1]"},
+	})
+}
+
+func checkLayers(t *testing.T, g *topo.Graph[string], expected [][]string) {
+	t.Helper()
+	layers, err := topo.SortedLayers(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestParseJSONCreatePlan checks that a plan creating everything from
+// scratch gives the graph of what it would create.
+func TestParseJSONCreatePlan(t *testing.T) {
+	const plan = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main"},
+        {"address": "aws_instance.web[0]"},
+        {"address": "aws_instance.web[1]"},
+        {"address": "aws_eip.web"}
+      ],
+      "child_modules": [
+        {
+          "address": "module.net",
+          "resources": [
+            {"address": "module.net.aws_subnet.a[\"eu\"]"},
+            {"address": "module.net.aws_subnet.a[\"us\"]"}
+          ]
+        }
+      ]
+    }
+  },
+  "prior_state": {"values": {"root_module": {}}},
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "expressions": {"cidr_block": {"constant_value": "10.0.0.0/16"}}},
+        {
+          "address": "aws_instance.web",
+          "expressions": {"ami": {"references": ["var.ami"]}},
+          "depends_on": ["module.net.aws_subnet.a"]
+        },
+        {
+          "address": "aws_eip.web",
+          "expressions": {"instance": {"references": ["aws_instance.web[0].id", "aws_instance.web[0]", "aws_instance.web"]}}
+        }
+      ],
+      "module_calls": {
+        "net": {
+          "module": {
+            "resources": [
+              {
+                "address": "aws_subnet.a",
+                "expressions": {"tags": [{"vpc": {"references": ["var.vpc_id"]}}], "vpc_id": {"references": ["aws_vpc.main.id"]}}
+              }
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+	g, err := terraform.ParseJSON(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkLayers(t, g, [][]string{
+		{"aws_vpc.main", "module.net.aws_subnet.a[\"eu\"]", "module.net.aws_subnet.a[\"us\"]"},
+		{"aws_instance.web[0]", "aws_instance.web[1]"},
+		{"aws_eip.web"},
+	})
+}
+
+// TestParseJSONCountModule checks that a state's dependencies on the
+// resources of a module with count reach every instance of them.
+func TestParseJSONCountModule(t *testing.T) {
+	const state = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main"},
+        {"address": "aws_instance.web", "depends_on": ["module.net.aws_subnet.a", "aws_vpc.main"]}
+      ],
+      "child_modules": [
+        {
+          "address": "module.net[0]",
+          "resources": [
+            {"address": "module.net[0].aws_subnet.a", "depends_on": ["aws_vpc.main"]}
+          ]
+        },
+        {
+          "address": "module.net[1]",
+          "resources": [
+            {"address": "module.net[1].aws_subnet.a", "depends_on": ["aws_vpc.main"]}
+          ]
+        }
+      ]
+    }
+  }
+}`
+	g, err := terraform.ParseJSON(strings.NewReader(state))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkLayers(t, g, [][]string{
+		{"aws_vpc.main"},
+		{"module.net[0].aws_subnet.a", "module.net[1].aws_subnet.a"},
+		{"aws_instance.web"},
+	})
+}
+
+// TestParseJSONModuleOutputs checks that references to a module's outputs
+// in a plan depend on the resources the outputs refer to, and that
+// depending on a whole module depends on all of its resources.
+func TestParseJSONModuleOutputs(t *testing.T) {
+	const plan = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_instance.web"},
+        {"address": "aws_route53_record.web"}
+      ],
+      "child_modules": [
+        {
+          "address": "module.net",
+          "resources": [
+            {"address": "module.net.aws_vpc.main"},
+            {"address": "module.net.aws_subnet.a"},
+            {"address": "module.net.aws_flow_log.main"}
+          ]
+        }
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_instance.web",
+          "expressions": {"subnet_id": {"references": ["module.net.subnet_id", "module.net"]}}
+        },
+        {
+          "address": "aws_route53_record.web",
+          "depends_on": ["module.net"]
+        }
+      ],
+      "module_calls": {
+        "net": {
+          "module": {
+            "outputs": {
+              "subnet_id": {"expression": {"references": ["aws_subnet.a.id", "aws_subnet.a"]}}
+            },
+            "resources": [
+              {"address": "aws_vpc.main"},
+              {"address": "aws_subnet.a", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+              {"address": "aws_flow_log.main", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+	g, err := terraform.ParseJSON(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := g.Dependencies("aws_instance.web"); !reflect.DeepEqual(deps, []string{"module.net.aws_subnet.a"}) {
+		t.Errorf("Expected aws_instance.web to depend on [module.net.aws_subnet.a], got %v", deps)
+	}
+	expected := []string{"module.net.aws_flow_log.main", "module.net.aws_subnet.a", "module.net.aws_vpc.main"}
+	if deps := g.Dependencies("aws_route53_record.web"); !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected aws_route53_record.web to depend on %v, got %v", expected, deps)
+	}
+}
@@ -0,0 +1,270 @@
+// Package dot parses the subset of the Graphviz DOT language needed to
+// read dependency graphs written by other tools: node and edge statements,
+// possibly inside subgraphs. Attributes are skipped.
+package dot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Graph is a parsed DOT graph.
+type Graph struct {
+	// Nodes are all node IDs, in order of first appearance.
+	Nodes []string
+	// Edges are in the order they appear. Edge chains like "a -> b -> c"
+	// are split into separate edges.
+	Edges []Edge
+}
+
+// Edge is a single edge.
+type Edge struct {
+	From, To string
+}
+
+// Parse reads a DOT graph. Only the first graph in the input is read.
+func Parse(r io.Reader) (*Graph, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(src), seen: make(map[string]bool)}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line(), err)
+	}
+	return &p.g, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokID
+	tokEdge
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	src  string
+	pos  int
+	g    Graph
+	seen map[string]bool
+}
+
+func (p *parser) parse() error {
+	// skip the header, "[strict] (graph|digraph) [ID]"
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == tokEOF {
+			return errors.New("no graph found")
+		}
+		if tok == (token{tokPunct, "{"}) {
+			break
+		}
+	}
+
+	depth := 1
+	var chain []string
+	flush := func() {
+		if len(chain) == 1 {
+			p.addNode(chain[0])
+		}
+		for i := 1; i < len(chain); i++ {
+			p.addNode(chain[i-1])
+			p.addNode(chain[i])
+			p.g.Edges = append(p.g.Edges, Edge{From: chain[i-1], To: chain[i]})
+		}
+		chain = chain[:0]
+	}
+
+	for depth > 0 {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokEOF:
+			return errors.New("unexpected end of input")
+		case tokEdge:
+			if len(chain) == 0 {
+				return errors.New("edge without a source")
+			}
+			next, err := p.next()
+			if err != nil {
+				return err
+			}
+			if next.kind != tokID {
+				return fmt.Errorf("expected node after edge operator, got %q", next.text)
+			}
+			chain = append(chain, next.text)
+		case tokID:
+			flush()
+			if strings.EqualFold(tok.text, "subgraph") {
+				// skip the subgraph's ID, if it has one
+				mark := p.pos
+				if next, err := p.next(); err != nil {
+					return err
+				} else if next.kind != tokID {
+					p.pos = mark
+				}
+				continue
+			}
+			if isKeyword(tok.text) {
+				continue
+			}
+			// "ID = ID" statements set graph attributes
+			mark := p.pos
+			if next, err := p.next(); err != nil {
+				return err
+			} else if next == (token{tokPunct, "="}) {
+				if _, err := p.next(); err != nil {
+					return err
+				}
+				continue
+			}
+			p.pos = mark
+			chain = append(chain, tok.text)
+		case tokPunct:
+			switch tok.text {
+			case "{":
+				flush()
+				depth++
+			case "}":
+				flush()
+				depth--
+			case "[":
+				if err := p.skipAttributes(); err != nil {
+					return err
+				}
+			case ";", ",":
+				flush()
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) addNode(id string) {
+	if !p.seen[id] {
+		p.seen[id] = true
+		p.g.Nodes = append(p.g.Nodes, id)
+	}
+}
+
+func (p *parser) skipAttributes() error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.kind == tokEOF:
+			return errors.New("unterminated attribute list")
+		case tok == (token{tokPunct, "]"}):
+			return nil
+		}
+	}
+}
+
+func isKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "node", "edge", "graph":
+		return true
+	}
+	return false
+}
+
+// next returns the next token.
+func (p *parser) next() (token, error) {
+	p.skipSpaceAndComments()
+	if p.pos >= len(p.src) {
+		return token{kind: tokEOF}, nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		return p.quoted()
+	case strings.HasPrefix(p.src[p.pos:], "->"), strings.HasPrefix(p.src[p.pos:], "--"):
+		p.pos += 2
+		return token{kind: tokEdge, text: p.src[p.pos-2 : p.pos]}, nil
+	case strings.IndexByte(punctuation, c) >= 0:
+		p.pos++
+		return token{kind: tokPunct, text: string(c)}, nil
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && !isSpace(p.src[p.pos]) &&
+			strings.IndexByte(punctuation+`"`, p.src[p.pos]) < 0 &&
+			!strings.HasPrefix(p.src[p.pos:], "->") && !strings.HasPrefix(p.src[p.pos:], "--") {
+			p.pos++
+		}
+		return token{kind: tokID, text: p.src[start:p.pos]}, nil
+	}
+}
+
+const punctuation = "{}[];,=:"
+
+// quoted reads a quoted ID. The only escape sequence is \", per the DOT
+// grammar; other backslashes are kept as-is, except that a backslash
+// before a newline continues the string on the next line.
+func (p *parser) quoted() (token, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return token{kind: tokID, text: b.String()}, nil
+		case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '"':
+			b.WriteByte('"')
+			p.pos++
+		case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n':
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return token{}, errors.New("unterminated string")
+}
+
+func (p *parser) skipSpaceAndComments() {
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		switch {
+		case isSpace(rest[0]):
+			p.pos++
+		case rest[0] == '#', strings.HasPrefix(rest, "//"):
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				p.pos += end + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case strings.HasPrefix(rest, "/*"):
+			if end := strings.Index(rest[2:], "*/"); end >= 0 {
+				p.pos += end + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) line() int {
+	return strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
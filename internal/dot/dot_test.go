@@ -0,0 +1,59 @@
+package dot_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo/internal/dot"
+)
+
+// TestParse checks parsing of node and edge statements.
+func TestParse(t *testing.T) {
+	const src = `/* generated */
+strict digraph "deps" {
+	rankdir = LR; node [shape=box, label="x -> y"]
+	// comment
+	"a" [label="A"]
+	a -> b -> "c \"quoted\""
+	subgraph cluster_0 {
+		d -- e
+		"f\nf2"
+	}
+	# trailing
+}
+digraph ignored { z }
+`
+	g, err := dot.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedNodes := []string{"a", "b", `c "quoted"`, "d", "e", `f\nf2`}
+	if !reflect.DeepEqual(g.Nodes, expectedNodes) {
+		t.Errorf("Expected nodes %q, got %q", expectedNodes, g.Nodes)
+	}
+	expectedEdges := []dot.Edge{
+		{From: "a", To: "b"},
+		{From: "b", To: `c "quoted"`},
+		{From: "d", To: "e"},
+	}
+	if !reflect.DeepEqual(g.Edges, expectedEdges) {
+		t.Errorf("Expected edges %q, got %q", expectedEdges, g.Edges)
+	}
+}
+
+// TestParseErrors checks that malformed input is rejected.
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"digraph { a -> }",
+		`digraph { "a }`,
+		"digraph { a [label=x ",
+		"digraph { a",
+	} {
+		if _, err := dot.Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("Expected error parsing %q", src)
+		}
+	}
+}
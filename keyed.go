@@ -0,0 +1,154 @@
+package topo
+
+import "slices"
+
+// KeyedGraph is a graph of values that are identified by a key, like
+// structs with an ID field. The values themselves don't need to be
+// comparable, so structs containing slices or maps can be nodes directly.
+// Values with the same key are the same node; the last one added is kept.
+// Use GraphBy to create one.
+type KeyedGraph[V any, K comparable] struct {
+	key    func(V) K
+	keys   Graph[K]
+	values map[K]V
+}
+
+// GraphBy returns an empty graph of values identified by key.
+func GraphBy[V any, K comparable](key func(V) K) *KeyedGraph[V, K] {
+	return &KeyedGraph[V, K]{key: key, values: make(map[K]V)}
+}
+
+// Keys returns the graph of the values' keys, for the analyses not offered
+// by KeyedGraph itself. Changes to it change the keyed graph too; values
+// for keys added to it directly are the zero value.
+func (g *KeyedGraph[V, K]) Keys() *Graph[K] {
+	return &g.keys
+}
+
+// AddNode adds a value to the graph with its dependencies, as
+// Graph.AddNode does.
+func (g *KeyedGraph[V, K]) AddNode(value V, deps []V) {
+	g.AddNodeOfKind(value, DefaultEdgeKind, deps)
+}
+
+// AddNodeOfKind adds a value to the graph with its dependencies of the
+// given kind, as Graph.AddNodeOfKind does.
+func (g *KeyedGraph[V, K]) AddNodeOfKind(value V, kind EdgeKind, deps []V) {
+	var keys []K
+	if deps != nil {
+		keys = make([]K, len(deps))
+		for i, dep := range deps {
+			keys[i] = g.remember(dep)
+		}
+	}
+	g.keys.AddNodeOfKind(g.remember(value), kind, keys)
+}
+
+// AddDependency adds a single dependency to a value, as
+// Graph.AddDependency does.
+func (g *KeyedGraph[V, K]) AddDependency(value V, dep V) {
+	g.keys.AddDependency(g.remember(value), g.remember(dep))
+}
+
+// RemoveNode removes a value from the graph, as Graph.RemoveNode does.
+func (g *KeyedGraph[V, K]) RemoveNode(value V) {
+	g.keys.RemoveNode(g.key(value))
+}
+
+// RemoveDependency removes a dependency from a value, as
+// Graph.RemoveDependency does.
+func (g *KeyedGraph[V, K]) RemoveDependency(value V, dep V) {
+	g.keys.RemoveDependency(g.key(value), g.key(dep))
+}
+
+// Value returns the value with a key, and whether it's in the graph.
+func (g *KeyedGraph[V, K]) Value(key K) (V, bool) {
+	if !g.has(key) {
+		var zero V
+		return zero, false
+	}
+	return g.values[key], true
+}
+
+// Nodes returns every value in the graph, as Graph.Nodes does.
+func (g *KeyedGraph[V, K]) Nodes() []V {
+	return g.lookup(g.keys.Nodes())
+}
+
+// Dependencies returns the values that a value depends on, as
+// Graph.Dependencies does.
+func (g *KeyedGraph[V, K]) Dependencies(value V) []V {
+	return g.lookup(g.keys.Dependencies(g.key(value)))
+}
+
+// Ancestors returns every value that any of the given values depends on,
+// as Graph.Ancestors does.
+func (g *KeyedGraph[V, K]) Ancestors(values ...V) []V {
+	return g.lookup(g.keys.Ancestors(g.keysOf(values)...))
+}
+
+// Descendants returns every value that depends on any of the given values,
+// as Graph.Descendants does.
+func (g *KeyedGraph[V, K]) Descendants(values ...V) []V {
+	return g.lookup(g.keys.Descendants(g.keysOf(values)...))
+}
+
+// SortByLayers sorts the graph into layers, as Graph.SortByLayers does.
+func (g *KeyedGraph[V, K]) SortByLayers() ([][]V, error) {
+	layers, err := g.keys.SortByLayers()
+	if err != nil {
+		return nil, err
+	}
+	return g.lookupLayers(layers), nil
+}
+
+// Cycles returns the groups of values that depend on each other in a
+// cycle, as Graph.Cycles does.
+func (g *KeyedGraph[V, K]) Cycles() [][]V {
+	return g.lookupLayers(g.keys.Cycles())
+}
+
+// remember keeps a value as the one for its key, returning the key.
+func (g *KeyedGraph[V, K]) remember(value V) K {
+	key := g.key(value)
+	g.values[key] = value
+	return key
+}
+
+// has reports whether a key is in the graph.
+func (g *KeyedGraph[V, K]) has(key K) bool {
+	return slices.Contains(g.keys.Nodes(), key)
+}
+
+// keysOf returns the keys of values.
+func (g *KeyedGraph[V, K]) keysOf(values []V) []K {
+	keys := make([]K, len(values))
+	for i, value := range values {
+		keys[i] = g.key(value)
+	}
+	return keys
+}
+
+// lookup returns the values with keys.
+func (g *KeyedGraph[V, K]) lookup(keys []K) []V {
+	if keys == nil {
+		return nil
+	}
+	values := make([]V, len(keys))
+	for i, key := range keys {
+		values[i] = g.values[key]
+	}
+	return values
+}
+
+// lookupLayers returns the values with keys, in layers.
+func (g *KeyedGraph[V, K]) lookupLayers(layers [][]K) [][]V {
+	if layers == nil {
+		return nil
+	}
+	values := make([][]V, len(layers))
+	for i, layer := range layers {
+		values[i] = g.lookup(layer)
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// service isn't comparable, because of its slice.
+type service struct {
+	Name  string
+	Ports []int
+}
+
+// TestGraphBy checks a graph of values that aren't comparable.
+func TestGraphBy(t *testing.T) {
+	db := service{"db", []int{5432}}
+	api := service{"api", []int{80, 443}}
+	web := service{"web", nil}
+
+	g := topo.GraphBy(func(s service) string { return s.Name })
+	g.AddNode(api, []service{db})
+	g.AddNode(web, []service{api})
+	g.AddNode(db, nil)
+	// a new value for the same key replaces the old one
+	db2 := service{"db", []int{5433}}
+	g.AddNode(db2, nil)
+
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]service{{db2}, {api}, {web}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+	if deps := g.Dependencies(web); !reflect.DeepEqual(deps, []service{api}) {
+		t.Errorf("Expected [%v], got %v", api, deps)
+	}
+	if ancestors := g.Ancestors(web); !reflect.DeepEqual(ancestors, []service{api, db2}) {
+		t.Errorf("Expected [%v %v], got %v", api, db2, ancestors)
+	}
+	if descendants := g.Descendants(db); !reflect.DeepEqual(descendants, []service{api, web}) {
+		t.Errorf("Expected [%v %v], got %v", api, web, descendants)
+	}
+	if v, ok := g.Value("db"); !ok || !reflect.DeepEqual(v, db2) {
+		t.Errorf("Expected %v, got %v (%v)", db2, v, ok)
+	}
+
+	g.RemoveNode(web)
+	if _, ok := g.Value("web"); ok {
+		t.Errorf("Expected web to be removed")
+	}
+	if nodes := g.Keys().Nodes(); !reflect.DeepEqual(nodes, []string{"api", "db"}) {
+		t.Errorf("Expected [api db], got %v", nodes)
+	}
+}
+
+// TestGraphByCycles checks that cycles are reported as values.
+func TestGraphByCycles(t *testing.T) {
+	a, b := service{Name: "a"}, service{Name: "b"}
+	g := topo.GraphBy(func(s service) string { return s.Name })
+	g.AddNode(a, []service{b})
+	g.AddDependency(b, a)
+
+	if _, err := g.SortByLayers(); err == nil {
+		t.Errorf("Expected an error")
+	}
+	expected := [][]service{{a, b}}
+	if cycles := g.Cycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("Expected %v, got %v", expected, cycles)
+	}
+}
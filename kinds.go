@@ -0,0 +1,153 @@
+package topo
+
+import (
+	"maps"
+	"slices"
+)
+
+// EdgeKind is the kind of a dependency, like "build" or "runtime", so that
+// dependencies of different kinds can be kept in one graph and included or
+// excluded per operation:
+//
+//	g.AddNode("app", []string{"lib"}) // of DefaultEdgeKind
+//	g.AddNodeOfKind("app", "runtime", []string{"db"})
+//	g.Dependencies("app")                    // [lib db]
+//	g.ExcludeKinds("runtime").SortByLayers() // ignores app -> db
+//
+// Each kind of a node's dependencies is declared separately: adding a node
+// again replaces its dependencies of that kind only. Dependencies and all
+// operations see the dependencies of every kind together. Reverse,
+// PruneTo, PruneFrom, and Map keep the kinds; Union, Intersection,
+// Difference, and Expand merge them into DefaultEdgeKind.
+type EdgeKind string
+
+// DefaultEdgeKind is the kind of dependencies added with AddNode.
+const DefaultEdgeKind EdgeKind = ""
+
+// AddNodeOfKind adds a node to the graph with its dependencies of the given
+// kind, replacing any it had of that kind before.
+func (g *Graph[T]) AddNodeOfKind(value T, kind EdgeKind, deps []T) {
+	value, deps = g.normalize(value), g.normalizeAll(deps)
+	if !g.allowNode(value) {
+		return
+	}
+	deps = g.allowDeps(value, deps)
+	g.record()
+	deps = g.add(value, kind, deps)
+	g.notify(Change[T]{Op: OpAddNode, Value: value, Kind: kind, Deps: deps})
+}
+
+// Kinds returns the kinds of the dependencies in the graph, in the order
+// they were first used.
+func (g *Graph[T]) Kinds() []EdgeKind {
+	var kinds []EdgeKind
+	for _, decl := range g.declarations() {
+		if len(decl.deps) > 0 && !slices.Contains(kinds, decl.kind) {
+			kinds = append(kinds, decl.kind)
+		}
+	}
+	return kinds
+}
+
+// OnlyKinds returns a new graph with only the dependencies of the given
+// kinds. Nodes without any stay in the graph, but values that only appear
+// as dependencies of other kinds are left out.
+func (g *Graph[T]) OnlyKinds(kinds ...EdgeKind) *Graph[T] {
+	return g.filterKinds(func(kind EdgeKind) bool {
+		return slices.Contains(kinds, kind)
+	})
+}
+
+// ExcludeKinds returns a new graph without the dependencies of the given
+// kinds. Nodes without any dependencies left stay in the graph, but values
+// that only appear as dependencies of the excluded kinds are left out.
+func (g *Graph[T]) ExcludeKinds(kinds ...EdgeKind) *Graph[T] {
+	return g.filterKinds(func(kind EdgeKind) bool {
+		return !slices.Contains(kinds, kind)
+	})
+}
+
+func (g *Graph[T]) filterKinds(keep func(EdgeKind) bool) *Graph[T] {
+	f := Graph[T]{
+		nodes: make([]node[T], len(g.nodes)),
+		pins:  maps.Clone(g.pins),
+		attrs: cloneAttrs(g.attrs, nil),
+	}
+	for i, n := range g.nodes {
+		if !keep(n.kind) {
+			n.deps = nil
+		}
+		f.nodes[i] = n
+	}
+	return &f
+}
+
+// kindKey identifies the dependencies of one kind of a node.
+type kindKey[T comparable] struct {
+	value T
+	kind  EdgeKind
+}
+
+// declarations returns the nodes that are in effect, the last one added for
+// each value and kind, in the order each value and kind was first added.
+func (g *Graph[T]) declarations() []node[T] {
+	index := make(map[kindKey[T]]int, len(g.nodes))
+	var decls []node[T]
+	for _, n := range g.nodes {
+		key := kindKey[T]{n.value, n.kind}
+		if i, ok := index[key]; ok {
+			decls[i].deps = n.deps
+		} else {
+			index[key] = len(decls)
+			decls = append(decls, n)
+		}
+	}
+	return decls
+}
+
+// declarationsByValue groups the declarations in effect by value. It also
+// returns every kind declared, in the order they were first used.
+func (g *Graph[T]) declarationsByValue() (map[T][]node[T], []EdgeKind) {
+	byValue := make(map[T][]node[T])
+	var kinds []EdgeKind
+	for _, decl := range g.declarations() {
+		byValue[decl.value] = append(byValue[decl.value], decl)
+		if !slices.Contains(kinds, decl.kind) {
+			kinds = append(kinds, decl.kind)
+		}
+	}
+	return byValue, kinds
+}
+
+// addKinds adds a node with its dependencies of each kind found in deps,
+// or without dependencies if none are.
+func (g *Graph[T]) addKinds(value T, kinds []EdgeKind, deps map[kindKey[T]][]T) {
+	added := false
+	for _, kind := range kinds {
+		if d, ok := deps[kindKey[T]{value, kind}]; ok {
+			g.AddNodeOfKind(value, kind, d)
+			added = true
+		}
+	}
+	if !added {
+		g.AddNode(value, nil)
+	}
+}
+
+// mergeDeps merges the dependencies of several kinds, in the order the
+// kinds were first added for the node, dropping those already included by
+// an earlier kind. A single list is returned as is.
+func mergeDeps[T comparable](lists ...[]T) []T {
+	if len(lists) == 0 {
+		return nil
+	}
+	merged := lists[0]
+	for _, deps := range lists[1:] {
+		for _, dep := range deps {
+			if !slices.Contains(merged, dep) {
+				merged = append(slices.Clip(merged), dep)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,98 @@
+package topo_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+func kindedGraph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNodeOfKind("app", "runtime", []string{"db", "lib"})
+	g.AddNode("lib", nil)
+	g.AddNodeOfKind("db", "runtime", []string{"app"})
+	return &g
+}
+
+// TestEdgeKinds checks that dependencies of each kind are kept apart and
+// seen together.
+func TestEdgeKinds(t *testing.T) {
+	g := kindedGraph()
+
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected app to depend on [lib db], got %v", deps)
+	}
+	if kinds := g.Kinds(); !reflect.DeepEqual(kinds, []topo.EdgeKind{topo.DefaultEdgeKind, "runtime"}) {
+		t.Errorf("Expected default and runtime kinds, got %v", kinds)
+	}
+
+	// adding a node again only replaces the dependencies of its kind
+	g.AddNode("app", []string{"config"})
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"config", "db", "lib"}) {
+		t.Errorf("Expected app to depend on [config db lib], got %v", deps)
+	}
+	g.AddDependency("app", "cache")
+	if deps := g.OnlyKinds(topo.DefaultEdgeKind).Dependencies("app"); !reflect.DeepEqual(deps, []string{"config", "cache"}) {
+		t.Errorf("Expected default dependencies [config cache], got %v", deps)
+	}
+}
+
+// TestEdgeKindsFilter checks sorting with kinds included or excluded.
+func TestEdgeKindsFilter(t *testing.T) {
+	g := kindedGraph()
+
+	// with every kind, app and db form a cycle
+	if _, err := g.SortByLayers(); err == nil {
+		t.Error("Expected a cycle with every kind of dependency")
+	}
+
+	if cycles := g.OnlyKinds("runtime").Cycles(); !reflect.DeepEqual(cycles, [][]string{{"app", "db"}}) {
+		t.Errorf("Expected runtime cycle [app db], got %v", cycles)
+	}
+
+	tests := []struct {
+		name     string
+		graph    *topo.Graph[string]
+		expected [][]string
+	}{
+		{"build only", g.OnlyKinds(topo.DefaultEdgeKind), [][]string{{"lib", "db"}, {"app"}}},
+		{"without runtime", g.ExcludeKinds("runtime"), [][]string{{"lib", "db"}, {"app"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers, err := tt.graph.SortByLayers()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(layers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, layers)
+			}
+		})
+	}
+}
+
+// TestEdgeKindsTransforms checks that transformations keep the kinds.
+func TestEdgeKindsTransforms(t *testing.T) {
+	g := kindedGraph()
+
+	reversed := g.Reverse().OnlyKinds("runtime")
+	if deps := reversed.Dependencies("db"); !reflect.DeepEqual(deps, []string{"app"}) {
+		t.Errorf("Expected reversed runtime dependencies of db [app], got %v", deps)
+	}
+	if deps := reversed.Dependencies("lib"); !reflect.DeepEqual(deps, []string{"app"}) {
+		t.Errorf("Expected reversed runtime dependencies of lib [app], got %v", deps)
+	}
+
+	pruned := g.PruneTo([]string{"lib"})
+	if nodes := pruned.Nodes(); !reflect.DeepEqual(nodes, []string{"lib"}) {
+		t.Errorf("Expected pruned nodes [lib], got %v", nodes)
+	}
+
+	upper := topo.Map(g, strings.ToUpper).ExcludeKinds(topo.DefaultEdgeKind)
+	if deps := upper.Dependencies("APP"); !reflect.DeepEqual(deps, []string{"DB", "LIB"}) {
+		t.Errorf("Expected mapped runtime dependencies [DB LIB], got %v", deps)
+	}
+}
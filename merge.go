@@ -0,0 +1,183 @@
+package topo
+
+import (
+	"maps"
+	"slices"
+)
+
+// MergeConflict is a node that both sides of a Merge changed, in ways that
+// contradict each other.
+type MergeConflict[T comparable] struct {
+	Value T
+	Kind  EdgeKind
+	// Base, Ours, and Theirs are the node's dependencies of Kind in each
+	// graph. They're nil where the value isn't a node, as when one side
+	// removed a node that the other changed.
+	Base, Ours, Theirs []T
+}
+
+// Merge combines two graphs that were both changed from a common base, as
+// when dependency metadata is edited on two branches. Changes made on only
+// one side are kept. Where both sides changed the same node's dependencies
+// of a kind differently, or one side removed a node that the other
+// changed, there's a conflict: it's returned, and the merged graph keeps
+// the node with the dependencies either side added, less those either side
+// removed.
+//
+// Nodes are added to the merged graph in the order of ours, followed by
+// those only in theirs. Conflicts are in the same order. Attributes are
+// merged the same way, except that where both sides changed one, ours is
+// kept without a conflict.
+func Merge[T comparable](base, ours, theirs *Graph[T]) (*Graph[T], []MergeConflict[T]) {
+	baseDecls, _ := base.declarationsByValue()
+	ourDecls, ourKinds := ours.declarationsByValue()
+	theirDecls, theirKinds := theirs.declarationsByValue()
+	kinds := slices.Clone(ourKinds)
+	for _, kind := range theirKinds {
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	var values []T
+	for _, g := range []*Graph[T]{ours, theirs, base} {
+		for _, n := range g.nodes {
+			if !slices.Contains(values, n.value) {
+				values = append(values, n.value)
+			}
+		}
+	}
+
+	var merged Graph[T]
+	var conflicts []MergeConflict[T]
+	for _, value := range values {
+		b, inBase := baseDecls[value]
+		o, inOurs := ourDecls[value]
+		x, inTheirs := theirDecls[value]
+		if !inOurs && !inTheirs {
+			continue
+		}
+
+		// a node removed on one side is only kept if the other changed it
+		if inBase && inOurs != inTheirs {
+			kept := o
+			if inTheirs {
+				kept = x
+			}
+			if sameDecls(kept, b, kinds) {
+				continue
+			}
+			for _, kind := range kinds {
+				k, bk := kindDeps(kept, kind), kindDeps(b, kind)
+				if !sameSet(k, bk) {
+					c := MergeConflict[T]{Value: value, Kind: kind, Base: nonNil(bk)}
+					if inOurs {
+						c.Ours = nonNil(k)
+					} else {
+						c.Theirs = nonNil(k)
+					}
+					conflicts = append(conflicts, c)
+				}
+			}
+			merged.addDecls(value, kinds, kept)
+			continue
+		}
+
+		var decls []node[T]
+		for _, kind := range kinds {
+			bk, ok, xk := kindDeps(b, kind), kindDeps(o, kind), kindDeps(x, kind)
+			var deps []T
+			switch {
+			case sameSet(ok, bk):
+				deps = xk
+			case sameSet(xk, bk), sameSet(ok, xk):
+				deps = ok
+			default:
+				c := MergeConflict[T]{Value: value, Kind: kind, Ours: nonNil(ok), Theirs: nonNil(xk)}
+				if inBase {
+					c.Base = nonNil(bk)
+				}
+				conflicts = append(conflicts, c)
+				for _, dep := range ok {
+					if slices.Contains(xk, dep) || !slices.Contains(bk, dep) {
+						deps = append(deps, dep)
+					}
+				}
+				for _, dep := range xk {
+					if !slices.Contains(ok, dep) && !slices.Contains(bk, dep) {
+						deps = append(deps, dep)
+					}
+				}
+			}
+			decls = append(decls, node[T]{value: value, kind: kind, deps: deps})
+		}
+		merged.addDecls(value, kinds, decls)
+	}
+	for _, value := range merged.Nodes() {
+		mergeAttrs(&merged, value, base.attrs[value], ours.attrs[value], theirs.attrs[value])
+	}
+	return &merged, conflicts
+}
+
+// mergeAttrs sets the attributes of a merged value, taking each from the
+// side that changed it, or from ours if both did.
+func mergeAttrs[T comparable](merged *Graph[T], value T, base, ours, theirs map[string]string) {
+	keys := slices.Concat(slices.Collect(maps.Keys(ours)), slices.Collect(maps.Keys(theirs)))
+	slices.Sort(keys)
+	for _, key := range slices.Compact(keys) {
+		b, inBase := base[key]
+		o, inOurs := ours[key]
+		attr, ok := o, inOurs
+		if inOurs == inBase && o == b {
+			attr, ok = theirs[key]
+		}
+		if ok {
+			merged.SetAttr(value, key, attr)
+		}
+	}
+}
+
+// addDecls adds a node with its dependencies of each kind, or without
+// dependencies if it has none.
+func (g *Graph[T]) addDecls(value T, kinds []EdgeKind, decls []node[T]) {
+	added := false
+	for _, kind := range kinds {
+		if deps := kindDeps(decls, kind); len(deps) > 0 {
+			g.AddNodeOfKind(value, kind, deps)
+			added = true
+		}
+	}
+	if !added {
+		g.AddNode(value, nil)
+	}
+}
+
+// kindDeps returns the dependencies of a kind among a node's declarations.
+func kindDeps[T comparable](decls []node[T], kind EdgeKind) []T {
+	for _, decl := range decls {
+		if decl.kind == kind {
+			return decl.deps
+		}
+	}
+	return nil
+}
+
+// sameDecls reports whether two nodes' declarations have the same
+// dependencies of every kind.
+func sameDecls[T comparable](a, b []node[T], kinds []EdgeKind) bool {
+	for _, kind := range kinds {
+		if !sameSet(kindDeps(a, kind), kindDeps(b, kind)) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonNil returns deps, or an empty slice if it's nil, to tell a node
+// without dependencies from a value that isn't a node.
+func nonNil[T any](deps []T) []T {
+	if deps == nil {
+		return []T{}
+	}
+	return deps
+}
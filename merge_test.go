@@ -0,0 +1,88 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestMerge checks merging changes made on two sides.
+func TestMerge(t *testing.T) {
+	var base topo.Graph[string]
+	base.AddNode("app", []string{"lib"})
+	base.AddNode("lib", []string{"base"})
+	base.AddNode("docs", nil)
+	base.AddNode("old", nil)
+	base.AddNode("tool", []string{"lib"})
+
+	var ours topo.Graph[string]
+	ours.AddNode("app", []string{"lib", "db"}) // only ours changed app
+	ours.AddNode("lib", []string{"base"})
+	ours.AddNode("docs", nil)
+	ours.AddNode("tool", []string{"lib"}) // ours removed old
+	ours.AddNode("db", nil)
+
+	var theirs topo.Graph[string]
+	theirs.AddNode("app", []string{"lib"})
+	theirs.AddNode("lib", []string{"base", "log"}) // only theirs changed lib
+	theirs.AddNode("old", nil)
+	theirs.AddNode("tool", []string{"lib"}) // theirs removed docs
+	theirs.AddNodeOfKind("tool", "runtime", []string{"db"})
+	theirs.AddNode("db", nil)
+
+	merged, conflicts := topo.Merge(&base, &ours, &theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+	expected := map[string]map[topo.EdgeKind][]string{
+		"app":  {"": {"lib", "db"}},
+		"lib":  {"": {"base", "log"}},
+		"base": {},
+		"tool": {"": {"lib"}, "runtime": {"db"}},
+		"db":   {},
+		"log":  {},
+	}
+	if got := describe(merged); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+// TestMergeConflicts checks contradictory changes on both sides.
+func TestMergeConflicts(t *testing.T) {
+	var base topo.Graph[string]
+	base.AddNode("app", []string{"lib", "old"})
+	base.AddNode("tool", nil)
+
+	var ours topo.Graph[string]
+	ours.AddNode("app", []string{"lib", "db"})
+	ours.AddNode("tool", []string{"lib"})
+	ours.AddNode("new", []string{"a"})
+
+	var theirs topo.Graph[string]
+	theirs.AddNode("app", []string{"old", "cache"}) // theirs removed tool
+	theirs.AddNode("new", []string{"b"})
+
+	merged, conflicts := topo.Merge(&base, &ours, &theirs)
+	expectedConflicts := []topo.MergeConflict[string]{
+		{Value: "app", Base: []string{"lib", "old"}, Ours: []string{"lib", "db"}, Theirs: []string{"old", "cache"}},
+		{Value: "tool", Base: []string{}, Ours: []string{"lib"}},
+		{Value: "new", Ours: []string{"a"}, Theirs: []string{"b"}},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Expected %v, got %v", expectedConflicts, conflicts)
+	}
+	expected := map[string]map[topo.EdgeKind][]string{
+		"app":   {"": {"db", "cache"}},
+		"tool":  {"": {"lib"}},
+		"new":   {"": {"a", "b"}},
+		"db":    {},
+		"cache": {},
+		"lib":   {},
+		"a":     {},
+		"b":     {},
+	}
+	if got := describe(merged); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,60 @@
+package topo
+
+import "slices"
+
+// LongestPath returns a longest chain of dependencies in the graph, from
+// a value with no dependencies to the last value that depends on it, each
+// value depending on the one before it. Its length is the graph's depth.
+// When several chains are equally long, the one ending at the first value
+// of the last layer is returned. Pinned layers are ignored. A graph with a
+// cycle returns ErrCyclicDependency.
+func (g *Graph[T]) LongestPath() ([]T, error) {
+	layers, err := g.sortByLayers()
+	if err != nil || len(layers) == 0 {
+		return nil, err
+	}
+	_, dependsOn := g.edges()
+	layer := make(map[T]int)
+	for i, values := range layers {
+		for _, value := range values {
+			layer[value] = i
+		}
+	}
+
+	// each value is in the layer after its deepest dependency, so some
+	// dependency is always one layer up
+	path := make([]T, len(layers))
+	value := layers[len(layers)-1][0]
+	for i := len(layers) - 1; i >= 0; i-- {
+		path[i] = value
+		if i > 0 {
+			deps := dependsOn[value]
+			value = deps[slices.IndexFunc(deps, func(dep T) bool {
+				return layer[dep] == i-1
+			})]
+		}
+	}
+	return path, nil
+}
+
+// Depth returns the number of values in the graph's longest chain of
+// dependencies, which is also the number of layers SortByLayers returns
+// when no values are pinned. An empty graph has a depth of zero.
+func (g *Graph[T]) Depth() (int, error) {
+	layers, err := g.sortByLayers()
+	return len(layers), err
+}
+
+// Width returns the number of values in the largest layer SortByLayers
+// returns, which bounds how much work can run in parallel.
+func (g *Graph[T]) Width() (int, error) {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return 0, err
+	}
+	width := 0
+	for _, layer := range layers {
+		width = max(width, len(layer))
+	}
+	return width, nil
+}
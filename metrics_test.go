@@ -0,0 +1,74 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestMetrics checks the longest path, depth, and width of graphs.
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(g *topo.Graph[string])
+		path  []string
+		width int
+	}{
+		{"empty", func(*topo.Graph[string]) {}, nil, 0},
+		{
+			name: "chain and fan-out",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("app", []string{"lib", "config"})
+				g.AddNode("lib", []string{"log", "base"})
+				g.AddNode("log", []string{"base"})
+				g.AddNode("tool", []string{"base"})
+			},
+			path:  []string{"base", "log", "lib", "app"},
+			width: 2,
+		},
+		{
+			name: "independent",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("a", nil)
+				g.AddNode("b", nil)
+				g.AddNode("c", nil)
+			},
+			path:  []string{"a"},
+			width: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			tt.build(&g)
+			path, err := g.LongestPath()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("Expected path %v, got %v", tt.path, path)
+			}
+			if depth, _ := g.Depth(); depth != len(tt.path) {
+				t.Errorf("Expected depth %d, got %d", len(tt.path), depth)
+			}
+			if width, _ := g.Width(); width != tt.width {
+				t.Errorf("Expected width %d, got %d", tt.width, width)
+			}
+		})
+	}
+
+	var g topo.Graph[string]
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+	if _, err := g.LongestPath(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+	if _, err := g.Depth(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+	if _, err := g.Width(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
@@ -0,0 +1,67 @@
+package topo
+
+import (
+	"slices"
+	"strings"
+)
+
+// NamespaceSeparator separates the namespace from the name in namespaced
+// keys like "team-a/api".
+const NamespaceSeparator = "/"
+
+// Qualify returns the key for a name within a namespace, like
+// "team-a/api". Use it to refer to nodes in other namespaces when adding
+// edges between them.
+func Qualify(namespace, name string) string {
+	return namespace + NamespaceSeparator + name
+}
+
+// SplitNamespace splits a key into its namespace and name, at the first
+// separator. A key without a namespace returns an empty namespace.
+func SplitNamespace(key string) (namespace, name string) {
+	namespace, name, ok := strings.Cut(key, NamespaceSeparator)
+	if !ok {
+		return "", key
+	}
+	return namespace, name
+}
+
+// WithNamespace returns a copy of the graph with every key prefixed with
+// the namespace.
+func WithNamespace(g *Graph[string], namespace string) *Graph[string] {
+	return Map(g, func(key string) string {
+		return Qualify(namespace, key)
+	})
+}
+
+// StripNamespace returns a copy of the graph with the namespace removed
+// from the keys in it. Keys in other namespaces are left as they are.
+func StripNamespace(g *Graph[string], namespace string) *Graph[string] {
+	prefix := namespace + NamespaceSeparator
+	return Map(g, func(key string) string {
+		return strings.TrimPrefix(key, prefix)
+	})
+}
+
+// Compose merges graphs from several subsystems into one, prefixing the
+// keys of each graph with the namespace it's given under, so that equal
+// keys from different subsystems don't collide. Namespaces are added in
+// sorted order.
+//
+// Edges between namespaces are added explicitly afterwards, for example:
+//
+//	g := topo.Compose(map[string]*topo.Graph[string]{"web": web, "db": db})
+//	g.AddDependency(topo.Qualify("web", "api"), topo.Qualify("db", "postgres"))
+func Compose(graphs map[string]*Graph[string]) *Graph[string] {
+	namespaces := make([]string, 0, len(graphs))
+	for namespace := range graphs {
+		namespaces = append(namespaces, namespace)
+	}
+	slices.Sort(namespaces)
+
+	var g Graph[string]
+	for _, namespace := range namespaces {
+		g.nodes = append(g.nodes, WithNamespace(graphs[namespace], namespace).nodes...)
+	}
+	return &g
+}
@@ -0,0 +1,61 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestCompose checks merging graphs with colliding keys under namespaces.
+func TestCompose(t *testing.T) {
+	var web, db topo.Graph[string]
+	web.AddNode("api", []string{"config"})
+	web.AddNode("config", nil)
+	db.AddNode("postgres", []string{"config"})
+	db.AddNode("config", nil)
+
+	g := topo.Compose(map[string]*topo.Graph[string]{"web": &web, "db": &db})
+	g.AddDependency(topo.Qualify("web", "api"), topo.Qualify("db", "postgres"))
+
+	expected := map[string][]string{
+		"db/postgres": {"db/config"},
+		"db/config":   nil,
+		"web/api":     {"web/config", "db/postgres"},
+		"web/config":  nil,
+	}
+	actual := make(map[string][]string)
+	for _, value := range g.Nodes() {
+		actual[value] = g.Dependencies(value)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	stripped := topo.StripNamespace(g, "web")
+	expectedNodes := []string{"db/postgres", "db/config", "api", "config"}
+	if nodes := stripped.Nodes(); !reflect.DeepEqual(nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, nodes)
+	}
+	if deps := stripped.Dependencies("api"); !reflect.DeepEqual(deps, []string{"config", "db/postgres"}) {
+		t.Errorf("Expected stripped dependencies, got %v", deps)
+	}
+}
+
+// TestSplitNamespace checks splitting keys into namespace and name.
+func TestSplitNamespace(t *testing.T) {
+	tests := []struct {
+		key, namespace, name string
+	}{
+		{"team-a/api", "team-a", "api"},
+		{"team-a/api/v2", "team-a", "api/v2"},
+		{"api", "", "api"},
+	}
+	for _, tt := range tests {
+		namespace, name := topo.SplitNamespace(tt.key)
+		if namespace != tt.namespace || name != tt.name {
+			t.Errorf("Expected %q to split into %q and %q, got %q and %q",
+				tt.key, tt.namespace, tt.name, namespace, name)
+		}
+	}
+}
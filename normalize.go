@@ -0,0 +1,43 @@
+package topo
+
+// WithKeyNormalizer makes the graph pass every value added to or removed
+// from it through fn first, so that values fn maps to the same key are the
+// same node. For keys from configuration edited by hand, for example,
+//
+//	g.WithKeyNormalizer(func(s string) string {
+//		return strings.ToLower(strings.TrimSpace(s))
+//	})
+//
+// keeps "App" and " app" from becoming separate nodes. A dependency listed
+// twice once normalized is only kept once, as with any duplicate.
+//
+// Normalization applies to AddNode, AddNodeOfKind, AddDependency,
+// RemoveNode, RemoveDependency, the pinning methods, and changesets applied
+// to the graph; queries like Dependencies take normalized values. Values
+// already in the graph aren't changed, so it should be set before nodes are
+// added. It returns the graph, to allow setting it where it's declared.
+func (g *Graph[T]) WithKeyNormalizer(fn func(T) T) *Graph[T] {
+	g.normalizer = fn
+	return g
+}
+
+// normalize returns the key of a value, by the graph's normalizer.
+func (g *Graph[T]) normalize(value T) T {
+	if g.normalizer == nil {
+		return value
+	}
+	return g.normalizer(value)
+}
+
+// normalizeAll returns the keys of values. Without a normalizer, values are
+// returned as they are.
+func (g *Graph[T]) normalizeAll(values []T) []T {
+	if g.normalizer == nil || values == nil {
+		return values
+	}
+	keys := make([]T, len(values))
+	for i, value := range values {
+		keys[i] = g.normalizer(value)
+	}
+	return keys
+}
@@ -0,0 +1,68 @@
+package topo_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+func lowerTrim(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// TestKeyNormalizer checks that normalized keys are the same node.
+func TestKeyNormalizer(t *testing.T) {
+	g := new(topo.Graph[string]).WithKeyNormalizer(lowerTrim)
+	g.AddNode("App", []string{"Lib", " lib", "DB"})
+	g.AddNode(" lib ", []string{"Base"})
+	g.AddNodeOfKind("app", "runtime", []string{"LOG"})
+	g.AddDependency("APP", "cache")
+	g.RemoveDependency("app", " DB")
+	g.AddNode("Old", nil)
+	g.RemoveNode("OLD")
+
+	expectedNodes := []string{"app", "lib", "cache", "base", "log"}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, nodes)
+	}
+	expectedDeps := []string{"lib", "cache", "log"}
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, expectedDeps) {
+		t.Errorf("Expected dependencies %v, got %v", expectedDeps, deps)
+	}
+
+	g.PinLayer("CACHE", 1)
+	expectedLayers := [][]string{{"base", "log"}, {"cache", "lib"}, {"app"}}
+	if layers, err := g.SortByLayers(); err != nil || !reflect.DeepEqual(layers, expectedLayers) {
+		t.Errorf("Expected layers %v, got %v (%v)", expectedLayers, layers, err)
+	}
+}
+
+// TestKeyNormalizerChanges checks that changes are normalized in
+// transactions and changesets, and reported normalized to observers.
+func TestKeyNormalizerChanges(t *testing.T) {
+	g := new(topo.Graph[string]).WithKeyNormalizer(lowerTrim)
+	var changes []string
+	g.Observe(func(c topo.Change[string]) {
+		changes = append(changes, c.String())
+	})
+
+	err := g.Apply(func(tx *topo.Tx[string]) error {
+		tx.AddNode("App", []string{"Lib"})
+		tx.AddNode("LIB", nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cs := topo.Changeset[string]{{Op: topo.OpAddDependency, Value: "APP", Deps: []string{" Lib"}}}
+	if err := cs.ApplyTo(g); err == nil {
+		t.Errorf("Expected adding an existing dependency to conflict")
+	}
+
+	expected := []string{"add node app [lib]", "add node lib"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changes)
+	}
+}
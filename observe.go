@@ -0,0 +1,108 @@
+package topo
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ChangeOp is the kind of change a Change describes.
+type ChangeOp int
+
+const (
+	// OpAddNode is a node added with AddNode or AddNodeOfKind. Kind and
+	// Deps hold the kind and dependencies it was added with.
+	OpAddNode ChangeOp = iota
+	// OpAddDependency is a dependency added with AddDependency. Deps holds
+	// the dependency.
+	OpAddDependency
+	// OpRemoveNode is a node removed with RemoveNode.
+	OpRemoveNode
+	// OpRemoveDependency is a dependency removed with RemoveDependency.
+	// Kind and Deps hold its kind and the dependency.
+	OpRemoveDependency
+)
+
+// String returns a short description of the operation.
+func (op ChangeOp) String() string {
+	switch op {
+	case OpAddNode:
+		return "add node"
+	case OpAddDependency:
+		return "add dependency"
+	case OpRemoveNode:
+		return "remove node"
+	case OpRemoveDependency:
+		return "remove dependency"
+	default:
+		return fmt.Sprintf("ChangeOp(%d)", int(op))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that changes can be
+// written as JSON.
+func (op ChangeOp) MarshalText() ([]byte, error) {
+	if op < OpAddNode || op > OpRemoveDependency {
+		return nil, fmt.Errorf("unknown %v", op)
+	}
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (op *ChangeOp) UnmarshalText(text []byte) error {
+	for o := OpAddNode; o <= OpRemoveDependency; o++ {
+		if o.String() == string(text) {
+			*op = o
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown change operation %q", text)
+}
+
+// Change describes a change made to a graph.
+type Change[T comparable] struct {
+	Op ChangeOp `json:"op"`
+	// Value is the node that was changed.
+	Value T `json:"value"`
+	// Kind is the kind of the dependencies involved.
+	Kind EdgeKind `json:"kind,omitempty"`
+	// Deps are the dependencies involved, as described by each ChangeOp.
+	Deps []T `json:"deps,omitempty"`
+}
+
+// String describes the change, like "add dependency app [lib]".
+func (c Change[T]) String() string {
+	s := fmt.Sprintf("%s %v", c.Op, c.Value)
+	if len(c.Deps) > 0 {
+		s += fmt.Sprintf(" %v", c.Deps)
+	}
+	if c.Kind != DefaultEdgeKind {
+		s += fmt.Sprintf(" (%s)", c.Kind)
+	}
+	return s
+}
+
+// Observe registers a function to be called after each change to the
+// graph, so that caches, indexes, and views of the graph can be kept up to
+// date without wrapping every call that changes it. Observers are called in
+// the order they were registered, and can read the graph. Calling the
+// returned function stops the observer from being called.
+//
+// Changes that have no effect, like removing a node that isn't there, are
+// not reported. Graphs returned by methods like Reverse and PruneTo are new
+// graphs, without observers.
+func (g *Graph[T]) Observe(fn func(Change[T])) (stop func()) {
+	o := &fn
+	g.observers = append(g.observers, o)
+	return func() {
+		g.observers = slices.DeleteFunc(g.observers, func(other *func(Change[T])) bool {
+			return other == o
+		})
+	}
+}
+
+// notify calls the graph's observers with a change.
+func (g *Graph[T]) notify(c Change[T]) {
+	for _, o := range slices.Clone(g.observers) {
+		(*o)(c)
+	}
+}
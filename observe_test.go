@@ -0,0 +1,61 @@
+package topo_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestObserve checks that observers see each change, and only changes.
+func TestObserve(t *testing.T) {
+	var g topo.Graph[string]
+	var changes []string
+	stop := g.Observe(func(c topo.Change[string]) {
+		changes = append(changes, fmt.Sprint(c.Op, " ", c.Value, " ", c.Kind, " ", c.Deps))
+		if len(g.Nodes()) == 0 && c.Op != topo.OpRemoveNode {
+			t.Errorf("Expected observer to see %v applied", c)
+		}
+	})
+
+	g.AddNode("app", []string{"lib"})
+	g.AddNodeOfKind("app", "runtime", []string{"db"})
+	g.AddDependency("app", "cache")
+	g.AddDependency("app", "lib")
+	g.RemoveDependency("app", "db")
+	g.RemoveDependency("app", "nope")
+	g.RemoveNode("lib")
+	g.RemoveNode("nope")
+	stop()
+	g.AddNode("ignored", nil)
+
+	expected := []string{
+		"add node app  [lib]",
+		"add node app runtime [db]",
+		"add dependency app  [cache]",
+		"remove dependency app runtime [db]",
+		"remove node lib  []",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %q, got %q", expected, changes)
+	}
+}
+
+// TestObserveMany checks that observers are called in order and can be
+// stopped independently.
+func TestObserveMany(t *testing.T) {
+	var g topo.Graph[int]
+	var calls []string
+	stopA := g.Observe(func(topo.Change[int]) { calls = append(calls, "a") })
+	g.Observe(func(topo.Change[int]) { calls = append(calls, "b") })
+	g.AddNode(1, nil)
+	stopA()
+	stopA()
+	g.AddNode(2, nil)
+
+	expected := []string{"a", "b", "b"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,315 @@
+package topo
+
+import "container/heap"
+
+// Partitioning is a graph split into parts, as returned by Partition.
+type Partitioning[T comparable] struct {
+	// Parts holds the subgraph of each part, with the dependencies between
+	// values in the same part.
+	Parts []*Graph[T]
+	// Cross holds the dependencies between values in different parts, in
+	// the order they were declared.
+	Cross []CrossEdge[T]
+}
+
+// CrossEdge is a dependency between values in different parts of a
+// partitioning.
+type CrossEdge[T comparable] struct {
+	Value     T
+	Kind      EdgeKind
+	Dep       T
+	ValuePart int
+	DepPart   int
+}
+
+// Partition splits the graph into k parts of about the same number of
+// values, with as few dependencies between parts as it can find, so that a
+// huge graph can be processed by several workers or machines that only
+// need to coordinate over the dependencies in Cross.
+//
+// Finding the fewest dependencies between parts is too slow for large
+// graphs, so Partition uses a multilevel heuristic: it repeatedly merges
+// pairs of values joined by dependencies into a smaller graph, splits the
+// smallest graph by growing each part around values that depend on each
+// other, and then undoes the merges, moving values whose dependencies are
+// mostly in another part to that part. Parts differ in size by at most
+// about 5%, unless the graph is too small for that.
+//
+// The result is the same for the same graph. Fewer than one part is
+// treated as one, and a graph with fewer values than parts has one part
+// per value.
+func (g *Graph[T]) Partition(k int) Partitioning[T] {
+	order, deps, dependents := g.interned()
+	if len(order) == 0 {
+		return Partitioning[T]{}
+	}
+	k = max(1, min(k, len(order)))
+
+	// the graph, without the directions of its edges, as the finest level
+	fine := &level{weight: make([]int, len(order)), adj: make([][]weighted, len(order))}
+	for i := range order {
+		fine.weight[i] = 1
+		for _, d := range deps[i] {
+			fine.adj[i] = append(fine.adj[i], weighted{d, 1})
+		}
+		for _, d := range dependents[i] {
+			fine.adj[i] = append(fine.adj[i], weighted{d, 1})
+		}
+	}
+
+	// merge until the graph is small enough to split directly, or stops
+	// getting smaller
+	levels := []*level{fine}
+	maxWeight := max(1, len(order)/(2*k))
+	for {
+		last := levels[len(levels)-1]
+		if len(last.weight) <= 20*k {
+			break
+		}
+		coarse := last.coarsen(maxWeight)
+		if len(coarse.weight) > len(last.weight)*9/10 {
+			break
+		}
+		levels = append(levels, coarse)
+	}
+
+	limit := len(order)/k + max(1, len(order)/(20*k))
+	coarsest := levels[len(levels)-1]
+	part := coarsest.grow(k, len(order))
+	coarsest.refine(part, k, limit)
+	for i := len(levels) - 2; i >= 0; i-- {
+		finer := make([]int, len(levels[i].weight))
+		for v, c := range levels[i].merged {
+			finer[v] = part[c]
+		}
+		part = finer
+		levels[i].refine(part, k, limit)
+	}
+
+	members := make([][]T, k)
+	for i, p := range part {
+		members[p] = append(members[p], order[i])
+	}
+	var p Partitioning[T]
+	for _, values := range members {
+		p.Parts = append(p.Parts, g.subgraph(order, values))
+	}
+	index := g.analysis().index
+	for _, decl := range g.declarations() {
+		valuePart := part[index[decl.value]]
+		for _, dep := range decl.deps {
+			if depPart := part[index[dep]]; depPart != valuePart {
+				p.Cross = append(p.Cross, CrossEdge[T]{decl.value, decl.kind, dep, valuePart, depPart})
+			}
+		}
+	}
+	return p
+}
+
+// weighted is an edge to a vertex of a level, weighted by how many edges
+// of the graph it stands for.
+type weighted struct {
+	to     int32
+	weight int
+}
+
+// level is the graph without directions, with some of its values merged
+// into single vertices, for partitioning.
+type level struct {
+	// weight is how many values each vertex stands for
+	weight []int
+	adj    [][]weighted
+	// merged is the vertex of the next coarser level each vertex was
+	// merged into
+	merged []int32
+}
+
+// coarsen merges pairs of vertices, each with the neighbour it shares the
+// heaviest edge with, unless that would make a vertex heavier than
+// maxWeight.
+func (l *level) coarsen(maxWeight int) *level {
+	l.merged = make([]int32, len(l.weight))
+	for i := range l.merged {
+		l.merged[i] = -1
+	}
+	var coarse level
+	for v := range l.weight {
+		if l.merged[v] >= 0 {
+			continue
+		}
+		c := int32(len(coarse.weight))
+		l.merged[v] = c
+		best, bestWeight := int32(-1), 0
+		for _, e := range l.adj[v] {
+			if l.merged[e.to] >= 0 || l.weight[v]+l.weight[e.to] > maxWeight {
+				continue
+			}
+			if e.weight > bestWeight || e.weight == bestWeight && l.weight[e.to] < l.weight[best] {
+				best, bestWeight = e.to, e.weight
+			}
+		}
+		weight := l.weight[v]
+		if best >= 0 {
+			l.merged[best] = c
+			weight += l.weight[best]
+		}
+		coarse.weight = append(coarse.weight, weight)
+	}
+
+	// combine the edges of merged vertices, dropping those between them
+	coarse.adj = make([][]weighted, len(coarse.weight))
+	at := make(map[int32]int)
+	members := make([][]int32, len(coarse.weight))
+	for v, c := range l.merged {
+		members[c] = append(members[c], int32(v))
+	}
+	for c, vs := range members {
+		clear(at)
+		for _, v := range vs {
+			for _, e := range l.adj[v] {
+				to := l.merged[e.to]
+				if to == int32(c) {
+					continue
+				}
+				if i, ok := at[to]; ok {
+					coarse.adj[c][i].weight += e.weight
+				} else {
+					at[to] = len(coarse.adj[c])
+					coarse.adj[c] = append(coarse.adj[c], weighted{to, e.weight})
+				}
+			}
+		}
+	}
+	return &coarse
+}
+
+// grow splits the level into k parts, growing each part in turn from the
+// first vertex not yet in one, adding the vertex most connected to the
+// part until it holds its share of what's left.
+func (l *level) grow(k, total int) []int {
+	part := make([]int, len(l.weight))
+	for i := range part {
+		part[i] = -1
+	}
+	conn := make([]int, len(l.weight))
+	next := 0
+	for p := range k - 1 {
+		target := total / (k - p)
+		size := 0
+		var h gainHeap
+		for size < target {
+			v := int32(-1)
+			for h.Len() > 0 {
+				top := heap.Pop(&h).(weighted)
+				if part[top.to] < 0 && conn[top.to] == top.weight {
+					v = top.to
+					break
+				}
+			}
+			if v < 0 {
+				for next < len(part) && part[next] >= 0 {
+					next++
+				}
+				seed := next
+				for seed < len(part) && (part[seed] >= 0 || conn[seed] < 0) {
+					seed++
+				}
+				if seed == len(part) {
+					break
+				}
+				v = int32(seed)
+			}
+			if size > 0 && size+l.weight[v] > target+max(1, target/20) {
+				// too heavy for this part; leave it for a later one
+				conn[v] = -1
+				continue
+			}
+			part[v] = p
+			size += l.weight[v]
+			for _, e := range l.adj[v] {
+				if part[e.to] < 0 && conn[e.to] >= 0 {
+					conn[e.to] += e.weight
+					heap.Push(&h, weighted{e.to, conn[e.to]})
+				}
+			}
+		}
+		total -= size
+		clear(conn)
+	}
+	for v := range part {
+		if part[v] < 0 {
+			part[v] = k - 1
+		}
+	}
+	return part
+}
+
+// refine moves vertices to the part most of their edges go to, as long as
+// that takes the part to at most limit values, until no move helps. A
+// vertex with as many edges to another part moves there if that part is
+// smaller, and a vertex in a part over the limit moves to the part it
+// loses least by.
+func (l *level) refine(part []int, k, limit int) {
+	size := make([]int, k)
+	for v, p := range part {
+		size[p] += l.weight[v]
+	}
+	conn := make([]int, k)
+	for range 8 {
+		improved := false
+		for v, w := range l.weight {
+			from := part[v]
+			if size[from] == w {
+				continue
+			}
+			for _, e := range l.adj[v] {
+				conn[part[e.to]] += e.weight
+			}
+			best, gain := -1, 0
+			for _, e := range l.adj[v] {
+				to := part[e.to]
+				if to == from || to == best || size[to]+w > limit {
+					continue
+				}
+				if g := conn[to] - conn[from]; best < 0 || g > gain || g == gain && size[to] < size[best] {
+					best, gain = to, g
+				}
+			}
+			for _, e := range l.adj[v] {
+				conn[part[e.to]] = 0
+			}
+			if best < 0 {
+				continue
+			}
+			if gain > 0 || gain == 0 && size[best]+w < size[from] || size[from] > limit {
+				part[v] = best
+				size[from] -= w
+				size[best] += w
+				improved = improved || gain > 0
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+}
+
+// gainHeap orders vertices by how connected they are to a growing part,
+// most first, then by position.
+type gainHeap []weighted
+
+func (h gainHeap) Len() int { return len(h) }
+func (h gainHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight > h[j].weight
+	}
+	return h[i].to < h[j].to
+}
+func (h gainHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *gainHeap) Push(x any)   { *h = append(*h, x.(weighted)) }
+func (h *gainHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
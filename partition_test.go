@@ -0,0 +1,180 @@
+package topo_test
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// checkPartitioning checks that every value is in exactly one part, and
+// every dependency is either in a part or a cross edge.
+func checkPartitioning[T comparable](t *testing.T, g *topo.Graph[T], p topo.Partitioning[T]) {
+	t.Helper()
+	partOf := make(map[T]int)
+	edges := 0
+	for i, part := range p.Parts {
+		for _, value := range part.Nodes() {
+			if j, ok := partOf[value]; ok {
+				t.Errorf("Expected %v in one part, got parts %d and %d", value, j, i)
+			}
+			partOf[value] = i
+			edges += len(part.Dependencies(value))
+		}
+	}
+	for _, value := range g.Nodes() {
+		if _, ok := partOf[value]; !ok {
+			t.Errorf("Expected %v in a part", value)
+		}
+	}
+	for _, e := range p.Cross {
+		if partOf[e.Value] != e.ValuePart || partOf[e.Dep] != e.DepPart || e.ValuePart == e.DepPart {
+			t.Errorf("Expected cross edge %v between parts %d and %d", e, partOf[e.Value], partOf[e.Dep])
+		}
+	}
+	total := 0
+	for _, value := range g.Nodes() {
+		total += len(g.Dependencies(value))
+	}
+	if edges+len(p.Cross) != total {
+		t.Errorf("Expected %d dependencies, got %d in parts and %d across", total, edges, len(p.Cross))
+	}
+}
+
+// TestPartition runs some basic test cases.
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    [][]string
+		k        int
+		expected [][]string
+		cross    int
+	}{
+		{
+			name:     "empty",
+			k:        2,
+			expected: nil,
+		},
+		{
+			name:     "separate clusters",
+			nodes:    [][]string{{"a"}, {"x"}, {"b", "a"}, {"y", "x"}, {"c", "b", "a"}, {"z", "y", "x"}},
+			k:        2,
+			expected: [][]string{{"a", "b", "c"}, {"x", "y", "z"}},
+		},
+		{
+			name:     "one part",
+			nodes:    [][]string{{"b", "a"}, {"c", "b"}},
+			k:        0,
+			expected: [][]string{{"b", "a", "c"}},
+		},
+		{
+			name:     "more parts than values",
+			nodes:    [][]string{{"b", "a"}},
+			k:        5,
+			expected: [][]string{{"b"}, {"a"}},
+			cross:    1,
+		},
+		{
+			name: "chain",
+			nodes: [][]string{
+				{"b", "a"}, {"c", "b"}, {"d", "c"}, {"e", "d"}, {"f", "e"},
+			},
+			k:        2,
+			expected: [][]string{{"b", "a", "c"}, {"d", "e", "f"}},
+			cross:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			for _, node := range tt.nodes {
+				g.AddNode(node[0], node[1:])
+			}
+			p := g.Partition(tt.k)
+			var parts [][]string
+			for _, part := range p.Parts {
+				parts = append(parts, part.Nodes())
+			}
+			if !reflect.DeepEqual(parts, tt.expected) {
+				t.Errorf("Expected parts %v, got %v", tt.expected, parts)
+			}
+			if len(p.Cross) != tt.cross {
+				t.Errorf("Expected %d cross edges, got %v", tt.cross, p.Cross)
+			}
+			checkPartitioning(t, &g, p)
+		})
+	}
+}
+
+// TestPartitionKinds checks that cross edges keep their kind, and parts
+// keep their values' kinds and attributes.
+func TestPartitionKinds(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("a", nil)
+	g.AddNode("b", []string{"a"})
+	g.AddNodeOfKind("x", "build", []string{"b"})
+	g.AddNode("y", []string{"x"})
+	g.SetAttr("x", "owner", "build-team")
+
+	p := g.Partition(2)
+	checkPartitioning(t, &g, p)
+	expected := []topo.CrossEdge[string]{{Value: "x", Kind: "build", Dep: "b", ValuePart: 1, DepPart: 0}}
+	if !reflect.DeepEqual(p.Cross, expected) {
+		t.Errorf("Expected cross edges %v, got %v", expected, p.Cross)
+	}
+	if owner, _ := p.Parts[1].Attr("x", "owner"); owner != "build-team" {
+		t.Errorf("Expected owner build-team, got %q", owner)
+	}
+}
+
+// TestPartitionLarge checks that parts of a large graph of clusters are
+// balanced, and cut far fewer dependencies than splitting it in order.
+func TestPartitionLarge(t *testing.T) {
+	// 16 clusters of 500 values, each depending on values in its own
+	// cluster, with a few dependencies between clusters; values of
+	// clusters are interleaved, so that splitting in order does badly
+	var g topo.Graph[int]
+	const clusters, size = 16, 500
+	for i := range clusters * size {
+		c := i % clusters
+		var deps []int
+		for _, back := range []int{1, 2, 7} {
+			if j := i - back*clusters; j >= 0 {
+				deps = append(deps, j)
+			}
+		}
+		if i%97 == 0 && i > 0 {
+			deps = append(deps, i-1-c%3)
+		}
+		g.AddNode(i, deps)
+	}
+
+	for _, k := range []int{2, 4, 8} {
+		t.Run(fmt.Sprint(k), func(t *testing.T) {
+			p := g.Partition(k)
+			checkPartitioning(t, &g, p)
+			if len(p.Parts) != k {
+				t.Fatalf("Expected %d parts, got %d", k, len(p.Parts))
+			}
+			limit := clusters*size/k + clusters*size/(20*k)
+			for i, part := range p.Parts {
+				if n := len(part.Nodes()); n > limit {
+					t.Errorf("Expected part %d to have at most %d values, got %d", i, limit, n)
+				}
+			}
+			// splitting in order cuts nearly every dependency; splitting
+			// by cluster cuts only the 82 between clusters
+			if cross := len(p.Cross); cross > 200 {
+				t.Errorf("Expected at most 200 cross edges, got %d", cross)
+			}
+			again := g.Partition(k)
+			if !slices.EqualFunc(p.Parts, again.Parts, func(a, b *topo.Graph[int]) bool {
+				return slices.Equal(a.Nodes(), b.Nodes())
+			}) {
+				t.Error("Expected the same parts for the same graph")
+			}
+		})
+	}
+}
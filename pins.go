@@ -0,0 +1,149 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrPinConflict is returned when values are pinned to layers that their
+// dependencies don't allow.
+var ErrPinConflict = errors.New("conflicting layer pins")
+
+// layerPin is where a value is pinned by PinLayer or PinMinLayer.
+type layerPin struct {
+	layer int
+	exact bool
+}
+
+// PinLayer pins a value to a layer of SortByLayers' output, so that it's
+// processed in that layer whatever its dependencies. Negative layers count
+// back from the end, so a value pinned to -1 is always in the last layer,
+// as cleanup or monitoring steps often need to be. Values are moved later
+// to make room for pinned values, and empty layers are left where nothing
+// else fits.
+//
+// If a pinned value's dependencies put it after its layer, or it has
+// dependents that can't fit after a layer counted from the end, sorting
+// fails with ErrPinConflict, naming the pinned values involved. Pinning a
+// value that isn't in the graph has no effect. Pinning a value again
+// replaces its earlier pin.
+func (g *Graph[T]) PinLayer(value T, layer int) {
+	g.pin(value, layerPin{layer: layer, exact: true})
+}
+
+// PinMinLayer keeps a value out of the layers before the given one, as
+// PinLayer does, but lets its dependencies put it later.
+func (g *Graph[T]) PinMinLayer(value T, layer int) {
+	g.pin(value, layerPin{layer: layer})
+}
+
+// Unpin removes any pin from a value.
+func (g *Graph[T]) Unpin(value T) {
+	value = g.normalize(value)
+	if _, ok := g.pins[value]; ok {
+		g.record()
+		delete(g.pins, value)
+		g.invalidate()
+	}
+}
+
+func (g *Graph[T]) pin(value T, pin layerPin) {
+	value = g.normalize(value)
+	g.record()
+	if g.pins == nil {
+		g.pins = make(map[T]layerPin)
+	}
+	g.pins[value] = pin
+	g.invalidate()
+}
+
+// sortPinned sorts the graph into layers, honoring pins. Layers counted
+// from the end depend on how many layers there are, which pins can add to,
+// so the placement is repeated until the number of layers settles.
+func (g *Graph[T]) sortPinned() ([][]T, error) {
+	layers, err := g.sortByLayers()
+	if err != nil {
+		return nil, err
+	}
+	order := Flatten(layers, nil)
+	_, dependsOn := g.edges()
+
+	// a value pinned k from the end needs fewer than k layers of
+	// dependents after it, or no number of layers will do
+	height := make(map[T]int)
+	for i := len(order) - 1; i >= 0; i-- {
+		value := order[i]
+		for _, dep := range dependsOn[value] {
+			height[dep] = max(height[dep], height[value]+1)
+		}
+	}
+	var conflicts []T
+	for _, value := range order {
+		if pin, ok := g.pins[value]; ok && pin.layer < 0 && height[value] >= -pin.layer {
+			conflicts = append(conflicts, value)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrPinConflict, conflicts)
+	}
+
+	depth := len(layers)
+	for {
+		layer := make(map[T]int, len(order))
+		newDepth := 0
+		for _, value := range order {
+			l := 0
+			for _, dep := range dependsOn[value] {
+				l = max(l, layer[dep]+1)
+			}
+			if pin, ok := g.pins[value]; ok {
+				l = max(l, pin.resolve(depth))
+			}
+			layer[value] = l
+			newDepth = max(newDepth, l+1)
+		}
+		if newDepth > depth {
+			depth = newDepth
+			continue
+		}
+
+		for _, value := range order {
+			if pin, ok := g.pins[value]; ok && pin.exact && layer[value] != pin.resolve(depth) {
+				for _, pinned := range g.pinnedAncestors(value) {
+					if !slices.Contains(conflicts, pinned) {
+						conflicts = append(conflicts, pinned)
+					}
+				}
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, fmt.Errorf("%w: %v", ErrPinConflict, conflicts)
+		}
+		result := make([][]T, depth)
+		for _, value := range order {
+			result[layer[value]] = append(result[layer[value]], value)
+		}
+		return result, nil
+	}
+}
+
+// resolve returns the index of the pinned layer, given the number of layers.
+func (p layerPin) resolve(depth int) int {
+	if p.layer < 0 {
+		return depth + p.layer
+	}
+	return p.layer
+}
+
+// pinnedAncestors returns a pinned value along with the pinned values it
+// depends on, which may be what pushed it out of its layer.
+func (g *Graph[T]) pinnedAncestors(value T) []T {
+	pinned := []T{value}
+	for _, ancestor := range g.Ancestors(value) {
+		if _, ok := g.pins[ancestor]; ok {
+			pinned = append(pinned, ancestor)
+		}
+	}
+	return pinned
+}
@@ -0,0 +1,123 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestPinLayer runs some basic test cases.
+func TestPinLayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    [][]string
+		pin      func(g *topo.Graph[string])
+		expected [][]string
+	}{
+		{
+			name:  "last layer",
+			nodes: [][]string{{"app", "lib"}, {"lib", "base"}, {"monitoring"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("monitoring", -1)
+			},
+			expected: [][]string{{"base"}, {"lib"}, {"monitoring", "app"}},
+		},
+		{
+			name:  "exact layer moves dependents",
+			nodes: [][]string{{"app", "lib"}, {"lib"}, {"docs"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("lib", 2)
+			},
+			expected: [][]string{{"docs"}, nil, {"lib"}, {"app"}},
+		},
+		{
+			name:  "minimum layer",
+			nodes: [][]string{{"app", "lib"}, {"lib", "base"}, {"smoke", "base"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinMinLayer("smoke", 2)
+				g.PinMinLayer("app", 1)
+			},
+			expected: [][]string{{"base"}, {"lib"}, {"smoke", "app"}},
+		},
+		{
+			name:  "last layer after pinned dependency",
+			nodes: [][]string{{"report", "db"}, {"db"}, {"app"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("db", 2)
+				g.PinLayer("report", -1)
+			},
+			expected: [][]string{{"app"}, nil, {"db"}, {"report"}},
+		},
+		{
+			name:  "unpinned",
+			nodes: [][]string{{"app", "lib"}, {"monitoring"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("monitoring", -1)
+				g.Unpin("monitoring")
+			},
+			expected: [][]string{{"lib", "monitoring"}, {"app"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			for _, node := range tt.nodes {
+				g.AddNode(node[0], node[1:])
+			}
+			tt.pin(&g)
+			layers, err := g.SortByLayers()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(layers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, layers)
+			}
+		})
+	}
+}
+
+// TestPinConflict checks that pins that can't be honored are reported.
+func TestPinConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    [][]string
+		pin      func(g *topo.Graph[string])
+		expected string
+	}{
+		{
+			name:  "dependency too late",
+			nodes: [][]string{{"app", "lib"}, {"lib"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("lib", 1)
+				g.PinLayer("app", 1)
+			},
+			expected: "conflicting layer pins: [app lib]",
+		},
+		{
+			name:  "dependents after last layer",
+			nodes: [][]string{{"app", "monitoring"}},
+			pin: func(g *topo.Graph[string]) {
+				g.PinLayer("monitoring", -1)
+			},
+			expected: "conflicting layer pins: [monitoring]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			for _, node := range tt.nodes {
+				g.AddNode(node[0], node[1:])
+			}
+			tt.pin(&g)
+			_, err := g.SortByLayers()
+			if !errors.Is(err, topo.ErrPinConflict) {
+				t.Fatalf("Expected %v, got %v", topo.ErrPinConflict, err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
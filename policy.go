@@ -0,0 +1,130 @@
+package topo
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Policy sets limits on the shape of a graph, to be enforced with
+// CheckPolicies as the graph grows. A limit of zero isn't checked.
+type Policy struct {
+	// MaxDepth limits the number of nodes in the longest chain of
+	// dependencies.
+	MaxDepth int
+	// MaxFanIn limits the number of nodes depending on any one node.
+	MaxFanIn int
+	// MaxFanOut limits the number of dependencies of any one node.
+	MaxFanOut int
+	// MaxLayerWidth limits the number of nodes in any layer of
+	// SortByLayers' output.
+	MaxLayerWidth int
+}
+
+// PolicyRule is the limit of a Policy that a Violation breaks.
+type PolicyRule int
+
+const (
+	// RuleMaxDepth is broken by a chain of dependencies longer than
+	// Policy.MaxDepth. Nodes holds the longest chain.
+	RuleMaxDepth PolicyRule = iota
+	// RuleMaxFanIn is broken by a node with more dependents than
+	// Policy.MaxFanIn. Nodes holds the node.
+	RuleMaxFanIn
+	// RuleMaxFanOut is broken by a node with more dependencies than
+	// Policy.MaxFanOut. Nodes holds the node.
+	RuleMaxFanOut
+	// RuleMaxLayerWidth is broken by a layer wider than
+	// Policy.MaxLayerWidth. Nodes holds the layer.
+	RuleMaxLayerWidth
+)
+
+// String returns a short description of the rule.
+func (r PolicyRule) String() string {
+	switch r {
+	case RuleMaxDepth:
+		return "max depth"
+	case RuleMaxFanIn:
+		return "max fan-in"
+	case RuleMaxFanOut:
+		return "max fan-out"
+	case RuleMaxLayerWidth:
+		return "max layer width"
+	default:
+		return fmt.Sprintf("PolicyRule(%d)", int(r))
+	}
+}
+
+// Violation is a limit of a Policy that the graph exceeds.
+type Violation[T comparable] struct {
+	Rule PolicyRule
+	// Limit is the limit set by the policy.
+	Limit int
+	// Actual is how far the graph goes.
+	Actual int
+	// Nodes are the nodes involved, as described by each PolicyRule.
+	Nodes []T
+}
+
+// String describes the violation, like "max fan-out: 4 > 3: [app]".
+func (v Violation[T]) String() string {
+	return fmt.Sprintf("%s: %d > %d: %v", v.Rule, v.Actual, v.Limit, v.Nodes)
+}
+
+// CheckPolicies returns every way the graph exceeds the limits of a
+// policy. Violations are grouped by rule, in the order of the PolicyRule
+// constants, and within a rule are in the order the nodes were first added,
+// or of the layers. A graph within its limits returns nil.
+//
+// Depth and layer width are only checked in graphs without cycles, which
+// Validate reports.
+func (g *Graph[T]) CheckPolicies(p Policy) []Violation[T] {
+	var violations []Violation[T]
+	add := func(rule PolicyRule, limit, actual int, nodes ...T) {
+		violations = append(violations, Violation[T]{Rule: rule, Limit: limit, Actual: actual, Nodes: nodes})
+	}
+	order, dependsOn := g.edges()
+
+	if p.MaxDepth > 0 {
+		if path, err := g.LongestPath(); err == nil && len(path) > p.MaxDepth {
+			add(RuleMaxDepth, p.MaxDepth, len(path), path...)
+		}
+	}
+
+	dependents := make(map[T][]T)
+	for _, value := range order {
+		for _, dep := range dependsOn[value] {
+			if !slices.Contains(dependents[dep], value) {
+				dependents[dep] = append(dependents[dep], value)
+			}
+		}
+	}
+	if p.MaxFanIn > 0 {
+		for _, value := range order {
+			if n := len(dependents[value]); n > p.MaxFanIn {
+				add(RuleMaxFanIn, p.MaxFanIn, n, value)
+			}
+		}
+	}
+	if p.MaxFanOut > 0 {
+		for _, value := range order {
+			distinct := make(map[T]bool)
+			for _, dep := range dependsOn[value] {
+				distinct[dep] = true
+			}
+			if n := len(distinct); n > p.MaxFanOut {
+				add(RuleMaxFanOut, p.MaxFanOut, n, value)
+			}
+		}
+	}
+
+	if p.MaxLayerWidth > 0 {
+		if layers, err := g.SortByLayers(); err == nil {
+			for _, layer := range layers {
+				if len(layer) > p.MaxLayerWidth {
+					add(RuleMaxLayerWidth, p.MaxLayerWidth, len(layer), layer...)
+				}
+			}
+		}
+	}
+	return violations
+}
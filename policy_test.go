@@ -0,0 +1,64 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestCheckPolicies runs some basic test cases.
+func TestCheckPolicies(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db", "cache", "lib"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("db", []string{"base"})
+	g.AddNode("cache", []string{"base"})
+
+	tests := []struct {
+		name     string
+		policy   topo.Policy
+		expected []string
+	}{
+		{"no limits", topo.Policy{}, nil},
+		{"within limits", topo.Policy{MaxDepth: 3, MaxFanIn: 3, MaxFanOut: 3, MaxLayerWidth: 3}, nil},
+		{"depth", topo.Policy{MaxDepth: 2}, []string{"max depth: 3 > 2: [base lib app]"}},
+		{"fan-in", topo.Policy{MaxFanIn: 2}, []string{"max fan-in: 3 > 2: [base]"}},
+		{"fan-out", topo.Policy{MaxFanOut: 2}, []string{"max fan-out: 3 > 2: [app]"}},
+		{"layer width", topo.Policy{MaxLayerWidth: 2}, []string{"max layer width: 3 > 2: [lib db cache]"}},
+		{
+			"all",
+			topo.Policy{MaxDepth: 1, MaxFanIn: 1, MaxFanOut: 1, MaxLayerWidth: 1},
+			[]string{
+				"max depth: 3 > 1: [base lib app]",
+				"max fan-in: 3 > 1: [base]",
+				"max fan-out: 3 > 1: [app]",
+				"max layer width: 3 > 1: [lib db cache]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range g.CheckPolicies(tt.policy) {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestCheckPoliciesCycle checks that fan limits are still checked in a
+// graph with cycles.
+func TestCheckPoliciesCycle(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("a", []string{"b", "c"})
+	g.AddNode("b", []string{"a"})
+
+	violations := g.CheckPolicies(topo.Policy{MaxDepth: 1, MaxFanOut: 1, MaxLayerWidth: 1})
+	if len(violations) != 1 || violations[0].Rule != topo.RuleMaxFanOut {
+		t.Errorf("Expected one %v violation, got %v", topo.RuleMaxFanOut, violations)
+	}
+}
@@ -0,0 +1,74 @@
+package topo
+
+import "slices"
+
+// Union returns a new graph with the nodes and dependencies of both graphs.
+// Nodes from g are added first, in their order, followed by the new nodes
+// from other; each node's dependencies from g come before those only in
+// other.
+func (g *Graph[T]) Union(other *Graph[T]) *Graph[T] {
+	order, dependsOn := g.edges()
+	otherOrder, otherDependsOn := other.edges()
+
+	var u Graph[T]
+	for _, value := range order {
+		deps := slices.Clone(dependsOn[value])
+		for _, dep := range otherDependsOn[value] {
+			if !slices.Contains(deps, dep) {
+				deps = append(deps, dep)
+			}
+		}
+		u.AddNode(value, deps)
+	}
+	for _, value := range otherOrder {
+		if !slices.Contains(order, value) {
+			u.AddNode(value, otherDependsOn[value])
+		}
+	}
+	return &u
+}
+
+// Intersection returns a new graph with only the nodes in both graphs, and
+// only the dependencies found in both. Nodes are added in the order of g.
+func (g *Graph[T]) Intersection(other *Graph[T]) *Graph[T] {
+	order, dependsOn := g.edges()
+	otherOrder, otherDependsOn := other.edges()
+
+	var i Graph[T]
+	for _, value := range order {
+		if !slices.Contains(otherOrder, value) {
+			continue
+		}
+		var deps []T
+		for _, dep := range dependsOn[value] {
+			if slices.Contains(otherDependsOn[value], dep) {
+				deps = append(deps, dep)
+			}
+		}
+		i.AddNode(value, deps)
+	}
+	return &i
+}
+
+// Difference returns a new graph with the dependencies of g that aren't in
+// other, along with the nodes of g that aren't in other. This answers
+// questions like "which edges are in config A but not in B". Nodes are
+// added in the order of g.
+func (g *Graph[T]) Difference(other *Graph[T]) *Graph[T] {
+	order, dependsOn := g.edges()
+	otherOrder, otherDependsOn := other.edges()
+
+	var d Graph[T]
+	for _, value := range order {
+		var deps []T
+		for _, dep := range dependsOn[value] {
+			if !slices.Contains(otherDependsOn[value], dep) {
+				deps = append(deps, dep)
+			}
+		}
+		if len(deps) > 0 || !slices.Contains(otherOrder, value) {
+			d.AddNode(value, deps)
+		}
+	}
+	return &d
+}
@@ -0,0 +1,68 @@
+package topo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestSetOperations checks union, intersection, and difference of graphs.
+func TestSetOperations(t *testing.T) {
+	var a topo.Graph[string]
+	a.AddNode("app", []string{"lib", "db"})
+	a.AddNode("lib", []string{"base"})
+	a.AddNode("tool", nil)
+
+	var b topo.Graph[string]
+	b.AddNode("app", []string{"lib", "cache"})
+	b.AddNode("lib", []string{"base"})
+	b.AddNode("web", []string{"app"})
+
+	tests := []struct {
+		name     string
+		result   *topo.Graph[string]
+		order    []string
+		expected map[string][]string
+	}{
+		{
+			name:   "union",
+			result: a.Union(&b),
+			order:  []string{"app", "lib", "db", "cache", "base", "tool", "web"},
+			expected: map[string][]string{
+				"app": {"lib", "db", "cache"}, "lib": {"base"}, "db": nil, "base": nil,
+				"tool": nil, "cache": nil, "web": {"app"},
+			},
+		},
+		{
+			name:   "intersection",
+			result: a.Intersection(&b),
+			order:  []string{"app", "lib", "base"},
+			expected: map[string][]string{
+				"app": {"lib"}, "lib": {"base"}, "base": nil,
+			},
+		},
+		{
+			name:   "difference",
+			result: a.Difference(&b),
+			order:  []string{"app", "db", "tool"},
+			expected: map[string][]string{
+				"app": {"db"}, "db": nil, "tool": nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if order := tt.result.Nodes(); !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("Expected nodes %v, got %v", tt.order, order)
+			}
+			actual := make(map[string][]string)
+			for _, value := range tt.result.Nodes() {
+				actual[value] = tt.result.Dependencies(value)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStalled is returned by Sorter.Ready when values remain that can never
+// become ready.
+var ErrStalled = errors.New("sort stalled")
+
+// Sorter sorts a snapshot of a graph into layers again and again, reusing
+// its working memory, so that each sort only allocates the layers it
+// returns. It's for services that sort the same graph continuously under
+// load; for occasional sorts, Graph.SortByLayers, which caches its result,
+// is simpler.
+//
+// A Sorter can also hand out values one at a time as they become ready,
+// with Ready and Done, for schedulers that start each value as soon as its
+// dependencies finish rather than waiting for a whole layer.
+//
+// A Sorter isn't safe for concurrent use; use one per goroutine.
+type Sorter[T comparable] struct {
+	order []T
+	l     layering
+	inc   *incremental
+	index map[T]int32
+	// batch holds the positions of the values being marked done
+	batch []int32
+}
+
+// Sorter returns a Sorter for the graph as it is now. Later changes to the
+// graph don't change what the Sorter sorts, and pins are ignored.
+func (g *Graph[T]) Sorter() *Sorter[T] {
+	order, deps, dependents := g.interned()
+	return &Sorter[T]{order: order, l: newLayering(deps, dependents), index: g.analysis().index}
+}
+
+// SortByLayers sorts the graph into layers, as Graph.SortByLayers does for
+// a graph without pins.
+func (s *Sorter[T]) SortByLayers() ([][]T, error) {
+	if !s.l.sort() {
+		return nil, ErrCyclicDependency
+	}
+	return layersOf(s.order, s.l.flat, s.l.ends), nil
+}
+
+// incremental is the state of handing out values with Ready and Done.
+type incremental struct {
+	waiting []int32
+	// ready holds the positions that became ready since Ready last
+	// returned
+	ready []int32
+	// handed and done mark the positions Ready has returned, and the
+	// positions that are done
+	handed, done bitset
+	// remaining counts the positions not done, and inFlight those handed
+	// out but not done
+	remaining, inFlight int
+}
+
+// Reset starts handing out values with Ready from the beginning again, as
+// if none were done.
+func (s *Sorter[T]) Reset() {
+	n := len(s.order)
+	inc := &incremental{
+		waiting:   make([]int32, n),
+		handed:    newBitset(n),
+		done:      newBitset(n),
+		remaining: n,
+	}
+	for i, deps := range s.l.deps {
+		inc.waiting[i] = int32(len(deps))
+		if len(deps) == 0 {
+			inc.ready = append(inc.ready, int32(i))
+		}
+	}
+	s.inc = inc
+}
+
+// Ready returns the values whose dependencies are all done, and that Ready
+// hasn't returned before, in the order they became ready. The first call
+// returns the values without dependencies. Each value returned should be
+// passed to Done once it's processed.
+//
+// Ready returns nothing while every value that could be ready is waiting
+// for one still being processed. If no value is being processed either,
+// but some aren't done, they can never become ready, and rather than
+// leaving the caller waiting forever Ready returns ErrStalled, listing
+// them. That happens when the graph has a cycle; a value that's never
+// passed to Done counts as still being processed, and InFlight lists
+// those.
+func (s *Sorter[T]) Ready() ([]T, error) {
+	return s.ReadyN(0)
+}
+
+// ReadyN is Ready, returning at most n values, or every value if n is zero
+// or less. The rest stay ready for the next call, in the same order, so that
+// a caller with a bounded queue can take only as many values as it has
+// room for, rather than holding every value of a wide graph at once.
+func (s *Sorter[T]) ReadyN(n int) ([]T, error) {
+	if s.inc == nil {
+		s.Reset()
+	}
+	inc := s.inc
+	if len(inc.ready) == 0 {
+		if inc.inFlight == 0 && inc.remaining > 0 {
+			var stuck []T
+			for i, value := range s.order {
+				if !inc.handed.has(i) {
+					stuck = append(stuck, value)
+				}
+			}
+			return nil, fmt.Errorf("%w: %v can never be ready", ErrStalled, stuck)
+		}
+		return nil, nil
+	}
+	take := len(inc.ready)
+	if n > 0 {
+		take = min(take, n)
+	}
+	values := make([]T, take)
+	for j, i := range inc.ready[:take] {
+		values[j] = s.order[i]
+		inc.handed.set(int(i))
+	}
+	inc.inFlight += take
+	inc.ready = append(inc.ready[:0], inc.ready[take:]...)
+	return values, nil
+}
+
+// InFlight returns the values Ready has returned that aren't done yet, in
+// the order they were first added to the graph. When nothing is still
+// being processed, these are the values a caller forgot to pass to Done.
+func (s *Sorter[T]) InFlight() []T {
+	if s.inc == nil {
+		return nil
+	}
+	var values []T
+	for i, value := range s.order {
+		if s.inc.handed.has(i) && !s.inc.done.has(i) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Done marks values returned by Ready as processed, so that the values
+// depending on them can become ready. Marking many values done at once is
+// cheaper than one at a time, for callers that finish thousands of small
+// values a second. It returns an error, and marks none done, if any value
+// isn't one Ready has returned, or is already done.
+func (s *Sorter[T]) Done(values ...T) error {
+	if s.inc == nil {
+		s.Reset()
+	}
+	inc := s.inc
+	batch := s.batch[:0]
+	fail := func(err error) error {
+		for _, i := range batch {
+			inc.done.unset(int(i))
+		}
+		s.batch = batch[:0]
+		return err
+	}
+	for _, value := range values {
+		i, ok := s.index[value]
+		switch {
+		case !ok:
+			return fail(fmt.Errorf("%v is not in the graph", value))
+		case !inc.handed.has(int(i)):
+			return fail(fmt.Errorf("%v is not ready", value))
+		case inc.done.has(int(i)):
+			return fail(fmt.Errorf("%v is already done", value))
+		}
+		inc.done.set(int(i))
+		batch = append(batch, i)
+	}
+
+	inc.remaining -= len(batch)
+	inc.inFlight -= len(batch)
+	for _, i := range batch {
+		for _, dependent := range s.l.dependents[i] {
+			inc.waiting[dependent]--
+			if inc.waiting[dependent] == 0 {
+				inc.ready = append(inc.ready, dependent)
+			}
+		}
+	}
+	s.batch = batch[:0]
+	return nil
+}
+
+// State returns the state of a value: StatePending until its dependencies
+// are done, StateReady until Ready returns it, StateRunning until it's
+// passed to Done, and StateSucceeded after. Values not in the graph are
+// reported as pending.
+func (s *Sorter[T]) State(value T) NodeState {
+	if s.inc == nil {
+		s.Reset()
+	}
+	i, ok := s.index[value]
+	switch {
+	case !ok:
+		return StatePending
+	case s.inc.done.has(int(i)):
+		return StateSucceeded
+	case s.inc.handed.has(int(i)):
+		return StateRunning
+	case s.inc.waiting[i] == 0:
+		return StateReady
+	default:
+		return StatePending
+	}
+}
+
+// Active reports whether any value isn't done yet.
+func (s *Sorter[T]) Active() bool {
+	if s.inc == nil {
+		return len(s.order) > 0
+	}
+	return s.inc.remaining > 0
+}
+
+// layering is the working memory of sorting values into layers by their
+// positions in a graph.
+type layering struct {
+	deps, dependents [][]int32
+	waiting          []int32
+	added            bitset
+	// flat holds the positions of the values sorted, layer by layer; ends
+	// holds the end of each layer in it
+	flat []int32
+	ends []int
+}
+
+func newLayering(deps, dependents [][]int32) layering {
+	return layering{
+		deps:       deps,
+		dependents: dependents,
+		waiting:    make([]int32, len(deps)),
+		added:      newBitset(len(deps)),
+		flat:       make([]int32, 0, len(deps)),
+	}
+}
+
+// sort puts each value in the layer after its last dependency, reporting
+// whether every value could be put in one, which fails if there's a cycle.
+func (l *layering) sort() bool {
+	l.flat, l.ends = l.flat[:0], l.ends[:0]
+	clear(l.added)
+
+	// nodes with no dependencies form the first layer
+	for i, deps := range l.deps {
+		l.waiting[i] = int32(len(deps))
+		if len(deps) == 0 {
+			l.flat = append(l.flat, int32(i))
+		}
+	}
+
+	// process the graph layer by layer
+	start := 0
+	for start < len(l.flat) {
+		// invariant: the current layer, flat[start:end], is finalized
+		end := len(l.flat)
+		l.ends = append(l.ends, end)
+
+		// resolve the dependencies on the current layer first, so that
+		// the next layer is in the order its nodes are first found below
+		for _, i := range l.flat[start:end] {
+			for _, dependent := range l.dependents[i] {
+				l.waiting[dependent]--
+			}
+		}
+		for _, i := range l.flat[start:end] {
+			for _, dependent := range l.dependents[i] {
+				if l.waiting[dependent] == 0 && !l.added.has(int(dependent)) {
+					l.flat = append(l.flat, dependent)
+					l.added.set(int(dependent))
+				}
+			}
+		}
+		start = end
+	}
+
+	// nodes left waiting are in or after a cycle
+	return len(l.flat) == len(l.deps)
+}
+
+// layersOf returns the values at the positions in flat, split into layers
+// at ends. It allocates only the layers and one slice they share.
+func layersOf[T any](order []T, flat []int32, ends []int) [][]T {
+	if len(ends) == 0 {
+		return nil
+	}
+	values := make([]T, len(flat))
+	for j, i := range flat {
+		values[j] = order[i]
+	}
+	layers := make([][]T, len(ends))
+	start := 0
+	for k, end := range ends {
+		layers[k] = values[start:end:end]
+		start = end
+	}
+	return layers
+}
@@ -0,0 +1,260 @@
+package topo_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestSorter checks that a Sorter sorts as SortByLayers does, and keeps
+// sorting the graph as it was when it was created.
+func TestSorter(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", nil)
+
+	s := g.Sorter()
+	g.AddNode("base", []string{"app"}) // a cycle the Sorter doesn't see
+
+	expected := [][]string{{"db", "base", "tool"}, {"lib"}, {"app"}}
+	for range 3 {
+		layers, err := s.SortByLayers()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(layers, expected) {
+			t.Errorf("Expected %v, got %v", expected, layers)
+		}
+	}
+
+	if _, err := g.Sorter().SortByLayers(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected ErrCyclicDependency, got %v", err)
+	}
+}
+
+// TestSorterReady checks handing out values as their dependencies are
+// done.
+func TestSorterReady(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+
+	s := g.Sorter()
+	if !s.Active() {
+		t.Fatal("Expected a new Sorter to be active")
+	}
+	if state := s.State("lib"); state != topo.StatePending {
+		t.Errorf("Expected lib pending, got %v", state)
+	}
+	if state := s.State("base"); state != topo.StateReady {
+		t.Errorf("Expected base ready, got %v", state)
+	}
+	steps := []struct {
+		done     []string
+		expected []string
+	}{
+		{nil, []string{"db", "base"}},
+		{nil, nil},
+		{[]string{"base"}, []string{"lib"}},
+		{[]string{"lib"}, nil},
+		{[]string{"db"}, []string{"app"}},
+		{[]string{"app"}, nil},
+	}
+	for _, step := range steps {
+		for _, value := range step.done {
+			if err := s.Done(value); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		ready, err := s.Ready()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ready, step.expected) {
+			t.Errorf("Expected %v ready after %v, got %v", step.expected, step.done, ready)
+		}
+	}
+	if s.Active() {
+		t.Error("Expected the Sorter to be done")
+	}
+	if state := s.State("app"); state != topo.StateSucceeded {
+		t.Errorf("Expected app succeeded, got %v", state)
+	}
+
+	for _, value := range []string{"app", "missing"} {
+		if err := s.Done(value); err == nil {
+			t.Errorf("Expected an error marking %s done", value)
+		}
+	}
+	s.Reset()
+	if err := s.Done("db"); err == nil {
+		t.Error("Expected an error marking db done before it's ready")
+	}
+	if ready, _ := s.Ready(); !reflect.DeepEqual(ready, []string{"db", "base"}) {
+		t.Errorf("Expected [db base] after Reset, got %v", ready)
+	}
+}
+
+// TestSorterReadyN checks taking ready values a few at a time.
+func TestSorterReadyN(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"a", "b", "c"})
+
+	s := g.Sorter()
+	steps := []struct {
+		n        int
+		done     []string
+		expected []string
+	}{
+		{2, nil, []string{"a", "b"}},
+		{2, []string{"a"}, []string{"c"}},
+		{0, []string{"b", "c"}, []string{"app"}},
+		{1, nil, nil},
+	}
+	for _, step := range steps {
+		if err := s.Done(step.done...); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ready, err := s.ReadyN(step.n)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ready, step.expected) {
+			t.Errorf("Expected %v ready after %v, got %v", step.expected, step.done, ready)
+		}
+	}
+	if state := s.State("app"); state != topo.StateRunning {
+		t.Errorf("Expected app running, got %v", state)
+	}
+}
+
+// TestSorterStalled checks that Ready reports values that can never be
+// ready, rather than leaving the caller waiting.
+func TestSorterStalled(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base", "app"})
+
+	s := g.Sorter()
+	ready, err := s.Ready()
+	if err != nil || !reflect.DeepEqual(ready, []string{"db", "base"}) {
+		t.Fatalf("Expected [db base], got %v, %v", ready, err)
+	}
+	// still processing db
+	if err := s.Done("base"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ready, err := s.Ready(); err != nil || len(ready) != 0 {
+		t.Errorf("Expected nothing ready while db is processed, got %v, %v", ready, err)
+	}
+	if inFlight := s.InFlight(); !reflect.DeepEqual(inFlight, []string{"db"}) {
+		t.Errorf("Expected [db] in flight, got %v", inFlight)
+	}
+	if state := s.State("db"); state != topo.StateRunning {
+		t.Errorf("Expected db running, got %v", state)
+	}
+
+	if err := s.Done("db"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = s.Ready()
+	if !errors.Is(err, topo.ErrStalled) {
+		t.Fatalf("Expected ErrStalled, got %v", err)
+	}
+	if expected := "sort stalled: [app lib] can never be ready"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+// TestSorterDoneBatch checks marking many values done at once, and that
+// a batch with a bad value marks none done.
+func TestSorterDoneBatch(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+
+	s := g.Sorter()
+	if _, err := s.Ready(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, batch := range [][]string{{"db", "app"}, {"db", "db"}, {"db", "missing"}} {
+		if err := s.Done(batch...); err == nil {
+			t.Errorf("Expected an error marking %v done", batch)
+		}
+	}
+	if inFlight := s.InFlight(); !reflect.DeepEqual(inFlight, []string{"db", "base"}) {
+		t.Errorf("Expected [db base] still in flight, got %v", inFlight)
+	}
+
+	if err := s.Done("db", "base"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ready, _ := s.Ready(); !reflect.DeepEqual(ready, []string{"lib", "tool"}) {
+		t.Errorf("Expected [lib tool], got %v", ready)
+	}
+	if err := s.Done("lib", "tool"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ready, _ := s.Ready(); !reflect.DeepEqual(ready, []string{"app"}) {
+		t.Errorf("Expected [app], got %v", ready)
+	}
+}
+
+// TestSorterAllocations checks that sorting again only allocates the
+// layers returned.
+func TestSorterAllocations(t *testing.T) {
+	s := denseGraph(1_000, 4).Sorter()
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := s.SortByLayers(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 2 {
+		t.Errorf("Expected at most 2 allocations, got %v", allocs)
+	}
+}
+
+// BenchmarkSorterDone compares marking values done one at a time and a
+// layer at a time.
+func BenchmarkSorterDone(b *testing.B) {
+	s := denseGraph(100_000, 4).Sorter()
+	for _, batched := range []bool{false, true} {
+		b.Run(fmt.Sprintf("batched=%v", batched), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				s.Reset()
+				for s.Active() {
+					ready, err := s.Ready()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if batched {
+						err = s.Done(ready...)
+					} else {
+						for _, value := range ready {
+							err = errors.Join(err, s.Done(value))
+						}
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSorter(b *testing.B) {
+	s := denseGraph(100_000, 4).Sorter()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := s.SortByLayers(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package topo
+
+import "fmt"
+
+// NodeState is where a value is in being processed, as reported by
+// Sorter.State and the executors in the exec package, so that systems
+// that persist or display runs can share one meaning for each state.
+//
+// Every value starts Pending, and only moves forward:
+//
+//	Pending → Ready → Running → Succeeded
+//	                          → Failed
+//	Pending, Ready → Skipped
+//	Ready → Cached
+//
+// Succeeded, Failed, Skipped, and Cached are final. A Sorter only tracks
+// the first four states, since it doesn't know whether processing a value
+// worked; the others are up to executors.
+type NodeState int
+
+const (
+	// StatePending is a value waiting for its dependencies.
+	StatePending NodeState = iota
+	// StateReady is a value whose dependencies are all done, waiting to be
+	// started.
+	StateReady
+	// StateRunning is a value being processed.
+	StateRunning
+	// StateSucceeded is a value processed without an error.
+	StateSucceeded
+	// StateFailed is a value whose processing returned an error.
+	StateFailed
+	// StateSkipped is a value that was never started, because a
+	// dependency failed or the run was stopped.
+	StateSkipped
+	// StateCached is a value that didn't need processing, because its
+	// result from an earlier run could be used.
+	StateCached
+)
+
+// String returns the name of the state.
+func (s NodeState) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateReady:
+		return "ready"
+	case StateRunning:
+		return "running"
+	case StateSucceeded:
+		return "succeeded"
+	case StateFailed:
+		return "failed"
+	case StateSkipped:
+		return "skipped"
+	case StateCached:
+		return "cached"
+	default:
+		return fmt.Sprintf("NodeState(%d)", int(s))
+	}
+}
+
+// Final reports whether a value in the state is finished with, and won't
+// change state again.
+func (s NodeState) Final() bool {
+	return s >= StateSucceeded && s <= StateCached
+}
+
+// CanBecome reports whether a value can move from the state to next, as
+// laid out in the documentation of NodeState.
+func (s NodeState) CanBecome(next NodeState) bool {
+	switch s {
+	case StatePending:
+		return next == StateReady || next == StateSkipped
+	case StateReady:
+		return next == StateRunning || next == StateSkipped || next == StateCached
+	case StateRunning:
+		return next == StateSucceeded || next == StateFailed
+	default:
+		return false
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that states can be
+// written as JSON.
+func (s NodeState) MarshalText() ([]byte, error) {
+	if s < StatePending || s > StateCached {
+		return nil, fmt.Errorf("unknown %v", s)
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *NodeState) UnmarshalText(text []byte) error {
+	for state := StatePending; state <= StateCached; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown node state %q", text)
+}
@@ -0,0 +1,62 @@
+package topo_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestNodeState checks the names of states and the moves between them.
+func TestNodeState(t *testing.T) {
+	tests := []struct {
+		state topo.NodeState
+		name  string
+		final bool
+		next  []topo.NodeState
+	}{
+		{topo.StatePending, "pending", false, []topo.NodeState{topo.StateReady, topo.StateSkipped}},
+		{topo.StateReady, "ready", false, []topo.NodeState{topo.StateRunning, topo.StateSkipped, topo.StateCached}},
+		{topo.StateRunning, "running", false, []topo.NodeState{topo.StateSucceeded, topo.StateFailed}},
+		{topo.StateSucceeded, "succeeded", true, nil},
+		{topo.StateFailed, "failed", true, nil},
+		{topo.StateSkipped, "skipped", true, nil},
+		{topo.StateCached, "cached", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if name := tt.state.String(); name != tt.name {
+				t.Errorf("Expected %q, got %q", tt.name, name)
+			}
+			if final := tt.state.Final(); final != tt.final {
+				t.Errorf("Expected final %v, got %v", tt.final, final)
+			}
+			for next := topo.StatePending; next <= topo.StateCached; next++ {
+				expected := false
+				for _, n := range tt.next {
+					expected = expected || n == next
+				}
+				if can := tt.state.CanBecome(next); can != expected {
+					t.Errorf("Expected CanBecome(%v) %v, got %v", next, expected, can)
+				}
+			}
+
+			data, err := json.Marshal(tt.state)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var state topo.NodeState
+			if err := json.Unmarshal(data, &state); err != nil || state != tt.state {
+				t.Errorf("Expected %v from %s, got %v, %v", tt.state, data, state, err)
+			}
+		})
+	}
+
+	if _, err := json.Marshal(topo.NodeState(99)); err == nil {
+		t.Error("Expected an error marshaling an unknown state")
+	}
+	var state topo.NodeState
+	if err := json.Unmarshal([]byte(`"done"`), &state); err == nil {
+		t.Error("Expected an error unmarshaling an unknown state")
+	}
+}
@@ -0,0 +1,618 @@
+// Package store keeps a graph of strings in a file on disk, backed by
+// bbolt, so that long-lived services don't need to rebuild large graphs
+// from their source data on every restart.
+//
+// A Store is changed a node or dependency at a time, like a topo.Graph,
+// with each change written to disk before it returns. The whole graph can
+// be loaded with Graph, or just the part a service needs with Load, and
+// the graph can be saved as numbered snapshots to be loaded later.
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+var (
+	// ErrSnapshotNotFound is returned when loading or deleting a snapshot
+	// that doesn't exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrUnsupportedVersion is returned when opening a store written by a
+	// newer version of this package, with a format it doesn't know.
+	ErrUnsupportedVersion = errors.New("unsupported store version")
+)
+
+// Version is the version of the format stores are kept in. Stores from
+// before versions were kept are version 0.
+const Version = 1
+
+// migrations upgrade stores from each version to the next. When the format
+// changes, Version goes up, and a migration from the version before is
+// added here, so that stores kept for years can still be opened.
+var migrations = map[uint64]func(tx *bolt.Tx) error{
+	// version 0 had the same format, without the version
+	0: func(*bolt.Tx) error { return nil },
+}
+
+var (
+	// nodesBucket maps each node to its record.
+	nodesBucket = []byte("nodes")
+	// orderBucket maps the sequence number of each node to the node, so
+	// that nodes are loaded in the order they were first added.
+	orderBucket = []byte("order")
+	// dependentsBucket holds a key of each dependency, a zero byte, and
+	// the node depending on it, for finding the dependents of a node.
+	dependentsBucket = []byte("dependents")
+	// snapshotsBucket maps each snapshot's version to a bucket holding
+	// copies of the nodes and order buckets, and its time.
+	snapshotsBucket = []byte("snapshots")
+	timeKey         = []byte("time")
+	// metaBucket holds the store's version.
+	metaBucket = []byte("meta")
+	versionKey = []byte("version")
+)
+
+// record is how a node is kept on disk.
+type record struct {
+	Seq  uint64     `json:"seq"`
+	Deps []kindDeps `json:"deps,omitempty"`
+}
+
+// kindDeps are a node's dependencies of one kind.
+type kindDeps struct {
+	Kind topo.EdgeKind `json:"kind,omitempty"`
+	Deps []string      `json:"deps"`
+}
+
+// deps returns the dependencies of every kind, without duplicates.
+func (r *record) deps() []string {
+	var all []string
+	for _, kd := range r.Deps {
+		for _, dep := range kd.Deps {
+			if !slices.Contains(all, dep) {
+				all = append(all, dep)
+			}
+		}
+	}
+	return all
+}
+
+// Store is a graph kept in a file. It's safe for concurrent use.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the store in a file, creating it if it doesn't exist. Only
+// one process can have a store open at a time. A store written by an older
+// version of this package is migrated to Version as it's opened.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		// a new store is written in the current format
+		fresh := tx.Bucket(nodesBucket) == nil
+		for _, name := range [][]byte{nodesBucket, orderBucket, dependentsBucket, snapshotsBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if fresh {
+			return tx.Bucket(metaBucket).Put(versionKey, seqKey(Version))
+		}
+		return migrate(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store's file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// AddNode adds a node with its dependencies, replacing those it had, as
+// topo.Graph.AddNode does.
+func (s *Store) AddNode(value string, deps []string) error {
+	return s.AddNodeOfKind(value, topo.DefaultEdgeKind, deps)
+}
+
+// AddNodeOfKind adds a node with its dependencies of the given kind,
+// replacing those it had of that kind, as topo.Graph.AddNodeOfKind does.
+func (s *Store) AddNodeOfKind(value string, kind topo.EdgeKind, deps []string) error {
+	return s.update(value, func(r *record) bool {
+		deps = unique(deps)
+		i := slices.IndexFunc(r.Deps, func(kd kindDeps) bool { return kd.Kind == kind })
+		if i < 0 {
+			r.Deps = append(r.Deps, kindDeps{Kind: kind, Deps: deps})
+		} else {
+			r.Deps[i].Deps = deps
+		}
+		return true
+	})
+}
+
+// AddDependency adds a single dependency to a node, keeping those it has,
+// as topo.Graph.AddDependency does.
+func (s *Store) AddDependency(value, dep string) error {
+	return s.update(value, func(r *record) bool {
+		if slices.Contains(r.deps(), dep) {
+			return false
+		}
+		i := slices.IndexFunc(r.Deps, func(kd kindDeps) bool { return kd.Kind == topo.DefaultEdgeKind })
+		if i < 0 {
+			r.Deps = append(r.Deps, kindDeps{Deps: []string{dep}})
+		} else {
+			r.Deps[i].Deps = append(r.Deps[i].Deps, dep)
+		}
+		return true
+	})
+}
+
+// RemoveDependency removes a dependency from a node, whatever its kind,
+// as topo.Graph.RemoveDependency does.
+func (s *Store) RemoveDependency(value, dep string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		r, ok, err := get(tx.Bucket(nodesBucket), value)
+		if err != nil || !ok {
+			return err
+		}
+		return removeDep(tx, value, r, dep)
+	})
+}
+
+// RemoveNode removes a node, along with every dependency on it, as
+// topo.Graph.RemoveNode does.
+func (s *Store) RemoveNode(value string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		nodes := tx.Bucket(nodesBucket)
+		for _, dependent := range dependents(tx, value) {
+			r, ok, err := get(nodes, dependent)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// a stale entry for a node that's gone; drop it
+				if err := index(tx, dependent, []string{value}, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := removeDep(tx, dependent, r, value); err != nil {
+				return err
+			}
+		}
+		r, ok, err := get(nodes, value)
+		if err != nil || !ok {
+			return err
+		}
+		if err := index(tx, value, r.deps(), nil); err != nil {
+			return err
+		}
+		if err := tx.Bucket(orderBucket).Delete(seqKey(r.Seq)); err != nil {
+			return err
+		}
+		return nodes.Delete([]byte(value))
+	})
+}
+
+// Dependencies returns the dependencies of a node, of every kind, as
+// topo.Graph.Dependencies does.
+func (s *Store) Dependencies(value string) ([]string, error) {
+	var deps []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		r, ok, err := get(tx.Bucket(nodesBucket), value)
+		if ok {
+			deps = r.deps()
+		}
+		return err
+	})
+	return deps, err
+}
+
+// Dependents returns the nodes that depend on a node directly, sorted.
+func (s *Store) Dependents(value string) ([]string, error) {
+	var nodes []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		nodes = dependents(tx, value)
+		return nil
+	})
+	return nodes, err
+}
+
+// Save replaces what's in the store with a graph, in a single
+// transaction. Every value in the graph is kept as a node, including those
+// that were only its dependencies. Snapshots are kept.
+func (s *Store) Save(g *topo.Graph[string]) error {
+	// each kind's dependencies, found in one pass over its graph, rather
+	// than a lookup per value and kind
+	kinds := g.Kinds()
+	byKind := make([]map[string][]string, len(kinds))
+	for i, kind := range kinds {
+		k := g.OnlyKinds(kind)
+		byKind[i] = make(map[string][]string)
+		for _, value := range k.Nodes() {
+			if deps := k.Dependencies(value); len(deps) > 0 {
+				byKind[i][value] = deps
+			}
+		}
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{nodesBucket, orderBucket, dependentsBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		// bolt splits pages only on commit, so keys put out of order in
+		// one transaction make each put copy the page: the nodes and
+		// their dependents are put sorted, after the order they're in
+		values := g.Nodes()
+		records := make(map[string]*record, len(values))
+		var keys [][]byte
+		for _, value := range values {
+			seq, err := tx.Bucket(orderBucket).NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(orderBucket).Put(seqKey(seq), []byte(value)); err != nil {
+				return err
+			}
+			r := &record{Seq: seq}
+			for i, kind := range kinds {
+				if deps := byKind[i][value]; len(deps) > 0 {
+					r.Deps = append(r.Deps, kindDeps{Kind: kind, Deps: deps})
+				}
+			}
+			records[value] = r
+			for _, dep := range r.deps() {
+				keys = append(keys, dependentKey(dep, value))
+			}
+		}
+		slices.Sort(values)
+		for _, value := range values {
+			data, err := json.Marshal(records[value])
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(nodesBucket).Put([]byte(value), data); err != nil {
+				return err
+			}
+		}
+		slices.SortFunc(keys, bytes.Compare)
+		for _, key := range keys {
+			if err := tx.Bucket(dependentsBucket).Put(key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Graph loads the whole graph.
+func (s *Store) Graph() (*topo.Graph[string], error) {
+	var g *topo.Graph[string]
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		g, err = load(tx.Bucket(nodesBucket), tx.Bucket(orderBucket))
+		return err
+	})
+	return g, err
+}
+
+// Load loads only the given nodes and the nodes they depend on, directly
+// or transitively, reading no more of the store than that. This is
+// PruneTo of the whole graph, without loading all of it.
+func (s *Store) Load(targets ...string) (*topo.Graph[string], error) {
+	var g topo.Graph[string]
+	err := s.db.View(func(tx *bolt.Tx) error {
+		nodes := tx.Bucket(nodesBucket)
+		records := make(map[string]*record)
+		var found []string
+		queue := slices.Clone(targets)
+		seen := make(map[string]bool)
+		for len(queue) > 0 {
+			value := queue[0]
+			queue = queue[1:]
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			r, ok, err := get(nodes, value)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			records[value] = r
+			found = append(found, value)
+			queue = append(queue, r.deps()...)
+		}
+		slices.SortFunc(found, func(a, b string) int {
+			return cmp.Compare(records[a].Seq, records[b].Seq)
+		})
+		for _, value := range found {
+			add(&g, value, records[value])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Snapshot is a saved copy of the graph in a store.
+type Snapshot struct {
+	// Version numbers snapshots, starting from 1, in the order they were
+	// taken.
+	Version uint64
+	Time    time.Time
+}
+
+// Snapshot saves a copy of the graph as it is now, returning it.
+func (s *Store) Snapshot() (Snapshot, error) {
+	var snap Snapshot
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		snapshots := tx.Bucket(snapshotsBucket)
+		version, err := snapshots.NextSequence()
+		if err != nil {
+			return err
+		}
+		snap = Snapshot{Version: version, Time: time.Now().UTC()}
+		b, err := snapshots.CreateBucket(seqKey(version))
+		if err != nil {
+			return err
+		}
+		t, err := snap.Time.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(timeKey, t); err != nil {
+			return err
+		}
+		for _, name := range [][]byte{nodesBucket, orderBucket} {
+			dst, err := b.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+			err = tx.Bucket(name).ForEach(func(k, v []byte) error {
+				return dst.Put(k, v)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return snap, err
+}
+
+// Snapshots returns the snapshots in the store, oldest first.
+func (s *Store) Snapshots() ([]Snapshot, error) {
+	var snaps []Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEachBucket(func(k []byte) error {
+			snap := Snapshot{Version: binary.BigEndian.Uint64(k)}
+			t := tx.Bucket(snapshotsBucket).Bucket(k).Get(timeKey)
+			if err := snap.Time.UnmarshalBinary(t); err != nil {
+				return err
+			}
+			snaps = append(snaps, snap)
+			return nil
+		})
+	})
+	return snaps, err
+}
+
+// LoadSnapshot loads the graph as it was when a snapshot was taken.
+func (s *Store) LoadSnapshot(version uint64) (*topo.Graph[string], error) {
+	var g *topo.Graph[string]
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket).Bucket(seqKey(version))
+		if b == nil {
+			return fmt.Errorf("%w: %d", ErrSnapshotNotFound, version)
+		}
+		var err error
+		g, err = load(b.Bucket(nodesBucket), b.Bucket(orderBucket))
+		return err
+	})
+	return g, err
+}
+
+// DeleteSnapshot deletes a snapshot.
+func (s *Store) DeleteSnapshot(version uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(snapshotsBucket).DeleteBucket(seqKey(version))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return fmt.Errorf("%w: %d", ErrSnapshotNotFound, version)
+		}
+		return err
+	})
+}
+
+// migrate upgrades a store to Version, applying the migration from each
+// version in turn.
+func migrate(tx *bolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+	var version uint64
+	if v := meta.Get(versionKey); v != nil {
+		version = binary.BigEndian.Uint64(v)
+	}
+	if version > Version {
+		return fmt.Errorf("%w: %d, newest known is %d", ErrUnsupportedVersion, version, Version)
+	}
+	for v := version; v < Version; v++ {
+		migration, ok := migrations[v]
+		if !ok {
+			return fmt.Errorf("%w: no migration from %d", ErrUnsupportedVersion, v)
+		}
+		if err := migration(tx); err != nil {
+			return fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	return meta.Put(versionKey, seqKey(Version))
+}
+
+// update changes a node's record with fn, adding the node if it isn't in
+// the store. fn reports whether it changed the record.
+func (s *Store) update(value string, fn func(r *record) bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		r, ok, err := get(tx.Bucket(nodesBucket), value)
+		if err != nil {
+			return err
+		}
+		var old []string
+		if !ok {
+			seq, err := tx.Bucket(orderBucket).NextSequence()
+			if err != nil {
+				return err
+			}
+			r = &record{Seq: seq}
+		} else {
+			old = r.deps()
+		}
+		if !fn(r) && old != nil {
+			return nil
+		}
+		if err := put(tx, value, r); err != nil {
+			return err
+		}
+		return index(tx, value, old, r.deps())
+	})
+}
+
+// removeDep removes a dependency of every kind from a node's record.
+func removeDep(tx *bolt.Tx, value string, r *record, dep string) error {
+	old := r.deps()
+	if !slices.Contains(old, dep) {
+		return nil
+	}
+	for i := range r.Deps {
+		r.Deps[i].Deps = slices.DeleteFunc(r.Deps[i].Deps, func(d string) bool { return d == dep })
+	}
+	if err := put(tx, value, r); err != nil {
+		return err
+	}
+	return index(tx, value, old, r.deps())
+}
+
+// get reads a node's record, and reports whether it's in the store.
+func get(nodes *bolt.Bucket, value string) (*record, bool, error) {
+	data := nodes.Get([]byte(value))
+	if data == nil {
+		return nil, false, nil
+	}
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, false, fmt.Errorf("reading node %q: %w", value, err)
+	}
+	return &r, true, nil
+}
+
+// put writes a node's record.
+func put(tx *bolt.Tx, value string, r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(orderBucket).Put(seqKey(r.Seq), []byte(value)); err != nil {
+		return err
+	}
+	return tx.Bucket(nodesBucket).Put([]byte(value), data)
+}
+
+// index updates the dependents of a node's dependencies, after they
+// changed from old to deps.
+func index(tx *bolt.Tx, value string, old, deps []string) error {
+	b := tx.Bucket(dependentsBucket)
+	for _, dep := range old {
+		if !slices.Contains(deps, dep) {
+			if err := b.Delete(dependentKey(dep, value)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dep := range deps {
+		if !slices.Contains(old, dep) {
+			if err := b.Put(dependentKey(dep, value), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dependents returns the nodes that depend on a node, sorted.
+func dependents(tx *bolt.Tx, value string) []string {
+	var nodes []string
+	prefix := dependentKey(value, "")
+	c := tx.Bucket(dependentsBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		nodes = append(nodes, string(k[len(prefix):]))
+	}
+	return nodes
+}
+
+// load builds a graph from buckets of nodes and their order.
+func load(nodes, order *bolt.Bucket) (*topo.Graph[string], error) {
+	var g topo.Graph[string]
+	err := order.ForEach(func(_, v []byte) error {
+		r, _, err := get(nodes, string(v))
+		if err != nil {
+			return err
+		}
+		add(&g, string(v), r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// add adds a node to a graph with its dependencies of each kind.
+func add(g *topo.Graph[string], value string, r *record) {
+	if len(r.Deps) == 0 {
+		g.AddNode(value, nil)
+	}
+	for _, kd := range r.Deps {
+		g.AddNodeOfKind(value, kd.Kind, kd.Deps)
+	}
+}
+
+// unique returns deps without duplicates, in the order first given.
+func unique(deps []string) []string {
+	kept := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if !slices.Contains(kept, dep) {
+			kept = append(kept, dep)
+		}
+	}
+	return kept
+}
+
+func seqKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
+
+func dependentKey(dep, value string) []byte {
+	return []byte(dep + "\x00" + value)
+}
@@ -0,0 +1,317 @@
+package store_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/store"
+)
+
+func open(t *testing.T) (*store.Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "graph.db")
+	s, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func sorted(t *testing.T, g *topo.Graph[string]) [][]string {
+	t.Helper()
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return layers
+}
+
+// TestStore checks that changes are kept, and survive reopening the store.
+func TestStore(t *testing.T) {
+	s, path := open(t)
+	must(t, s.AddNode("app", []string{"lib", "base"}))
+	must(t, s.AddNode("lib", []string{"base"}))
+	must(t, s.AddNode("base", nil))
+	must(t, s.AddNode("tool", nil))
+	must(t, s.AddDependency("tool", "base"))
+	must(t, s.AddDependency("tool", "base"))
+	must(t, s.AddNodeOfKind("app", "test", []string{"tool"}))
+
+	deps, err := s.Dependencies("app")
+	must(t, err)
+	if expected := []string{"lib", "base", "tool"}; !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected dependencies %v, got %v", expected, deps)
+	}
+	dependents, err := s.Dependents("base")
+	must(t, err)
+	if expected := []string{"app", "lib", "tool"}; !reflect.DeepEqual(dependents, expected) {
+		t.Errorf("Expected dependents %v, got %v", expected, dependents)
+	}
+
+	must(t, s.Close())
+	s, err = store.Open(path)
+	must(t, err)
+	defer s.Close()
+
+	g, err := s.Graph()
+	must(t, err)
+	expected := [][]string{{"base"}, {"lib", "tool"}, {"app"}}
+	if layers := sorted(t, g); !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected layers %v, got %v", expected, layers)
+	}
+	if kinds := g.Kinds(); !reflect.DeepEqual(kinds, []topo.EdgeKind{topo.DefaultEdgeKind, "test"}) {
+		t.Errorf("Expected kinds [ test], got %v", kinds)
+	}
+}
+
+// TestStoreRemove checks that removing a node removes the dependencies on
+// it, as a graph does.
+func TestStoreRemove(t *testing.T) {
+	s, _ := open(t)
+	must(t, s.AddNode("app", []string{"lib", "base"}))
+	must(t, s.AddNode("lib", []string{"base"}))
+	must(t, s.AddNode("base", nil))
+
+	must(t, s.RemoveNode("base"))
+	g, err := s.Graph()
+	must(t, err)
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib"}) {
+		t.Errorf("Expected nodes [app lib], got %v", nodes)
+	}
+	if deps := g.Dependencies("lib"); len(deps) != 0 {
+		t.Errorf("Expected lib to have no dependencies, got %v", deps)
+	}
+	dependents, err := s.Dependents("base")
+	must(t, err)
+	if len(dependents) != 0 {
+		t.Errorf("Expected base to have no dependents, got %v", dependents)
+	}
+
+	must(t, s.RemoveDependency("app", "lib"))
+	deps, err := s.Dependencies("app")
+	must(t, err)
+	if len(deps) != 0 {
+		t.Errorf("Expected app to have no dependencies, got %v", deps)
+	}
+	// removing what isn't there does nothing
+	must(t, s.RemoveNode("missing"))
+	must(t, s.RemoveDependency("missing", "app"))
+}
+
+// TestStoreRemoveStale checks that removing a node skips dependents that
+// are no longer in the store.
+func TestStoreRemoveStale(t *testing.T) {
+	s, path := open(t)
+	must(t, s.AddNode("app", []string{"base"}))
+	must(t, s.AddNode("base", nil))
+	must(t, s.Close())
+
+	db, err := bolt.Open(path, 0o600, nil)
+	must(t, err)
+	must(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("nodes")).Delete([]byte("app"))
+	}))
+	must(t, db.Close())
+
+	s, err = store.Open(path)
+	must(t, err)
+	defer s.Close()
+	must(t, s.RemoveNode("base"))
+	dependents, err := s.Dependents("base")
+	must(t, err)
+	if len(dependents) != 0 {
+		t.Errorf("Expected base to have no dependents, got %v", dependents)
+	}
+}
+
+// TestStoreLoad checks that Load loads only the targets and their
+// dependencies, like PruneTo.
+func TestStoreLoad(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("other", nil)
+
+	s, _ := open(t)
+	must(t, s.Save(&g))
+
+	tests := []struct {
+		targets  []string
+		expected []string
+	}{
+		{[]string{"app"}, []string{"app", "lib", "base"}},
+		{[]string{"tool", "lib"}, []string{"lib", "base", "tool"}},
+		{[]string{"missing"}, nil},
+	}
+	for _, tt := range tests {
+		loaded, err := s.Load(tt.targets...)
+		must(t, err)
+		if nodes := loaded.Nodes(); !reflect.DeepEqual(nodes, tt.expected) {
+			t.Errorf("Expected %v to load %v, got %v", tt.targets, tt.expected, nodes)
+		}
+		if pruned := g.PruneTo(tt.targets); len(pruned.Nodes()) > 0 && !reflect.DeepEqual(sorted(t, loaded), sorted(t, pruned)) {
+			t.Errorf("Expected %v to load the same graph as PruneTo", tt.targets)
+		}
+	}
+}
+
+// TestStoreSave checks that saving replaces the graph in the store.
+func TestStoreSave(t *testing.T) {
+	s, _ := open(t)
+	must(t, s.AddNode("old", nil))
+
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNodeOfKind("app", "build", []string{"tool"})
+	must(t, s.Save(&g))
+
+	loaded, err := s.Graph()
+	must(t, err)
+	if nodes := loaded.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib", "tool"}) {
+		t.Errorf("Expected nodes [app lib tool], got %v", nodes)
+	}
+	if deps := loaded.OnlyKinds("build").Dependencies("app"); !reflect.DeepEqual(deps, []string{"tool"}) {
+		t.Errorf("Expected build dependencies [tool], got %v", deps)
+	}
+	dependents, err := s.Dependents("lib")
+	must(t, err)
+	if !reflect.DeepEqual(dependents, []string{"app"}) {
+		t.Errorf("Expected dependents [app], got %v", dependents)
+	}
+}
+
+// TestStoreSnapshots checks that snapshots keep the graph as it was.
+func TestStoreSnapshots(t *testing.T) {
+	s, _ := open(t)
+	must(t, s.AddNode("app", []string{"lib"}))
+	first, err := s.Snapshot()
+	must(t, err)
+	must(t, s.AddNode("app", []string{"lib", "base"}))
+	second, err := s.Snapshot()
+	must(t, err)
+	must(t, s.RemoveNode("lib"))
+
+	if first.Version != 1 || second.Version != 2 {
+		t.Errorf("Expected versions 1 and 2, got %d and %d", first.Version, second.Version)
+	}
+	snaps, err := s.Snapshots()
+	must(t, err)
+	if len(snaps) != 2 || snaps[0].Version != 1 || !snaps[1].Time.Equal(second.Time) {
+		t.Errorf("Expected snapshots %v, got %v", []store.Snapshot{first, second}, snaps)
+	}
+
+	tests := []struct {
+		version  uint64
+		expected []string
+	}{
+		{1, []string{"lib"}},
+		{2, []string{"lib", "base"}},
+	}
+	for _, tt := range tests {
+		g, err := s.LoadSnapshot(tt.version)
+		must(t, err)
+		if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, tt.expected) {
+			t.Errorf("Expected version %d to have dependencies %v, got %v", tt.version, tt.expected, deps)
+		}
+	}
+
+	must(t, s.DeleteSnapshot(1))
+	if _, err := s.LoadSnapshot(1); !errors.Is(err, store.ErrSnapshotNotFound) {
+		t.Errorf("Expected ErrSnapshotNotFound, got %v", err)
+	}
+	if err := s.DeleteSnapshot(1); !errors.Is(err, store.ErrSnapshotNotFound) {
+		t.Errorf("Expected ErrSnapshotNotFound, got %v", err)
+	}
+	// versions aren't reused after deleting
+	third, err := s.Snapshot()
+	must(t, err)
+	if third.Version != 3 {
+		t.Errorf("Expected version 3, got %d", third.Version)
+	}
+}
+
+// TestStoreVersion checks that stores from before versions were kept are
+// migrated, and that stores from newer versions aren't opened.
+func TestStoreVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		// version is written to the store, or removed if nil
+		version []byte
+		err     error
+	}{
+		{"unversioned", nil, nil},
+		{"current", binary.BigEndian.AppendUint64(nil, store.Version), nil},
+		{"newer", binary.BigEndian.AppendUint64(nil, store.Version+1), store.ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, path := open(t)
+			must(t, s.AddNode("app", []string{"lib"}))
+			must(t, s.Close())
+
+			db, err := bolt.Open(path, 0o600, nil)
+			must(t, err)
+			must(t, db.Update(func(tx *bolt.Tx) error {
+				if tt.version == nil {
+					return tx.DeleteBucket([]byte("meta"))
+				}
+				return tx.Bucket([]byte("meta")).Put([]byte("version"), tt.version)
+			}))
+			must(t, db.Close())
+
+			s, err = store.Open(path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			defer s.Close()
+			deps, err := s.Dependencies("app")
+			must(t, err)
+			if !reflect.DeepEqual(deps, []string{"lib"}) {
+				t.Errorf("Expected [lib], got %v", deps)
+			}
+		})
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	var g topo.Graph[string]
+	for i := range 40_000 {
+		var deps []string
+		for j := 1; j <= 4 && j*j <= i; j++ {
+			deps = append(deps, fmt.Sprint(i-j*j))
+		}
+		g.AddNode(fmt.Sprint(i), deps)
+		if i%10 == 0 && i > 0 {
+			g.AddNodeOfKind(fmt.Sprint(i), "build", []string{fmt.Sprint(i - 1)})
+		}
+	}
+	s, err := store.Open(filepath.Join(b.TempDir(), "graph.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	for b.Loop() {
+		if err := s.Save(&g); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrStructTags is returned by FromStructs when the structs' topo tags
+// don't say how to build a graph from them.
+var ErrStructTags = errors.New("invalid topo struct tags")
+
+// FromStructs builds a graph from a slice of structs, or of pointers to
+// structs, whose topo tags say which field identifies each struct and which
+// hold its dependencies, saving the loop that adds each to the graph:
+//
+//	type Service struct {
+//		Name     string   `topo:"id"`
+//		Requires []string `topo:"deps"`
+//		Uses     []string `topo:"deps,kind=runtime"`
+//	}
+//
+//	g, err := topo.FromStructs[string](services)
+//
+// The fields can also be named in a single tag, usually on a blank field,
+// for structs whose fields are tagged for other purposes:
+//
+//	type Service struct {
+//		_        struct{} `topo:"id=Name,deps=Requires"`
+//		Name     string   `json:"name"`
+//		Requires []string `json:"requires"`
+//	}
+//
+// The id field must be of type T, and the dependency fields slices of T.
+// Dependency fields of the same kind are combined, in the order of the
+// fields. Structs are added in the order of the slice, as AddNode adds
+// them.
+func FromStructs[T comparable](slice any) (*Graph[T], error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: expected a slice of structs, got %T", ErrStructTags, slice)
+	}
+	elem := v.Type().Elem()
+	pointers := elem.Kind() == reflect.Pointer
+	if pointers {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: expected a slice of structs, got %T", ErrStructTags, slice)
+	}
+	fields, err := structFieldsOf[T](elem)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph[T]{}
+	for i := range v.Len() {
+		s := v.Index(i)
+		if pointers {
+			if s.IsNil() {
+				return nil, fmt.Errorf("%w: element %d is nil", ErrStructTags, i)
+			}
+			s = s.Elem()
+		}
+		value := s.FieldByIndex(fields.id).Interface().(T)
+		if len(fields.deps) == 0 {
+			g.AddNode(value, nil)
+		}
+		for _, d := range fields.deps {
+			var deps []T
+			for _, index := range d.indexes {
+				list := s.FieldByIndex(index)
+				for j := range list.Len() {
+					deps = append(deps, list.Index(j).Interface().(T))
+				}
+			}
+			g.AddNodeOfKind(value, d.kind, deps)
+		}
+	}
+	return g, nil
+}
+
+// structFields are the fields FromStructs reads.
+type structFields struct {
+	id   []int
+	deps []depField
+}
+
+// depField is the fields of dependencies of one kind.
+type depField struct {
+	indexes [][]int
+	kind    EdgeKind
+}
+
+// structFieldsOf finds the fields named by a struct type's topo tags.
+func structFieldsOf[T comparable](t reflect.Type) (structFields, error) {
+	var fields structFields
+	var idName string
+	valueType := reflect.TypeFor[T]()
+	// lookup finds a field named in a tag, or the tagged field itself
+	lookup := func(tagged reflect.StructField, name string) (reflect.StructField, error) {
+		if name == "" {
+			return tagged, nil
+		}
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return f, fmt.Errorf("%w: %s has no field %s", ErrStructTags, t, name)
+		}
+		return f, nil
+	}
+
+	for _, tagged := range reflect.VisibleFields(t) {
+		tag, ok := tagged.Tag.Lookup("topo")
+		if !ok {
+			continue
+		}
+		var kind EdgeKind
+		var idField, depsField *string
+		for _, part := range strings.Split(tag, ",") {
+			key, name, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "id":
+				idField = &name
+			case "deps":
+				depsField = &name
+			case "kind":
+				kind = EdgeKind(name)
+			default:
+				return fields, fmt.Errorf("%w: unknown option %q on %s.%s", ErrStructTags, key, t, tagged.Name)
+			}
+		}
+
+		if kind != DefaultEdgeKind && depsField == nil {
+			return fields, fmt.Errorf("%w: kind without deps on %s.%s", ErrStructTags, t, tagged.Name)
+		}
+		if idField != nil {
+			f, err := lookup(tagged, *idField)
+			if err != nil {
+				return fields, err
+			}
+			if fields.id != nil {
+				return fields, fmt.Errorf("%w: %s has ids %s and %s", ErrStructTags, t, idName, f.Name)
+			}
+			if !f.IsExported() || f.Type != valueType {
+				return fields, fmt.Errorf("%w: id %s.%s must be an exported %s", ErrStructTags, t, f.Name, valueType)
+			}
+			fields.id, idName = f.Index, f.Name
+		}
+		if depsField != nil {
+			f, err := lookup(tagged, *depsField)
+			if err != nil {
+				return fields, err
+			}
+			if !f.IsExported() || f.Type.Kind() != reflect.Slice || f.Type.Elem() != valueType {
+				return fields, fmt.Errorf("%w: dependencies %s.%s must be an exported []%s", ErrStructTags, t, f.Name, valueType)
+			}
+			i := slices.IndexFunc(fields.deps, func(d depField) bool { return d.kind == kind })
+			if i < 0 {
+				fields.deps = append(fields.deps, depField{kind: kind})
+				i = len(fields.deps) - 1
+			}
+			fields.deps[i].indexes = append(fields.deps[i].indexes, f.Index)
+		}
+	}
+	if fields.id == nil {
+		return fields, fmt.Errorf("%w: %s has no id field", ErrStructTags, t)
+	}
+	return fields, nil
+}
@@ -0,0 +1,139 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+type taggedService struct {
+	Name     string   `topo:"id"`
+	Requires []string `topo:"deps"`
+	Uses     []string `topo:"deps,kind=runtime"`
+}
+
+type taggedImage struct {
+	_    struct{} `topo:"id=Ref,deps=From"`
+	Ref  string   `json:"ref"`
+	From []string `json:"from"`
+}
+
+type taggedPackage struct {
+	Name          string   `topo:"id"`
+	Requires      []string `topo:"deps"`
+	BuildRequires []string `topo:"deps"`
+	Uses          []string `topo:"deps,kind=runtime"`
+	Recommends    []string `topo:"deps,kind=runtime"`
+}
+
+type taggedPort struct {
+	Number int   `topo:"id"`
+	After  []int `topo:"deps"`
+}
+
+// TestFromStructs checks building graphs from tagged structs.
+func TestFromStructs(t *testing.T) {
+	services, err := topo.FromStructs[string]([]taggedService{
+		{Name: "app", Requires: []string{"lib"}, Uses: []string{"db"}},
+		{Name: "lib"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := services.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected [lib db], got %v", deps)
+	}
+	if kinds := services.Kinds(); !reflect.DeepEqual(kinds, []topo.EdgeKind{"", "runtime"}) {
+		t.Errorf("Expected [ runtime], got %q", kinds)
+	}
+
+	images, err := topo.FromStructs[string]([]*taggedImage{{Ref: "app", From: []string{"base"}}, {Ref: "base"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if nodes := images.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "base"}) {
+		t.Errorf("Expected [app base], got %v", nodes)
+	}
+	if deps := images.Dependencies("app"); !reflect.DeepEqual(deps, []string{"base"}) {
+		t.Errorf("Expected [base], got %v", deps)
+	}
+
+	packages, err := topo.FromStructs[string]([]taggedPackage{
+		{Name: "app", Requires: []string{"lib"}, BuildRequires: []string{"gcc"}, Uses: []string{"db"}, Recommends: []string{"cache"}},
+		{Name: "lib", BuildRequires: []string{"gcc"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := packages.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "gcc", "db", "cache"}) {
+		t.Errorf("Expected [lib gcc db cache], got %v", deps)
+	}
+	if deps := packages.Dependencies("lib"); !reflect.DeepEqual(deps, []string{"gcc"}) {
+		t.Errorf("Expected [gcc], got %v", deps)
+	}
+
+	ports, err := topo.FromStructs[int]([2]taggedPort{{Number: 443, After: []int{80}}, {Number: 80}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	layers, err := topo.SortedLayers(ports)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := [][]int{{80}, {443}}; !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestFromStructsErrors checks the structs that can't be turned into a
+// graph.
+func TestFromStructsErrors(t *testing.T) {
+	type noID struct {
+		Deps []string `topo:"deps"`
+	}
+	type twoIDs struct {
+		A string `topo:"id"`
+		B string `topo:"id"`
+	}
+	type wrongType struct {
+		ID int `topo:"id"`
+	}
+	type wrongDeps struct {
+		ID   string `topo:"id"`
+		Deps string `topo:"deps"`
+	}
+	type missing struct {
+		_ struct{} `topo:"id=Name"`
+	}
+	type unknown struct {
+		ID string `topo:"key"`
+	}
+	type lonelyKind struct {
+		ID string `topo:"id,kind=runtime"`
+	}
+
+	tests := []struct {
+		name  string
+		slice any
+	}{
+		{"not a slice", taggedService{}},
+		{"not structs", []string{"a"}},
+		{"no id", []noID{}},
+		{"two ids", []twoIDs{}},
+		{"wrong id type", []wrongType{}},
+		{"wrong deps type", []wrongDeps{}},
+		{"missing field", []missing{}},
+		{"unknown option", []unknown{}},
+		{"kind without deps", []lonelyKind{}},
+		{"nil element", []*taggedService{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := topo.FromStructs[string](tt.slice); !errors.Is(err, topo.ErrStructTags) {
+				t.Errorf("Expected %v, got %v", topo.ErrStructTags, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package topo
 
-import "errors"
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+)
 
 // ErrCyclicDependency is returned when the graph contains a cycle.
 var ErrCyclicDependency = errors.New("cyclic dependency detected")
@@ -11,105 +18,838 @@ var ErrCyclicDependency = errors.New("cyclic dependency detected")
 // so X, Y, and Z must be processed before A.
 type node[T comparable] struct {
 	value T
+	kind  EdgeKind
 	deps  []T
+	// repeats are the dependencies that were listed more than once, each
+	// time after the first; see Duplicates.
+	repeats []T
 }
 
 // Graph represents a collection of nodes with their dependencies.
+//
+// The results of analyses like SortByLayers and Ancestors are cached until
+// the graph next changes, so asking again is cheap. Methods that only read
+// the graph are safe to call concurrently, as long as nothing changes it.
 type Graph[T comparable] struct {
-	nodes []node[T]
+	nodes      []node[T]
+	pins       map[T]layerPin
+	attrs      map[T]map[string]string
+	observers  []*func(Change[T])
+	history    *history[T]
+	normalizer func(T) T
+	zeroGuard  func(error) bool
+	// cache holds the *analysis[T] of the graph, nil once it changes; an
+	// atomic.Value rather than a mutex keeps graphs copyable
+	cache atomic.Value
 }
 
 // AddNode adds a node to the graph with its dependencies.
 func (g *Graph[T]) AddNode(value T, deps []T) {
+	value, deps = g.normalize(value), g.normalizeAll(deps)
+	if !g.allowNode(value) {
+		return
+	}
+	deps = g.allowDeps(value, deps)
+	g.record()
+	deps = g.add(value, DefaultEdgeKind, deps)
+	g.notify(Change[T]{Op: OpAddNode, Value: value, Deps: deps})
+}
+
+// add declares a node's dependencies of a kind, without notifying
+// observers. Dependencies listed more than once are only kept once; the
+// ones kept are returned.
+func (g *Graph[T]) add(value T, kind EdgeKind, deps []T) []T {
+	deps, repeats := unique(deps)
 	g.nodes = append(g.nodes, node[T]{
-		value: value,
-		deps:  deps,
+		value:   value,
+		kind:    kind,
+		deps:    deps,
+		repeats: repeats,
 	})
+	g.invalidate()
+	return deps
+}
+
+// AddDependency adds a single dependency to a node, keeping the
+// dependencies it already has. Unlike AddNode, which replaces a node's
+// dependencies when it's added again, this is safe for adding edges to a
+// graph built elsewhere. The node's declaration is extended in place, so
+// the new dependency is ordered with the ones it already has, as if it had
+// been listed when the node was added. Adding a dependency the node already
+// has does nothing.
+func (g *Graph[T]) AddDependency(value T, dep T) {
+	value, dep = g.normalize(value), g.normalize(dep)
+	if !g.allowNode(value) || len(g.allowDeps(value, []T{dep})) == 0 {
+		return
+	}
+	// the last declaration of each kind is the one in effect; the last of
+	// the default kind is extended
+	last := -1
+	var kinds []EdgeKind
+	for i := len(g.nodes) - 1; i >= 0; i-- {
+		n := g.nodes[i]
+		if n.value != value || slices.Contains(kinds, n.kind) {
+			continue
+		}
+		if slices.Contains(n.deps, dep) {
+			return
+		}
+		kinds = append(kinds, n.kind)
+		if n.kind == DefaultEdgeKind {
+			last = i
+		}
+	}
+	g.record()
+	if last < 0 {
+		g.add(value, DefaultEdgeKind, []T{dep})
+	} else {
+		if g.history != nil {
+			// snapshots share the nodes, which must then only be appended
+			// to or replaced
+			g.nodes = slices.Clone(g.nodes)
+		}
+		g.nodes[last].deps = append(slices.Clip(g.nodes[last].deps), dep)
+		g.invalidate()
+	}
+	g.notify(Change[T]{Op: OpAddDependency, Value: value, Deps: []T{dep}})
+}
+
+// RemoveNode removes a value from the graph: its dependencies of every
+// kind, any pin or attributes, and every dependency on it. Values that were
+// only in the graph as its dependencies are no longer in it. Removing a
+// value that isn't in the graph does nothing.
+func (g *Graph[T]) RemoveNode(value T) {
+	value = g.normalize(value)
+	found := false
+	var nodes []node[T]
+	for _, n := range g.nodes {
+		if n.value == value {
+			found = true
+			continue
+		}
+		if slices.Contains(n.deps, value) {
+			found = true
+			n.deps = slices.DeleteFunc(slices.Clone(n.deps), func(d T) bool { return d == value })
+		}
+		nodes = append(nodes, n)
+	}
+	if !found {
+		return
+	}
+	g.record()
+	g.nodes = nodes
+	delete(g.pins, value)
+	delete(g.attrs, value)
+	g.invalidate()
+	g.notify(Change[T]{Op: OpRemoveNode, Value: value})
+}
+
+// RemoveDependency removes a dependency from a node, whatever its kind;
+// observers are told of each kind it's removed from. A value that was only
+// in the graph as this dependency is no longer in it. Removing a dependency
+// the node doesn't have does nothing.
+func (g *Graph[T]) RemoveDependency(value T, dep T) {
+	value, dep = g.normalize(value), g.normalize(dep)
+	var kinds []EdgeKind
+	nodes := slices.Clone(g.nodes)
+	for i, n := range nodes {
+		if n.value == value && slices.Contains(n.deps, dep) {
+			if !slices.Contains(kinds, n.kind) {
+				kinds = append(kinds, n.kind)
+			}
+			nodes[i].deps = slices.DeleteFunc(slices.Clone(n.deps), func(d T) bool { return d == dep })
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+	g.record()
+	g.nodes = nodes
+	g.invalidate()
+	for _, kind := range kinds {
+		g.notify(Change[T]{Op: OpRemoveDependency, Value: value, Kind: kind, Deps: []T{dep}})
+	}
+}
+
+// Nodes returns every value in the graph, including values that only
+// appear as dependencies, in the order they were first added.
+func (g *Graph[T]) Nodes() []T {
+	order, _ := g.edges()
+	return slices.Clone(order)
+}
+
+// Dependencies returns the values that the given value depends on. If the
+// value was added more than once, the dependencies from the last AddNode
+// call are returned, along with the last ones of every other EdgeKind.
+func (g *Graph[T]) Dependencies(value T) []T {
+	_, dependsOn := g.edges()
+	return slices.Clone(dependsOn[value])
 }
 
 // SortByLayers performs a topological sort of the graph, returning layers
 // where each layer contains nodes that can be processed in parallel.
 // Each layer must be processed before the next layer.
+//
+// Pinned values are put in the layers they're pinned to; see PinLayer.
 func (g *Graph[T]) SortByLayers() ([][]T, error) {
-	// node values to dependencies
-	dependsOn := make(map[T][]T)
-	// reverse: node values to nodes that depend on them
+	return g.analysis().sortedLayers(func() ([][]T, error) {
+		if len(g.pins) > 0 {
+			return g.sortPinned()
+		}
+		return g.sortByLayers()
+	})
+}
+
+// sortByLayers puts each value in the layer after its last dependency,
+// ignoring pins.
+func (g *Graph[T]) sortByLayers() ([][]T, error) {
+	order, deps, dependents := g.interned()
+	l := newLayering(deps, dependents)
+	if !l.sort() {
+		return nil, ErrCyclicDependency
+	}
+	return layersOf(order, l.flat, l.ends), nil
+}
+
+// ForEachLayer sorts the graph into layers, as SortByLayers does, and calls
+// fn with the index and values of each layer in turn. If fn returns an
+// error, no more layers are visited and the error is returned. If the graph
+// has a cycle, fn isn't called at all and ErrCyclicDependency is returned.
+func (g *Graph[T]) ForEachLayer(fn func(i int, layer []T) error) error {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return err
+	}
+	for i, layer := range layers {
+		if err := fn(i, layer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortByLayersFunc is like SortByLayers, but orders the nodes within each
+// layer using cmp, which returns a negative number when a should come
+// before b, a positive number when a should come after b, and zero when
+// their order doesn't matter, as with slices.SortFunc. For example, to run
+// the longest tasks of each layer first:
+//
+//	layers, err := g.SortByLayersFunc(func(a, b Task) int {
+//		return cmp.Compare(b.Duration, a.Duration)
+//	})
+func (g *Graph[T]) SortByLayersFunc(cmp func(a, b T) int) ([][]T, error) {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		slices.SortStableFunc(layer, cmp)
+	}
+	return layers, nil
+}
+
+// SortedLayers is like SortByLayers, but sorts the values of each layer in
+// ascending order, so that layers can be compared and printed as they are.
+// It's a function rather than a method since it needs values that can be
+// ordered.
+func SortedLayers[T cmp.Ordered](g *Graph[T]) ([][]T, error) {
+	return g.SortByLayersFunc(cmp.Compare[T])
+}
+
+// Flatten concatenates layers into a single order, where each value still
+// comes after its dependencies. Within each layer, values are ordered by
+// less, keeping their order where less doesn't decide; if less is nil,
+// layers are kept as they are. The layers themselves aren't changed.
+func Flatten[T any](layers [][]T, less func(a, b T) bool) []T {
+	var flat []T
+	for _, layer := range layers {
+		start := len(flat)
+		flat = append(flat, layer...)
+		if less != nil {
+			slices.SortStableFunc(flat[start:], func(a, b T) int {
+				switch {
+				case less(a, b):
+					return -1
+				case less(b, a):
+					return 1
+				default:
+					return 0
+				}
+			})
+		}
+	}
+	return flat
+}
+
+// SortByLayersStable is like SortByLayers, but orders the nodes within each
+// layer by when they were first added to the graph, so that layers follow
+// the order a configuration was written in.
+func (g *Graph[T]) SortByLayersStable() ([][]T, error) {
+	position := g.positions()
+	return g.SortByLayersFunc(func(a, b T) int {
+		return position[a] - position[b]
+	})
+}
+
+// SortStable returns every value in the graph in a single order where each
+// value comes after its dependencies. Where dependencies allow, values keep
+// the order they were first added in: a value only moves later when one of
+// its dependencies was added after it.
+func (g *Graph[T]) SortStable() ([]T, error) {
+	order, deps, dependents := g.interned()
+	waiting := make([]int, len(order))
+	var ready positions
+	for i := range order {
+		waiting[i] = len(deps[i])
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	// always take the earliest ready value
+	heap.Init(&ready)
+	result := make([]T, 0, len(order))
+	for ready.Len() > 0 {
+		i := heap.Pop(&ready).(int)
+		result = append(result, order[i])
+		for _, dependent := range dependents[i] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				heap.Push(&ready, int(dependent))
+			}
+		}
+	}
+	if len(result) < len(order) {
+		return nil, ErrCyclicDependency
+	}
+	return result, nil
+}
+
+// SortShuffled returns every value in the graph in a random order where
+// each value comes after its dependencies. The same seed always gives the
+// same order for the same graph, so a test that fails on a shuffled order
+// can be reproduced.
+//
+// Any valid order can be returned, which makes this useful for finding code
+// that relies on one particular order, but the orders aren't all equally
+// likely: counting them to choose fairly is impractical for large graphs.
+func (g *Graph[T]) SortShuffled(seed int64) ([]T, error) {
+	order, dependsOn := g.edges()
+	waiting := make(map[T]int, len(order))
 	dependedOnBy := make(map[T][]T)
-	// all values in the graph
-	allValues := make(map[T]bool)
+	var ready []T
+	for _, value := range order {
+		deps := dependsOn[value]
+		for _, dep := range deps {
+			dependedOnBy[dep] = append(dependedOnBy[dep], value)
+		}
+		waiting[value] = len(deps)
+		if len(deps) == 0 {
+			ready = append(ready, value)
+		}
+	}
+
+	r := rand.New(rand.NewPCG(uint64(seed), 0))
+	result := make([]T, 0, len(order))
+	for len(ready) > 0 {
+		i := r.IntN(len(ready))
+		value := ready[i]
+		ready[i] = ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		result = append(result, value)
+		for _, dependent := range dependedOnBy[value] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(result) < len(order) {
+		return nil, ErrCyclicDependency
+	}
+	return result, nil
+}
+
+// Comparator returns a function for ordering values consistently with the
+// graph, for use with slices.SortFunc and the like. It compares values by
+// the layer SortByLayers puts them in, so dependencies sort before their
+// dependents, and values in the same layer compare as equal, keeping their
+// order under a stable sort. Values not in the graph sort after all others.
+//
+// The comparison is computed once, when Comparator is called, so later
+// changes to the graph don't affect it.
+func (g *Graph[T]) Comparator() (func(a, b T) int, error) {
+	layers, err := g.SortByLayers()
+	if err != nil {
+		return nil, err
+	}
+	layer := make(map[T]int)
+	for i, values := range layers {
+		for _, value := range values {
+			layer[value] = i
+		}
+	}
+	index := func(value T) int {
+		if i, ok := layer[value]; ok {
+			return i
+		}
+		return len(layers)
+	}
+	return func(a, b T) int {
+		return index(a) - index(b)
+	}, nil
+}
+
+// Before reports whether a must come before b: that is, whether b depends
+// on a, directly or transitively. Values in a cycle must each come before
+// the others.
+func (g *Graph[T]) Before(a, b T) bool {
+	return slices.Contains(g.Ancestors(b), a)
+}
+
+// positions maps each value to its index in Nodes().
+func (g *Graph[T]) positions() map[T]int {
+	order, _ := g.edges()
+	position := make(map[T]int, len(order))
+	for i, value := range order {
+		position[value] = i
+	}
+	return position
+}
+
+// positions is a min-heap of indexes into a graph's values.
+type positions []int
+
+func (h positions) Len() int           { return len(h) }
+func (h positions) Less(i, j int) bool { return h[i] < h[j] }
+func (h positions) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *positions) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *positions) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Reverse returns a new graph with every dependency inverted, so that each
+// node depends on the nodes that depended on it in the original graph.
+// Sorting the reversed graph gives an order for tearing things down.
+func (g *Graph[T]) Reverse() *Graph[T] {
+	order, _ := g.edges()
+	byValue, kinds := g.declarationsByValue()
+	dependedOnBy := make(map[kindKey[T]][]T)
+	for _, value := range order {
+		for _, decl := range byValue[value] {
+			for _, dep := range decl.deps {
+				key := kindKey[T]{dep, decl.kind}
+				dependedOnBy[key] = append(dependedOnBy[key], value)
+			}
+		}
+	}
+
+	var r Graph[T]
+	for _, value := range order {
+		r.addKinds(value, kinds, dependedOnBy)
+	}
+	return &r
+}
+
+// Map returns a new graph with every value replaced by f(value), keeping the
+// dependencies between them. Values that f maps to the same result are
+// merged into one node, with the dependencies of all of them.
+func Map[T, U comparable](g *Graph[T], f func(T) U) *Graph[U] {
+	order, _ := g.edges()
+	byValue, kinds := g.declarationsByValue()
+	var mapped []U
+	seen := make(map[U]bool)
+	deps := make(map[kindKey[U]][]U)
+	for _, value := range order {
+		m := f(value)
+		if !seen[m] {
+			seen[m] = true
+			mapped = append(mapped, m)
+		}
+		for _, decl := range byValue[value] {
+			key := kindKey[U]{m, decl.kind}
+			deps[key] = deps[key] // declared, even without dependencies
+			for _, dep := range decl.deps {
+				if d := f(dep); !slices.Contains(deps[key], d) {
+					deps[key] = append(deps[key], d)
+				}
+			}
+		}
+	}
+
+	var r Graph[U]
+	for _, m := range mapped {
+		r.addKinds(m, kinds, deps)
+	}
+	return &r
+}
+
+// edges returns every value in the graph, including those that only appear
+// as dependencies, in order of first appearance. It also returns the
+// dependencies of each node, as returned by Dependencies. They're cached,
+// so they must not be changed.
+func (g *Graph[T]) edges() ([]T, map[T][]T) {
+	a := g.analysis()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.dependsOn == nil {
+		a.order, a.dependsOn, a.index = g.findEdges()
+	}
+	return a.order, a.dependsOn
+}
+
+// interned returns every value in the graph, as edges does, along with the
+// dependencies and dependents of each by position, so that sorting can use
+// slices and bitsets rather than maps. They're cached, as with edges.
+func (g *Graph[T]) interned() (order []T, deps, dependents [][]int32) {
+	order, dependsOn := g.edges()
+	a := g.analysis()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.deps == nil {
+		a.deps, a.dependents = intern(order, dependsOn, a.index)
+	}
+	return order, a.deps, a.dependents
+}
+
+// findEdges computes what edges returns, and the position of each value.
+func (g *Graph[T]) findEdges() ([]T, map[T][]T, map[T]int32) {
+	var order []T
+	index := make(map[T]int32)
+	visit := func(value T) {
+		if _, seen := index[value]; !seen {
+			index[value] = int32(len(order))
+			order = append(order, value)
+		}
+	}
 	for _, node := range g.nodes {
-		allValues[node.value] = true
-		dependsOn[node.value] = node.deps
+		visit(node.value)
 		for _, dep := range node.deps {
-			allValues[dep] = true
-			dependedOnBy[dep] = append(dependedOnBy[dep], node.value)
+			visit(dep)
 		}
 	}
 
-	// find nodes with no dependencies;
-	// these form the first layer
-	var currentLayer []T
-	for value := range allValues {
-		deps, exists := dependsOn[value]
-		if !exists || len(deps) == 0 {
-			currentLayer = append(currentLayer, value)
+	dependsOn := make(map[T][]T)
+	for _, decl := range g.declarations() {
+		if deps, ok := dependsOn[decl.value]; ok {
+			dependsOn[decl.value] = mergeDeps(deps, decl.deps)
+		} else {
+			dependsOn[decl.value] = decl.deps
 		}
 	}
+	return order, dependsOn, index
+}
+
+// Cycles returns the groups of nodes that depend on each other in a cycle.
+// These are the strongly connected components of the graph that contain
+// more than one node, along with any node that depends on itself.
+//
+// Within each group, nodes are in the order they were first added, and
+// groups are ordered by their first node. A graph without cycles returns
+// nil.
+func (g *Graph[T]) Cycles() [][]T {
+	order, dependsOn := g.edges()
+	position := make(map[T]int, len(order))
+	for i, value := range order {
+		position[value] = i
+	}
 
-	// process the graph layer by layer
-	var result [][]T
-	visited := make(map[T]bool)
-	for len(currentLayer) > 0 {
-		// invariant: current layer is finalized
-		result = append(result, currentLayer)
+	// Tarjan's algorithm
+	index := make(map[T]int, len(order))
+	lowlink := make(map[T]int, len(order))
+	onStack := make(map[T]bool)
+	var stack []T
+	var cycles [][]T
+	var strongConnect func(value T)
+	strongConnect = func(value T) {
+		index[value] = len(index)
+		lowlink[value] = index[value]
+		stack = append(stack, value)
+		onStack[value] = true
 
-		// mark these nodes as visited
-		for _, value := range currentLayer {
-			visited[value] = true
+		selfLoop := false
+		for _, dep := range dependsOn[value] {
+			if dep == value {
+				selfLoop = true
+			}
+			if _, visited := index[dep]; !visited {
+				strongConnect(dep)
+				lowlink[value] = min(lowlink[value], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[value] = min(lowlink[value], index[dep])
+			}
 		}
 
-		// find the next layer - nodes that depend on the current layer nodes
-		// and have all their dependencies resolved
-		var nextLayer []T
-		layerDepMap := make(map[T]bool) // To avoid duplicates in the next layer
+		if lowlink[value] != index[value] {
+			return
+		}
+		var component []T
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == value {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			slices.SortFunc(component, func(a, b T) int {
+				return position[a] - position[b]
+			})
+			cycles = append(cycles, component)
+		}
+	}
 
-		for _, value := range currentLayer {
-			// find nodes that depend on this one
-			for _, dependent := range dependedOnBy[value] {
-				// skip if already processed or in next layer
-				if visited[dependent] || layerDepMap[dependent] {
-					continue
-				}
+	for _, value := range order {
+		if _, visited := index[value]; !visited {
+			strongConnect(value)
+		}
+	}
+	slices.SortFunc(cycles, func(a, b []T) int {
+		return position[a[0]] - position[b[0]]
+	})
+	return cycles
+}
 
-				// check if all dependencies of this dependent node are visited
-				allDepsVisited := true
-				for _, dep := range dependsOn[dependent] {
-					if !visited[dep] {
-						allDepsVisited = false
-						break
-					}
-				}
+// Ancestors returns every value that any of the given values depends on,
+// directly or transitively. The given values themselves are not included.
+// Values are returned in the order they were first added to the graph.
+func (g *Graph[T]) Ancestors(values ...T) []T {
+	find := func() []T {
+		order, dependsOn := g.edges()
+		return reachable(order, dependsOn, values)
+	}
+	if len(values) == 1 {
+		return g.analysis().ancestorsOf(values[0], find)
+	}
+	return find()
+}
+
+// Descendants returns every value that depends on any of the given values,
+// directly or transitively. The given values themselves are not included.
+// Values are returned in the order they were first added to the graph.
+func (g *Graph[T]) Descendants(values ...T) []T {
+	order, dependsOn := g.edges()
+	dependedOnBy := make(map[T][]T)
+	for _, value := range order {
+		for _, dep := range dependsOn[value] {
+			dependedOnBy[dep] = append(dependedOnBy[dep], value)
+		}
+	}
+	return reachable(order, dependedOnBy, values)
+}
+
+// Isolated returns the values with no edges at all: nodes without
+// dependencies that nothing depends on. These are often dead entries in
+// configuration. Values are returned in the order they were first added.
+func (g *Graph[T]) Isolated() []T {
+	order, dependsOn := g.edges()
+	hasDependents := make(map[T]bool)
+	for _, value := range order {
+		for _, dep := range dependsOn[value] {
+			hasDependents[dep] = true
+		}
+	}
 
-				// if all dependencies are satisfied, add to next layer
-				if allDepsVisited {
-					nextLayer = append(nextLayer, dependent)
-					layerDepMap[dependent] = true
+	var isolated []T
+	for _, value := range order {
+		if len(dependsOn[value]) == 0 && !hasDependents[value] {
+			isolated = append(isolated, value)
+		}
+	}
+	return isolated
+}
+
+// Roots returns the values nothing depends on, like the targets of a build
+// or the services at the top of a stack, in the order they were first
+// added.
+func (g *Graph[T]) Roots() []T {
+	order, dependsOn := g.edges()
+	hasDependents := make(map[T]bool)
+	for _, value := range order {
+		for _, dep := range dependsOn[value] {
+			hasDependents[dep] = true
+		}
+	}
+
+	var roots []T
+	for _, value := range order {
+		if !hasDependents[value] {
+			roots = append(roots, value)
+		}
+	}
+	return roots
+}
+
+// Unreachable returns the values that none of the given roots depend on,
+// directly or transitively, and that aren't roots themselves. With the
+// entry points of a system as roots, these are the entries nothing uses.
+// Values are returned in the order they were first added.
+func (g *Graph[T]) Unreachable(roots ...T) []T {
+	order, dependsOn := g.edges()
+	used := make(map[T]bool)
+	for _, value := range append(reachable(order, dependsOn, roots), roots...) {
+		used[value] = true
+	}
+
+	var unreachable []T
+	for _, value := range order {
+		if !used[value] {
+			unreachable = append(unreachable, value)
+		}
+	}
+	return unreachable
+}
+
+// PruneTo returns a new graph with only the given targets and everything
+// they depend on, directly or transitively: what has to be processed to
+// produce the targets. Targets that aren't in the graph are ignored.
+func (g *Graph[T]) PruneTo(targets []T) *Graph[T] {
+	order, dependsOn := g.edges()
+	keep := append(reachable(order, dependsOn, targets), targets...)
+	return g.subgraph(order, keep)
+}
+
+// PruneFrom returns a new graph with only the given roots and everything
+// that depends on them, directly or transitively: what has to be processed
+// again when the roots change. Dependencies on nodes outside of that set
+// are dropped. Roots that aren't in the graph are ignored.
+func (g *Graph[T]) PruneFrom(roots []T) *Graph[T] {
+	keep := append(g.Descendants(roots...), roots...)
+	order, _ := g.edges()
+	return g.subgraph(order, keep)
+}
+
+// Expand returns a new graph with a node replaced by a subgraph. The roots
+// of the subgraph, which depend on nothing within it, take on the node's
+// dependencies, and the nodes that depended on the node depend on the
+// leaves of the subgraph instead, which nothing within it depends on. This
+// composes high-level plans from reusable fragments:
+//
+//	// "deploy" becomes "push" followed by "migrate" and "restart"
+//	var deploy topo.Graph[string]
+//	deploy.AddNode("push", nil)
+//	deploy.AddNode("migrate", []string{"push"})
+//	deploy.AddNode("restart", []string{"push"})
+//	plan = plan.Expand("deploy", &deploy)
+//
+// Expanding into an empty subgraph removes the node, with its dependents
+// depending on its dependencies directly. The subgraph's values should be
+// distinct from the rest of the graph's. If the node isn't in the graph, an
+// unchanged copy is returned.
+func (g *Graph[T]) Expand(value T, sub *Graph[T]) *Graph[T] {
+	order, dependsOn := g.edges()
+	subOrder, subDependsOn := sub.edges()
+
+	isLeaf := make(map[T]bool, len(subOrder))
+	for _, s := range subOrder {
+		isLeaf[s] = true
+	}
+	for _, s := range subOrder {
+		for _, dep := range subDependsOn[s] {
+			isLeaf[dep] = false
+		}
+	}
+	var leaves []T
+	for _, s := range subOrder {
+		if isLeaf[s] {
+			leaves = append(leaves, s)
+		}
+	}
+	if len(subOrder) == 0 {
+		leaves = dependsOn[value]
+	}
+
+	var e Graph[T]
+	for _, v := range order {
+		if v == value {
+			for _, s := range subOrder {
+				deps := subDependsOn[s]
+				if len(deps) == 0 {
+					deps = slices.Clone(dependsOn[value])
 				}
+				e.AddNode(s, deps)
 			}
+			continue
 		}
+		var deps []T
+		for _, dep := range dependsOn[v] {
+			replacement := []T{dep}
+			if dep == value {
+				replacement = leaves
+			}
+			for _, r := range replacement {
+				if !slices.Contains(deps, r) {
+					deps = append(deps, r)
+				}
+			}
+		}
+		e.AddNode(v, deps)
+	}
+	return &e
+}
 
-		currentLayer = nextLayer
+// subgraph returns a new graph with only the values in keep, and only the
+// dependencies between them. Nodes are added in the same order as in g.
+func (g *Graph[T]) subgraph(order []T, keep []T) *Graph[T] {
+	kept := make(map[T]bool, len(keep))
+	for _, value := range keep {
+		kept[value] = true
 	}
+	byValue, _ := g.declarationsByValue()
+	var s Graph[T]
+	for _, value := range order {
+		if !kept[value] {
+			continue
+		}
+		if len(byValue[value]) == 0 {
+			s.AddNode(value, nil)
+		}
+		for _, decl := range byValue[value] {
+			var deps []T
+			for _, dep := range decl.deps {
+				if kept[dep] {
+					deps = append(deps, dep)
+				}
+			}
+			s.AddNodeOfKind(value, decl.kind, deps)
+		}
+		if pin, ok := g.pins[value]; ok {
+			s.pin(value, pin)
+		}
+	}
+	s.attrs = cloneAttrs(g.attrs, func(value T) bool { return kept[value] })
+	return &s
+}
 
-	// check if all nodes were visited
-	for value := range allValues {
-		if !visited[value] {
-			// if this is a node in our original graph (not just a dependency)
-			if _, exists := dependsOn[value]; exists && len(dependsOn[value]) > 0 {
-				return nil, ErrCyclicDependency
+// reachable returns the values reachable from start by following next,
+// excluding start itself, in the same order as order.
+func reachable[T comparable](order []T, next map[T][]T, start []T) []T {
+	seen := make(map[T]bool)
+	queue := slices.Clone(start)
+	for len(queue) > 0 {
+		value := queue[0]
+		queue = queue[1:]
+		for _, n := range next[value] {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
 			}
 		}
 	}
+	for _, value := range start {
+		delete(seen, value)
+	}
 
-	return result, nil
+	var result []T
+	for _, value := range order {
+		if seen[value] {
+			result = append(result, value)
+		}
+	}
+	return result
 }
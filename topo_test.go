@@ -2,8 +2,12 @@ package topo_test
 
 import (
 	"errors"
+	"fmt"
+	"math/rand/v2"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/sam-fredrickson/go-topo"
@@ -108,3 +112,752 @@ func TestSortByLayers(t *testing.T) {
 		})
 	}
 }
+
+// TestReverse checks that reversing a graph inverts its layers.
+func TestReverse(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("A", []string{})
+	g.AddNode("B", []string{"A"})
+	g.AddNode("C", []string{"A", "B"})
+	g.AddNode("D", []string{"B"})
+
+	result, err := g.Reverse().SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range result {
+		sort.Strings(result[i])
+	}
+
+	expected := [][]string{{"C", "D"}, {"B"}, {"A"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// reversing twice gives back the original layering
+	result, err = g.Reverse().Reverse().SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range result {
+		sort.Strings(result[i])
+	}
+	expected = [][]string{{"A"}, {"B"}, {"C", "D"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// TestNodes checks the node and dependency accessors.
+func TestNodes(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("B", []string{"A"})
+	g.AddNode("C", []string{"B", "D"})
+	g.AddNode("B", []string{})
+
+	expected := []string{"B", "A", "C", "D"}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Expected nodes %v, got %v", expected, nodes)
+	}
+	if deps := g.Dependencies("C"); !reflect.DeepEqual(deps, []string{"B", "D"}) {
+		t.Errorf("Expected dependencies [B D], got %v", deps)
+	}
+	if deps := g.Dependencies("B"); len(deps) != 0 {
+		t.Errorf("Expected last AddNode to win, got %v", deps)
+	}
+	if deps := g.Dependencies("D"); deps != nil {
+		t.Errorf("Expected no dependencies, got %v", deps)
+	}
+}
+
+// TestCycles checks that cycles are reported as strongly connected
+// components.
+func TestCycles(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("A", []string{"B"})
+	g.AddNode("B", []string{"C"})
+	g.AddNode("C", []string{"A", "D"})
+	g.AddNode("D", []string{})
+	g.AddNode("E", []string{"E"})
+	g.AddNode("F", []string{"G"})
+	g.AddNode("G", []string{"F", "A"})
+
+	expected := [][]string{{"A", "B", "C"}, {"E"}, {"F", "G"}}
+	if cycles := g.Cycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("Expected %v, got %v", expected, cycles)
+	}
+
+	var acyclic topo.Graph[string]
+	acyclic.AddNode("A", []string{})
+	acyclic.AddNode("B", []string{"A"})
+	if cycles := acyclic.Cycles(); cycles != nil {
+		t.Errorf("Expected no cycles, got %v", cycles)
+	}
+}
+
+// TestAncestorsDescendants checks transitive dependency queries.
+func TestAncestorsDescendants(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("base", []string{})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("test", []string{"app", "tool"})
+
+	tests := []struct {
+		name     string
+		got      []string
+		expected []string
+	}{
+		{"ancestors of test", g.Ancestors("test"), []string{"base", "lib", "app", "tool"}},
+		{"ancestors of app and tool", g.Ancestors("app", "tool"), []string{"base", "lib"}},
+		{"ancestors of base", g.Ancestors("base"), nil},
+		{"descendants of lib", g.Descendants("lib"), []string{"app", "test"}},
+		{"descendants of base", g.Descendants("base"), []string{"lib", "app", "tool", "test"}},
+		{"descendants of unknown", g.Descendants("unknown"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+		})
+	}
+}
+
+// TestPrune checks keeping only the nodes needed for targets, or affected
+// by roots.
+func TestPrune(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("base", []string{})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("app", []string{"lib", "config"})
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("test", []string{"app", "tool"})
+
+	tests := []struct {
+		name     string
+		pruned   *topo.Graph[string]
+		expected map[string][]string
+	}{
+		{
+			name:   "to app",
+			pruned: g.PruneTo([]string{"app"}),
+			expected: map[string][]string{
+				"base": nil, "lib": {"base"}, "app": {"lib", "config"}, "config": nil,
+			},
+		},
+		{
+			name:   "to tool and unknown",
+			pruned: g.PruneTo([]string{"tool", "unknown"}),
+			expected: map[string][]string{
+				"base": nil, "tool": {"base"},
+			},
+		},
+		{
+			name:   "from lib",
+			pruned: g.PruneFrom([]string{"lib"}),
+			expected: map[string][]string{
+				"lib": nil, "app": {"lib"}, "test": {"app"},
+			},
+		},
+		{
+			name:     "from nothing",
+			pruned:   g.PruneFrom(nil),
+			expected: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := make(map[string][]string)
+			for _, value := range tt.pruned.Nodes() {
+				actual[value] = tt.pruned.Dependencies(value)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// TestMap checks mapping values, merging those that map to the same value.
+func TestMap(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("App", []string{"lib", "LIB"})
+	g.AddNode("lib", nil)
+
+	m := topo.Map(&g, strings.ToLower)
+	if nodes := m.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib"}) {
+		t.Errorf("Expected nodes [app lib], got %v", nodes)
+	}
+	if deps := m.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib"}) {
+		t.Errorf("Expected app to depend on [lib], got %v", deps)
+	}
+}
+
+// TestAddDependency checks adding single edges without losing existing
+// ones.
+func TestAddDependency(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(1, []int{2})
+	g.AddDependency(1, 3)
+	g.AddDependency(1, 3)
+	g.AddDependency(4, 1)
+
+	if deps := g.Dependencies(1); !reflect.DeepEqual(deps, []int{2, 3}) {
+		t.Errorf("Expected 1 to depend on [2 3], got %v", deps)
+	}
+	if deps := g.Dependencies(4); !reflect.DeepEqual(deps, []int{1}) {
+		t.Errorf("Expected 4 to depend on [1], got %v", deps)
+	}
+}
+
+// TestAddDependencyValidate checks that adding dependencies one at a time
+// extends a node's declaration rather than declaring it again.
+func TestAddDependencyValidate(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", nil)
+	g.AddNode("c", nil)
+	g.AddNode("e", nil)
+	g.EnableHistory(0)
+	g.AddNode("a", []string{"b"})
+	g.AddDependency("a", "c")
+	g.AddDependency("d", "a")
+	g.AddDependency("d", "e")
+	if issues := g.Validate(); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+	if deps := g.Dependencies("d"); !reflect.DeepEqual(deps, []string{"a", "e"}) {
+		t.Errorf("Expected d to depend on [a e], got %v", deps)
+	}
+	if !g.Undo() {
+		t.Fatal("Expected a change to undo")
+	}
+	if deps := g.Dependencies("d"); !reflect.DeepEqual(deps, []string{"a"}) {
+		t.Errorf("Expected d to depend on [a] after undo, got %v", deps)
+	}
+	if !g.Undo() || !g.Undo() {
+		t.Fatal("Expected changes to undo")
+	}
+	if deps := g.Dependencies("a"); !reflect.DeepEqual(deps, []string{"b"}) {
+		t.Errorf("Expected a to depend on [b] after undo, got %v", deps)
+	}
+}
+
+// TestDependenciesCopy checks that changing the dependencies returned
+// doesn't change the graph.
+func TestDependenciesCopy(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(1, []int{2, 3})
+	g.Dependencies(1)[0] = 4
+	if deps := g.Dependencies(1); !reflect.DeepEqual(deps, []int{2, 3}) {
+		t.Errorf("Expected 1 to depend on [2 3], got %v", deps)
+	}
+}
+
+// TestExpand checks splicing subgraphs in place of nodes.
+func TestExpand(t *testing.T) {
+	var plan topo.Graph[string]
+	plan.AddNode("build", nil)
+	plan.AddNode("deploy", []string{"build"})
+	plan.AddNode("verify", []string{"deploy"})
+
+	var deploy topo.Graph[string]
+	deploy.AddNode("push", nil)
+	deploy.AddNode("migrate", []string{"push"})
+	deploy.AddNode("restart", []string{"push"})
+
+	tests := []struct {
+		name     string
+		expanded *topo.Graph[string]
+		expected map[string][]string
+	}{
+		{
+			name:     "subgraph",
+			expanded: plan.Expand("deploy", &deploy),
+			expected: map[string][]string{
+				"build":   nil,
+				"push":    {"build"},
+				"migrate": {"push"},
+				"restart": {"push"},
+				"verify":  {"migrate", "restart"},
+			},
+		},
+		{
+			name:     "empty subgraph",
+			expanded: plan.Expand("deploy", &topo.Graph[string]{}),
+			expected: map[string][]string{
+				"build":  nil,
+				"verify": {"build"},
+			},
+		},
+		{
+			name:     "unknown node",
+			expanded: plan.Expand("unknown", &deploy),
+			expected: map[string][]string{
+				"build":  nil,
+				"deploy": {"build"},
+				"verify": {"deploy"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := make(map[string][]string)
+			for _, value := range tt.expanded.Nodes() {
+				actual[value] = tt.expanded.Dependencies(value)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// TestIsolatedUnreachable checks finding dead entries.
+func TestIsolatedUnreachable(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"base"})
+	g.AddNode("legacy", nil)
+	g.AddNode("self", []string{"self"})
+
+	tests := []struct {
+		name     string
+		got      []string
+		expected []string
+	}{
+		{"isolated", g.Isolated(), []string{"legacy"}},
+		{"roots", g.Roots(), []string{"app", "tool", "legacy"}},
+		{"unreachable from app", g.Unreachable("app"), []string{"tool", "legacy", "self"}},
+		{"unreachable from app and tool", g.Unreachable("app", "tool"), []string{"legacy", "self"}},
+		{"unreachable from nothing", g.Unreachable(), g.Nodes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+		})
+	}
+}
+
+// TestSortByLayersFunc checks ordering nodes within layers.
+func TestSortByLayersFunc(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", nil)
+	g.AddNode("c", nil)
+	g.AddNode("a", nil)
+	g.AddNode("z", []string{"a", "b"})
+	g.AddNode("y", []string{"c"})
+
+	priority := map[string]int{"c": 1, "y": 2}
+	tests := []struct {
+		name     string
+		cmp      func(a, b string) int
+		expected [][]string
+	}{
+		{"by name", strings.Compare, [][]string{{"a", "b", "c"}, {"y", "z"}}},
+		{
+			"by priority, highest first",
+			func(a, b string) int { return priority[b] - priority[a] },
+			[][]string{{"c", "b", "a"}, {"y", "z"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers, err := g.SortByLayersFunc(tt.cmp)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(layers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, layers)
+			}
+		})
+	}
+
+	g.AddNode("a", []string{"z"})
+	if _, err := g.SortByLayersFunc(strings.Compare); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestSortedLayers checks sorting the values of each layer in ascending
+// order.
+func TestSortedLayers(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(3, nil)
+	g.AddNode(1, nil)
+	g.AddNode(10, []int{3, 1})
+	g.AddNode(2, []int{1})
+
+	layers, err := topo.SortedLayers(&g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]int{{1, 3}, {2, 10}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+
+	g.AddNode(1, []int{10})
+	if _, err := topo.SortedLayers(&g); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestSortStable checks that sorting keeps the order nodes were added in
+// wherever dependencies allow.
+func TestSortStable(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(g *topo.Graph[string])
+		expected []string
+	}{
+		{
+			name: "no dependencies",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("c", nil)
+				g.AddNode("a", nil)
+				g.AddNode("b", nil)
+			},
+			expected: []string{"c", "a", "b"},
+		},
+		{
+			name: "dependencies added later",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("app", []string{"lib"})
+				g.AddNode("docs", nil)
+				g.AddNode("lib", []string{"base"})
+				g.AddNode("base", nil)
+			},
+			expected: []string{"docs", "base", "lib", "app"},
+		},
+		{
+			name: "dependency listed before its declaration",
+			build: func(g *topo.Graph[string]) {
+				g.AddNode("x", []string{"z"})
+				g.AddNode("y", nil)
+				g.AddNode("z", nil)
+				g.AddNode("w", nil)
+			},
+			expected: []string{"z", "x", "y", "w"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			tt.build(&g)
+			order, err := g.SortStable()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(order, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, order)
+			}
+		})
+	}
+
+	var g topo.Graph[string]
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+	g.AddNode("c", nil)
+	if _, err := g.SortStable(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestSortByLayersStable checks that layers keep the order nodes were added in.
+func TestSortByLayersStable(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("web", []string{"db", "cache"})
+	g.AddNode("worker", []string{"queue"})
+	g.AddNode("queue", nil)
+	g.AddNode("db", nil)
+	g.AddNode("cache", nil)
+
+	layers, err := g.SortByLayersStable()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"db", "cache", "queue"}, {"web", "worker"}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected %v, got %v", expected, layers)
+	}
+}
+
+// TestSortShuffled checks that shuffled orders are valid, reproducible, and
+// varied.
+func TestSortShuffled(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("db", nil)
+	g.AddNode("base", nil)
+	g.AddNode("docs", nil)
+
+	seen := make(map[string]bool)
+	for seed := range int64(50) {
+		order, err := g.SortShuffled(seed)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(order) != 5 {
+			t.Fatalf("Expected 5 values, got %v", order)
+		}
+		for i, value := range order {
+			for _, dep := range g.Dependencies(value) {
+				if !slices.Contains(order[:i], dep) {
+					t.Errorf("Expected %s before %s, got %v", dep, value, order)
+				}
+			}
+		}
+		again, _ := g.SortShuffled(seed)
+		if !reflect.DeepEqual(order, again) {
+			t.Errorf("Expected seed %d to give %v again, got %v", seed, order, again)
+		}
+		seen[strings.Join(order, " ")] = true
+	}
+	if len(seen) < 5 {
+		t.Errorf("Expected varied orders, got %d distinct", len(seen))
+	}
+
+	g.AddNode("base", []string{"app"})
+	if _, err := g.SortShuffled(1); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestComparator checks sorting external slices in dependency order.
+func TestComparator(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("docs", nil)
+
+	cmp, err := g.Comparator()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	values := []string{"unknown", "app", "docs", "lib", "base", "db"}
+	slices.SortStableFunc(values, cmp)
+	expected := []string{"docs", "base", "db", "lib", "app", "unknown"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+
+	g.AddNode("base", []string{"app"})
+	if _, err := g.Comparator(); !errors.Is(err, topo.ErrCyclicDependency) {
+		t.Errorf("Expected %v, got %v", topo.ErrCyclicDependency, err)
+	}
+}
+
+// TestBefore checks transitive ordering between pairs of values.
+func TestBefore(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("docs", nil)
+	g.AddNode("x", []string{"y"})
+	g.AddNode("y", []string{"x"})
+
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"base", "app", true},
+		{"lib", "app", true},
+		{"app", "base", false},
+		{"docs", "app", false},
+		{"app", "app", false},
+		{"x", "y", true},
+		{"y", "x", true},
+	}
+	for _, tt := range tests {
+		if got := g.Before(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Expected Before(%s, %s) to be %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+// TestForEachLayer checks visiting layers and stopping early.
+func TestForEachLayer(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", []string{"base"})
+
+	var visited [][]string
+	err := g.ForEachLayer(func(i int, layer []string) error {
+		if i != len(visited) {
+			t.Errorf("Expected index %d, got %d", len(visited), i)
+		}
+		visited = append(visited, layer)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]string{{"base"}, {"lib"}, {"app"}}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+
+	stop := errors.New("stop")
+	visited = nil
+	err = g.ForEachLayer(func(i int, layer []string) error {
+		visited = append(visited, layer)
+		if i == 1 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected %v, got %v", stop, err)
+	}
+	if len(visited) != 2 {
+		t.Errorf("Expected 2 layers visited, got %v", visited)
+	}
+
+	g.AddNode("base", []string{"app"})
+	called := false
+	err = g.ForEachLayer(func(int, []string) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, topo.ErrCyclicDependency) || called {
+		t.Errorf("Expected %v without calls, got %v (called: %v)", topo.ErrCyclicDependency, err, called)
+	}
+}
+
+// TestFlatten runs some basic test cases.
+func TestFlatten(t *testing.T) {
+	layers := [][]string{{"c", "a", "b"}, {"z"}, {}, {"y", "x"}}
+	tests := []struct {
+		name     string
+		less     func(a, b string) bool
+		expected []string
+	}{
+		{"layer order", nil, []string{"c", "a", "b", "z", "y", "x"}},
+		{"by name", func(a, b string) bool { return a < b }, []string{"a", "b", "c", "z", "x", "y"}},
+		{"ties kept", func(a, b string) bool { return false }, []string{"c", "a", "b", "z", "y", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flat := topo.Flatten(layers, tt.less)
+			if !reflect.DeepEqual(flat, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, flat)
+			}
+		})
+	}
+	if layers[0][0] != "c" {
+		t.Errorf("Expected layers to be unchanged, got %v", layers)
+	}
+	if flat := topo.Flatten[string](nil, nil); flat != nil {
+		t.Errorf("Expected nil, got %v", flat)
+	}
+}
+
+// TestRemove checks removing nodes and dependencies.
+func TestRemove(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNodeOfKind("app", "runtime", []string{"cache"})
+	g.AddNode("lib", []string{"base"})
+	g.PinLayer("lib", 1)
+
+	g.RemoveDependency("app", "cache")
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected [lib db], got %v", deps)
+	}
+	if slices.Contains(g.Nodes(), "cache") {
+		t.Errorf("Expected cache to be gone, got %v", g.Nodes())
+	}
+
+	g.RemoveNode("lib")
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"db"}) {
+		t.Errorf("Expected [db], got %v", deps)
+	}
+	expected := []string{"app", "db"}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Expected %v, got %v", expected, nodes)
+	}
+
+	// the pin went with the node
+	g.AddNode("lib", nil)
+	layers, err := g.SortByLayers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedLayers := [][]string{{"db", "lib"}, {"app"}}
+	if !reflect.DeepEqual(layers, expectedLayers) {
+		t.Errorf("Expected %v, got %v", expectedLayers, layers)
+	}
+}
+
+// denseGraph returns a graph of n nodes, each depending on up to deps
+// earlier ones chosen at random.
+func denseGraph(n, deps int) *topo.Graph[int] {
+	r := rand.New(rand.NewPCG(1, 2))
+	var g topo.Graph[int]
+	for i := range n {
+		var d []int
+		for range min(i, deps) {
+			d = append(d, r.IntN(i))
+		}
+		g.AddNode(i, d)
+	}
+	return &g
+}
+
+func BenchmarkSortByLayers(b *testing.B) {
+	for _, n := range []int{1_000, 100_000} {
+		g := denseGraph(n, 4)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for b.Loop() {
+				// a copy, so the sort isn't cached
+				b.StopTimer()
+				c := g.Clone()
+				b.StartTimer()
+				if _, err := c.SortByLayers(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("%d cached", n), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := g.SortByLayers(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSortStable(b *testing.B) {
+	g := denseGraph(100_000, 4)
+	for b.Loop() {
+		if _, err := g.SortStable(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAncestors(b *testing.B) {
+	g := denseGraph(100_000, 4)
+	for b.Loop() {
+		b.StopTimer()
+		c := g.Clone()
+		b.StartTimer()
+		c.Ancestors(99_999)
+	}
+}
+
+func BenchmarkDependencies(b *testing.B) {
+	g := denseGraph(100_000, 4)
+	for b.Loop() {
+		for i := range 100_000 {
+			g.Dependencies(i)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package topotest provides helpers for tests of code that builds graphs.
+package topotest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// UpdateEnv is the environment variable that makes Golden write golden
+// files instead of comparing against them, when set to a non-empty value:
+//
+//	TOPOTEST_UPDATE=1 go test ./...
+const UpdateEnv = "TOPOTEST_UPDATE"
+
+// Golden compares a graph against the golden file at path, failing the test
+// with a line-by-line diff if they differ. The graph is written as
+// EncodeCanonical writes it, followed by its layers, one per line with
+// their values sorted, or by its cycles if it can't be sorted:
+//
+//	app
+//	app -> lib
+//	lib
+//
+//	# layers
+//	1: lib
+//	2: app
+//
+// so a golden file is the same however the graph was built, and a change
+// to the structure shows up as the lines added and removed. Running the
+// tests with UpdateEnv set writes the golden files instead.
+func Golden[T comparable](t testing.TB, g *topo.Graph[T], path string) {
+	t.Helper()
+	actual, err := Snapshot(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o600); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Golden file %s doesn't exist; run the test with %s=1 to write it", path, UpdateEnv)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Graph differs from golden file %s (-expected +actual):\n%s", path, diff(string(expected), string(actual)))
+	}
+}
+
+// Snapshot returns the text Golden compares against golden files.
+func Snapshot[T comparable](g *topo.Graph[T]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := g.EncodeCanonical(&buf); err != nil {
+		return nil, err
+	}
+	layers, err := g.SortByLayers()
+	if err != nil {
+		buf.WriteString("\n# cycles\n")
+		for _, cycle := range sortedText(g.Cycles()) {
+			fmt.Fprintln(&buf, strings.Join(cycle, " "))
+		}
+		return buf.Bytes(), nil
+	}
+	buf.WriteString("\n# layers\n")
+	for i, layer := range layerText(layers) {
+		fmt.Fprintf(&buf, "%d: %s\n", i+1, strings.Join(layer, " "))
+	}
+	return buf.Bytes(), nil
+}
+
+// layerText returns layers as text, with each layer sorted. Each layer is
+// written by EncodeCanonical, as a graph of its values alone, so values are
+// quoted as they are in the rest of the snapshot.
+func layerText[T comparable](layers [][]T) [][]string {
+	text := make([][]string, len(layers))
+	for i, layer := range layers {
+		var g topo.Graph[T]
+		for _, value := range layer {
+			g.AddNode(value, nil)
+		}
+		var b strings.Builder
+		_ = g.EncodeCanonical(&b) // writing to a strings.Builder can't fail
+		text[i] = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	}
+	return text
+}
+
+// sortedText returns groups of values as text, with each group and the
+// groups themselves sorted, so that their order doesn't depend on how the
+// graph was built.
+func sortedText[T comparable](groups [][]T) [][]string {
+	text := layerText(groups)
+	slices.SortFunc(text, slices.Compare)
+	return text
+}
+
+// diff returns the lines of two texts that differ, with "-" before lines
+// only in expected, "+" before lines only in actual, and lines in both
+// indented, from a longest common subsequence of their lines.
+func diff(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&sb, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			fmt.Fprintf(&sb, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package topotest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/topotest"
+)
+
+// recorder is a testing.TB that records failures instead of failing. Like
+// testing.T, Fatalf stops the goroutine that calls it.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+	runtime.Goexit()
+}
+
+// golden calls Golden with a recorder, returning its failures.
+func golden(g *topo.Graph[string], path string) *recorder {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		topotest.Golden(r, g, path)
+	}()
+	<-done
+	return r
+}
+
+func deployGraph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNodeOfKind("app", "runtime", []string{"db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("base", nil)
+	g.AddNode("db", nil)
+	return &g
+}
+
+// TestGolden checks comparing graphs against a golden file.
+func TestGolden(t *testing.T) {
+	if r := golden(deployGraph(), "testdata/deploy.golden"); len(r.errors) > 0 {
+		t.Errorf("Unexpected failures: %v", r.errors)
+	}
+
+	// the same graph built in another order
+	var g topo.Graph[string]
+	g.AddNode("db", nil)
+	g.AddNode("base", nil)
+	g.AddNode("lib", []string{"base"})
+	g.AddNodeOfKind("app", "runtime", []string{"db"})
+	g.AddNode("app", []string{"lib"})
+	if r := golden(&g, "testdata/deploy.golden"); len(r.errors) > 0 {
+		t.Errorf("Unexpected failures: %v", r.errors)
+	}
+
+	g.AddNode("lib", []string{"base", "cache"})
+	r := golden(&g, "testdata/deploy.golden")
+	if len(r.errors) != 1 {
+		t.Fatalf("Expected 1 failure, got %v", r.errors)
+	}
+	for _, line := range []string{"+ lib -> cache\n", "- 1: base db\n", "+ 1: base cache db\n", "  2: lib\n"} {
+		if !strings.Contains(r.errors[0], line) {
+			t.Errorf("Expected %q in diff, got:\n%s", line, r.errors[0])
+		}
+	}
+
+	r = golden(&g, filepath.Join(t.TempDir(), "missing.golden"))
+	if !r.fatal || !strings.Contains(r.errors[0], topotest.UpdateEnv) {
+		t.Errorf("Expected failure naming %s, got %v", topotest.UpdateEnv, r.errors)
+	}
+}
+
+// TestGoldenUpdate checks that golden files are written when asked.
+func TestGoldenUpdate(t *testing.T) {
+	t.Setenv(topotest.UpdateEnv, "1")
+	path := filepath.Join(t.TempDir(), "new", "deploy.golden")
+	if r := golden(deployGraph(), path); len(r.errors) > 0 {
+		t.Fatalf("Unexpected failures: %v", r.errors)
+	}
+	actual, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, err := os.ReadFile("testdata/deploy.golden")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+// TestSnapshot checks the text of graphs with cycles and unusual values.
+func TestSnapshot(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("b", []string{"a"})
+	g.AddNode("a", []string{"b"})
+	g.AddNode("my app", []string{"a"})
+
+	actual, err := topotest.Snapshot(&g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\"my app\"\n\"my app\" -> a\na\na -> b\nb\nb -> a\n\n# cycles\na b\n"
+	if string(actual) != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+// TestSnapshotLayers checks that values in layers are quoted as they are
+// in the rest of the snapshot.
+func TestSnapshotLayers(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("my app", []string{"lib", "(base)"})
+	g.AddNode("lib", []string{"(base)"})
+
+	actual, err := topotest.Snapshot(&g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\"(base)\"\n\"my app\"\n\"my app\" -> \"(base)\"\n\"my app\" -> lib\nlib\nlib -> \"(base)\"\n\n" +
+		"# layers\n1: \"(base)\"\n2: lib\n3: \"my app\"\n"
+	if string(actual) != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
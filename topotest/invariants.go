@@ -0,0 +1,124 @@
+package topotest
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// CheckInvariants checks properties that hold for every graph, failing the
+// test for each one that doesn't:
+//
+//   - each sort, layered or not, puts every value after its dependencies,
+//     as ValidateLayers checks, with every value exactly once
+//   - the sorts fail with ErrCyclicDependency exactly when Cycles finds
+//     cycles
+//   - Cycles finds the values that can reach each other, as a separate
+//     search of the graph's dependencies does
+//   - reversing the graph twice gives the graph back
+//
+// It's meant for fuzz targets, and property tests of code that builds
+// graphs, as a check that a graph is consistent whatever it holds:
+//
+//	f.Fuzz(func(t *testing.T, data []byte) {
+//		topotest.CheckInvariants(t, parse(data))
+//	})
+func CheckInvariants[T comparable](t testing.TB, g *topo.Graph[T]) {
+	t.Helper()
+	cycles := g.Cycles()
+	checkCycles(t, g, cycles)
+
+	sorts := []struct {
+		name string
+		sort func() ([][]T, error)
+	}{
+		{"SortByLayers", g.SortByLayers},
+		{"SortByLayersStable", g.SortByLayersStable},
+		{"Sorter.SortByLayers", g.Sorter().SortByLayers},
+		{"SortStable", func() ([][]T, error) { return flat(g.SortStable()) }},
+		{"SortShuffled", func() ([][]T, error) { return flat(g.SortShuffled(1)) }},
+	}
+	for _, s := range sorts {
+		layers, err := s.sort()
+		switch {
+		case len(cycles) > 0 && !errors.Is(err, topo.ErrCyclicDependency):
+			t.Errorf("%s: Expected error %v for cycles %v, got %v", s.name, topo.ErrCyclicDependency, cycles, err)
+		case len(cycles) == 0 && err != nil:
+			t.Errorf("%s: Unexpected error: %v", s.name, err)
+		case err == nil:
+			if err := g.ValidateLayers(layers); err != nil {
+				t.Errorf("%s: Invalid order %v: %v", s.name, layers, err)
+			}
+		}
+	}
+
+	expected, err := Snapshot(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	actual, err := Snapshot(g.Reverse().Reverse())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Reverse of reverse differs from the graph (-expected +actual):\n%s", diff(string(expected), string(actual)))
+	}
+}
+
+// flat returns a sort as layers of one value each, so that it can be
+// checked with ValidateLayers.
+func flat[T any](order []T, err error) ([][]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	layers := make([][]T, len(order))
+	for i, value := range order {
+		layers[i] = []T{value}
+	}
+	return layers, nil
+}
+
+// checkCycles compares the cycles found by Cycles against the strongly
+// connected components found by searching from each value in turn, which
+// is slow but simple enough to trust.
+func checkCycles[T comparable](t testing.TB, g *topo.Graph[T], cycles [][]T) {
+	t.Helper()
+	nodes := g.Nodes()
+	reaches := make(map[T]map[T]bool, len(nodes))
+	for _, value := range nodes {
+		seen := make(map[T]bool)
+		stack := slices.Clone(g.Dependencies(value))
+		for len(stack) > 0 {
+			next := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, g.Dependencies(next)...)
+			}
+		}
+		reaches[value] = seen
+	}
+
+	var expected [][]T
+	grouped := make(map[T]bool)
+	for _, value := range nodes {
+		if grouped[value] || !reaches[value][value] {
+			continue
+		}
+		var group []T
+		for _, other := range nodes {
+			if reaches[value][other] && reaches[other][value] {
+				group = append(group, other)
+				grouped[other] = true
+			}
+		}
+		expected = append(expected, group)
+	}
+
+	if !slices.EqualFunc(sortedText(expected), sortedText(cycles), slices.Equal) {
+		t.Errorf("Expected cycles %v, got %v", expected, cycles)
+	}
+}
@@ -0,0 +1,62 @@
+package topotest_test
+
+import (
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+	"github.com/sam-fredrickson/go-topo/topotest"
+)
+
+// TestCheckInvariants checks that graphs of every shape pass.
+func TestCheckInvariants(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(g *topo.Graph[string])
+	}{
+		{"empty", func(*topo.Graph[string]) {}},
+		{"chain", func(g *topo.Graph[string]) {
+			g.AddNode("app", []string{"lib"})
+			g.AddNode("lib", []string{"base"})
+		}},
+		{"diamond", func(g *topo.Graph[string]) {
+			g.AddNode("app", []string{"left", "right"})
+			g.AddNode("left", []string{"base"})
+			g.AddNode("right", []string{"base"})
+		}},
+		{"kinds", func(g *topo.Graph[string]) {
+			g.AddNode("app", []string{"lib"})
+			g.AddNodeOfKind("app", "runtime", []string{"db"})
+		}},
+		{"cycle", func(g *topo.Graph[string]) {
+			g.AddNode("a", []string{"b"})
+			g.AddNode("b", []string{"c"})
+			g.AddNode("c", []string{"a"})
+			g.AddNode("d", []string{"a"})
+		}},
+		{"self-loop", func(g *topo.Graph[string]) {
+			g.AddNode("a", []string{"a", "b"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			tt.build(&g)
+			topotest.CheckInvariants(t, &g)
+		})
+	}
+}
+
+// FuzzCheckInvariants checks graphs built from arbitrary bytes, each pair
+// of which is a node and a dependency.
+func FuzzCheckInvariants(f *testing.F) {
+	f.Add([]byte{1, 2, 2, 3})
+	f.Add([]byte{1, 2, 2, 1, 3, 3})
+	f.Add([]byte{0, 1, 0, 2, 1, 3, 2, 3, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var g topo.Graph[byte]
+		for i := 0; i+1 < len(data); i += 2 {
+			g.AddNode(data[i]%16, []byte{data[i+1] % 16})
+		}
+		topotest.CheckInvariants(t, &g)
+	})
+}
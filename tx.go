@@ -0,0 +1,94 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrUndeclaredDependency is returned by Apply when a dependency was never
+// added as a node itself.
+var ErrUndeclaredDependency = errors.New("undeclared dependency")
+
+// Tx is a transaction started by Apply. It's a working copy of the graph:
+// it can be changed and read like any graph, and its changes are only kept
+// if the whole transaction succeeds.
+type Tx[T comparable] struct {
+	Graph[T]
+}
+
+// Apply changes the graph in a transaction, so that a set of changes, like
+// those from reloading a configuration, is applied all at once or not at
+// all. The changes fn makes to tx are checked together once it returns:
+// the resulting graph must have no cycles, and every dependency must have
+// been added as a node itself. If fn returns an error, or the checks fail,
+// the graph is left as it was and the error is returned; the checks' errors
+// wrap ErrCyclicDependency or ErrUndeclaredDependency.
+//
+// Observers of the graph are only notified once the transaction succeeds,
+// of each change it made, in order.
+func (g *Graph[T]) Apply(fn func(tx *Tx[T]) error) error {
+	tx, changes := g.begin()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	var errs []error
+	if cycles := tx.Cycles(); len(cycles) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrCyclicDependency, cycles))
+	}
+	if undeclared := tx.undeclared(); len(undeclared) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrUndeclaredDependency, undeclared))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	g.commit(tx, *changes)
+	return nil
+}
+
+// begin starts a transaction, returning the changes made in it so far.
+func (g *Graph[T]) begin() (*Tx[T], *[]Change[T]) {
+	tx := &Tx[T]{Graph[T]{
+		nodes:      slices.Clone(g.nodes),
+		pins:       maps.Clone(g.pins),
+		attrs:      cloneAttrs(g.attrs, nil),
+		normalizer: g.normalizer,
+		zeroGuard:  g.zeroGuard,
+	}}
+	var changes []Change[T]
+	tx.Observe(func(c Change[T]) {
+		changes = append(changes, c)
+	})
+	return tx, &changes
+}
+
+// commit keeps the changes made in a transaction, notifying observers.
+func (g *Graph[T]) commit(tx *Tx[T], changes []Change[T]) {
+	g.record()
+	g.nodes = tx.nodes
+	g.pins = tx.pins
+	g.attrs = tx.attrs
+	g.invalidate()
+	for _, c := range changes {
+		g.notify(c)
+	}
+}
+
+// undeclared returns the values that are only in the graph as
+// dependencies, in the order they were first added.
+func (g *Graph[T]) undeclared() []T {
+	declared := make(map[T]bool)
+	for _, n := range g.nodes {
+		declared[n.value] = true
+	}
+	var undeclared []T
+	for _, value := range g.Nodes() {
+		if !declared[value] {
+			undeclared = append(undeclared, value)
+		}
+	}
+	return undeclared
+}
@@ -0,0 +1,110 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestApply checks that a successful transaction is applied and observed.
+func TestApply(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib"})
+	g.AddNode("lib", nil)
+	var ops []topo.ChangeOp
+	g.Observe(func(c topo.Change[string]) { ops = append(ops, c.Op) })
+
+	err := g.Apply(func(tx *topo.Tx[string]) error {
+		tx.AddNode("db", nil)
+		tx.AddDependency("app", "db")
+		if len(ops) != 0 {
+			t.Errorf("Expected no changes observed yet, got %v", ops)
+		}
+		if deps := tx.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+			t.Errorf("Expected [lib db] in transaction, got %v", deps)
+		}
+		if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib"}) {
+			t.Errorf("Expected [lib] outside transaction, got %v", deps)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deps := g.Dependencies("app"); !reflect.DeepEqual(deps, []string{"lib", "db"}) {
+		t.Errorf("Expected [lib db], got %v", deps)
+	}
+	expected := []topo.ChangeOp{topo.OpAddNode, topo.OpAddDependency}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Expected %v, got %v", expected, ops)
+	}
+}
+
+// TestApplyRollback checks that failed transactions change nothing.
+func TestApplyRollback(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name     string
+		fn       func(tx *topo.Tx[string]) error
+		expected error
+		message  string
+	}{
+		{
+			name: "error",
+			fn: func(tx *topo.Tx[string]) error {
+				tx.RemoveNode("lib")
+				return failed
+			},
+			expected: failed,
+			message:  "failed",
+		},
+		{
+			name: "cycle",
+			fn: func(tx *topo.Tx[string]) error {
+				tx.AddNode("lib", []string{"app"})
+				return nil
+			},
+			expected: topo.ErrCyclicDependency,
+			message:  "cyclic dependency detected: [[app lib]]",
+		},
+		{
+			name: "undeclared",
+			fn: func(tx *topo.Tx[string]) error {
+				tx.AddDependency("lib", "base")
+				tx.RemoveNode("db")
+				return nil
+			},
+			expected: topo.ErrUndeclaredDependency,
+			message:  "undeclared dependency: [base]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g topo.Graph[string]
+			g.AddNode("app", []string{"lib"})
+			g.AddNode("lib", nil)
+			g.AddNode("db", nil)
+			observed := 0
+			g.Observe(func(topo.Change[string]) { observed++ })
+
+			err := g.Apply(tt.fn)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, err)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("Expected %q, got %q", tt.message, err.Error())
+			}
+			if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app", "lib", "db"}) {
+				t.Errorf("Expected graph unchanged, got %v", nodes)
+			}
+			if deps := g.Dependencies("lib"); len(deps) != 0 {
+				t.Errorf("Expected lib unchanged, got %v", deps)
+			}
+			if observed != 0 {
+				t.Errorf("Expected no changes observed, got %d", observed)
+			}
+		})
+	}
+}
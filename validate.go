@@ -0,0 +1,264 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrInvalidLayers is returned by ValidateLayers for each problem with a
+// layering.
+var ErrInvalidLayers = errors.New("invalid layers")
+
+// Severity is how serious an Issue is.
+type Severity int
+
+const (
+	// SeverityInfo marks issues that are worth knowing about but harmless,
+	// like redundant edges.
+	SeverityInfo Severity = iota
+	// SeverityWarning marks issues that are probably mistakes but don't
+	// stop the graph from being sorted.
+	SeverityWarning
+	// SeverityError marks issues that stop the graph from being sorted.
+	SeverityError
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// IssueKind is the kind of problem an Issue describes.
+type IssueKind int
+
+const (
+	// IssueCycle is a group of nodes that depend on each other. Nodes holds
+	// the group, as returned by Cycles.
+	IssueCycle IssueKind = iota
+	// IssueSelfLoop is a node that depends on itself. Nodes holds the node.
+	IssueSelfLoop
+	// IssueConflictingDeclaration is a node added more than once with
+	// different dependencies; only the last are used. Nodes holds the node.
+	IssueConflictingDeclaration
+	// IssueDuplicateEdge is a dependency listed more than once for the same
+	// node. Nodes holds the node and the dependency.
+	IssueDuplicateEdge
+	// IssueUndeclaredDependency is a dependency that was never added as a
+	// node itself. Nodes holds the dependency.
+	IssueUndeclaredDependency
+	// IssueOrphan is a node without dependencies that nothing depends on.
+	// Nodes holds the node.
+	IssueOrphan
+	// IssueRedundantEdge is a dependency that is already implied by
+	// another, transitive dependency. Nodes holds the node and the
+	// dependency.
+	IssueRedundantEdge
+)
+
+// String returns a short description of the kind of issue.
+func (k IssueKind) String() string {
+	switch k {
+	case IssueCycle:
+		return "cycle"
+	case IssueSelfLoop:
+		return "self-loop"
+	case IssueConflictingDeclaration:
+		return "conflicting declaration"
+	case IssueDuplicateEdge:
+		return "duplicate edge"
+	case IssueUndeclaredDependency:
+		return "undeclared dependency"
+	case IssueOrphan:
+		return "orphan"
+	case IssueRedundantEdge:
+		return "redundant edge"
+	default:
+		return fmt.Sprintf("IssueKind(%d)", int(k))
+	}
+}
+
+// Issue is a structural problem found by Validate.
+type Issue[T comparable] struct {
+	Kind     IssueKind
+	Severity Severity
+	// Nodes are the nodes involved, as described by each IssueKind.
+	Nodes []T
+}
+
+// String describes the issue, like "warning: duplicate edge: [app lib]".
+func (i Issue[T]) String() string {
+	return fmt.Sprintf("%s: %s: %v", i.Severity, i.Kind, i.Nodes)
+}
+
+// Validate checks the graph for structural problems and returns all of
+// them, rather than stopping at the first. Issues are grouped by kind, in
+// the order of the IssueKind constants, and within a kind are in the order
+// the nodes were first added. A graph without problems returns nil.
+func (g *Graph[T]) Validate() []Issue[T] {
+	var issues []Issue[T]
+	add := func(kind IssueKind, severity Severity, nodes ...T) {
+		issues = append(issues, Issue[T]{Kind: kind, Severity: severity, Nodes: nodes})
+	}
+	order, dependsOn := g.edges()
+
+	selfLoops := make(map[T]bool)
+	for _, value := range order {
+		if slices.Contains(dependsOn[value], value) {
+			selfLoops[value] = true
+		}
+	}
+	inCycle := make(map[T]bool)
+	for _, cycle := range g.Cycles() {
+		for _, value := range cycle {
+			inCycle[value] = true
+		}
+		if len(cycle) > 1 {
+			add(IssueCycle, SeverityError, cycle...)
+		}
+	}
+	for _, value := range order {
+		if selfLoops[value] {
+			add(IssueSelfLoop, SeverityError, value)
+		}
+	}
+
+	// declarations of each kind, in order of each node's first one
+	declared := make(map[T]bool)
+	byKind := make(map[kindKey[T]][][]T)
+	for _, node := range g.nodes {
+		declared[node.value] = true
+		key := kindKey[T]{node.value, node.kind}
+		byKind[key] = append(byKind[key], node.deps)
+	}
+	_, kinds := g.declarationsByValue()
+	for _, value := range order {
+		if conflicting(kinds, func(kind EdgeKind) [][]T {
+			return byKind[kindKey[T]{value, kind}]
+		}) {
+			add(IssueConflictingDeclaration, SeverityWarning, value)
+		}
+	}
+
+	for _, d := range g.Duplicates() {
+		add(IssueDuplicateEdge, SeverityWarning, d.Value, d.Dep)
+	}
+
+	for _, value := range order {
+		if !declared[value] {
+			add(IssueUndeclaredDependency, SeverityWarning, value)
+		}
+	}
+	for _, value := range g.Isolated() {
+		add(IssueOrphan, SeverityInfo, value)
+	}
+
+	// an edge is redundant if its dependency can be reached through one of
+	// the node's other dependencies; within cycles, everything is
+	// reachable, so they're left to IssueCycle
+	for _, value := range order {
+		if inCycle[value] {
+			continue
+		}
+		deps := dependsOn[value]
+		for i, dep := range deps {
+			if slices.Index(deps, dep) != i {
+				continue
+			}
+			others := slices.DeleteFunc(slices.Clone(deps), func(d T) bool { return d == dep })
+			if slices.Contains(reachable(order, dependsOn, others), dep) {
+				add(IssueRedundantEdge, SeverityInfo, value, dep)
+			}
+		}
+	}
+
+	return issues
+}
+
+// ValidateLayers checks that a layering, such as an order written by hand,
+// is valid for the graph: every value is in exactly one layer, there are no
+// values the graph doesn't have, and each value is in a later layer than
+// all of its dependencies. It returns every problem found, each wrapping
+// ErrInvalidLayers, joined with errors.Join, or nil if the layering is
+// valid. Problems with the layers' contents come first, in layer order,
+// then missing values and misplaced dependencies, in the order values were
+// first added to the graph.
+func (g *Graph[T]) ValidateLayers(layers [][]T) error {
+	var errs []error
+	order, dependsOn := g.edges()
+	known := make(map[T]bool, len(order))
+	for _, value := range order {
+		known[value] = true
+	}
+
+	layerOf := make(map[T]int)
+	for i, layer := range layers {
+		for _, value := range layer {
+			if !known[value] {
+				errs = append(errs, fmt.Errorf("%w: %v in layer %d is not in the graph", ErrInvalidLayers, value, i))
+				continue
+			}
+			if j, ok := layerOf[value]; ok {
+				errs = append(errs, fmt.Errorf("%w: %v is in layer %d and layer %d", ErrInvalidLayers, value, j, i))
+				continue
+			}
+			layerOf[value] = i
+		}
+	}
+	for _, value := range order {
+		if _, ok := layerOf[value]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %v is in no layer", ErrInvalidLayers, value))
+		}
+	}
+	for _, value := range order {
+		i, ok := layerOf[value]
+		if !ok {
+			continue
+		}
+		for _, dep := range dependsOn[value] {
+			if j, ok := layerOf[dep]; ok && j >= i {
+				errs = append(errs, fmt.Errorf("%w: %v in layer %d depends on %v in layer %d", ErrInvalidLayers, value, i, dep, j))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// conflicting reports whether any kind of a node was declared with
+// different dependencies.
+func conflicting[T comparable](kinds []EdgeKind, declsOf func(EdgeKind) [][]T) bool {
+	for _, kind := range kinds {
+		decls := declsOf(kind)
+		for i := 1; i < len(decls); i++ {
+			if !sameSet(decls[i], decls[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameSet reports whether a and b hold the same values, ignoring order and
+// repetition.
+func sameSet[T comparable](a, b []T) bool {
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			return false
+		}
+	}
+	for _, v := range b {
+		if !slices.Contains(a, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestValidate checks that every kind of issue is found in one pass.
+func TestValidate(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "base", "lib"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("tool", []string{"x"})
+	g.AddNode("tool", []string{"base", "ext"})
+	g.AddNode("base", nil)
+	g.AddNode("lonely", nil)
+	g.AddNode("self", []string{"self"})
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+
+	expected := []topo.Issue[string]{
+		{Kind: topo.IssueCycle, Severity: topo.SeverityError, Nodes: []string{"a", "b"}},
+		{Kind: topo.IssueSelfLoop, Severity: topo.SeverityError, Nodes: []string{"self"}},
+		{Kind: topo.IssueConflictingDeclaration, Severity: topo.SeverityWarning, Nodes: []string{"tool"}},
+		{Kind: topo.IssueDuplicateEdge, Severity: topo.SeverityWarning, Nodes: []string{"app", "lib"}},
+		{Kind: topo.IssueUndeclaredDependency, Severity: topo.SeverityWarning, Nodes: []string{"x"}},
+		{Kind: topo.IssueUndeclaredDependency, Severity: topo.SeverityWarning, Nodes: []string{"ext"}},
+		{Kind: topo.IssueOrphan, Severity: topo.SeverityInfo, Nodes: []string{"x"}},
+		{Kind: topo.IssueOrphan, Severity: topo.SeverityInfo, Nodes: []string{"lonely"}},
+		{Kind: topo.IssueRedundantEdge, Severity: topo.SeverityInfo, Nodes: []string{"app", "base"}},
+	}
+	issues := g.Validate()
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Expected issues:\n%v\ngot:\n%v", expected, issues)
+	}
+}
+
+// TestValidateClean checks that a well-formed graph has no issues.
+func TestValidateClean(t *testing.T) {
+	var g topo.Graph[int]
+	g.AddNode(1, nil)
+	g.AddNode(2, []int{1})
+	g.AddNode(3, []int{1})
+	g.AddNode(4, []int{2, 3})
+	if issues := g.Validate(); issues != nil {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+// TestIssueString checks the description of an issue.
+func TestIssueString(t *testing.T) {
+	issue := topo.Issue[string]{
+		Kind:     topo.IssueRedundantEdge,
+		Severity: topo.SeverityInfo,
+		Nodes:    []string{"app", "base"},
+	}
+	expected := "info: redundant edge: [app base]"
+	if issue.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, issue.String())
+	}
+}
+
+// TestValidateLayers checks hand-written layerings against a graph.
+func TestValidateLayers(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+
+	tests := []struct {
+		name     string
+		layers   [][]string
+		expected []string
+	}{
+		{"valid", [][]string{{"base", "db"}, {"lib"}, {"app"}}, nil},
+		{"valid but not tight", [][]string{{"base"}, {"db"}, {"lib"}, {}, {"app"}}, nil},
+		{
+			"unknown and duplicate",
+			[][]string{{"base", "db", "cache"}, {"lib", "base"}, {"app"}},
+			[]string{
+				"invalid layers: cache in layer 0 is not in the graph",
+				"invalid layers: base is in layer 0 and layer 1",
+			},
+		},
+		{
+			"missing",
+			[][]string{{"base", "db"}, {"app"}},
+			[]string{
+				"invalid layers: lib is in no layer",
+			},
+		},
+		{
+			"misplaced dependencies",
+			[][]string{{"base", "db", "lib"}, {"app"}},
+			[]string{
+				"invalid layers: lib in layer 0 depends on base in layer 0",
+			},
+		},
+		{
+			"reversed",
+			[][]string{{"app"}, {"lib"}, {"base", "db"}},
+			[]string{
+				"invalid layers: app in layer 0 depends on lib in layer 1",
+				"invalid layers: app in layer 0 depends on db in layer 2",
+				"invalid layers: lib in layer 1 depends on base in layer 2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := g.ValidateLayers(tt.layers)
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, topo.ErrInvalidLayers) {
+				t.Fatalf("Expected %v, got %v", topo.ErrInvalidLayers, err)
+			}
+			if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
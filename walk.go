@@ -0,0 +1,124 @@
+package topo
+
+import "errors"
+
+// SkipBranch can be returned by Visitor.Pre to skip the values beyond the
+// current one. Values that can also be reached another way are still
+// visited.
+var SkipBranch = errors.New("skip this branch")
+
+// SkipAll can be returned by a Visitor's callbacks to stop a walk early.
+// The walk then returns nil.
+var SkipAll = errors.New("skip everything")
+
+// Visitor holds the callbacks of a walk through a graph. Either callback
+// may be nil. Any error other than SkipBranch or SkipAll stops the walk and
+// is returned from it.
+type Visitor[T comparable] struct {
+	// Pre is called when a value is first reached, before the values
+	// beyond it.
+	Pre func(value T) error
+	// Post is called after every value beyond this one has been visited,
+	// even if Pre returned SkipBranch. It isn't called in breadth-first
+	// walks.
+	Post func(value T) error
+	// BreadthFirst visits values in order of their distance from the
+	// start, rather than following each branch to its end first.
+	BreadthFirst bool
+}
+
+// WalkUp visits from and everything it depends on, directly or
+// transitively, with dependencies visited in the order they were declared.
+// Each value is visited once, even in a graph with cycles.
+func (g *Graph[T]) WalkUp(from T, visit Visitor[T]) error {
+	_, dependsOn := g.edges()
+	return visit.walk(from, dependsOn)
+}
+
+// WalkDown visits from and everything that depends on it, directly or
+// transitively, with dependents visited in the order they were first added
+// to the graph. Each value is visited once, even in a graph with cycles.
+func (g *Graph[T]) WalkDown(from T, visit Visitor[T]) error {
+	order, dependsOn := g.edges()
+	dependedOnBy := make(map[T][]T)
+	for _, value := range order {
+		for _, dep := range dependsOn[value] {
+			dependedOnBy[dep] = append(dependedOnBy[dep], value)
+		}
+	}
+	return visit.walk(from, dependedOnBy)
+}
+
+func (v Visitor[T]) walk(from T, next map[T][]T) error {
+	var err error
+	if v.BreadthFirst {
+		err = v.breadthFirst(from, next)
+	} else {
+		err = v.depthFirst(from, next, make(map[T]bool))
+	}
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (v Visitor[T]) depthFirst(value T, next map[T][]T, seen map[T]bool) error {
+	seen[value] = true
+	err := v.pre(value)
+	if errors.Is(err, SkipBranch) {
+		return v.post(value)
+	}
+	if err != nil {
+		return err
+	}
+	for _, n := range next[value] {
+		if seen[n] {
+			continue
+		}
+		if err := v.depthFirst(n, next, seen); err != nil {
+			return err
+		}
+	}
+	return v.post(value)
+}
+
+func (v Visitor[T]) breadthFirst(from T, next map[T][]T) error {
+	seen := map[T]bool{from: true}
+	queue := []T{from}
+	for len(queue) > 0 {
+		value := queue[0]
+		queue = queue[1:]
+		err := v.pre(value)
+		if errors.Is(err, SkipBranch) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, n := range next[value] {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+func (v Visitor[T]) pre(value T) error {
+	if v.Pre == nil {
+		return nil
+	}
+	return v.Pre(value)
+}
+
+// post calls Post, for which SkipBranch is meaningless and ignored.
+func (v Visitor[T]) post(value T) error {
+	if v.Post == nil {
+		return nil
+	}
+	if err := v.Post(value); !errors.Is(err, SkipBranch) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// walkGraph returns a graph for the walk tests:
+//
+//	app -> lib -> base
+//	app -> db -> base
+//	cli -> lib
+func walkGraph() *topo.Graph[string] {
+	var g topo.Graph[string]
+	g.AddNode("app", []string{"lib", "db"})
+	g.AddNode("lib", []string{"base"})
+	g.AddNode("db", []string{"base"})
+	g.AddNode("cli", []string{"lib"})
+	return &g
+}
+
+// TestWalkUp checks walks toward dependencies, with both hooks.
+func TestWalkUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		visitor  func(events *[]string) topo.Visitor[string]
+		expected []string
+	}{
+		{
+			name: "depth first",
+			visitor: func(events *[]string) topo.Visitor[string] {
+				return topo.Visitor[string]{
+					Pre:  func(v string) error { *events = append(*events, "pre "+v); return nil },
+					Post: func(v string) error { *events = append(*events, "post "+v); return nil },
+				}
+			},
+			expected: []string{
+				"pre app", "pre lib", "pre base", "post base", "post lib",
+				"pre db", "post db", "post app",
+			},
+		},
+		{
+			name: "breadth first",
+			visitor: func(events *[]string) topo.Visitor[string] {
+				return topo.Visitor[string]{
+					Pre:          func(v string) error { *events = append(*events, v); return nil },
+					BreadthFirst: true,
+				}
+			},
+			expected: []string{"app", "lib", "db", "base"},
+		},
+		{
+			name: "skip branch",
+			visitor: func(events *[]string) topo.Visitor[string] {
+				return topo.Visitor[string]{
+					Pre: func(v string) error {
+						*events = append(*events, v)
+						if v == "lib" {
+							return topo.SkipBranch
+						}
+						return nil
+					},
+				}
+			},
+			expected: []string{"app", "lib", "db", "base"},
+		},
+		{
+			name: "skip all",
+			visitor: func(events *[]string) topo.Visitor[string] {
+				return topo.Visitor[string]{
+					Pre: func(v string) error {
+						*events = append(*events, v)
+						if v == "lib" {
+							return topo.SkipAll
+						}
+						return nil
+					},
+				}
+			},
+			expected: []string{"app", "lib"},
+		},
+	}
+	g := walkGraph()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			if err := g.WalkUp("app", tt.visitor(&events)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(events, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, events)
+			}
+		})
+	}
+}
+
+// TestWalkDown checks walks toward dependents, and stopping with an error.
+func TestWalkDown(t *testing.T) {
+	g := walkGraph()
+	for _, bfs := range []bool{false, true} {
+		var visited []string
+		err := g.WalkDown("base", topo.Visitor[string]{
+			Pre:          func(v string) error { visited = append(visited, v); return nil },
+			BreadthFirst: bfs,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"base", "lib", "app", "cli", "db"}
+		if bfs {
+			expected = []string{"base", "lib", "db", "app", "cli"}
+		}
+		if !reflect.DeepEqual(visited, expected) {
+			t.Errorf("Expected %v, got %v", expected, visited)
+		}
+	}
+
+	stop := errors.New("stop")
+	err := g.WalkDown("base", topo.Visitor[string]{
+		Post: func(v string) error {
+			if v == "app" {
+				return stop
+			}
+			return nil
+		},
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected %v, got %v", stop, err)
+	}
+}
+
+// TestWalkCycle checks that walks visit each value of a cycle once.
+func TestWalkCycle(t *testing.T) {
+	var g topo.Graph[string]
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"c"})
+	g.AddNode("c", []string{"a"})
+
+	var visited []string
+	err := g.WalkUp("a", topo.Visitor[string]{
+		Pre: func(v string) error { visited = append(visited, v); return nil },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
@@ -0,0 +1,49 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrZeroValue is passed to the function given to WithZeroValueGuard when
+// the zero value is added to the graph.
+var ErrZeroValue = errors.New("zero value added")
+
+// WithZeroValueGuard makes the graph call fn whenever the zero value of T
+// is added as a node or a dependency, like an empty string from a blank
+// field in a configuration, which would otherwise quietly become a node.
+// fn is given an error wrapping ErrZeroValue that says where it was added,
+// and returns whether to add it anyway: a guard that only warns logs the
+// error and returns true, and one that rejects zero values returns false.
+//
+// A rejected node isn't added at all, and a rejected dependency is left out
+// of the dependencies it was added with. In a changeset applied to the
+// graph, a rejected change makes ApplyTo fail. Like WithKeyNormalizer, it
+// applies to what's added after it's set, and it returns the graph.
+func (g *Graph[T]) WithZeroValueGuard(fn func(err error) bool) *Graph[T] {
+	g.zeroGuard = fn
+	return g
+}
+
+// allowNode reports whether a node can be added, by the zero value guard.
+func (g *Graph[T]) allowNode(value T) bool {
+	var zero T
+	if g.zeroGuard == nil || value != zero {
+		return true
+	}
+	return g.zeroGuard(fmt.Errorf("%w as a node", ErrZeroValue))
+}
+
+// allowDeps returns the dependencies of a node that the zero value guard
+// allows.
+func (g *Graph[T]) allowDeps(value T, deps []T) []T {
+	var zero T
+	if g.zeroGuard == nil || !slices.Contains(deps, zero) {
+		return deps
+	}
+	if g.zeroGuard(fmt.Errorf("%w as a dependency of %v", ErrZeroValue, value)) {
+		return deps
+	}
+	return slices.DeleteFunc(slices.Clone(deps), func(dep T) bool { return dep == zero })
+}
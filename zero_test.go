@@ -0,0 +1,68 @@
+package topo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sam-fredrickson/go-topo"
+)
+
+// TestZeroValueGuard checks warning about and rejecting zero values.
+func TestZeroValueGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    bool
+		nodes    []string
+		warnings []string
+	}{
+		{"warn", true, []string{"app", "", "lib"}, []string{
+			"zero value added as a dependency of app",
+			"zero value added as a node",
+			"zero value added as a dependency of lib",
+		}},
+		{"reject", false, []string{"app", "lib"}, []string{
+			"zero value added as a dependency of app",
+			"zero value added as a node",
+			"zero value added as a dependency of lib",
+		}},
+	}
+	for _, test := range tests {
+		var warnings []string
+		g := new(topo.Graph[string]).WithZeroValueGuard(func(err error) bool {
+			if !errors.Is(err, topo.ErrZeroValue) {
+				t.Errorf("%s: expected ErrZeroValue, got %v", test.name, err)
+			}
+			warnings = append(warnings, err.Error())
+			return test.allow
+		})
+		g.AddNode("app", []string{"", "lib"})
+		g.AddNode("", nil)
+		g.AddDependency("lib", "")
+
+		if nodes := g.Nodes(); !reflect.DeepEqual(nodes, test.nodes) {
+			t.Errorf("%s: expected nodes %v, got %v", test.name, test.nodes, nodes)
+		}
+		if !reflect.DeepEqual(warnings, test.warnings) {
+			t.Errorf("%s: expected warnings %v, got %v", test.name, test.warnings, warnings)
+		}
+	}
+}
+
+// TestZeroValueGuardChangeset checks that a rejected change fails the
+// changeset.
+func TestZeroValueGuardChangeset(t *testing.T) {
+	g := new(topo.Graph[string]).WithZeroValueGuard(func(error) bool { return false })
+	g.AddNode("app", nil)
+
+	cs := topo.Changeset[string]{
+		{Op: topo.OpAddNode, Value: "lib"},
+		{Op: topo.OpAddDependency, Value: "app", Deps: []string{""}},
+	}
+	if err := cs.ApplyTo(g); !errors.Is(err, topo.ErrChangeConflict) {
+		t.Errorf("Expected ErrChangeConflict, got %v", err)
+	}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, []string{"app"}) {
+		t.Errorf("Expected [app], got %v", nodes)
+	}
+}